@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -9,12 +10,29 @@ import (
 	"time"
 
 	"github.com/aescanero/dago-adapters/pkg/llm"
+	"github.com/aescanero/dago-libs/pkg/ports"
+	"github.com/aescanero/dago-node-executor/internal/cassette"
 	"github.com/aescanero/dago-node-executor/internal/config"
 	"github.com/aescanero/dago-node-executor/internal/executor"
+	"github.com/aescanero/dago-node-executor/internal/fakellm"
+	"github.com/aescanero/dago-node-executor/internal/metrics"
+	"github.com/aescanero/dago-node-executor/internal/queue/natsqueue"
+	"github.com/aescanero/dago-node-executor/internal/queue/redisqueue"
+	"github.com/aescanero/dago-node-executor/internal/ratelimit"
+	"github.com/aescanero/dago-node-executor/internal/toolaccess"
+	"github.com/aescanero/dago-node-executor/internal/toolchain"
+	"github.com/aescanero/dago-node-executor/internal/tracing"
 	"github.com/aescanero/dago-node-executor/internal/worker"
+	"github.com/aescanero/dago-node-executor/pkg/queue"
+	"github.com/aescanero/dago-node-executor/pkg/queue/memqueue"
+	"github.com/aescanero/dago-node-executor/pkg/routing"
+	"github.com/aescanero/dago-node-executor/pkg/tools/api"
+	"github.com/aescanero/dago-node-executor/pkg/tools/builtin"
+	"github.com/aescanero/dago-node-executor/pkg/tools/catalog"
 	"github.com/aescanero/dago-node-executor/pkg/tools/function"
 	"github.com/aescanero/dago-node-executor/pkg/tools/mcp"
 
+	"github.com/nats-io/nats.go"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -27,6 +45,19 @@ var (
 )
 
 func main() {
+	// Subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "run":
+			os.Exit(runCommand(os.Args[2:]))
+		case "validate":
+			os.Exit(validateCommand(os.Args[2:]))
+		case "version":
+			fmt.Printf("executor-worker %s (built %s)\n", Version, BuildTime)
+			return
+		}
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -35,7 +66,7 @@ func main() {
 	}
 
 	// Initialize logger
-	logger := initLogger(cfg.LogLevel)
+	logger, logLevel := initLogger(cfg.LogLevel)
 	defer func() { _ = logger.Sync() }()
 
 	logger.Info("starting executor worker",
@@ -43,53 +74,120 @@ func main() {
 		zap.String("build_time", BuildTime),
 		zap.String("worker_id", cfg.WorkerID))
 
-	// Initialize Redis client
-	redisClient := redis.NewClient(&redis.Options{
-		Addr:     cfg.RedisAddr,
-		Password: cfg.RedisPass,
-		DB:       cfg.RedisDB,
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Config{
+		Enabled:     cfg.TracingEnabled,
+		Endpoint:    cfg.TracingEndpoint,
+		Insecure:    cfg.TracingInsecure,
+		ServiceName: cfg.TracingServiceName,
+		Version:     Version,
+		SampleRatio: cfg.TracingSampleRatio,
 	})
+	if err != nil {
+		logger.Fatal("failed to initialize tracing", zap.Error(err))
+	}
 
-	// Test Redis connection
-	ctx := context.Background()
-	if err := redisClient.Ping(ctx).Err(); err != nil {
-		logger.Fatal("failed to connect to Redis", zap.Error(err))
+	// Initialize metrics
+	m := metrics.New()
+
+	// Initialize queue backends
+	backends, err := newQueueBackends(context.Background(), cfg, logger, m)
+	if err != nil {
+		logger.Fatal("failed to initialize queue backend", zap.Error(err))
 	}
-	logger.Info("connected to Redis", zap.String("addr", cfg.RedisAddr))
 
 	// Initialize LLM client using dago-adapters
-	llmClient, err := llm.NewClient(&llm.Config{
-		Provider: cfg.LLMProvider,
-		APIKey:   cfg.LLMAPIKey,
-		Logger:   logger,
-	})
+	llmClient, err := newLLMClient(cfg, logger)
 	if err != nil {
 		logger.Fatal("failed to create LLM client", zap.Error(err))
 	}
 
 	// Initialize tool clients
-	mcpClient := mcp.NewClient(cfg.GetMCPServers(), logger)
-	functionRegistry := function.NewRegistry(logger)
-
-	// Register built-in tools
-	registerBuiltInTools(functionRegistry)
-
-	// Create composite tool client (try MCP first, then function registry)
-	toolClient := newCompositeToolClient(mcpClient, functionRegistry, logger)
+	workspaces := newWorkspaces(cfg)
+	if workspaces != nil {
+		// Fail at startup rather than on the first fs_write
+		if err := os.MkdirAll(workspaces.Dir(), 0o700); err != nil {
+			logger.Fatal("failed to create workspace directory", zap.Error(err))
+		}
+	}
+	mcpClient, toolClient, err := newToolClient(cfg, newScratchStore(cfg, backends), workspaces, logger)
+	if err != nil {
+		logger.Fatal("failed to load tool catalog", zap.Error(err))
+	}
+	m.SetToolFilter(func(name string) bool {
+		_, ok := toolClient.Lookup(name)
+		return ok
+	})
+	toolLimiter := newToolLimiter(cfg, backends, logger, m)
+	toolCache := toolchain.NewCache(toolCacheTTLs(cfg), cfg.ToolCacheSize)
+	toolAccess := toolaccess.NewEnforcer(toolAccessPolicies(cfg), logger, m)
+	tools := newToolChain(cfg, toolClient, toolAccess, toolCache, toolLimiter, logger)
 
 	// Initialize executor
-	exec := executor.NewExecutor(llmClient, toolClient, logger, cfg.MaxIterations)
+	execOpts := []executor.Option{
+		executor.WithMetrics(m),
+		executor.WithSettings(executorSettings(cfg)),
+		executor.WithValidation(executor.ValidationPolicy(cfg.NodeValidation)),
+	}
+	if limiter := newRateLimiter(cfg, backends, logger, m); limiter != nil {
+		execOpts = append(execOpts, executor.WithRateLimiter(limiter))
+	}
+	exec := executor.NewExecutor(llmClient, tools, logger, cfg.MaxIterations, execOpts...)
+
+	// Join the routing registry
+	routingCtx, stopRouting := context.WithCancel(context.Background())
+	defer stopRouting()
+	var member *routing.Member
+	var capabilities worker.RequirementChecker
+	var router worker.Router
+	if cfg.RoutingEnabled {
+		member, err = joinRouting(routingCtx, cfg, backends, toolClient, logger)
+		if err != nil {
+			logger.Fatal("failed to enable routing", zap.Error(err))
+		}
+		capabilities = member
+		router = member.Registry()
+	}
 
 	// Create worker
+	var artifacts worker.ArtifactExporter
+	if workspaces != nil {
+		artifacts = workspaces
+	}
 	w := worker.NewWorker(&worker.Config{
-		ID:          cfg.WorkerID,
-		RedisClient: redisClient,
-		Executor:    exec,
-		Logger:      logger,
+		ID:           cfg.WorkerID,
+		Source:       backends.source,
+		Events:       backends.events,
+		State:        backends.state,
+		Executor:     exec,
+		Logger:       logger,
+		Metrics:      m,
+		Capabilities: capabilities,
+		Router:       router,
+		Artifacts:    artifacts,
+
+		LoopStallTimeout: cfg.LoopStallTimeout,
 	})
 
 	// Start health server
 	healthServer := worker.NewHealthServer(w, cfg.HealthPort, logger)
+	healthServer.Handle("/metrics", m.Handler())
+	if mem, ok := backends.source.(*memqueue.Backend); ok {
+		if cfg.AdminToken != "" {
+			healthServer.Handle("/queue/", memqueue.Handler(mem, cfg.AdminToken))
+		} else {
+			logger.Info("queue API disabled (ADMIN_TOKEN not set)")
+		}
+	}
+	healthServer.AddCheck(worker.NewMCPCheck(mcpClient), false)
+	if cfg.LLMCheckInterval > 0 && !cfg.OfflineLLM() {
+		healthServer.AddCheck(worker.NewLLMCheck(llmClient, cfg.LLMModel, cfg.LLMCheckInterval), false)
+	}
+	if cfg.AdminToken != "" {
+		healthServer.Handle("/admin/", worker.NewAdminHandler(w, cfg.AdminToken, logLevel, logger))
+	} else {
+		logger.Info("admin API disabled (ADMIN_TOKEN not set)")
+	}
 	if err := healthServer.Start(); err != nil {
 		logger.Fatal("failed to start health server", zap.Error(err))
 	}
@@ -99,16 +197,45 @@ func main() {
 		logger.Fatal("failed to start worker", zap.Error(err))
 	}
 
+	if member != nil {
+		go member.Run(routingCtx)
+	}
+
+	sweepCtx, stopSweeping := context.WithCancel(context.Background())
+	defer stopSweeping()
+	if workspaces != nil {
+		go sweepWorkspaces(sweepCtx, workspaces, backends.state, cfg.WorkspaceSweepInterval, logger)
+	}
+
 	logger.Info("executor worker started",
 		zap.String("worker_id", cfg.WorkerID),
 		zap.Int("health_port", cfg.HealthPort))
 
-	// Wait for interrupt signal
+	// Reload safe settings on SIGHUP or config file change
+	watcher := config.NewWatcher(cfg, logger)
+	watcher.OnReload(func(c *config.Config) {
+		logLevel.SetLevel(parseLogLevel(c.LogLevel))
+		exec.UpdateSettings(executorSettings(c))
+		mcpClient.SetServers(mcpServers(c))
+		toolAccess.SetPolicies(toolAccessPolicies(c))
+		toolCache.SetTTLs(toolCacheTTLs(c))
+		if toolLimiter != nil {
+			toolLimiter.SetPolicies(toolPolicies(c))
+		}
+	})
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	go watcher.Run(watchCtx)
+
+	// Wait for interrupt signal or a completed drain
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
-	<-sigCh
-
-	logger.Info("received shutdown signal")
+	select {
+	case <-sigCh:
+		logger.Info("received shutdown signal")
+	case <-w.Drained():
+		logger.Info("worker drained, shutting down")
+	}
 
 	// Graceful shutdown
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -119,99 +246,648 @@ func main() {
 		logger.Error("health server shutdown error", zap.Error(err))
 	}
 
+	// Stop receiving routed work before draining
+	stopRouting()
+	if member != nil {
+		if err := member.Leave(shutdownCtx); err != nil {
+			logger.Error("routing registry leave error", zap.Error(err))
+		}
+	}
+
 	if err := w.Stop(shutdownCtx); err != nil {
 		logger.Error("worker shutdown error", zap.Error(err))
 	}
 
-	if err := redisClient.Close(); err != nil {
-		logger.Error("Redis close error", zap.Error(err))
+	if err := backends.Close(); err != nil {
+		logger.Error("queue backend close error", zap.Error(err))
+	}
+
+	if err := mcpClient.Close(); err != nil {
+		logger.Error("MCP client close error", zap.Error(err))
+	}
+
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error("tracing shutdown error", zap.Error(err))
 	}
 
 	logger.Info("executor worker shut down complete")
 }
 
-// initLogger initializes the logger
-func initLogger(level string) *zap.Logger {
-	var zapLevel zapcore.Level
+// queueBackends holds the worker's work source, event sink and state store
+type queueBackends struct {
+	source queue.WorkSource
+	events queue.EventSink
+	state  queue.StateStore
+
+	// redis and workStreams are set when Redis backs the work source or
+	// the state store
+	redis       redis.UniversalClient
+	workStreams []redisqueue.Stream
+
+	// closers run in reverse order on shutdown
+	closers []func() error
+}
+
+// Close closes the backends and their connections
+func (b *queueBackends) Close() error {
+	var errs []error
+	for i := len(b.closers) - 1; i >= 0; i-- {
+		errs = append(errs, b.closers[i]())
+	}
+	return errors.Join(errs...)
+}
+
+// newQueueBackends creates the configured queue backends
+func newQueueBackends(ctx context.Context, cfg *config.Config, logger *zap.Logger, m *metrics.Metrics) (*queueBackends, error) {
+	switch cfg.QueueBackend {
+	case "memory":
+		logger.Warn("using the in-memory queue backend; work, events and state are lost on restart")
+		backend := memqueue.New()
+		return &queueBackends{source: backend, events: backend, state: backend}, nil
+
+	case "nats":
+		// Graph state stays on Redis
+		backends, err := newRedisBackends(ctx, cfg, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		nc, err := nats.Connect(cfg.NATSURL, nats.Name(cfg.WorkerID))
+		if err != nil {
+			_ = backends.Close()
+			return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+		}
+		backends.closers = append(backends.closers, func() error { nc.Close(); return nil })
+		logger.Info("connected to NATS", zap.String("url", nc.ConnectedUrlRedacted()))
+
+		backend, err := natsqueue.New(nc, natsqueue.Config{
+			Stream:       cfg.NATSStream,
+			Subject:      cfg.NATSSubject,
+			Durable:      cfg.NATSConsumer,
+			DLQSubject:   cfg.NATSDLQSubject,
+			EventsStream: cfg.NATSEventsStream,
+			MaxDeliver:   cfg.NATSMaxDeliver,
+			AckWait:      cfg.NATSAckWait,
+			Logger:       logger,
+			Metrics:      m,
+		})
+		if err != nil {
+			_ = backends.Close()
+			return nil, err
+		}
+		backends.source = backend
+		backends.events = backend
+		backends.closers = append(backends.closers, backend.Close)
+		return backends, nil
+
+	default:
+		return newRedisBackends(ctx, cfg, logger)
+	}
+}
+
+// newRedisBackends connects to Redis and uses it for work, events and state
+func newRedisBackends(ctx context.Context, cfg *config.Config, logger *zap.Logger) (*queueBackends, error) {
+	redisClient, err := redisqueue.NewClient(redisClientConfig(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis settings: %w", err)
+	}
+	if err := redisClient.Ping(ctx).Err(); err != nil {
+		_ = redisClient.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	logger.Info("connected to Redis",
+		zap.String("mode", cfg.RedisMode),
+		zap.String("addr", cfg.RedisAddr),
+		zap.Bool("tls", cfg.RedisTLS))
+
+	workStreams, err := cfg.WorkStreams()
+	if err != nil {
+		_ = redisClient.Close()
+		return nil, err
+	}
+	streams := make([]redisqueue.Stream, len(workStreams))
+	for i, stream := range workStreams {
+		streams[i] = redisqueue.Stream{Key: stream.Key, Weight: stream.Weight}
+	}
+
+	backend := redisqueue.New(redisClient, redisqueue.Config{
+		Consumer:      cfg.WorkerID,
+		Namespace:     cfg.RedisNamespace,
+		Streams:       streams,
+		ConsumerGroup: cfg.RedisConsumerGroup,
+		StatePrefix:   cfg.RedisStatePrefix,
+		EventsPrefix:  cfg.RedisEventsPrefix,
+		StateTTL:      cfg.RedisStateTTL,
+		HashTags:      cfg.RedisMode == redisqueue.ModeCluster,
+	})
+	return &queueBackends{
+		source:      backend,
+		events:      backend,
+		state:       backend,
+		redis:       redisClient,
+		workStreams: streams,
+		closers:     []func() error{redisClient.Close},
+	}, nil
+}
+
+// redisClientConfig maps the Redis settings to a client config
+func redisClientConfig(cfg *config.Config) redisqueue.ClientConfig {
+	return redisqueue.ClientConfig{
+		Mode:             cfg.RedisMode,
+		Addrs:            redisqueue.SplitAddrs(cfg.RedisAddr),
+		SentinelMaster:   cfg.RedisSentinelMaster,
+		SentinelAddrs:    cfg.RedisSentinelAddrs,
+		SentinelUsername: cfg.RedisSentinelUsername,
+		SentinelPassword: cfg.RedisSentinelPass,
+		Username:         cfg.RedisUsername,
+		Password:         cfg.RedisPass,
+		DB:               cfg.RedisDB,
+		ClientName:       cfg.WorkerID,
+		TLS: redisqueue.TLSConfig{
+			Enabled:            cfg.RedisTLS,
+			CAFile:             cfg.RedisTLSCAFile,
+			CertFile:           cfg.RedisTLSCertFile,
+			KeyFile:            cfg.RedisTLSKeyFile,
+			ServerName:         cfg.RedisTLSServerName,
+			InsecureSkipVerify: cfg.RedisTLSInsecureSkipVerify,
+		},
+		PoolSize:     cfg.RedisPoolSize,
+		MinIdleConns: cfg.RedisMinIdleConns,
+		PoolTimeout:  cfg.RedisPoolTimeout,
+		DialTimeout:  cfg.RedisDialTimeout,
+		ReadTimeout:  cfg.RedisReadTimeout,
+		WriteTimeout: cfg.RedisWriteTimeout,
+		MaxRetries:   cfg.RedisMaxRetries,
+	}
+}
+
+// initLogger initializes the logger. The returned level can be changed at runtime.
+func initLogger(level string) (*zap.Logger, zap.AtomicLevel) {
+	config := zap.NewProductionConfig()
+	atomicLevel := zap.NewAtomicLevelAt(parseLogLevel(level))
+	config.Level = atomicLevel
+	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	logger, err := config.Build()
+	if err != nil {
+		panic(fmt.Sprintf("failed to initialize logger: %v", err))
+	}
+
+	return logger, atomicLevel
+}
+
+// parseLogLevel converts a configured log level to a zap level
+func parseLogLevel(level string) zapcore.Level {
 	switch level {
 	case "debug":
-		zapLevel = zapcore.DebugLevel
+		return zapcore.DebugLevel
 	case "info":
-		zapLevel = zapcore.InfoLevel
+		return zapcore.InfoLevel
 	case "warn":
-		zapLevel = zapcore.WarnLevel
+		return zapcore.WarnLevel
 	case "error":
-		zapLevel = zapcore.ErrorLevel
+		return zapcore.ErrorLevel
 	default:
-		zapLevel = zapcore.InfoLevel
+		return zapcore.InfoLevel
 	}
+}
 
-	config := zap.NewProductionConfig()
-	config.Level = zap.NewAtomicLevelAt(zapLevel)
-	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+// newLLMClient creates the LLM client for the configured provider,
+// recording it to a cassette if enabled
+func newLLMClient(cfg *config.Config, logger *zap.Logger) (ports.LLMClient, error) {
+	switch cfg.LLMProvider {
+	case "replay":
+		return cassette.NewReplayer(cfg.LLMCassette)
+	case "fake":
+		return fakellm.NewFromFile(cfg.LLMFakeScript)
+	}
 
-	logger, err := config.Build()
+	provider := cfg.Providers[cfg.LLMProvider]
+	client, err := llm.NewClient(&llm.Config{
+		Provider: cfg.LLMProvider,
+		APIKey:   cfg.LLMAPIKey,
+		BaseURL:  provider.BaseURL,
+		Timeout:  int(provider.Timeout.Seconds()),
+		Logger:   logger,
+	})
 	if err != nil {
-		panic(fmt.Sprintf("failed to initialize logger: %v", err))
+		return nil, err
+	}
+
+	if cfg.LLMCassetteRecord {
+		logger.Info("recording LLM interactions", zap.String("cassette", cfg.LLMCassette))
+		return cassette.NewRecorder(client, cfg.LLMCassette, logger)
+	}
+	return client, nil
+}
+
+// newRateLimiter creates the LLM rate limiter shared through Redis, or
+// returns nil when it is disabled or there is no Redis
+func newRateLimiter(cfg *config.Config, backends *queueBackends, logger *zap.Logger, m *metrics.Metrics) *ratelimit.Limiter {
+	if !cfg.LLMRateLimitEnabled || cfg.OfflineLLM() {
+		return nil
+	}
+	if backends.redis == nil {
+		logger.Info("LLM rate limiting disabled: it needs Redis")
+		return nil
+	}
+
+	limits := make([]ratelimit.Limit, 0, len(cfg.RateLimits()))
+	for _, limit := range cfg.RateLimits() {
+		limits = append(limits, ratelimit.Limit{
+			Provider: limit.Provider,
+			Model:    limit.Model,
+			RPM:      limit.RPM,
+			TPM:      limit.TPM,
+		})
+	}
+
+	return ratelimit.New(backends.redis, ratelimit.Config{
+		Provider:  cfg.LLMProvider,
+		APIKey:    cfg.LLMAPIKey,
+		Limits:    limits,
+		MaxWait:   cfg.LLMRateLimitMaxWait,
+		Namespace: cfg.RedisNamespace,
+		Logger:    logger,
+		Metrics:   m,
+	})
+}
+
+// newToolLimiter creates the limiter applying tool policies through Redis,
+// or returns nil when there is no Redis
+func newToolLimiter(cfg *config.Config, backends *queueBackends, logger *zap.Logger, m *metrics.Metrics) *ratelimit.ToolLimiter {
+	if backends.redis == nil {
+		if len(toolPolicies(cfg)) > 0 {
+			logger.Warn("tool concurrency, rate and breaker policies ignored: they need Redis")
+		}
+		return nil
+	}
+
+	return ratelimit.NewToolLimiter(backends.redis, ratelimit.ToolLimiterConfig{
+		Policies:  toolPolicies(cfg),
+		MaxWait:   cfg.ToolLimitMaxWait,
+		Namespace: cfg.RedisNamespace,
+		IsFailure: toolchain.BackendFailure,
+		Logger:    logger,
+		Metrics:   m,
+	})
+}
+
+// toolPolicies returns the tool policies that limit calls
+func toolPolicies(cfg *config.Config) map[string]ratelimit.ToolPolicy {
+	policies := make(map[string]ratelimit.ToolPolicy)
+	for tool, policy := range cfg.ToolPolicies {
+		if policy.MaxConcurrent <= 0 && policy.RatePerSecond <= 0 && policy.BreakerThreshold <= 0 {
+			continue
+		}
+		policies[tool] = ratelimit.ToolPolicy{
+			MaxConcurrent:    policy.MaxConcurrent,
+			RatePerSecond:    policy.RatePerSecond,
+			BreakerThreshold: policy.BreakerThreshold,
+			BreakerCooldown:  policy.BreakerCooldown,
+		}
+	}
+	return policies
+}
+
+// newToolClient creates the MCP client and the tool catalog routing each
+// tool to the MCP server, function registry or OpenAPI document that owns
+// it. The catalog is loaded before returning and refreshed whenever an MCP
+// server reports that its tools changed. Conflicting tool names are an error.
+func newToolClient(cfg *config.Config, scratch builtin.ScratchStore, workspaces *function.Workspaces, logger *zap.Logger) (*mcp.Client, *catalog.Catalog, error) {
+	mcpClient := mcp.NewClient(mcpServers(cfg), logger)
+	functionRegistry := function.NewRegistry(logger)
+
+	// Register built-in tools
+	graphID := func(ctx context.Context) string { return toolchain.CallFrom(ctx).GraphID }
+	if cfg.BuiltinTools {
+		builtin.Register(functionRegistry, builtin.Options{Scratch: scratch, GraphID: graphID})
+	}
+	if workspaces != nil {
+		function.RegisterFSTools(functionRegistry, workspaces, graphID)
+	}
+	if len(cfg.HTTPEndpoints) > 0 {
+		apiClient, err := newAPIClient(cfg, httpEndpoints(cfg), logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		api.RegisterHTTPTool(functionRegistry, apiClient)
+	}
+
+	tools := catalog.New(logger)
+	tools.Add("mcp", mcpClient)
+	tools.Add("registry", catalog.Flat(functionRegistry))
+
+	// OpenAPI operations get a client of their own, so http_request can't
+	// reach their servers
+	if len(cfg.OpenAPISpecs) > 0 {
+		openAPIClient, err := newAPIClient(cfg, nil, logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		tools.Add("openapi", api.NewBackend(openAPIClient, openAPISpecs(cfg), logger))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), toolCatalogTimeout)
+	defer cancel()
+	if err := tools.Refresh(ctx); err != nil {
+		var conflicts *catalog.ConflictError
+		if errors.As(err, &conflicts) {
+			_ = mcpClient.Close()
+			return nil, nil, err
+		}
+		logger.Warn("some tools could not be listed", zap.Error(err))
+	}
+
+	mcpClient.OnToolsChanged(func(server string) {
+		go refreshToolCatalog(tools, logger)
+	})
+	return mcpClient, tools, nil
+}
+
+// toolCatalogTimeout bounds a tool catalog refresh
+const toolCatalogTimeout = 10 * time.Second
+
+// refreshToolCatalog reloads the tool catalog after a change
+func refreshToolCatalog(tools *catalog.Catalog, logger *zap.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), toolCatalogTimeout)
+	defer cancel()
+
+	var conflicts *catalog.ConflictError
+	switch err := tools.Refresh(ctx); {
+	case errors.As(err, &conflicts):
+		logger.Error("tool catalog has conflicting names; each is kept on its first owner", zap.Error(err))
+	case err != nil:
+		logger.Warn("some tools could not be listed", zap.Error(err))
 	}
+}
+
+// newToolChain wraps the tool client in the configured middlewares, from
+// the outermost: audit log, access policies and hooks, result cache and
+// limits
+func newToolChain(cfg *config.Config, client toolchain.ToolClient, access *toolaccess.Enforcer, cache *toolchain.Cache, limiter *ratelimit.ToolLimiter, logger *zap.Logger) toolchain.ToolClient {
+	var middlewares []toolchain.Middleware
+	if cfg.ToolAuditEnabled {
+		middlewares = append(middlewares, toolchain.Audit(toolchain.AuditConfig{
+			Logger:    logger,
+			LogParams: cfg.ToolAuditParams,
+			Redactor:  toolchain.NewRedactor(cfg.ToolRedactKeys...),
+		}))
+	}
+
+	before, after := toolHooks(cfg, logger)
+	before = append([]toolchain.BeforeHook{access.Hook()}, before...)
+	middlewares = append(middlewares, toolchain.Before(before...))
+	if len(after) > 0 {
+		middlewares = append(middlewares, toolchain.After(after...))
+	}
+
+	middlewares = append(middlewares, toolchain.Cached(cache))
+	if limiter != nil {
+		middlewares = append(middlewares, toolchain.Limit(limiter))
+	}
+	return toolchain.Chain(client, middlewares...)
+}
+
+// toolAccessPolicies converts the configured tool access policies
+func toolAccessPolicies(cfg *config.Config) toolaccess.Policies {
+	convert := func(policy config.ToolAccessPolicy) toolaccess.Policy {
+		converted := toolaccess.Policy{Allow: policy.Allow, Deny: policy.Deny}
+		if len(policy.Args) > 0 {
+			converted.Args = make(map[string]map[string]toolaccess.ArgConstraint, len(policy.Args))
+		}
+		for glob, args := range policy.Args {
+			converted.Args[glob] = make(map[string]toolaccess.ArgConstraint, len(args))
+			for name, c := range args {
+				converted.Args[glob][name] = toolaccess.ArgConstraint{
+					Required: c.Required,
+					Values:   c.Values,
+					Prefixes: c.Prefixes,
+					Paths:    c.Paths,
+					Pattern:  c.Pattern,
+				}
+			}
+		}
+		return converted
+	}
+
+	policies := toolaccess.Policies{
+		Default: convert(cfg.ToolAccess.Default),
+		Tenants: make(map[string]toolaccess.Policy, len(cfg.ToolAccess.Tenants)),
+		Graphs:  make(map[string]toolaccess.Policy, len(cfg.ToolAccess.Graphs)),
+	}
+	for tenant, policy := range cfg.ToolAccess.Tenants {
+		policies.Tenants[tenant] = convert(policy)
+	}
+	for graph, policy := range cfg.ToolAccess.Graphs {
+		policies.Graphs[graph] = convert(policy)
+	}
+	return policies
+}
+
+// toolCacheTTLs returns the cache TTLs of read-only tools
+func toolCacheTTLs(cfg *config.Config) map[string]time.Duration {
+	ttls := make(map[string]time.Duration)
+	for tool, policy := range cfg.ToolPolicies {
+		if policy.CacheTTL > 0 {
+			ttls[tool] = policy.CacheTTL
+		}
+	}
+	return ttls
+}
+
+// executorSettings derives the executor's runtime settings from the config
+func executorSettings(cfg *config.Config) executor.Settings {
+	prices := make(map[string]executor.ModelPrice, len(cfg.Prices))
+	for model, price := range cfg.Prices {
+		prices[model] = executor.ModelPrice{
+			InputPerMillion:  price.InputPerMillion,
+			OutputPerMillion: price.OutputPerMillion,
+		}
+	}
+
+	toolTimeouts := make(map[string]time.Duration, len(cfg.ToolPolicies))
+	for tool, policy := range cfg.ToolPolicies {
+		toolTimeouts[tool] = policy.Timeout
+	}
+
+	return executor.Settings{
+		MaxIterations: cfg.MaxIterations,
+		DefaultModel:  cfg.LLMModel,
+		ModelAliases:  cfg.ModelAliases,
+		Profiles:      cfg.Profiles,
+		Prices:        prices,
+		ToolTimeouts:  toolTimeouts,
 
-	return logger
+		StrictModeDetection: cfg.StrictModeDetection,
+	}
 }
 
-// compositeToolClient tries MCP first, then falls back to function registry
-type compositeToolClient struct {
-	mcp      *mcp.Client
-	registry *function.Registry
-	logger   *zap.Logger
+// mcpServers converts the configured MCP servers for the MCP client
+func mcpServers(cfg *config.Config) []mcp.ServerConfig {
+	servers := make([]mcp.ServerConfig, 0, len(cfg.GetMCPServers()))
+	for _, server := range cfg.GetMCPServers() {
+		servers = append(servers, mcp.ServerConfig{
+			Name:    server.Name,
+			URL:     server.URL,
+			Command: server.Command,
+			Args:    server.Args,
+			Env:     server.Env,
+		})
+	}
+	return servers
 }
 
-func newCompositeToolClient(mcpClient *mcp.Client, registry *function.Registry, logger *zap.Logger) *compositeToolClient {
-	return &compositeToolClient{
-		mcp:      mcpClient,
-		registry: registry,
-		logger:   logger,
+// toolHooks builds the configured policy webhooks run before and after
+// tool calls; before hooks run after the tool access policies
+func toolHooks(cfg *config.Config, logger *zap.Logger) ([]toolchain.BeforeHook, []toolchain.AfterHook) {
+	var before []toolchain.BeforeHook
+	var after []toolchain.AfterHook
+	for _, hook := range cfg.ToolHooks {
+		webhook := toolchain.NewWebhook(toolchain.WebhookConfig{
+			Name:     hook.Name,
+			URL:      hook.URL,
+			Tools:    hook.Tools,
+			Timeout:  hook.Timeout,
+			FailOpen: hook.FailOpen,
+			Headers:  hook.Headers,
+			Logger:   logger,
+		})
+		if hook.Phase == toolchain.PhaseAfter {
+			after = append(after, webhook.After())
+		} else {
+			before = append(before, webhook.Before())
+		}
 	}
+	return before, after
 }
 
-func (c *compositeToolClient) Execute(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error) {
-	// Try MCP first
-	result, err := c.mcp.Execute(ctx, toolName, params)
-	if err == nil {
-		return result, nil
+// httpEndpoints returns the endpoints of the http_request tool
+func httpEndpoints(cfg *config.Config) map[string]api.Endpoint {
+	endpoints := make(map[string]api.Endpoint, len(cfg.HTTPEndpoints))
+	for name, endpoint := range cfg.HTTPEndpoints {
+		endpoints[name] = api.Endpoint{
+			BaseURL: endpoint.BaseURL,
+			Auth:    endpoint.Auth,
+			Methods: endpoint.Methods,
+			Headers: endpoint.Headers,
+		}
 	}
+	return endpoints
+}
 
-	c.logger.Debug("MCP execution failed, trying function registry",
-		zap.String("tool", toolName),
-		zap.Error(err))
+// openAPISpecs returns the OpenAPI documents whose operations become tools
+func openAPISpecs(cfg *config.Config) []api.Spec {
+	specs := make([]api.Spec, 0, len(cfg.OpenAPISpecs))
+	for _, spec := range cfg.OpenAPISpecs {
+		specs = append(specs, api.Spec{
+			Name:       spec.Name,
+			Source:     spec.Source,
+			BaseURL:    spec.BaseURL,
+			Auth:       spec.Auth,
+			Operations: spec.Operations,
+			Headers:    spec.Headers,
+		})
+	}
+	return specs
+}
+
+// newAPIClient creates an HTTP client for endpoints with the configured
+// auth profiles, timeout and response size cap
+func newAPIClient(cfg *config.Config, endpoints map[string]api.Endpoint, logger *zap.Logger) (*api.Client, error) {
+	profiles := make(map[string]api.AuthProfile, len(cfg.AuthProfiles))
+	for name, profile := range cfg.AuthProfiles {
+		profiles[name] = api.AuthProfile{
+			Type:     profile.Type,
+			Token:    profile.Token,
+			Username: profile.Username,
+			Password: profile.Password,
+			Key:      profile.Key,
+			Header:   profile.Header,
+			Query:    profile.Query,
+		}
+	}
 
-	// Fallback to function registry
-	return c.registry.Execute(ctx, toolName, params)
+	client, err := api.NewClient(api.Config{
+		Endpoints:        endpoints,
+		AuthProfiles:     profiles,
+		Timeout:          cfg.HTTPToolTimeout,
+		MaxResponseBytes: int64(cfg.HTTPToolMaxResponseKB) << 10,
+		Vars:             callVars,
+		Logger:           logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+	return client, nil
 }
 
-func (c *compositeToolClient) ListTools(ctx context.Context) ([]string, error) {
-	// Get tools from both sources
-	mcpTools, _ := c.mcp.ListTools(ctx)
-	registryTools, _ := c.registry.ListTools(ctx)
+// callVars returns the template variables of a tool call
+func callVars(ctx context.Context) map[string]string {
+	call := toolchain.CallFrom(ctx)
+	return map[string]string{
+		"graph_id": call.GraphID,
+		"node_id":  call.NodeID,
+		"tenant":   call.Tenant,
+	}
+}
 
-	// Combine and deduplicate
-	toolSet := make(map[string]bool)
-	for _, tool := range mcpTools {
-		toolSet[tool] = true
+// newWorkspaces returns the per-graph workspaces of the fs tools, or nil
+// when they are disabled. Nothing is created on disk until a tool writes.
+func newWorkspaces(cfg *config.Config) *function.Workspaces {
+	if !cfg.FSTools {
+		return nil
 	}
-	for _, tool := range registryTools {
-		toolSet[tool] = true
+	return function.NewWorkspaces(function.WorkspaceConfig{
+		Dir:          cfg.WorkspaceDir,
+		QuotaBytes:   int64(cfg.WorkspaceQuotaMB) << 20,
+		MaxFileBytes: int64(cfg.WorkspaceMaxFileMB) << 20,
+		MaxFiles:     cfg.WorkspaceMaxFiles,
+	})
+}
+
+// sweepWorkspaces periodically removes the workspaces of graphs whose
+// state has expired
+func sweepWorkspaces(ctx context.Context, workspaces *function.Workspaces, state queue.StateStore, interval time.Duration, logger *zap.Logger) {
+	checker, ok := state.(queue.StateChecker)
+	if !ok {
+		logger.Warn("workspaces are not swept: the state store can't check for expired graphs")
+		return
 	}
 
-	tools := make([]string, 0, len(toolSet))
-	for tool := range toolSet {
-		tools = append(tools, tool)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	expired := func(ctx context.Context, graphID string) (bool, error) {
+		exists, err := checker.Exists(ctx, graphID)
+		return err == nil && !exists, err
 	}
 
-	return tools, nil
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		removed, err := workspaces.Sweep(ctx, expired)
+		if err != nil {
+			logger.Warn("workspace sweep failed", zap.Error(err))
+		}
+		if removed > 0 {
+			logger.Info("removed workspaces of expired graphs", zap.Int("count", removed))
+		}
+	}
 }
 
-// registerBuiltInTools registers built-in tools
-func registerBuiltInTools(registry *function.Registry) {
-	// MVP: No built-in tools yet
-	// TODO: Add common built-in tools (e.g., string manipulation, math)
+// newScratchStore returns the graph scratchpad store of the built-in
+// tools: in Redis when there is one, so all workers share it, otherwise in
+// memory
+func newScratchStore(cfg *config.Config, backends *queueBackends) builtin.ScratchStore {
+	if backends.redis == nil {
+		return builtin.NewMemoryScratch(cfg.ScratchTTL)
+	}
+	return builtin.NewRedisScratch(backends.redis, builtin.RedisScratchConfig{
+		Namespace: cfg.RedisNamespace,
+		TTL:       cfg.ScratchTTL,
+	})
 }
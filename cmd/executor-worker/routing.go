@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aescanero/dago-node-executor/internal/config"
+	"github.com/aescanero/dago-node-executor/internal/queue/redisqueue"
+	"github.com/aescanero/dago-node-executor/internal/toolchain"
+	"github.com/aescanero/dago-node-executor/pkg/routing"
+	"go.uber.org/zap"
+)
+
+// joinRouting registers the worker's capabilities in the routing registry
+// and makes the Redis backend consume the priority streams and the routes
+// the worker can serve, alongside the configured work streams
+func joinRouting(ctx context.Context, cfg *config.Config, backends *queueBackends, tools toolchain.ToolClient, logger *zap.Logger) (*routing.Member, error) {
+	backend, ok := backends.source.(*redisqueue.Backend)
+	if !ok || backends.redis == nil {
+		return nil, fmt.Errorf("routing requires the redis queue backend")
+	}
+
+	weights, err := cfg.PriorityWeights()
+	if err != nil {
+		return nil, err
+	}
+
+	registry := routing.NewRegistry(backends.redis, routing.RegistryConfig{
+		Namespace:  cfg.RedisNamespace,
+		BaseStream: cfg.RoutingBaseStream,
+		TTL:        cfg.RoutingRegistryTTL,
+	})
+
+	member := registry.Join(workerCapabilities(cfg, tools), func(routes []routing.Route) error {
+		streams := append([]redisqueue.Stream(nil), backends.workStreams...)
+		for _, route := range routes {
+			streams = append(streams, redisqueue.Stream{
+				Key:    route.Stream,
+				Weight: weights[string(route.Priority)],
+			})
+		}
+		return backend.SetStreams(ctx, streams)
+	}, logger)
+
+	if err := member.Refresh(ctx); err != nil {
+		return nil, fmt.Errorf("failed to join routing registry: %w", err)
+	}
+	return member, nil
+}
+
+// workerCapabilities returns a function collecting the capabilities the
+// worker advertises: its LLM provider, default model, model aliases and
+// extra models, available tools and labels
+func workerCapabilities(cfg *config.Config, tools toolchain.ToolClient) func(ctx context.Context) routing.Capabilities {
+	return func(ctx context.Context) routing.Capabilities {
+		models := append([]string{cfg.LLMModel}, cfg.WorkerModels...)
+		for alias, model := range cfg.ModelAliases {
+			models = append(models, alias, model)
+		}
+
+		// MCP servers may be slow or down; advertise what answers in time
+		toolCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		toolNames, _ := tools.ListTools(toolCtx)
+
+		return routing.Capabilities{
+			WorkerID:  cfg.WorkerID,
+			Providers: []string{cfg.LLMProvider},
+			Models:    models,
+			Tools:     toolNames,
+			Labels:    cfg.Labels(),
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aescanero/dago-node-executor/internal/config"
+	"github.com/aescanero/dago-node-executor/internal/queue/redisqueue"
+	"github.com/aescanero/dago-node-executor/pkg/queue/memqueue"
+	"github.com/aescanero/dago-node-executor/pkg/routing"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// listTools is a tool client listing a fixed set of tools
+type listTools []string
+
+func (l listTools) Execute(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error) {
+	return nil, nil
+}
+
+func (l listTools) ListTools(ctx context.Context) ([]string, error) {
+	return l, nil
+}
+
+func TestJoinRouting(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	cfg := &config.Config{
+		WorkerID:               "w1",
+		WorkerLabels:           []string{"gpu", "region=eu"},
+		WorkerModels:           []string{"llama3:70b"},
+		LLMProvider:            "ollama",
+		LLMModel:               "llama3:8b",
+		ModelAliases:           map[string]string{"fast": "llama3:8b"},
+		RoutingBaseStream:      "executor.work",
+		RoutingPriorityWeights: []string{"high=3", "normal=2", "low=1"},
+		RoutingRegistryTTL:     time.Minute,
+	}
+	backend := redisqueue.New(client, redisqueue.Config{Consumer: "w1"})
+	backends := &queueBackends{source: backend, redis: client}
+
+	if _, err := joinRouting(ctx, cfg, &queueBackends{source: memqueue.New()}, listTools{}, zap.NewNop()); err == nil {
+		t.Error("joinRouting() with the memory backend succeeded, want an error")
+	}
+
+	member, err := joinRouting(ctx, cfg, backends, listTools{"search"}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("joinRouting() error = %v", err)
+	}
+
+	caps := member.Capabilities()
+	want := routing.Capabilities{
+		WorkerID:  "w1",
+		Providers: []string{"ollama"},
+		Models:    []string{"fast", "llama3:70b", "llama3:8b"},
+		Tools:     []string{"search"},
+		Labels:    map[string]string{"gpu": "true", "region": "eu"},
+	}
+	caps.UpdatedAt = time.Time{}
+	if !reflect.DeepEqual(caps, want) {
+		t.Errorf("Capabilities() = %+v, want %+v", caps, want)
+	}
+
+	// The priority streams are consumed by weight: of six fetches while
+	// every stream has work, three go to high, two to normal and one to low
+	add := func(stream string, n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			if err := client.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: map[string]interface{}{"data": "{}"}}).Err(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	fetch := func() string {
+		t.Helper()
+		messages, err := backend.Fetch(ctx, 1)
+		if err != nil || len(messages) != 1 {
+			t.Fatalf("Fetch() = %v, %v; want one message", messages, err)
+		}
+		return messages[0].Source
+	}
+	for _, priority := range routing.Priorities {
+		add("executor.work."+string(priority), 6)
+	}
+	counts := make(map[string]int)
+	for i := 0; i < 6; i++ {
+		counts[fetch()]++
+	}
+	wantCounts := map[string]int{"executor.work.high": 3, "executor.work.normal": 2, "executor.work.low": 1}
+	if !reflect.DeepEqual(counts, wantCounts) {
+		t.Errorf("fetches per stream = %v, want %v", counts, wantCounts)
+	}
+
+	// A route recorded for the worker's capabilities is consumed after the
+	// next refresh, alongside the priority streams
+	registry := routing.NewRegistry(client, routing.RegistryConfig{})
+	stream, err := registry.Route(ctx, routing.PriorityLow, routing.Requirements{Tools: []string{"search"}})
+	if err != nil {
+		t.Fatalf("Route() error = %v", err)
+	}
+	if err := member.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	add(stream, 1)
+	routed := 0
+	for i := 0; i < 13; i++ {
+		if fetch() == stream {
+			routed++
+		}
+	}
+	if routed != 1 {
+		t.Errorf("fetched %d messages from the routed stream %s, want 1", routed, stream)
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-node-executor/internal/config"
+	"github.com/aescanero/dago-node-executor/internal/executor"
+	"github.com/aescanero/dago-node-executor/internal/toolaccess"
+	"github.com/aescanero/dago-node-executor/internal/toolchain"
+	"github.com/aescanero/dago-node-executor/pkg/tools/builtin"
+	"github.com/aescanero/dago-node-executor/pkg/tools/function"
+	"go.uber.org/zap"
+)
+
+const runUsage = `Usage: executor-worker run --node FILE [flags]
+
+Runs a single node locally against a state file, without Redis, using the
+LLM and tool clients from the usual environment and CONFIG_FILE settings.
+Prints the result as JSON on stdout; logs go to stderr.
+
+Flags:
+`
+
+// runTraceEntry is one LLM or tool call in the run output
+type runTraceEntry struct {
+	Iteration    int                    `json:"iteration,omitempty"`
+	Type         string                 `json:"type"`
+	Model        string                 `json:"model,omitempty"`
+	Tool         string                 `json:"tool,omitempty"`
+	Params       map[string]interface{} `json:"params,omitempty"`
+	Result       interface{}            `json:"result,omitempty"`
+	Content      string                 `json:"content,omitempty"`
+	ToolCalls    []string               `json:"tool_calls,omitempty"`
+	InputTokens  int                    `json:"input_tokens,omitempty"`
+	OutputTokens int                    `json:"output_tokens,omitempty"`
+	DurationMS   int64                  `json:"duration_ms"`
+	Error        string                 `json:"error,omitempty"`
+}
+
+// runUsageTotals aggregates usage across a run
+type runUsageTotals struct {
+	LLMCalls     int `json:"llm_calls"`
+	ToolCalls    int `json:"tool_calls"`
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// runOutput is the JSON document printed by the run subcommand
+type runOutput struct {
+	NodeID     string                 `json:"node_id"`
+	Mode       executor.ExecutionMode `json:"mode"`
+	Output     interface{}            `json:"output,omitempty"`
+	Artifacts  []function.Artifact    `json:"artifacts,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	Usage      runUsageTotals         `json:"usage"`
+	Trace      []runTraceEntry        `json:"trace"`
+	DurationMS int64                  `json:"duration_ms"`
+}
+
+// inputFlags collects repeated --input key=value flags
+type inputFlags map[string]interface{}
+
+func (f inputFlags) String() string { return "" }
+
+func (f inputFlags) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	f[key] = val
+	return nil
+}
+
+// runCommand implements `executor-worker run` and returns the exit code
+func runCommand(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), runUsage)
+		fs.PrintDefaults()
+	}
+
+	nodeFile := fs.String("node", "", "node config JSON: a work item or a bare config object (required)")
+	stateFile := fs.String("state", "", "GraphState JSON file (default: empty state)")
+	nodeID := fs.String("node-id", "", "node ID (default: from the work item, or \"local\")")
+	dryRun := fs.Bool("dry-run", false, "print the resolved mode, prompt and params without calling the LLM or tools")
+	timeout := fs.Duration("timeout", 10*time.Minute, "execution timeout")
+	verbose := fs.Bool("verbose", false, "debug logging on stderr")
+	inputs := inputFlags{}
+	fs.Var(inputs, "input", "set a state input as key=value (repeatable)")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if *nodeFile == "" {
+		fmt.Fprintln(os.Stderr, "run: --node is required")
+		fs.Usage()
+		return 2
+	}
+
+	nodeConfig, err := readNodeConfig(*nodeFile, *nodeID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "run: %v\n", err)
+		return 2
+	}
+
+	state, err := readGraphState(*stateFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "run: %v\n", err)
+		return 2
+	}
+	for key, value := range inputs {
+		state.Inputs[key] = value
+	}
+
+	// Credentials are only needed when actually calling the LLM
+	cfg, err := config.Parse()
+	if err == nil && !*dryRun {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "run: invalid config: %v\n", err)
+		return 2
+	}
+
+	level := "warn"
+	if *verbose {
+		level = "debug"
+	}
+	logger, _ := initLogger(level)
+	defer func() { _ = logger.Sync() }()
+
+	if *dryRun {
+		exec := executor.NewExecutor(nil, nil, logger, cfg.MaxIterations,
+			executor.WithSettings(executorSettings(cfg)))
+		plan, err := exec.Plan(state, nodeConfig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "run: %v\n", err)
+			return 1
+		}
+		return writeRunJSON(os.Stdout, plan)
+	}
+
+	llmClient, err := newLLMClient(cfg, logger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "run: failed to create LLM client: %v\n", err)
+		return 1
+	}
+	workspaces := newWorkspaces(cfg)
+	mcpClient, toolCatalog, err := newToolClient(cfg, builtin.NewMemoryScratch(cfg.ScratchTTL), workspaces, logger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "run: %v\n", err)
+		return 1
+	}
+	defer mcpClient.Close()
+	toolAccess := toolaccess.NewEnforcer(toolAccessPolicies(cfg), logger, nil)
+	toolCache := toolchain.NewCache(toolCacheTTLs(cfg), cfg.ToolCacheSize)
+	toolClient := newToolChain(cfg, toolCatalog, toolAccess, toolCache, nil, logger)
+
+	exec := executor.NewExecutor(llmClient, toolClient, logger, cfg.MaxIterations,
+		executor.WithSettings(executorSettings(cfg)))
+
+	mode, err := exec.ResolveMode(nodeConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "run: %v\n", err)
+		return 1
+	}
+
+	out := runOutput{
+		NodeID: nodeConfig.NodeID,
+		Mode:   mode,
+		Trace:  []runTraceEntry{},
+	}
+
+	iteration := 0
+	observer := &executor.Observer{
+		OnIteration: func(n int) {
+			iteration = n
+		},
+		OnLLMCall: func(call executor.LLMCallInfo) {
+			entry := runTraceEntry{
+				Iteration:  iteration,
+				Type:       "llm",
+				Model:      call.Request.Model,
+				DurationMS: call.Duration.Milliseconds(),
+			}
+			if call.Response != nil {
+				entry.Content = call.Response.Content
+				entry.InputTokens = call.Response.Usage.InputTokens
+				entry.OutputTokens = call.Response.Usage.OutputTokens
+				for _, toolCall := range call.Response.ToolCalls {
+					entry.ToolCalls = append(entry.ToolCalls, toolCall.Name)
+				}
+			}
+			if call.Err != nil {
+				entry.Error = call.Err.Error()
+			}
+			out.Usage.LLMCalls++
+			out.Usage.InputTokens += entry.InputTokens
+			out.Usage.OutputTokens += entry.OutputTokens
+			out.Trace = append(out.Trace, entry)
+		},
+		OnToolCall: func(call executor.ToolCallInfo) {
+			entry := runTraceEntry{
+				Iteration:  iteration,
+				Type:       "tool",
+				Tool:       call.Tool,
+				Params:     call.Params,
+				Result:     call.Result,
+				DurationMS: call.Duration.Milliseconds(),
+			}
+			if call.Err != nil {
+				entry.Error = call.Err.Error()
+			}
+			out.Usage.ToolCalls++
+			out.Trace = append(out.Trace, entry)
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	ctx = executor.ContextWithObserver(ctx, observer)
+
+	start := time.Now()
+	result, execErr := exec.Execute(ctx, state, nodeConfig)
+	out.DurationMS = time.Since(start).Milliseconds()
+	out.Output = result
+	if execErr != nil {
+		out.Error = execErr.Error()
+		logger.Debug("node execution failed", zap.Error(execErr))
+	} else if workspaces != nil {
+		if patterns, _ := executor.ArtifactPatterns(nodeConfig); len(patterns) > 0 {
+			out.Artifacts, err = workspaces.Export(ctx, state.GraphID, patterns)
+			if err != nil {
+				logger.Warn("failed to export node artifacts", zap.Error(err))
+			}
+		}
+	}
+
+	if code := writeRunJSON(os.Stdout, out); code != 0 {
+		return code
+	}
+	if execErr != nil {
+		return 1
+	}
+	return 0
+}
+
+// readNodeConfig reads a node config file. It accepts either a work item
+// ({"node_id": ..., "config": {...}}) or a bare node config object.
+func readNodeConfig(path, nodeID string) (*executor.NodeConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read node config: %w", err)
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse node config %s: %w", path, err)
+	}
+
+	nodeConfig := &executor.NodeConfig{NodeID: "local", Config: raw}
+	if inner, ok := raw["config"].(map[string]interface{}); ok {
+		nodeConfig.Config = inner
+		if id, ok := raw["node_id"].(string); ok && id != "" {
+			nodeConfig.NodeID = id
+		}
+		if tenant, ok := raw["tenant"].(string); ok {
+			nodeConfig.Tenant = tenant
+		}
+	}
+	if nodeID != "" {
+		nodeConfig.NodeID = nodeID
+	}
+
+	return nodeConfig, nil
+}
+
+// readGraphState reads a GraphState JSON file, or returns an empty state if path is empty
+func readGraphState(path string) (*domain.GraphState, error) {
+	state := &domain.GraphState{GraphID: "local"}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read state: %w", err)
+		}
+		if err := json.Unmarshal(data, state); err != nil {
+			return nil, fmt.Errorf("failed to parse state %s: %w", path, err)
+		}
+	}
+
+	if state.Inputs == nil {
+		state.Inputs = make(map[string]interface{})
+	}
+	if state.NodeStates == nil {
+		state.NodeStates = make(map[string]*domain.NodeState)
+	}
+
+	return state, nil
+}
+
+// writeRunJSON prints v as indented JSON and returns the exit code
+func writeRunJSON(w io.Writer, v interface{}) int {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "run: failed to encode output: %v\n", err)
+		return 1
+	}
+	return 0
+}
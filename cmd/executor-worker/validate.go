@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aescanero/dago-node-executor/internal/config"
+	"github.com/aescanero/dago-node-executor/internal/executor"
+)
+
+const validateUsage = `Usage: executor-worker validate --node FILE [flags]
+
+Checks a node config against the schema of its mode without executing it:
+missing and mistyped fields, unknown keys, template variables (with
+--state) and referenced tools. Exits 1 if the config has errors.
+
+Flags:
+`
+
+// validateCommand implements `executor-worker validate` and returns the exit code
+func validateCommand(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), validateUsage)
+		fs.PrintDefaults()
+	}
+
+	nodeFile := fs.String("node", "", "node config JSON: a work item or a bare config object (required)")
+	stateFile := fs.String("state", "", "sample GraphState JSON file to check template variables against")
+	nodeID := fs.String("node-id", "", "node ID (default: from the work item, or \"local\")")
+	skipTools := fs.Bool("skip-tools", false, "do not check referenced tools against the tool catalog")
+	strict := fs.Bool("strict", false, "treat warnings as errors")
+	jsonOutput := fs.Bool("json", false, "print the result as JSON")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if *nodeFile == "" {
+		fmt.Fprintln(os.Stderr, "validate: --node is required")
+		fs.Usage()
+		return 2
+	}
+
+	nodeConfig, err := readNodeConfig(*nodeFile, *nodeID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "validate: %v\n", err)
+		return 2
+	}
+
+	opts := executor.ValidateOptions{SkipToolCheck: *skipTools}
+	if *stateFile != "" {
+		if opts.State, err = readGraphState(*stateFile); err != nil {
+			fmt.Fprintf(os.Stderr, "validate: %v\n", err)
+			return 2
+		}
+	}
+
+	// Profiles and aliases come from the config; credentials are not needed
+	cfg, err := config.Parse()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "validate: invalid config: %v\n", err)
+		return 2
+	}
+
+	logger, _ := initLogger("error")
+	defer func() { _ = logger.Sync() }()
+
+	var toolClient executor.ToolClient
+	if !*skipTools {
+		// The fs tools are registered for their definitions only; their
+		// workspace directory isn't created
+		mcpClient, toolCatalog, err := newToolClient(cfg, nil, newWorkspaces(cfg), logger)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "validate: %v\n", err)
+			return 2
+		}
+		defer mcpClient.Close()
+		toolClient = toolCatalog
+	}
+	exec := executor.NewExecutor(nil, toolClient, logger, cfg.MaxIterations,
+		executor.WithSettings(executorSettings(cfg)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result := exec.Validate(ctx, nodeConfig, opts)
+	failed := !result.Valid() || (*strict && len(result.Issues) > 0)
+
+	if *jsonOutput {
+		if code := writeRunJSON(os.Stdout, result); code != 0 {
+			return code
+		}
+	} else {
+		for _, issue := range result.Issues {
+			fmt.Println(issue)
+		}
+		status := "valid"
+		if failed {
+			status = "invalid"
+		}
+		if result.Mode == "" {
+			fmt.Printf("node %s: %s\n", result.NodeID, status)
+		} else {
+			fmt.Printf("node %s (%s mode): %s\n", result.NodeID, result.Mode, status)
+		}
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
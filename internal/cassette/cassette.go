@@ -0,0 +1,202 @@
+package cassette
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// formatVersion is the version of the cassette file format
+const formatVersion = 1
+
+// Method names recorded in interactions
+const (
+	MethodComplete           = "complete"
+	MethodCompleteWithTools  = "complete_with_tools"
+	MethodCompleteStructured = "complete_structured"
+	MethodGenerateCompletion = "generate_completion"
+)
+
+// Interaction is a recorded request and its outcome
+type Interaction struct {
+	// Key is the hash of the normalized request
+	Key    string `json:"key"`
+	Method string `json:"method"`
+
+	// Request is the normalized request, kept for humans reading diffs
+	Request json.RawMessage `json:"request"`
+
+	// Response is the provider response; empty if the call failed
+	Response json.RawMessage `json:"response,omitempty"`
+
+	// Error is the error returned by the provider, if any
+	Error string `json:"error,omitempty"`
+}
+
+// Cassette is the content of a cassette file
+type Cassette struct {
+	Version      int            `json:"version"`
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Load reads a cassette file
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	if c.Version != formatVersion {
+		return nil, fmt.Errorf("unsupported cassette version %d in %s", c.Version, path)
+	}
+
+	return &c, nil
+}
+
+// Save writes the cassette to path, replacing the file atomically
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create cassette directory: %w", err)
+		}
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+
+	return nil
+}
+
+// normalizedMessage is a message with insignificant differences removed
+type normalizedMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	Name    string `json:"name,omitempty"`
+}
+
+// normalizedTool is a tool definition as it takes part in the request key
+type normalizedTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// normalizedRequest is the request content the key is computed from.
+// Messages are trimmed, roles lowercased and tools sorted by name, so
+// cosmetic differences do not break replay.
+type normalizedRequest struct {
+	Method      string                 `json:"method"`
+	Model       string                 `json:"model"`
+	System      string                 `json:"system,omitempty"`
+	Messages    []normalizedMessage    `json:"messages"`
+	MaxTokens   int                    `json:"max_tokens,omitempty"`
+	Temperature float64                `json:"temperature,omitempty"`
+	TopP        float64                `json:"top_p,omitempty"`
+	Stop        []string               `json:"stop,omitempty"`
+	Tools       []normalizedTool       `json:"tools,omitempty"`
+	Schema      map[string]interface{} `json:"schema,omitempty"`
+}
+
+// key returns the hash identifying the request and its normalized JSON
+func (r *normalizedRequest) key() (string, json.RawMessage, error) {
+	sort.Slice(r.Tools, func(i, j int) bool { return r.Tools[i].Name < r.Tools[j].Name })
+
+	// encoding/json sorts map keys, so the encoding is canonical
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), data, nil
+}
+
+// normalizeCompletion normalizes a ports completion request
+func normalizeCompletion(method string, req ports.CompletionRequest, tools []ports.Tool, schema ports.JSONSchema) *normalizedRequest {
+	n := &normalizedRequest{
+		Method:      method,
+		Model:       req.Model,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		Stop:        req.Stop,
+		Schema:      schema,
+	}
+	for _, msg := range req.Messages {
+		n.Messages = append(n.Messages, normalizedMessage{
+			Role:    strings.ToLower(msg.Role),
+			Content: strings.TrimSpace(msg.Content),
+			Name:    msg.Name,
+		})
+	}
+	for _, tool := range tools {
+		n.Tools = append(n.Tools, normalizedTool(tool))
+	}
+	return n
+}
+
+// normalizeGenerate normalizes a GenerateCompletion request. The executor
+// passes a *domain.LLMRequest; other values are rejected.
+func normalizeGenerate(req interface{}) (*normalizedRequest, error) {
+	var r *domain.LLMRequest
+	switch v := req.(type) {
+	case *domain.LLMRequest:
+		r = v
+	case domain.LLMRequest:
+		r = &v
+	default:
+		return nil, fmt.Errorf("unsupported request type %T", req)
+	}
+
+	n := &normalizedRequest{
+		Method:      MethodGenerateCompletion,
+		Model:       r.Model,
+		System:      strings.TrimSpace(r.System),
+		MaxTokens:   r.MaxTokens,
+		Temperature: r.Temperature,
+	}
+	for _, msg := range r.Messages {
+		n.Messages = append(n.Messages, normalizedMessage{
+			Role:    strings.ToLower(msg.Role),
+			Content: strings.TrimSpace(msg.Content),
+		})
+	}
+	for _, tool := range r.Tools {
+		n.Tools = append(n.Tools, normalizedTool(tool))
+	}
+	return n, nil
+}
+
+// describe summarizes a request for error messages
+func (r *normalizedRequest) describe() string {
+	last := ""
+	if len(r.Messages) > 0 {
+		last = r.Messages[len(r.Messages)-1].Content
+		if len(last) > 80 {
+			last = last[:80] + "..."
+		}
+	}
+	return fmt.Sprintf("%s model=%s messages=%d last=%q", r.Method, r.Model, len(r.Messages), last)
+}
@@ -0,0 +1,250 @@
+package cassette
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/ports"
+	"github.com/aescanero/dago-node-executor/internal/fakellm"
+	"go.uber.org/zap"
+)
+
+// generateKey returns the key of a GenerateCompletion request
+func generateKey(t *testing.T, req domain.LLMRequest) string {
+	t.Helper()
+	n, err := normalizeGenerate(&req)
+	if err != nil {
+		t.Fatalf("normalizeGenerate() error = %v", err)
+	}
+	key, _, err := n.key()
+	if err != nil {
+		t.Fatalf("key() error = %v", err)
+	}
+	return key
+}
+
+// baseRequest returns the request the normalization cases vary
+func baseRequest() domain.LLMRequest {
+	return domain.LLMRequest{
+		Model:     "claude-test",
+		System:    "You are terse.",
+		MaxTokens: 256,
+		Messages: []domain.Message{
+			{Role: "user", Content: "What is 2+2?"},
+		},
+		Tools: []domain.Tool{
+			{Name: "calculator", Description: "Evaluates arithmetic", Parameters: map[string]interface{}{"type": "object", "required": []interface{}{"expr"}}},
+			{Name: "search", Description: "Searches the web"},
+		},
+	}
+}
+
+func TestRequestKey(t *testing.T) {
+	base := generateKey(t, baseRequest())
+
+	// The key is part of the file format: changing it breaks every
+	// recorded cassette
+	const want = "161f35806e2f8ca3e58ae2695332f37e472a442e8d1593b5c61ffa39a09a4a08"
+	if base != want {
+		t.Errorf("key = %s, want %s", base, want)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(r *domain.LLMRequest)
+		same   bool
+	}{
+		{"content whitespace", func(r *domain.LLMRequest) { r.Messages[0].Content = "  What is 2+2?\n" }, true},
+		{"system whitespace", func(r *domain.LLMRequest) { r.System = "\nYou are terse.  " }, true},
+		{"role case", func(r *domain.LLMRequest) { r.Messages[0].Role = "User" }, true},
+		{"tool order", func(r *domain.LLMRequest) { r.Tools[0], r.Tools[1] = r.Tools[1], r.Tools[0] }, true},
+		{"content", func(r *domain.LLMRequest) { r.Messages[0].Content = "What is 2+3?" }, false},
+		{"inner whitespace", func(r *domain.LLMRequest) { r.Messages[0].Content = "What is  2+2?" }, false},
+		{"role", func(r *domain.LLMRequest) { r.Messages[0].Role = "assistant" }, false},
+		{"model", func(r *domain.LLMRequest) { r.Model = "claude-other" }, false},
+		{"max tokens", func(r *domain.LLMRequest) { r.MaxTokens = 512 }, false},
+		{"temperature", func(r *domain.LLMRequest) { r.Temperature = 0.5 }, false},
+		{"tool description", func(r *domain.LLMRequest) { r.Tools[1].Description = "Searches" }, false},
+		{"tool parameters", func(r *domain.LLMRequest) { r.Tools[0].Parameters["type"] = "array" }, false},
+		{"dropped tool", func(r *domain.LLMRequest) { r.Tools = r.Tools[:1] }, false},
+		{"extra message", func(r *domain.LLMRequest) {
+			r.Messages = append(r.Messages, domain.Message{Role: "user", Content: "Thanks"})
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := baseRequest()
+			tt.modify(&req)
+			if got := generateKey(t, req) == base; got != tt.same {
+				t.Errorf("same key = %v, want %v", got, tt.same)
+			}
+		})
+	}
+}
+
+func TestRequestKeyMethods(t *testing.T) {
+	req := ports.CompletionRequest{
+		Model:    "claude-test",
+		Messages: []ports.Message{{Role: "user", Content: "hi"}},
+	}
+
+	keys := make(map[string]string)
+	for _, n := range []*normalizedRequest{
+		normalizeCompletion(MethodComplete, req, nil, nil),
+		normalizeCompletion(MethodCompleteWithTools, req, []ports.Tool{{Name: "search"}}, nil),
+		normalizeCompletion(MethodCompleteStructured, req, nil, ports.JSONSchema{"type": "object"}),
+	} {
+		key, _, err := n.key()
+		if err != nil {
+			t.Fatalf("key() error = %v", err)
+		}
+		if other, ok := keys[key]; ok {
+			t.Errorf("%s and %s share a key", n.Method, other)
+		}
+		keys[key] = n.Method
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "run.json")
+	ctx := context.Background()
+
+	question := &domain.LLMRequest{
+		Model:    "claude-test",
+		Messages: []domain.Message{{Role: "user", Content: "What is 2+2?"}},
+	}
+	chat := ports.CompletionRequest{
+		Model:    "claude-test",
+		Messages: []ports.Message{{Role: "user", Content: "hello"}},
+	}
+	failing := &domain.LLMRequest{
+		Model:    "claude-test",
+		Messages: []domain.Message{{Role: "user", Content: "fail"}},
+	}
+
+	live := fakellm.New(
+		fakellm.Response{Content: "4"},
+		fakellm.Response{Content: "four"},
+		fakellm.Response{Content: "hi there"},
+		fakellm.Response{Error: fakellm.ErrorServer},
+	)
+	recorder, err := NewRecorder(live, path, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+	if _, err := recorder.GenerateCompletion(ctx, question); err != nil {
+		t.Fatalf("GenerateCompletion() error = %v", err)
+	}
+	if _, err := recorder.GenerateCompletion(ctx, question); err != nil {
+		t.Fatalf("GenerateCompletion() error = %v", err)
+	}
+	if _, err := recorder.Complete(ctx, chat); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	_, liveErr := recorder.GenerateCompletion(ctx, failing)
+	if liveErr == nil {
+		t.Fatal("GenerateCompletion() succeeded, want the injected failure")
+	}
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(c.Interactions) != 4 {
+		t.Fatalf("cassette has %d interactions, want 4", len(c.Interactions))
+	}
+
+	replayer, err := NewReplayer(path)
+	if err != nil {
+		t.Fatalf("NewReplayer() error = %v", err)
+	}
+
+	// Identical requests are replayed in recording order, then the last
+	// recording repeats; cosmetic differences still match
+	reworded := *question
+	reworded.Messages = []domain.Message{{Role: "USER", Content: " What is 2+2? "}}
+	for i, want := range []string{"4", "four", "four"} {
+		req := question
+		if i == 1 {
+			req = &reworded
+		}
+		resp, err := replayer.GenerateCompletion(ctx, req)
+		if err != nil {
+			t.Fatalf("GenerateCompletion() #%d error = %v", i, err)
+		}
+		if got := resp.(*domain.LLMResponse); got.Content != want || got.Model != "claude-test" {
+			t.Errorf("GenerateCompletion() #%d = %+v, want %q", i, got, want)
+		}
+	}
+
+	resp, err := replayer.Complete(ctx, chat)
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if resp.Message.Content != "hi there" || resp.Message.Role != "assistant" {
+		t.Errorf("Complete() = %+v, want the recorded message", resp)
+	}
+
+	if _, err := replayer.GenerateCompletion(ctx, failing); err == nil || err.Error() != liveErr.Error() {
+		t.Errorf("GenerateCompletion() error = %v, want the recorded %v", err, liveErr)
+	}
+
+	// Recording again appends to the cassette
+	live.Enqueue(fakellm.Response{Content: "5"})
+	recorder, err = NewRecorder(live, path, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+	if _, err := recorder.GenerateCompletion(ctx, question); err != nil {
+		t.Fatalf("GenerateCompletion() error = %v", err)
+	}
+	if c, err := Load(path); err != nil || len(c.Interactions) != 5 {
+		t.Errorf("cassette after a second recording = %v, %v; want 5 interactions", c, err)
+	}
+}
+
+func TestReplayUnmatched(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.json")
+	live := fakellm.New(fakellm.Response{Content: "4"})
+	recorder, err := NewRecorder(live, path, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+	recorded := ports.CompletionRequest{
+		Model:    "claude-test",
+		Messages: []ports.Message{{Role: "user", Content: "What is 2+2?"}},
+	}
+	if _, err := recorder.Complete(context.Background(), recorded); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+
+	replayer, err := NewReplayer(path)
+	if err != nil {
+		t.Fatalf("NewReplayer() error = %v", err)
+	}
+	ctx := context.Background()
+
+	changed := recorded
+	changed.Messages = []ports.Message{{Role: "user", Content: "What is 2+3?"}}
+	if _, err := replayer.Complete(ctx, changed); !errors.Is(err, ErrNoInteraction) || !strings.Contains(err.Error(), "2+3") {
+		t.Errorf("Complete() with another message error = %v, want ErrNoInteraction naming the request", err)
+	}
+
+	// The same messages through another method are another request
+	if _, err := replayer.CompleteWithTools(ctx, recorded, nil); !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("CompleteWithTools() error = %v, want ErrNoInteraction", err)
+	}
+
+	if _, err := replayer.GenerateCompletion(ctx, "What is 2+2?"); err == nil || errors.Is(err, ErrNoInteraction) {
+		t.Errorf("GenerateCompletion() with a string error = %v, want an unsupported request error", err)
+	}
+
+	if _, err := NewReplayer(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("NewReplayer() without a cassette succeeded, want an error")
+	}
+}
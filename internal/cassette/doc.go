@@ -0,0 +1,9 @@
+// Package cassette records LLM interactions to files and replays them
+// offline.
+//
+// A Recorder wraps a live ports.LLMClient and writes every request and its
+// response (or error) to a cassette file. A Replayer serves those
+// responses without a provider, matching requests by a hash of their
+// normalized content, and fails on requests the cassette does not contain.
+// This makes LLM and agent nodes testable without provider credentials.
+package cassette
@@ -0,0 +1,112 @@
+package cassette
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/aescanero/dago-libs/pkg/ports"
+	"go.uber.org/zap"
+)
+
+// Recorder is a ports.LLMClient that forwards calls to a live client and
+// records every interaction to a cassette file. Interactions are appended
+// to an existing cassette and the file is rewritten after each call.
+type Recorder struct {
+	client ports.LLMClient
+	path   string
+	logger *zap.Logger
+
+	mu       sync.Mutex
+	cassette *Cassette
+}
+
+// NewRecorder creates a recorder writing to path
+func NewRecorder(client ports.LLMClient, path string, logger *zap.Logger) (*Recorder, error) {
+	c, err := Load(path)
+	if errors.Is(err, os.ErrNotExist) {
+		c = &Cassette{Version: formatVersion}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &Recorder{
+		client:   client,
+		path:     path,
+		logger:   logger,
+		cassette: c,
+	}, nil
+}
+
+// Complete forwards and records a text completion
+func (r *Recorder) Complete(ctx context.Context, req ports.CompletionRequest) (*ports.CompletionResponse, error) {
+	resp, err := r.client.Complete(ctx, req)
+	r.record(normalizeCompletion(MethodComplete, req, nil, nil), resp, err)
+	return resp, err
+}
+
+// CompleteWithTools forwards and records a completion with tools
+func (r *Recorder) CompleteWithTools(ctx context.Context, req ports.CompletionRequest, tools []ports.Tool) (*ports.CompletionResponse, error) {
+	resp, err := r.client.CompleteWithTools(ctx, req, tools)
+	r.record(normalizeCompletion(MethodCompleteWithTools, req, tools, nil), resp, err)
+	return resp, err
+}
+
+// CompleteStructured forwards and records a structured completion
+func (r *Recorder) CompleteStructured(ctx context.Context, req ports.CompletionRequest, schema ports.JSONSchema) (*ports.StructuredResponse, error) {
+	resp, err := r.client.CompleteStructured(ctx, req, schema)
+	r.record(normalizeCompletion(MethodCompleteStructured, req, nil, schema), resp, err)
+	return resp, err
+}
+
+// GenerateCompletion forwards and records a domain.LLMRequest completion
+func (r *Recorder) GenerateCompletion(ctx context.Context, req interface{}) (interface{}, error) {
+	resp, err := r.client.GenerateCompletion(ctx, req)
+
+	n, nerr := normalizeGenerate(req)
+	if nerr != nil {
+		r.logger.Warn("not recording LLM interaction", zap.Error(nerr))
+		return resp, err
+	}
+	r.record(n, resp, err)
+
+	return resp, err
+}
+
+// record appends an interaction and saves the cassette. Recording failures
+// are logged; they never fail the call itself.
+func (r *Recorder) record(req *normalizedRequest, resp interface{}, callErr error) {
+	key, reqJSON, err := req.key()
+	if err != nil {
+		r.logger.Warn("not recording LLM interaction", zap.Error(err))
+		return
+	}
+
+	interaction := &Interaction{
+		Key:     key,
+		Method:  req.Method,
+		Request: reqJSON,
+	}
+	if callErr != nil {
+		interaction.Error = callErr.Error()
+	} else {
+		respJSON, err := json.Marshal(resp)
+		if err != nil {
+			r.logger.Warn("not recording LLM interaction",
+				zap.Error(fmt.Errorf("failed to encode response: %w", err)))
+			return
+		}
+		interaction.Response = respJSON
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	if err := r.cassette.Save(r.path); err != nil {
+		r.logger.Error("failed to save cassette", zap.String("path", r.path), zap.Error(err))
+	}
+}
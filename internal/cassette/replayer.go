@@ -0,0 +1,113 @@
+package cassette
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// ErrNoInteraction is returned for requests the cassette does not contain
+var ErrNoInteraction = errors.New("no recorded interaction matches the request")
+
+// Replayer is a ports.LLMClient serving recorded interactions offline.
+// Identical requests recorded several times are replayed in recording
+// order; once exhausted, the last recording is repeated.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions map[string][]*Interaction
+	served       map[string]int
+}
+
+// NewReplayer loads the cassette at path
+func NewReplayer(path string) (*Replayer, error) {
+	c, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Replayer{
+		interactions: make(map[string][]*Interaction),
+		served:       make(map[string]int),
+	}
+	for _, interaction := range c.Interactions {
+		r.interactions[interaction.Key] = append(r.interactions[interaction.Key], interaction)
+	}
+
+	return r, nil
+}
+
+// Complete replays a text completion
+func (r *Replayer) Complete(ctx context.Context, req ports.CompletionRequest) (*ports.CompletionResponse, error) {
+	var resp ports.CompletionResponse
+	if err := r.replay(normalizeCompletion(MethodComplete, req, nil, nil), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CompleteWithTools replays a completion with tools
+func (r *Replayer) CompleteWithTools(ctx context.Context, req ports.CompletionRequest, tools []ports.Tool) (*ports.CompletionResponse, error) {
+	var resp ports.CompletionResponse
+	if err := r.replay(normalizeCompletion(MethodCompleteWithTools, req, tools, nil), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CompleteStructured replays a structured completion
+func (r *Replayer) CompleteStructured(ctx context.Context, req ports.CompletionRequest, schema ports.JSONSchema) (*ports.StructuredResponse, error) {
+	var resp ports.StructuredResponse
+	if err := r.replay(normalizeCompletion(MethodCompleteStructured, req, nil, schema), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GenerateCompletion replays a domain.LLMRequest completion
+func (r *Replayer) GenerateCompletion(ctx context.Context, req interface{}) (interface{}, error) {
+	n, err := normalizeGenerate(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp domain.LLMResponse
+	if err := r.replay(n, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// replay finds the interaction for req and decodes its response into out
+func (r *Replayer) replay(req *normalizedRequest, out interface{}) error {
+	key, _, err := req.key()
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	recorded := r.interactions[key]
+	if len(recorded) == 0 {
+		r.mu.Unlock()
+		return fmt.Errorf("%w: key %s (%s)", ErrNoInteraction, key[:12], req.describe())
+	}
+	i := r.served[key]
+	if i >= len(recorded) {
+		i = len(recorded) - 1
+	}
+	r.served[key]++
+	interaction := recorded[i]
+	r.mu.Unlock()
+
+	if interaction.Error != "" {
+		return errors.New(interaction.Error)
+	}
+	if err := json.Unmarshal(interaction.Response, out); err != nil {
+		return fmt.Errorf("failed to decode recorded response: %w", err)
+	}
+	return nil
+}
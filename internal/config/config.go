@@ -2,75 +2,671 @@ package config
 
 import (
 	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/caarlos0/env/v10"
 )
 
+// supportedProviders lists the LLM providers available through dago-adapters
+var supportedProviders = map[string]bool{
+	"anthropic": true,
+	"openai":    true,
+	"gemini":    true,
+	"ollama":    true,
+
+	// Offline providers: cassette replay (see LLMCassette) and a
+	// scripted fake (see LLMFakeScript)
+	"replay": true,
+	"fake":   true,
+}
+
+// keylessProviders lists the providers that need no API key
+var keylessProviders = map[string]bool{
+	"ollama": true,
+	"replay": true,
+	"fake":   true,
+}
+
+// offlineProviders lists the providers that serve canned responses
+var offlineProviders = map[string]bool{
+	"replay": true,
+	"fake":   true,
+}
+
+// specNamePattern matches OpenAPI spec names, which prefix tool names
+var specNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
 // Config holds all configuration for the executor worker
 type Config struct {
+	// Config file (optional, YAML or JSON)
+	ConfigFile           string        `env:"CONFIG_FILE"`
+	ConfigReloadInterval time.Duration `env:"CONFIG_RELOAD_INTERVAL" envDefault:"10s"`
+
 	// Worker
 	WorkerID string `env:"WORKER_ID" envDefault:"executor-1"`
 
-	// Redis
-	RedisAddr string `env:"REDIS_ADDR" envDefault:"localhost:6379"`
-	RedisPass string `env:"REDIS_PASS"`
-	RedisDB   int    `env:"REDIS_DB" envDefault:"0"`
+	// Capabilities advertised for routing in addition to the LLM provider,
+	// model aliases and tools. Labels are key[=value] entries.
+	WorkerLabels []string `env:"WORKER_LABELS" envSeparator:","`
+	WorkerModels []string `env:"WORKER_MODELS" envSeparator:","`
+
+	// Priority and capability routing over Redis streams. Priority weights
+	// are priority=weight entries.
+	RoutingEnabled         bool          `env:"ROUTING_ENABLED" envDefault:"false"`
+	RoutingBaseStream      string        `env:"ROUTING_BASE_STREAM" envDefault:"executor.work"`
+	RoutingPriorityWeights []string      `env:"ROUTING_PRIORITY_WEIGHTS" envSeparator:"," envDefault:"high=4,normal=2,low=1"`
+	RoutingRegistryTTL     time.Duration `env:"ROUTING_REGISTRY_TTL" envDefault:"30s"`
+
+	// Queue backend for work, events and state (redis, nats, memory)
+	QueueBackend string `env:"QUEUE_BACKEND" envDefault:"redis"`
+
+	// NATS JetStream work and events (graph state stays on Redis)
+	NATSURL          string        `env:"NATS_URL" envDefault:"nats://localhost:4222"`
+	NATSStream       string        `env:"NATS_STREAM" envDefault:"EXECUTOR_WORK"`
+	NATSSubject      string        `env:"NATS_SUBJECT" envDefault:"executor.work"`
+	NATSConsumer     string        `env:"NATS_CONSUMER" envDefault:"executor-workers"`
+	NATSDLQSubject   string        `env:"NATS_DLQ_SUBJECT" envDefault:"executor.work.dlq"`
+	NATSEventsStream string        `env:"NATS_EVENTS_STREAM" envDefault:"DAGO_EVENTS"`
+	NATSMaxDeliver   int           `env:"NATS_MAX_DELIVER" envDefault:"5"`
+	NATSAckWait      time.Duration `env:"NATS_ACK_WAIT" envDefault:"15m"`
+
+	// Redis. RedisAddr is comma-separated cluster seeds in cluster mode.
+	RedisMode     string `env:"REDIS_MODE" envDefault:"standalone"`
+	RedisAddr     string `env:"REDIS_ADDR" envDefault:"localhost:6379"`
+	RedisUsername string `env:"REDIS_USERNAME"`
+	RedisPass     string `env:"REDIS_PASS"`
+	RedisDB       int    `env:"REDIS_DB" envDefault:"0"`
+
+	// Redis keys. Work streams are key[=weight] entries.
+	RedisNamespace     string        `env:"REDIS_NAMESPACE"`
+	RedisWorkStreams   []string      `env:"REDIS_WORK_STREAMS" envSeparator:"," envDefault:"executor.work"`
+	RedisConsumerGroup string        `env:"REDIS_CONSUMER_GROUP" envDefault:"executor-workers"`
+	RedisStatePrefix   string        `env:"REDIS_STATE_PREFIX" envDefault:"dago:state:"`
+	RedisEventsPrefix  string        `env:"REDIS_EVENTS_PREFIX" envDefault:"dago:events:"`
+	RedisStateTTL      time.Duration `env:"REDIS_STATE_TTL" envDefault:"24h"`
+
+	// Redis Sentinel (REDIS_MODE=sentinel)
+	RedisSentinelMaster   string   `env:"REDIS_SENTINEL_MASTER"`
+	RedisSentinelAddrs    []string `env:"REDIS_SENTINEL_ADDRS" envSeparator:","`
+	RedisSentinelUsername string   `env:"REDIS_SENTINEL_USERNAME"`
+	RedisSentinelPass     string   `env:"REDIS_SENTINEL_PASS"`
+
+	// Redis TLS
+	RedisTLS                   bool   `env:"REDIS_TLS" envDefault:"false"`
+	RedisTLSCAFile             string `env:"REDIS_TLS_CA_FILE"`
+	RedisTLSCertFile           string `env:"REDIS_TLS_CERT_FILE"`
+	RedisTLSKeyFile            string `env:"REDIS_TLS_KEY_FILE"`
+	RedisTLSServerName         string `env:"REDIS_TLS_SERVER_NAME"`
+	RedisTLSInsecureSkipVerify bool   `env:"REDIS_TLS_INSECURE_SKIP_VERIFY" envDefault:"false"`
+
+	// Redis connection pool (0 keeps the client defaults)
+	RedisPoolSize     int           `env:"REDIS_POOL_SIZE" envDefault:"0"`
+	RedisMinIdleConns int           `env:"REDIS_MIN_IDLE_CONNS" envDefault:"0"`
+	RedisPoolTimeout  time.Duration `env:"REDIS_POOL_TIMEOUT" envDefault:"0"`
+	RedisDialTimeout  time.Duration `env:"REDIS_DIAL_TIMEOUT" envDefault:"0"`
+	RedisReadTimeout  time.Duration `env:"REDIS_READ_TIMEOUT" envDefault:"0"`
+	RedisWriteTimeout time.Duration `env:"REDIS_WRITE_TIMEOUT" envDefault:"0"`
+	RedisMaxRetries   int           `env:"REDIS_MAX_RETRIES" envDefault:"0"`
 
 	// LLM
 	LLMProvider string `env:"LLM_PROVIDER" envDefault:"anthropic"`
 	LLMAPIKey   string `env:"LLM_API_KEY"`
 	LLMModel    string `env:"LLM_MODEL" envDefault:"claude-sonnet-4-20250514"`
 
+	// Shared LLM rate limit across workers (needs Redis). RPM and TPM
+	// apply to all models of LLM_PROVIDER; per-model limits come from the
+	// config file. 0 is unlimited.
+	LLMRateLimitEnabled bool          `env:"LLM_RATE_LIMIT_ENABLED" envDefault:"true"`
+	LLMRateLimitRPM     int           `env:"LLM_RATE_LIMIT_RPM" envDefault:"0"`
+	LLMRateLimitTPM     int           `env:"LLM_RATE_LIMIT_TPM" envDefault:"0"`
+	LLMRateLimitMaxWait time.Duration `env:"LLM_RATE_LIMIT_MAX_WAIT" envDefault:"1m"`
+
+	// LLM cassette: replayed by the replay provider, or recorded from the
+	// configured provider when LLMCassetteRecord is set
+	LLMCassette       string `env:"LLM_CASSETTE"`
+	LLMCassetteRecord bool   `env:"LLM_CASSETTE_RECORD" envDefault:"false"`
+
+	// Script file for the fake provider
+	LLMFakeScript string `env:"LLM_FAKE_SCRIPT"`
+
 	// MCP
 	MCPServers []string `env:"MCP_SERVERS" envSeparator:","`
 
+	// Longest wait for a rate or concurrency slot of a tool with a policy
+	// in tool_policies (needs Redis)
+	ToolLimitMaxWait time.Duration `env:"TOOL_LIMIT_MAX_WAIT" envDefault:"30s"`
+
+	// Audit log of tool calls; params are only logged, redacted, when
+	// ToolAuditParams is set. ToolRedactKeys adds secret parameter names
+	// to the built-in ones.
+	ToolAuditEnabled bool     `env:"TOOL_AUDIT_ENABLED" envDefault:"true"`
+	ToolAuditParams  bool     `env:"TOOL_AUDIT_PARAMS" envDefault:"false"`
+	ToolRedactKeys   []string `env:"TOOL_REDACT_KEYS" envSeparator:","`
+
+	// Results kept for tools with a cache_ttl in tool_policies
+	ToolCacheSize int `env:"TOOL_CACHE_SIZE" envDefault:"1000"`
+
+	// Built-in standard tools (math_eval, json_query, scratch_set, ...);
+	// the graph scratchpad expires after ScratchTTL without writes
+	BuiltinTools bool          `env:"BUILTIN_TOOLS" envDefault:"true"`
+	ScratchTTL   time.Duration `env:"SCRATCH_TTL" envDefault:"24h"`
+
+	// fs_* tools confined to a per-graph workspace under WorkspaceDir
+	// (default: a dago-workspaces directory in the system temp dir),
+	// disabled unless FS_TOOLS=true. Workspaces of graphs whose state
+	// expired are removed every WorkspaceSweepInterval.
+	FSTools                bool          `env:"FS_TOOLS" envDefault:"false"`
+	WorkspaceDir           string        `env:"WORKSPACE_DIR"`
+	WorkspaceQuotaMB       int           `env:"WORKSPACE_QUOTA_MB" envDefault:"100"`
+	WorkspaceMaxFileMB     int           `env:"WORKSPACE_MAX_FILE_MB" envDefault:"10"`
+	WorkspaceMaxFiles      int           `env:"WORKSPACE_MAX_FILES" envDefault:"10000"`
+	WorkspaceSweepInterval time.Duration `env:"WORKSPACE_SWEEP_INTERVAL" envDefault:"10m"`
+
+	// http_request tool, registered when the config file defines
+	// http.endpoints
+	HTTPToolTimeout       time.Duration `env:"HTTP_TOOL_TIMEOUT" envDefault:"30s"`
+	HTTPToolMaxResponseKB int           `env:"HTTP_TOOL_MAX_RESPONSE_KB" envDefault:"1024"`
+
 	// Agent
 	MaxIterations int `env:"MAX_ITERATIONS" envDefault:"10"`
 
+	// Node config validation before execution (off, warn, strict)
+	NodeValidation string `env:"NODE_VALIDATION" envDefault:"warn"`
+
+	// Reject node configs without an explicit mode whose keys are ambiguous
+	StrictModeDetection bool `env:"STRICT_MODE_DETECTION" envDefault:"false"`
+
 	// Logging
 	LogLevel string `env:"LOG_LEVEL" envDefault:"info"`
 
 	// Health
-	HealthPort int `env:"HEALTH_PORT" envDefault:"8081"`
+	HealthPort       int           `env:"HEALTH_PORT" envDefault:"8081"`
+	LoopStallTimeout time.Duration `env:"LOOP_STALL_TIMEOUT" envDefault:"15m"`
+	LLMCheckInterval time.Duration `env:"LLM_CHECK_INTERVAL" envDefault:"10m"`
+
+	// Admin API (disabled when empty)
+	AdminToken string `env:"ADMIN_TOKEN"`
+
+	// Tracing
+	TracingEnabled     bool    `env:"TRACING_ENABLED" envDefault:"false"`
+	TracingEndpoint    string  `env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	TracingInsecure    bool    `env:"OTEL_EXPORTER_OTLP_INSECURE" envDefault:"false"`
+	TracingServiceName string  `env:"OTEL_SERVICE_NAME" envDefault:"dago-node-executor"`
+	TracingSampleRatio float64 `env:"TRACING_SAMPLE_RATIO" envDefault:"1.0"`
+
+	// File-only settings (see File)
+	Providers     map[string]ProviderConfig
+	ModelAliases  map[string]string
+	Prices        map[string]ModelPrice
+	Profiles      map[string]map[string]interface{}
+	ToolPolicies  map[string]ToolPolicy
+	ToolAccess    ToolAccess
+	MCPServerDefs []MCPServer
+	RateLimitDefs []RateLimit
+	HTTPEndpoints map[string]HTTPEndpoint
+	AuthProfiles  map[string]AuthProfile
+	OpenAPISpecs  []OpenAPISpec
+	ToolHooks     []ToolHook
 }
 
-// Load reads configuration from environment variables
+// Load reads configuration from environment variables and, if CONFIG_FILE
+// is set, from a YAML or JSON file. Environment variables take precedence.
 func Load() (*Config, error) {
+	cfg, err := Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// Parse reads configuration from the environment and the config file
+// without validating it
+func Parse() (*Config, error) {
 	cfg := &Config{}
 	if err := env.Parse(cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
-	if err := cfg.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid config: %w", err)
+	if cfg.ConfigFile != "" {
+		f, err := readFile(cfg.ConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		f.apply(cfg)
+	}
+
+	// Fall back to the provider credentials from the file
+	if cfg.LLMAPIKey == "" {
+		cfg.LLMAPIKey = cfg.Providers[cfg.LLMProvider].APIKey
 	}
 
 	return cfg, nil
 }
 
+// validateRedis checks the Redis connection settings
+func (c *Config) validateRedis() error {
+	switch c.RedisMode {
+	case "standalone":
+		if c.RedisAddr == "" {
+			return fmt.Errorf("redis address is required")
+		}
+		if strings.Contains(c.RedisAddr, ",") {
+			return fmt.Errorf("REDIS_ADDR lists several addresses; set REDIS_MODE=cluster for cluster seeds")
+		}
+	case "sentinel":
+		if c.RedisSentinelMaster == "" || len(c.RedisSentinelAddrs) == 0 {
+			return fmt.Errorf("REDIS_SENTINEL_MASTER and REDIS_SENTINEL_ADDRS are required in sentinel mode")
+		}
+	case "cluster":
+		if c.RedisAddr == "" {
+			return fmt.Errorf("redis cluster seed addresses are required")
+		}
+		if c.RedisDB != 0 {
+			return fmt.Errorf("REDIS_DB is not supported in cluster mode")
+		}
+	default:
+		return fmt.Errorf("unsupported redis mode: %s", c.RedisMode)
+	}
+
+	streams, err := c.WorkStreams()
+	if err != nil {
+		return err
+	}
+	if c.RedisMode == "cluster" && len(streams) > 1 {
+		// Several streams are read in one command, so they must share a slot
+		tag := hashTag(c.namespaced(streams[0].Key))
+		for _, stream := range streams[1:] {
+			if tag == "" || hashTag(c.namespaced(stream.Key)) != tag {
+				return fmt.Errorf("in cluster mode, work streams must share a hash tag, e.g. {work}.priority and {work}.bulk")
+			}
+		}
+	}
+	if c.RedisMode == "cluster" && c.RoutingEnabled {
+		// Routed streams are named after the base stream and read together
+		// with the work streams
+		tag := hashTag(c.namespaced(c.RoutingBaseStream))
+		if tag == "" || tag != hashTag(c.namespaced(streams[0].Key)) {
+			return fmt.Errorf("in cluster mode with routing, ROUTING_BASE_STREAM must share the work streams' hash tag, e.g. {work}")
+		}
+	}
+	if c.RedisConsumerGroup == "" {
+		return fmt.Errorf("REDIS_CONSUMER_GROUP is required")
+	}
+	if c.RedisStateTTL <= 0 {
+		return fmt.Errorf("REDIS_STATE_TTL must be positive")
+	}
+
+	if (c.RedisTLSCertFile == "") != (c.RedisTLSKeyFile == "") {
+		return fmt.Errorf("REDIS_TLS_CERT_FILE and REDIS_TLS_KEY_FILE must be set together")
+	}
+	if !c.RedisTLS && (c.RedisTLSCAFile != "" || c.RedisTLSCertFile != "") {
+		return fmt.Errorf("redis TLS files are set but REDIS_TLS is false")
+	}
+	if c.RedisPoolSize < 0 || c.RedisMinIdleConns < 0 {
+		return fmt.Errorf("redis pool sizes must not be negative")
+	}
+
+	return nil
+}
+
+// validateRouting checks the routing settings
+func (c *Config) validateRouting() error {
+	if !c.RoutingEnabled {
+		return nil
+	}
+	if c.RoutingBaseStream == "" {
+		return fmt.Errorf("ROUTING_BASE_STREAM is required")
+	}
+	if _, err := c.PriorityWeights(); err != nil {
+		return err
+	}
+	if c.RoutingRegistryTTL < 3*time.Second {
+		return fmt.Errorf("ROUTING_REGISTRY_TTL must be at least 3s")
+	}
+	return nil
+}
+
+// WorkStream is a Redis work stream and its relative share of fetches
+type WorkStream struct {
+	Key    string
+	Weight int
+}
+
+// WorkStreams parses the key[=weight] entries of REDIS_WORK_STREAMS
+func (c *Config) WorkStreams() ([]WorkStream, error) {
+	if len(c.RedisWorkStreams) == 0 {
+		return nil, fmt.Errorf("REDIS_WORK_STREAMS is empty")
+	}
+
+	seen := make(map[string]bool)
+	streams := make([]WorkStream, 0, len(c.RedisWorkStreams))
+	for _, entry := range c.RedisWorkStreams {
+		key, weight, hasWeight := strings.Cut(strings.TrimSpace(entry), "=")
+		stream := WorkStream{Key: strings.TrimSpace(key), Weight: 1}
+		if stream.Key == "" {
+			return nil, fmt.Errorf("invalid work stream %q: empty key", entry)
+		}
+		if hasWeight {
+			n, err := strconv.Atoi(strings.TrimSpace(weight))
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid work stream %q: weight must be a positive integer", entry)
+			}
+			stream.Weight = n
+		}
+		if seen[stream.Key] {
+			return nil, fmt.Errorf("duplicate work stream %s", stream.Key)
+		}
+		seen[stream.Key] = true
+		streams = append(streams, stream)
+	}
+	return streams, nil
+}
+
+// PriorityWeights parses the priority=weight entries of
+// ROUTING_PRIORITY_WEIGHTS. Priorities left out get weight 1.
+func (c *Config) PriorityWeights() (map[string]int, error) {
+	weights := map[string]int{"high": 1, "normal": 1, "low": 1}
+	for _, entry := range c.RoutingPriorityWeights {
+		priority, weight, _ := strings.Cut(strings.TrimSpace(entry), "=")
+		priority = strings.TrimSpace(priority)
+		if _, ok := weights[priority]; !ok {
+			return nil, fmt.Errorf("invalid priority weight %q: priority must be high, normal or low", entry)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(weight))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid priority weight %q: weight must be a positive integer", entry)
+		}
+		weights[priority] = n
+	}
+	return weights, nil
+}
+
+// Labels parses the key[=value] entries of WORKER_LABELS; a bare key has
+// the value "true"
+func (c *Config) Labels() map[string]string {
+	labels := make(map[string]string, len(c.WorkerLabels))
+	for _, entry := range c.WorkerLabels {
+		key, value, hasValue := strings.Cut(strings.TrimSpace(entry), "=")
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		if !hasValue {
+			value = "true"
+		}
+		labels[key] = strings.TrimSpace(value)
+	}
+	return labels
+}
+
+// namespaced returns key as stored in Redis, under REDIS_NAMESPACE if set
+func (c *Config) namespaced(key string) string {
+	if c.RedisNamespace == "" {
+		return key
+	}
+	return c.RedisNamespace + ":" + key
+}
+
+// hashTag returns the Redis Cluster hash tag of key, or "" if it has none
+func hashTag(key string) string {
+	start := strings.IndexByte(key, '{')
+	if start < 0 {
+		return ""
+	}
+	end := strings.IndexByte(key[start+1:], '}')
+	if end <= 0 {
+		return ""
+	}
+	return key[start+1 : start+1+end]
+}
+
+// OfflineLLM reports whether the LLM provider serves canned responses
+// instead of calling a model
+func (c *Config) OfflineLLM() bool {
+	return offlineProviders[c.LLMProvider]
+}
+
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
 	if c.WorkerID == "" {
 		return fmt.Errorf("worker ID is required")
 	}
 
-	if c.RedisAddr == "" {
-		return fmt.Errorf("redis address is required")
+	switch c.QueueBackend {
+	case "redis":
+		if err := c.validateRedis(); err != nil {
+			return err
+		}
+		if err := c.validateRouting(); err != nil {
+			return err
+		}
+	case "nats":
+		if c.NATSURL == "" {
+			return fmt.Errorf("NATS URL is required")
+		}
+		// Graph state stays on Redis
+		if err := c.validateRedis(); err != nil {
+			return err
+		}
+		if c.NATSMaxDeliver < 1 {
+			return fmt.Errorf("NATS_MAX_DELIVER must be at least 1")
+		}
+		if c.NATSAckWait <= 0 {
+			return fmt.Errorf("NATS_ACK_WAIT must be positive")
+		}
+		if c.NATSDLQSubject == c.NATSSubject {
+			return fmt.Errorf("NATS_DLQ_SUBJECT must differ from NATS_SUBJECT")
+		}
+	case "memory":
+	default:
+		return fmt.Errorf("unsupported queue backend: %s", c.QueueBackend)
+	}
+	if c.RoutingEnabled && c.QueueBackend != "redis" {
+		return fmt.Errorf("routing requires the redis queue backend")
 	}
 
-	if c.LLMAPIKey == "" {
+	if !supportedProviders[c.LLMProvider] {
+		return fmt.Errorf("unsupported LLM provider: %s", c.LLMProvider)
+	}
+
+	if c.LLMAPIKey == "" && !keylessProviders[c.LLMProvider] {
 		return fmt.Errorf("LLM API key is required")
 	}
 
-	if c.LLMProvider != "anthropic" {
-		return fmt.Errorf("unsupported LLM provider: %s (only 'anthropic' supported in MVP)", c.LLMProvider)
+	if c.LLMProvider == "replay" || c.LLMCassetteRecord {
+		if c.LLMCassette == "" {
+			return fmt.Errorf("LLM_CASSETTE is required to replay or record a cassette")
+		}
+	}
+	if c.OfflineLLM() && c.LLMCassetteRecord {
+		return fmt.Errorf("cannot record a cassette with the %s provider", c.LLMProvider)
+	}
+	if c.LLMProvider == "fake" && c.LLMFakeScript == "" {
+		return fmt.Errorf("LLM_FAKE_SCRIPT is required for the fake provider")
+	}
+
+	for name := range c.Providers {
+		if !supportedProviders[name] {
+			return fmt.Errorf("unsupported provider in config file: %s", name)
+		}
+	}
+
+	for alias, model := range c.ModelAliases {
+		if model == "" {
+			return fmt.Errorf("model alias %s has no target", alias)
+		}
+		if _, ok := c.ModelAliases[model]; ok {
+			return fmt.Errorf("model alias %s points to another alias (%s)", alias, model)
+		}
+	}
+
+	for model, price := range c.Prices {
+		if price.InputPerMillion < 0 || price.OutputPerMillion < 0 {
+			return fmt.Errorf("negative price for model %s", model)
+		}
+	}
+
+	if c.LLMRateLimitMaxWait < 0 {
+		return fmt.Errorf("LLM_RATE_LIMIT_MAX_WAIT must not be negative")
+	}
+	for _, limit := range c.RateLimits() {
+		if !supportedProviders[limit.Provider] {
+			return fmt.Errorf("unsupported provider in rate limit: %s", limit.Provider)
+		}
+		if limit.RPM < 0 || limit.TPM < 0 {
+			return fmt.Errorf("negative rate limit for %s %s", limit.Provider, limit.Model)
+		}
+	}
+
+	if c.ToolLimitMaxWait < 0 {
+		return fmt.Errorf("TOOL_LIMIT_MAX_WAIT must not be negative")
+	}
+	if c.ToolCacheSize < 0 {
+		return fmt.Errorf("TOOL_CACHE_SIZE must not be negative")
+	}
+	if c.ScratchTTL <= 0 {
+		return fmt.Errorf("SCRATCH_TTL must be positive")
+	}
+	if c.WorkspaceQuotaMB <= 0 || c.WorkspaceMaxFileMB <= 0 || c.WorkspaceMaxFiles <= 0 {
+		return fmt.Errorf("WORKSPACE_QUOTA_MB, WORKSPACE_MAX_FILE_MB and WORKSPACE_MAX_FILES must be positive")
+	}
+	if c.WorkspaceSweepInterval <= 0 {
+		return fmt.Errorf("WORKSPACE_SWEEP_INTERVAL must be positive")
+	}
+	for tool, policy := range c.ToolPolicies {
+		if policy.Timeout < 0 {
+			return fmt.Errorf("negative timeout in tool policy %s", tool)
+		}
+		if policy.CacheTTL < 0 {
+			return fmt.Errorf("negative cache_ttl in tool policy %s", tool)
+		}
+		if policy.MaxConcurrent < 0 || policy.RatePerSecond < 0 || policy.BreakerThreshold < 0 || policy.BreakerCooldown < 0 {
+			return fmt.Errorf("negative limit in tool policy %s", tool)
+		}
+	}
+
+	if err := c.ToolAccess.validate(); err != nil {
+		return err
+	}
+	hookNames := make(map[string]bool)
+	for _, hook := range c.ToolHooks {
+		if hook.Name == "" {
+			return fmt.Errorf("tool hook name is required")
+		}
+		if hookNames[hook.Name] {
+			return fmt.Errorf("duplicate tool hook: %s", hook.Name)
+		}
+		hookNames[hook.Name] = true
+		if hook.Phase != "before" && hook.Phase != "after" {
+			return fmt.Errorf("tool hook %s: phase must be before or after", hook.Name)
+		}
+		u, err := url.Parse(hook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("tool hook %s: url must be an http or https URL", hook.Name)
+		}
+		if hook.Timeout < 0 {
+			return fmt.Errorf("tool hook %s: negative timeout", hook.Name)
+		}
+		for _, glob := range hook.Tools {
+			if _, err := path.Match(glob, ""); err != nil {
+				return fmt.Errorf("tool hook %s: invalid tools pattern %q", hook.Name, glob)
+			}
+		}
+	}
+
+	if c.HTTPToolTimeout <= 0 || c.HTTPToolMaxResponseKB <= 0 {
+		return fmt.Errorf("HTTP_TOOL_TIMEOUT and HTTP_TOOL_MAX_RESPONSE_KB must be positive")
+	}
+	for name, profile := range c.AuthProfiles {
+		if err := profile.validate(); err != nil {
+			return fmt.Errorf("auth profile %s: %w", name, err)
+		}
+	}
+	for name, endpoint := range c.HTTPEndpoints {
+		u, err := url.Parse(endpoint.BaseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("http endpoint %s: base_url must be an http or https URL", name)
+		}
+		if _, ok := c.AuthProfiles[endpoint.Auth]; endpoint.Auth != "" && !ok {
+			return fmt.Errorf("http endpoint %s: unknown auth profile %s", name, endpoint.Auth)
+		}
+	}
+	specNames := make(map[string]bool)
+	for _, spec := range c.OpenAPISpecs {
+		if !specNamePattern.MatchString(spec.Name) || strings.Contains(spec.Name, "__") {
+			return fmt.Errorf("openapi spec name %q must be letters, digits, '-' and single '_'", spec.Name)
+		}
+		if specNames[spec.Name] {
+			return fmt.Errorf("duplicate openapi spec name: %s", spec.Name)
+		}
+		specNames[spec.Name] = true
+		if spec.Source == "" {
+			return fmt.Errorf("openapi spec %s: source is required", spec.Name)
+		}
+		if spec.BaseURL != "" {
+			u, err := url.Parse(spec.BaseURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("openapi spec %s: base_url must be an http or https URL", spec.Name)
+			}
+		}
+		if _, ok := c.AuthProfiles[spec.Auth]; spec.Auth != "" && !ok {
+			return fmt.Errorf("openapi spec %s: unknown auth profile %s", spec.Name, spec.Auth)
+		}
+		for _, glob := range spec.Operations {
+			if _, err := path.Match(glob, ""); err != nil {
+				return fmt.Errorf("openapi spec %s: invalid operations pattern %q", spec.Name, glob)
+			}
+		}
+	}
+
+	serverNames := make(map[string]bool)
+	for _, server := range c.GetMCPServers() {
+		if server.Name == "" {
+			return fmt.Errorf("MCP server name is required")
+		}
+		if serverNames[server.Name] {
+			return fmt.Errorf("duplicate MCP server: %s", server.Name)
+		}
+		serverNames[server.Name] = true
+
+		if (server.URL == "") == (server.Command == "") {
+			return fmt.Errorf("MCP server %s needs exactly one of url or command", server.Name)
+		}
+		if server.URL != "" {
+			if u, err := url.Parse(server.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("MCP server %s: url must be http or https: %s", server.Name, server.URL)
+			}
+		}
+	}
+
+	if c.ConfigReloadInterval < 0 {
+		return fmt.Errorf("config reload interval must not be negative")
 	}
 
 	if c.MaxIterations < 1 {
 		return fmt.Errorf("max iterations must be at least 1")
 	}
 
+	switch c.NodeValidation {
+	case "off", "warn", "strict":
+	default:
+		return fmt.Errorf("invalid node validation policy: %s", c.NodeValidation)
+	}
+
+	if c.TracingSampleRatio < 0 || c.TracingSampleRatio > 1 {
+		return fmt.Errorf("tracing sample ratio must be between 0 and 1")
+	}
+
 	validLogLevels := map[string]bool{
 		"debug": true,
 		"info":  true,
@@ -84,14 +680,79 @@ func (c *Config) Validate() error {
 	return nil
 }
 
-// GetMCPServers returns the list of MCP servers
-func (c *Config) GetMCPServers() []string {
-	var servers []string
+// RateLimits returns the provider-wide limit from LLM_RATE_LIMIT_RPM and
+// LLM_RATE_LIMIT_TPM, if set, followed by the limits from the config file
+func (c *Config) RateLimits() []RateLimit {
+	var limits []RateLimit
+	if c.LLMRateLimitRPM != 0 || c.LLMRateLimitTPM != 0 {
+		limits = append(limits, RateLimit{Provider: c.LLMProvider, RPM: c.LLMRateLimitRPM, TPM: c.LLMRateLimitTPM})
+	}
+	return append(limits, c.RateLimitDefs...)
+}
+
+// GetMCPServers returns the MCP servers from MCP_SERVERS followed by those
+// defined in the config file. Servers from MCP_SERVERS are named after
+// their address.
+func (c *Config) GetMCPServers() []MCPServer {
+	var servers []MCPServer
 	for _, server := range c.MCPServers {
 		server = strings.TrimSpace(server)
 		if server != "" {
-			servers = append(servers, server)
+			servers = append(servers, MCPServer{Name: server, URL: server})
+		}
+	}
+	return append(servers, c.MCPServerDefs...)
+}
+
+// validate checks the globs and patterns of the tool access policies
+func (a ToolAccess) validate() error {
+	scopes := map[string]ToolAccessPolicy{"default": a.Default}
+	for tenant, policy := range a.Tenants {
+		scopes["tenant "+tenant] = policy
+	}
+	for graph, policy := range a.Graphs {
+		scopes["graph "+graph] = policy
+	}
+
+	for scope, policy := range scopes {
+		globs := append(append([]string(nil), policy.Allow...), policy.Deny...)
+		for glob, args := range policy.Args {
+			globs = append(globs, glob)
+			for name, constraint := range args {
+				if _, err := regexp.Compile(constraint.Pattern); err != nil {
+					return fmt.Errorf("tool access policy of %s: invalid pattern for argument %s of %s: %w", scope, name, glob, err)
+				}
+			}
+		}
+		for _, glob := range globs {
+			if _, err := path.Match(glob, ""); err != nil || glob == "" {
+				return fmt.Errorf("tool access policy of %s: invalid tool pattern %q", scope, glob)
+			}
+		}
+	}
+	return nil
+}
+
+// validate checks the profile has the fields of its type
+func (p AuthProfile) validate() error {
+	switch p.Type {
+	case "bearer":
+		if p.Token == "" {
+			return fmt.Errorf("bearer profile needs a token")
+		}
+	case "basic":
+		if p.Username == "" {
+			return fmt.Errorf("basic profile needs a username")
 		}
+	case "api_key":
+		if p.Key == "" {
+			return fmt.Errorf("api_key profile needs a key")
+		}
+		if p.Header != "" && p.Query != "" {
+			return fmt.Errorf("api_key profile takes a header or a query parameter, not both")
+		}
+	default:
+		return fmt.Errorf("unknown auth type %q", p.Type)
 	}
-	return servers
+	return nil
 }
@@ -1,4 +1,7 @@
 // Package config provides configuration management for the executor worker.
 //
-// Configuration is loaded from environment variables and validated on startup.
+// Configuration is loaded from environment variables and, optionally, from
+// a YAML or JSON file (CONFIG_FILE). Environment variables take precedence
+// over the file. Configuration is validated on startup and on reload; a
+// Watcher applies safe settings on SIGHUP or file change.
 package config
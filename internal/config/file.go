@@ -0,0 +1,436 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProviderConfig holds credentials and connection settings for an LLM provider
+type ProviderConfig struct {
+	APIKey  string        `yaml:"api_key" json:"api_key"`
+	BaseURL string        `yaml:"base_url" json:"base_url"`
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+}
+
+// ModelPrice is the price of a model in USD per million tokens
+type ModelPrice struct {
+	InputPerMillion  float64 `yaml:"input_per_million" json:"input_per_million"`
+	OutputPerMillion float64 `yaml:"output_per_million" json:"output_per_million"`
+}
+
+// ToolPolicy holds execution settings for a single tool
+type ToolPolicy struct {
+	// Timeout bounds a single call of the tool
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+
+	// CacheTTL keeps results for repeated calls with the same params; set
+	// it only for read-only tools
+	CacheTTL time.Duration `yaml:"cache_ttl" json:"cache_ttl"`
+
+	// MaxConcurrent caps the calls in progress across all workers
+	MaxConcurrent int `yaml:"max_concurrent" json:"max_concurrent"`
+
+	// RatePerSecond caps the calls per second across all workers
+	RatePerSecond float64 `yaml:"rate_per_second" json:"rate_per_second"`
+
+	// BreakerThreshold consecutive failures make the tool unavailable for
+	// BreakerCooldown (default 30s)
+	BreakerThreshold int           `yaml:"breaker_threshold" json:"breaker_threshold"`
+	BreakerCooldown  time.Duration `yaml:"breaker_cooldown" json:"breaker_cooldown"`
+}
+
+// ToolAccess holds the tool access policies applied to every call, and to
+// the calls of a tenant or graph ID
+type ToolAccess struct {
+	Default ToolAccessPolicy            `yaml:"default" json:"default"`
+	Tenants map[string]ToolAccessPolicy `yaml:"tenants" json:"tenants"`
+	Graphs  map[string]ToolAccessPolicy `yaml:"graphs" json:"graphs"`
+}
+
+// ToolAccessPolicy allows and denies tools by name or glob, and
+// constrains the arguments of tools matching the globs of Args
+type ToolAccessPolicy struct {
+	Allow []string                                `yaml:"allow" json:"allow"`
+	Deny  []string                                `yaml:"deny" json:"deny"`
+	Args  map[string]map[string]ToolArgConstraint `yaml:"args" json:"args"`
+}
+
+// ToolArgConstraint restricts one tool argument
+type ToolArgConstraint struct {
+	Required bool     `yaml:"required" json:"required"`
+	Values   []string `yaml:"values" json:"values"`
+	Prefixes []string `yaml:"prefixes" json:"prefixes"`
+	Paths    []string `yaml:"paths" json:"paths"`
+	Pattern  string   `yaml:"pattern" json:"pattern"`
+}
+
+// RateLimit caps the calls to a provider's model, or to all its models
+// when Model is empty. Zero RPM or TPM is unlimited.
+type RateLimit struct {
+	Provider string `yaml:"provider" json:"provider"`
+	Model    string `yaml:"model" json:"model"`
+	RPM      int    `yaml:"rpm" json:"rpm"`
+	TPM      int    `yaml:"tpm" json:"tpm"`
+}
+
+// MCPServer defines an MCP server reachable by URL or launched as a command
+type MCPServer struct {
+	Name    string            `yaml:"name" json:"name"`
+	URL     string            `yaml:"url" json:"url"`
+	Command string            `yaml:"command" json:"command"`
+	Args    []string          `yaml:"args" json:"args"`
+	Env     map[string]string `yaml:"env" json:"env"`
+}
+
+// HTTPEndpoint is a service the http_request tool may call
+type HTTPEndpoint struct {
+	BaseURL string            `yaml:"base_url" json:"base_url"`
+	Auth    string            `yaml:"auth" json:"auth"`
+	Methods []string          `yaml:"methods" json:"methods"`
+	Headers map[string]string `yaml:"headers" json:"headers"`
+}
+
+// OpenAPISpec is an OpenAPI 3 document whose operations become tools
+type OpenAPISpec struct {
+	Name       string            `yaml:"name" json:"name"`
+	Source     string            `yaml:"source" json:"source"`
+	BaseURL    string            `yaml:"base_url" json:"base_url"`
+	Auth       string            `yaml:"auth" json:"auth"`
+	Operations []string          `yaml:"operations" json:"operations"`
+	Headers    map[string]string `yaml:"headers" json:"headers"`
+}
+
+// ToolHook is a policy webhook asked about tool calls before they run or
+// after they return
+type ToolHook struct {
+	Name     string            `yaml:"name" json:"name"`
+	Phase    string            `yaml:"phase" json:"phase"`
+	URL      string            `yaml:"url" json:"url"`
+	Tools    []string          `yaml:"tools" json:"tools"`
+	Timeout  time.Duration     `yaml:"timeout" json:"timeout"`
+	FailOpen bool              `yaml:"fail_open" json:"fail_open"`
+	Headers  map[string]string `yaml:"headers" json:"headers"`
+}
+
+// AuthProfile holds the credentials of a service, referenced by name
+type AuthProfile struct {
+	Type     string `yaml:"type" json:"type"`
+	Token    string `yaml:"token" json:"token"`
+	Username string `yaml:"username" json:"username"`
+	Password string `yaml:"password" json:"password"`
+	Key      string `yaml:"key" json:"key"`
+	Header   string `yaml:"header" json:"header"`
+	Query    string `yaml:"query" json:"query"`
+}
+
+// File is the schema of the optional configuration file (YAML or JSON).
+// Scalar settings mirror environment variables, which take precedence.
+type File struct {
+	WorkerID      *string `yaml:"worker_id" json:"worker_id"`
+	LogLevel      *string `yaml:"log_level" json:"log_level"`
+	MaxIterations *int    `yaml:"max_iterations" json:"max_iterations"`
+	HealthPort    *int    `yaml:"health_port" json:"health_port"`
+
+	WorkerLabels []string `yaml:"worker_labels" json:"worker_labels"`
+	WorkerModels []string `yaml:"worker_models" json:"worker_models"`
+
+	ToolLimitMaxWait *time.Duration `yaml:"tool_limit_max_wait" json:"tool_limit_max_wait"`
+	ToolCacheSize    *int           `yaml:"tool_cache_size" json:"tool_cache_size"`
+	BuiltinTools     *bool          `yaml:"builtin_tools" json:"builtin_tools"`
+	ScratchTTL       *time.Duration `yaml:"scratch_ttl" json:"scratch_ttl"`
+	FSTools          *bool          `yaml:"fs_tools" json:"fs_tools"`
+
+	Workspace struct {
+		Dir           *string        `yaml:"dir" json:"dir"`
+		QuotaMB       *int           `yaml:"quota_mb" json:"quota_mb"`
+		MaxFileMB     *int           `yaml:"max_file_mb" json:"max_file_mb"`
+		MaxFiles      *int           `yaml:"max_files" json:"max_files"`
+		SweepInterval *time.Duration `yaml:"sweep_interval" json:"sweep_interval"`
+	} `yaml:"workspace" json:"workspace"`
+
+	ToolAudit struct {
+		Enabled    *bool    `yaml:"enabled" json:"enabled"`
+		Params     *bool    `yaml:"params" json:"params"`
+		RedactKeys []string `yaml:"redact_keys" json:"redact_keys"`
+	} `yaml:"tool_audit" json:"tool_audit"`
+
+	HTTP struct {
+		Timeout       *time.Duration          `yaml:"timeout" json:"timeout"`
+		MaxResponseKB *int                    `yaml:"max_response_kb" json:"max_response_kb"`
+		Endpoints     map[string]HTTPEndpoint `yaml:"endpoints" json:"endpoints"`
+	} `yaml:"http" json:"http"`
+
+	Routing struct {
+		Enabled         *bool          `yaml:"enabled" json:"enabled"`
+		BaseStream      *string        `yaml:"base_stream" json:"base_stream"`
+		PriorityWeights []string       `yaml:"priority_weights" json:"priority_weights"`
+		RegistryTTL     *time.Duration `yaml:"registry_ttl" json:"registry_ttl"`
+	} `yaml:"routing" json:"routing"`
+
+	Queue struct {
+		Backend *string `yaml:"backend" json:"backend"`
+	} `yaml:"queue" json:"queue"`
+
+	NATS struct {
+		URL          *string        `yaml:"url" json:"url"`
+		Stream       *string        `yaml:"stream" json:"stream"`
+		Subject      *string        `yaml:"subject" json:"subject"`
+		Consumer     *string        `yaml:"consumer" json:"consumer"`
+		DLQSubject   *string        `yaml:"dlq_subject" json:"dlq_subject"`
+		EventsStream *string        `yaml:"events_stream" json:"events_stream"`
+		MaxDeliver   *int           `yaml:"max_deliver" json:"max_deliver"`
+		AckWait      *time.Duration `yaml:"ack_wait" json:"ack_wait"`
+	} `yaml:"nats" json:"nats"`
+
+	Redis struct {
+		Mode     *string `yaml:"mode" json:"mode"`
+		Addr     *string `yaml:"addr" json:"addr"`
+		Username *string `yaml:"username" json:"username"`
+		Password *string `yaml:"password" json:"password"`
+		DB       *int    `yaml:"db" json:"db"`
+
+		SentinelMaster   *string  `yaml:"sentinel_master" json:"sentinel_master"`
+		SentinelAddrs    []string `yaml:"sentinel_addrs" json:"sentinel_addrs"`
+		SentinelPassword *string  `yaml:"sentinel_password" json:"sentinel_password"`
+
+		TLS struct {
+			Enabled    *bool   `yaml:"enabled" json:"enabled"`
+			CAFile     *string `yaml:"ca_file" json:"ca_file"`
+			CertFile   *string `yaml:"cert_file" json:"cert_file"`
+			KeyFile    *string `yaml:"key_file" json:"key_file"`
+			ServerName *string `yaml:"server_name" json:"server_name"`
+		} `yaml:"tls" json:"tls"`
+
+		PoolSize *int `yaml:"pool_size" json:"pool_size"`
+
+		Namespace     *string        `yaml:"namespace" json:"namespace"`
+		WorkStreams   []string       `yaml:"work_streams" json:"work_streams"`
+		ConsumerGroup *string        `yaml:"consumer_group" json:"consumer_group"`
+		StatePrefix   *string        `yaml:"state_prefix" json:"state_prefix"`
+		EventsPrefix  *string        `yaml:"events_prefix" json:"events_prefix"`
+		StateTTL      *time.Duration `yaml:"state_ttl" json:"state_ttl"`
+	} `yaml:"redis" json:"redis"`
+
+	LLM struct {
+		Provider *string `yaml:"provider" json:"provider"`
+		APIKey   *string `yaml:"api_key" json:"api_key"`
+		Model    *string `yaml:"model" json:"model"`
+
+		RateLimit struct {
+			Enabled *bool          `yaml:"enabled" json:"enabled"`
+			RPM     *int           `yaml:"rpm" json:"rpm"`
+			TPM     *int           `yaml:"tpm" json:"tpm"`
+			MaxWait *time.Duration `yaml:"max_wait" json:"max_wait"`
+		} `yaml:"rate_limit" json:"rate_limit"`
+	} `yaml:"llm" json:"llm"`
+
+	// File-only settings
+	Providers    map[string]ProviderConfig         `yaml:"providers" json:"providers"`
+	ModelAliases map[string]string                 `yaml:"model_aliases" json:"model_aliases"`
+	Prices       map[string]ModelPrice             `yaml:"prices" json:"prices"`
+	Profiles     map[string]map[string]interface{} `yaml:"profiles" json:"profiles"`
+	ToolPolicies map[string]ToolPolicy             `yaml:"tool_policies" json:"tool_policies"`
+	ToolAccess   ToolAccess                        `yaml:"tool_access" json:"tool_access"`
+	MCPServers   []MCPServer                       `yaml:"mcp_servers" json:"mcp_servers"`
+	AuthProfiles map[string]AuthProfile            `yaml:"auth_profiles" json:"auth_profiles"`
+	OpenAPI      []OpenAPISpec                     `yaml:"openapi" json:"openapi"`
+	ToolHooks    []ToolHook                        `yaml:"tool_hooks" json:"tool_hooks"`
+	RateLimits   []RateLimit                       `yaml:"rate_limits" json:"rate_limits"`
+}
+
+// readFile parses a YAML or JSON configuration file
+func readFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// JSON is valid YAML, so a single decoder handles both formats
+	var f File
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&f); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filepath.Base(path), err)
+	}
+
+	return &f, nil
+}
+
+// apply merges the file into cfg. Settings whose environment variable is
+// set keep the environment value. Secrets may reference environment
+// variables as ${VAR}.
+func (f *File) apply(cfg *Config) {
+	setString(&cfg.WorkerID, f.WorkerID, "WORKER_ID")
+	setString(&cfg.LogLevel, f.LogLevel, "LOG_LEVEL")
+	setInt(&cfg.MaxIterations, f.MaxIterations, "MAX_ITERATIONS")
+	setInt(&cfg.HealthPort, f.HealthPort, "HEALTH_PORT")
+
+	setStrings(&cfg.WorkerLabels, f.WorkerLabels, "WORKER_LABELS")
+	setStrings(&cfg.WorkerModels, f.WorkerModels, "WORKER_MODELS")
+	setDuration(&cfg.ToolLimitMaxWait, f.ToolLimitMaxWait, "TOOL_LIMIT_MAX_WAIT")
+	setInt(&cfg.ToolCacheSize, f.ToolCacheSize, "TOOL_CACHE_SIZE")
+	setBool(&cfg.BuiltinTools, f.BuiltinTools, "BUILTIN_TOOLS")
+	setDuration(&cfg.ScratchTTL, f.ScratchTTL, "SCRATCH_TTL")
+	setBool(&cfg.FSTools, f.FSTools, "FS_TOOLS")
+	setString(&cfg.WorkspaceDir, f.Workspace.Dir, "WORKSPACE_DIR")
+	setInt(&cfg.WorkspaceQuotaMB, f.Workspace.QuotaMB, "WORKSPACE_QUOTA_MB")
+	setInt(&cfg.WorkspaceMaxFileMB, f.Workspace.MaxFileMB, "WORKSPACE_MAX_FILE_MB")
+	setInt(&cfg.WorkspaceMaxFiles, f.Workspace.MaxFiles, "WORKSPACE_MAX_FILES")
+	setDuration(&cfg.WorkspaceSweepInterval, f.Workspace.SweepInterval, "WORKSPACE_SWEEP_INTERVAL")
+	setDuration(&cfg.HTTPToolTimeout, f.HTTP.Timeout, "HTTP_TOOL_TIMEOUT")
+	setInt(&cfg.HTTPToolMaxResponseKB, f.HTTP.MaxResponseKB, "HTTP_TOOL_MAX_RESPONSE_KB")
+	setBool(&cfg.ToolAuditEnabled, f.ToolAudit.Enabled, "TOOL_AUDIT_ENABLED")
+	setBool(&cfg.ToolAuditParams, f.ToolAudit.Params, "TOOL_AUDIT_PARAMS")
+	setStrings(&cfg.ToolRedactKeys, f.ToolAudit.RedactKeys, "TOOL_REDACT_KEYS")
+	setBool(&cfg.RoutingEnabled, f.Routing.Enabled, "ROUTING_ENABLED")
+	setString(&cfg.RoutingBaseStream, f.Routing.BaseStream, "ROUTING_BASE_STREAM")
+	setStrings(&cfg.RoutingPriorityWeights, f.Routing.PriorityWeights, "ROUTING_PRIORITY_WEIGHTS")
+	setDuration(&cfg.RoutingRegistryTTL, f.Routing.RegistryTTL, "ROUTING_REGISTRY_TTL")
+
+	setString(&cfg.QueueBackend, f.Queue.Backend, "QUEUE_BACKEND")
+
+	setString(&cfg.NATSURL, expand(f.NATS.URL), "NATS_URL")
+	setString(&cfg.NATSStream, f.NATS.Stream, "NATS_STREAM")
+	setString(&cfg.NATSSubject, f.NATS.Subject, "NATS_SUBJECT")
+	setString(&cfg.NATSConsumer, f.NATS.Consumer, "NATS_CONSUMER")
+	setString(&cfg.NATSDLQSubject, f.NATS.DLQSubject, "NATS_DLQ_SUBJECT")
+	setString(&cfg.NATSEventsStream, f.NATS.EventsStream, "NATS_EVENTS_STREAM")
+	setInt(&cfg.NATSMaxDeliver, f.NATS.MaxDeliver, "NATS_MAX_DELIVER")
+	setDuration(&cfg.NATSAckWait, f.NATS.AckWait, "NATS_ACK_WAIT")
+
+	setString(&cfg.RedisMode, f.Redis.Mode, "REDIS_MODE")
+	setString(&cfg.RedisAddr, f.Redis.Addr, "REDIS_ADDR")
+	setString(&cfg.RedisUsername, f.Redis.Username, "REDIS_USERNAME")
+	setString(&cfg.RedisPass, expand(f.Redis.Password), "REDIS_PASS")
+	setInt(&cfg.RedisDB, f.Redis.DB, "REDIS_DB")
+	setString(&cfg.RedisSentinelMaster, f.Redis.SentinelMaster, "REDIS_SENTINEL_MASTER")
+	setStrings(&cfg.RedisSentinelAddrs, f.Redis.SentinelAddrs, "REDIS_SENTINEL_ADDRS")
+	setString(&cfg.RedisSentinelPass, expand(f.Redis.SentinelPassword), "REDIS_SENTINEL_PASS")
+	setBool(&cfg.RedisTLS, f.Redis.TLS.Enabled, "REDIS_TLS")
+	setString(&cfg.RedisTLSCAFile, f.Redis.TLS.CAFile, "REDIS_TLS_CA_FILE")
+	setString(&cfg.RedisTLSCertFile, f.Redis.TLS.CertFile, "REDIS_TLS_CERT_FILE")
+	setString(&cfg.RedisTLSKeyFile, f.Redis.TLS.KeyFile, "REDIS_TLS_KEY_FILE")
+	setString(&cfg.RedisTLSServerName, f.Redis.TLS.ServerName, "REDIS_TLS_SERVER_NAME")
+	setInt(&cfg.RedisPoolSize, f.Redis.PoolSize, "REDIS_POOL_SIZE")
+	setString(&cfg.RedisNamespace, f.Redis.Namespace, "REDIS_NAMESPACE")
+	setStrings(&cfg.RedisWorkStreams, f.Redis.WorkStreams, "REDIS_WORK_STREAMS")
+	setString(&cfg.RedisConsumerGroup, f.Redis.ConsumerGroup, "REDIS_CONSUMER_GROUP")
+	setString(&cfg.RedisStatePrefix, f.Redis.StatePrefix, "REDIS_STATE_PREFIX")
+	setString(&cfg.RedisEventsPrefix, f.Redis.EventsPrefix, "REDIS_EVENTS_PREFIX")
+	setDuration(&cfg.RedisStateTTL, f.Redis.StateTTL, "REDIS_STATE_TTL")
+
+	setString(&cfg.LLMProvider, f.LLM.Provider, "LLM_PROVIDER")
+	setString(&cfg.LLMAPIKey, expand(f.LLM.APIKey), "LLM_API_KEY")
+	setString(&cfg.LLMModel, f.LLM.Model, "LLM_MODEL")
+	setBool(&cfg.LLMRateLimitEnabled, f.LLM.RateLimit.Enabled, "LLM_RATE_LIMIT_ENABLED")
+	setInt(&cfg.LLMRateLimitRPM, f.LLM.RateLimit.RPM, "LLM_RATE_LIMIT_RPM")
+	setInt(&cfg.LLMRateLimitTPM, f.LLM.RateLimit.TPM, "LLM_RATE_LIMIT_TPM")
+	setDuration(&cfg.LLMRateLimitMaxWait, f.LLM.RateLimit.MaxWait, "LLM_RATE_LIMIT_MAX_WAIT")
+
+	cfg.Providers = make(map[string]ProviderConfig, len(f.Providers))
+	for name, provider := range f.Providers {
+		provider.APIKey = os.ExpandEnv(provider.APIKey)
+		cfg.Providers[name] = provider
+	}
+
+	cfg.ModelAliases = f.ModelAliases
+	cfg.Prices = f.Prices
+	cfg.Profiles = f.Profiles
+	cfg.ToolPolicies = f.ToolPolicies
+	cfg.ToolAccess = f.ToolAccess
+	cfg.RateLimitDefs = f.RateLimits
+	cfg.HTTPEndpoints = f.HTTP.Endpoints
+	cfg.OpenAPISpecs = f.OpenAPI
+
+	cfg.ToolHooks = make([]ToolHook, 0, len(f.ToolHooks))
+	for _, hook := range f.ToolHooks {
+		headers := make(map[string]string, len(hook.Headers))
+		for k, v := range hook.Headers {
+			headers[k] = os.ExpandEnv(v)
+		}
+		hook.Headers = headers
+		cfg.ToolHooks = append(cfg.ToolHooks, hook)
+	}
+
+	cfg.AuthProfiles = make(map[string]AuthProfile, len(f.AuthProfiles))
+	for name, profile := range f.AuthProfiles {
+		profile.Token = os.ExpandEnv(profile.Token)
+		profile.Username = os.ExpandEnv(profile.Username)
+		profile.Password = os.ExpandEnv(profile.Password)
+		profile.Key = os.ExpandEnv(profile.Key)
+		cfg.AuthProfiles[name] = profile
+	}
+
+	cfg.MCPServerDefs = make([]MCPServer, 0, len(f.MCPServers))
+	for _, server := range f.MCPServers {
+		env := make(map[string]string, len(server.Env))
+		for k, v := range server.Env {
+			env[k] = os.ExpandEnv(v)
+		}
+		server.Env = env
+		cfg.MCPServerDefs = append(cfg.MCPServerDefs, server)
+	}
+}
+
+func setString(dst *string, value *string, envKey string) {
+	if value == nil {
+		return
+	}
+	if _, ok := os.LookupEnv(envKey); ok {
+		return
+	}
+	*dst = *value
+}
+
+func setInt(dst *int, value *int, envKey string) {
+	if value == nil {
+		return
+	}
+	if _, ok := os.LookupEnv(envKey); ok {
+		return
+	}
+	*dst = *value
+}
+
+func setBool(dst *bool, value *bool, envKey string) {
+	if value == nil {
+		return
+	}
+	if _, ok := os.LookupEnv(envKey); ok {
+		return
+	}
+	*dst = *value
+}
+
+func setDuration(dst *time.Duration, value *time.Duration, envKey string) {
+	if value == nil {
+		return
+	}
+	if _, ok := os.LookupEnv(envKey); ok {
+		return
+	}
+	*dst = *value
+}
+
+func setStrings(dst *[]string, value []string, envKey string) {
+	if len(value) == 0 {
+		return
+	}
+	if _, ok := os.LookupEnv(envKey); ok {
+		return
+	}
+	*dst = value
+}
+
+func expand(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	expanded := os.ExpandEnv(*value)
+	return &expanded
+}
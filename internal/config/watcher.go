@@ -0,0 +1,164 @@
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// reloadableFields are the Config fields applied on reload. All other
+// changes are reported and require a restart.
+var reloadableFields = map[string]bool{
+	"LogLevel":      true,
+	"MaxIterations": true,
+	"ModelAliases":  true,
+	"Profiles":      true,
+	"Prices":        true,
+	"ToolPolicies":  true,
+	"ToolAccess":    true,
+	"MCPServers":    true,
+	"MCPServerDefs": true,
+}
+
+// Watcher reloads the configuration on SIGHUP or when the config file
+// changes, and notifies subscribers with the new configuration. Only
+// reloadable fields change; the rest keep their startup values.
+type Watcher struct {
+	logger *zap.Logger
+
+	mu       sync.RWMutex
+	current  *Config
+	modTime  time.Time
+	handlers []func(*Config)
+}
+
+// NewWatcher creates a watcher starting from cfg
+func NewWatcher(cfg *Config, logger *zap.Logger) *Watcher {
+	return &Watcher{
+		logger:  logger,
+		current: cfg,
+		modTime: fileModTime(cfg.ConfigFile),
+	}
+}
+
+// OnReload registers a function called with the configuration after each reload
+func (w *Watcher) OnReload(fn func(*Config)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handlers = append(w.handlers, fn)
+}
+
+// Current returns the active configuration
+func (w *Watcher) Current() *Config {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current
+}
+
+// Run reloads on SIGHUP and, if a config file is used, when its
+// modification time changes. It blocks until ctx is cancelled.
+func (w *Watcher) Run(ctx context.Context) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	defer signal.Stop(sigCh)
+
+	var poll <-chan time.Time
+	cfg := w.Current()
+	if cfg.ConfigFile != "" && cfg.ConfigReloadInterval > 0 {
+		ticker := time.NewTicker(cfg.ConfigReloadInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigCh:
+			w.logger.Info("received SIGHUP, reloading configuration")
+			w.Reload()
+		case <-poll:
+			if w.fileChanged() {
+				w.logger.Info("config file changed, reloading configuration")
+				w.Reload()
+			}
+		}
+	}
+}
+
+// Reload loads and validates the configuration again. On error the
+// active configuration is kept.
+func (w *Watcher) Reload() {
+	modTime := fileModTime(w.Current().ConfigFile)
+
+	next, err := Load()
+
+	w.mu.Lock()
+	w.modTime = modTime
+	if err != nil {
+		w.mu.Unlock()
+		w.logger.Error("config reload failed, keeping current configuration", zap.Error(err))
+		return
+	}
+
+	current := w.current
+	updated := *current
+	var ignored []string
+
+	currentValue := reflect.ValueOf(current).Elem()
+	nextValue := reflect.ValueOf(next).Elem()
+	updatedValue := reflect.ValueOf(&updated).Elem()
+	for i := 0; i < nextValue.NumField(); i++ {
+		name := nextValue.Type().Field(i).Name
+		if reflect.DeepEqual(currentValue.Field(i).Interface(), nextValue.Field(i).Interface()) {
+			continue
+		}
+		if !reloadableFields[name] {
+			ignored = append(ignored, name)
+			continue
+		}
+		updatedValue.Field(i).Set(nextValue.Field(i))
+	}
+
+	w.current = &updated
+	handlers := make([]func(*Config), len(w.handlers))
+	copy(handlers, w.handlers)
+	w.mu.Unlock()
+
+	if len(ignored) > 0 {
+		w.logger.Warn("configuration changes require a restart and were not applied",
+			zap.Strings("fields", ignored))
+	}
+
+	for _, handler := range handlers {
+		handler(&updated)
+	}
+
+	w.logger.Info("configuration reloaded")
+}
+
+// fileChanged reports whether the config file changed since the last load
+func (w *Watcher) fileChanged() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return !fileModTime(w.current.ConfigFile).Equal(w.modTime)
+}
+
+// fileModTime returns the file modification time, or zero if there is none
+func fileModTime(path string) time.Time {
+	if path == "" {
+		return time.Time{}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
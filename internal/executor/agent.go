@@ -3,25 +3,96 @@ package executor
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-node-executor/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+// agentMode is the built-in agent mode handler
+type agentMode struct {
+	e *Executor
+}
+
+func (m *agentMode) Describe() ModeDescription {
+	return ModeDescription{
+		Mode:        ModeAgent,
+		Description: "Reasoning-action loop with tool execution",
+		Keys:        []string{"llm_config", "tools", "task", "max_iterations"},
+	}
+}
+
+func (m *agentMode) Validate(ctx context.Context, config *NodeConfig, opts ValidateOptions) []ValidationIssue {
+	result := &ValidationResult{}
+
+	if llmConfig := m.e.validateLLMConfig(result, config, ModeAgent); llmConfig != nil {
+		if _, ok := llmConfig["prompt"]; ok {
+			result.add(SeverityWarning, "llm_config.prompt", "not used in agent mode; set task instead")
+		}
+	}
+
+	var toolNames []string
+	for i, tool := range getSliceConfig(config.Config, "tools") {
+		name, ok := tool.(string)
+		if !ok || name == "" {
+			result.add(SeverityError, fmt.Sprintf("tools[%d]", i), "must be a tool name")
+			continue
+		}
+		toolNames = append(toolNames, name)
+	}
+	if len(getSliceConfig(config.Config, "tools")) == 0 {
+		result.add(SeverityError, "tools", "required for agent mode")
+	}
+
+	if v, ok := config.Config["task"]; ok {
+		if _, ok := v.(string); !ok {
+			result.add(SeverityError, "task", "must be a string")
+		}
+	} else {
+		result.add(SeverityWarning, "task", "not set; the agent gets a generic task")
+	}
+	if v, ok := config.Config["max_iterations"]; ok {
+		if n, ok := asInteger(v); !ok || n <= 0 {
+			result.add(SeverityError, "max_iterations", "must be a positive integer")
+		}
+	}
+
+	if !opts.SkipToolCheck {
+		m.e.checkToolsExist(ctx, result, "tools", toolNames)
+	}
+
+	return result.Issues
+}
+
+func (m *agentMode) Execute(ctx context.Context, state *domain.GraphState, config *NodeConfig) (interface{}, error) {
+	return m.e.executeAgent(ctx, state, config)
+}
+
 // executeAgent executes a node in agent mode (reasoning-action loop)
 func (e *Executor) executeAgent(ctx context.Context, state *domain.GraphState, config *NodeConfig) (interface{}, error) {
 	llmConfig := getMapConfig(config.Config, "llm_config")
 	if llmConfig == nil {
 		return nil, fmt.Errorf("llm_config required for agent mode")
 	}
+	llmConfig = e.ResolveLLMConfig(llmConfig)
 
 	tools := getSliceConfig(config.Config, "tools")
 	if len(tools) == 0 {
 		return nil, fmt.Errorf("tools required for agent mode")
 	}
+	toolNames := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		if name, ok := tool.(string); ok && name != "" {
+			toolNames = append(toolNames, name)
+		}
+	}
+	toolDefs := e.toolDefinitions(ctx, toolNames)
 
-	maxIterations := getIntConfig(config.Config, "max_iterations", e.maxIterations)
+	maxIterations := getIntConfig(config.Config, "max_iterations", e.currentSettings().MaxIterations)
 	system := getStringConfig(llmConfig, "system", "You are a helpful AI assistant with access to tools.")
 
 	// Build conversation history
@@ -39,26 +110,30 @@ func (e *Executor) executeAgent(ctx context.Context, state *domain.GraphState, c
 		e.logger.Debug("agent iteration",
 			zap.String("node_id", config.NodeID),
 			zap.Int("iteration", iteration))
+		notifyIteration(ctx, iteration+1)
+
+		iterCtx, span := tracing.Start(ctx, "agent.iteration", trace.WithAttributes(
+			attribute.String("dago.node_id", config.NodeID),
+			attribute.Int("dago.iteration", iteration),
+		))
 
 		// Construct LLM request with tools
 		req := &domain.LLMRequest{
-			Model:       getStringConfig(llmConfig, "model", "claude-sonnet-4-20250514"),
+			Model:       getStringConfig(llmConfig, "model", ""),
 			System:      system,
 			Messages:    messages,
 			Temperature: getFloatConfig(llmConfig, "temperature", 0.7),
 			MaxTokens:   getIntConfig(llmConfig, "max_tokens", 4096),
+			Tools:       toolDefs,
 		}
 
 		// Call LLM
-		respInterface, err := e.llmClient.GenerateCompletion(ctx, req)
+		resp, err := e.CallLLM(iterCtx, req)
 		if err != nil {
-			return nil, fmt.Errorf("LLM call failed at iteration %d: %w", iteration, err)
-		}
-
-		// Type assert response
-		resp, ok := respInterface.(*domain.LLMResponse)
-		if !ok {
-			return nil, fmt.Errorf("unexpected response type from LLM at iteration %d", iteration)
+			err = fmt.Errorf("LLM call failed at iteration %d: %w", iteration, err)
+			e.metrics.ObserveAgentIterations(iteration + 1)
+			tracing.End(span, err)
+			return nil, err
 		}
 
 		// Add assistant response to conversation
@@ -73,6 +148,8 @@ func (e *Executor) executeAgent(ctx context.Context, state *domain.GraphState, c
 			e.logger.Info("agent completed",
 				zap.String("node_id", config.NodeID),
 				zap.Int("iterations", iteration+1))
+			e.metrics.ObserveAgentIterations(iteration + 1)
+			tracing.End(span, nil)
 			return map[string]interface{}{
 				"result":     resp.Content,
 				"iterations": iteration + 1,
@@ -85,14 +162,12 @@ func (e *Executor) executeAgent(ctx context.Context, state *domain.GraphState, c
 			zap.Int("tool_count", len(toolCalls)))
 
 		for _, toolCall := range toolCalls {
-			result, err := e.toolClient.Execute(ctx, toolCall.Name, toolCall.Input)
+			result, err := e.CallTool(iterCtx, toolCall.Name, toolCall.Input)
 			if err != nil {
 				e.logger.Error("tool execution failed",
 					zap.String("tool", toolCall.Name),
 					zap.Error(err))
-				result = map[string]interface{}{
-					"error": err.Error(),
-				}
+				result = toolErrorResult(err)
 			}
 
 			// Add tool result to conversation
@@ -102,7 +177,23 @@ func (e *Executor) executeAgent(ctx context.Context, state *domain.GraphState, c
 				Content: fmt.Sprintf("Tool result for %s: %s", toolCall.Name, string(resultJSON)),
 			})
 		}
+
+		span.End()
 	}
 
+	e.metrics.ObserveAgentIterations(maxIterations)
 	return nil, fmt.Errorf("max iterations (%d) reached without completion", maxIterations)
 }
+
+// toolErrorResult is the result reported to the model for a failed tool
+// call. Errors that describe themselves to the model, such as a rejection
+// by the tool's policy, provide their own structured result.
+func toolErrorResult(err error) map[string]interface{} {
+	var described interface{ ToolResult() map[string]interface{} }
+	if errors.As(err, &described) {
+		return described.ToolResult()
+	}
+	return map[string]interface{}{
+		"error": err.Error(),
+	}
+}
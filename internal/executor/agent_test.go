@@ -0,0 +1,95 @@
+package executor
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-node-executor/internal/fakellm"
+	"go.uber.org/zap"
+)
+
+// describedTools is a tool client describing some of its tools
+type describedTools struct {
+	names []string
+	defs  []domain.Tool
+}
+
+func (d *describedTools) Execute(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error) {
+	return "ok", nil
+}
+
+func (d *describedTools) ListTools(ctx context.Context) ([]string, error) {
+	return d.names, nil
+}
+
+func (d *describedTools) Definitions() []domain.Tool {
+	return d.defs
+}
+
+func TestAgentOffersToolDefinitions(t *testing.T) {
+	search := domain.Tool{
+		Name:        "search",
+		Description: "Search the web",
+		Parameters:  map[string]interface{}{"type": "object", "required": []interface{}{"q"}},
+	}
+	readFile := domain.Tool{Name: "files__read", Description: "Read a file"}
+	tools := &describedTools{
+		names: []string{"files__read", "files__write", "search", "other"},
+		defs:  []domain.Tool{readFile, search},
+	}
+
+	tests := []struct {
+		name  string
+		tools []interface{}
+		want  []domain.Tool
+	}{
+		{
+			name:  "described tools",
+			tools: []interface{}{"search"},
+			want:  []domain.Tool{search},
+		},
+		{
+			name:  "globs expand against the catalog",
+			tools: []interface{}{"files__*", "search", "files__read"},
+			want: []domain.Tool{
+				readFile,
+				{Name: "files__write", Parameters: map[string]interface{}{"type": "object"}},
+				search,
+			},
+		},
+		{
+			name:  "unknown tools are offered by name",
+			tools: []interface{}{"missing"},
+			want:  []domain.Tool{{Name: "missing", Parameters: map[string]interface{}{"type": "object"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm := fakellm.New(fakellm.Response{Content: "done"})
+			e := NewExecutor(llm, tools, zap.NewNop(), 5)
+			config := &NodeConfig{NodeID: "n1", Config: map[string]interface{}{
+				"mode":       "agent",
+				"llm_config": map[string]interface{}{"model": "fake"},
+				"tools":      tt.tools,
+			}}
+			if _, err := e.Execute(context.Background(), &domain.GraphState{GraphID: "g1"}, config); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+
+			requests := llm.Requests()
+			if len(requests) != 1 {
+				t.Fatalf("got %d LLM requests, want 1", len(requests))
+			}
+			req, ok := requests[0].(*domain.LLMRequest)
+			if !ok {
+				t.Fatalf("request is %T, want *domain.LLMRequest", requests[0])
+			}
+			if !reflect.DeepEqual(req.Tools, tt.want) {
+				t.Errorf("request tools = %+v, want %+v", req.Tools, tt.want)
+			}
+		})
+	}
+}
@@ -1,9 +1,12 @@
 // Package executor implements the core execution logic for nodes.
 //
-// Supports three execution modes:
+// Supports three built-in execution modes:
 //   - Agent: Reasoning-action loop with tool execution
 //   - LLM: Single LLM completion
 //   - Tool: Direct tool execution
 //
-// Mode is automatically detected based on node configuration.
+// A node names its mode in the "mode" config field. Configs without one
+// have their mode inferred from their keys, which is deprecated.
+// Further modes implement ModeHandler and are added with RegisterMode, or
+// for every executor with RegisterMode of the public pkg/executor package.
 package executor
@@ -3,65 +3,356 @@ package executor
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/aescanero/dago-libs/pkg/domain"
 	"github.com/aescanero/dago-libs/pkg/ports"
+	"github.com/aescanero/dago-node-executor/internal/metrics"
+	"github.com/aescanero/dago-node-executor/internal/toolaccess"
+	"github.com/aescanero/dago-node-executor/internal/toolchain"
+	"github.com/aescanero/dago-node-executor/internal/tracing"
+	pkgexecutor "github.com/aescanero/dago-node-executor/pkg/executor"
+	"github.com/aescanero/dago-node-executor/pkg/tools/function"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
 // Executor executes nodes in different modes
 type Executor struct {
-	llmClient     ports.LLMClient
-	toolClient    ToolClient
-	logger        *zap.Logger
-	maxIterations int
+	llmClient  ports.LLMClient
+	limiter    RateLimiter
+	toolClient ToolClient
+	logger     *zap.Logger
+	metrics    *metrics.Metrics
+	settings   atomic.Pointer[Settings]
+	validation ValidationPolicy
+	modes      map[ExecutionMode]ModeHandler
+	modesMu    sync.RWMutex
 }
 
-// NodeConfig represents the configuration for a node execution
-type NodeConfig struct {
-	NodeID string
-	Config map[string]interface{}
+// Option configures optional executor behavior
+type Option func(*Executor)
+
+// WithMetrics enables Prometheus instrumentation of executions, LLM and tool calls
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(e *Executor) {
+		e.metrics = m
+	}
 }
 
+// RateLimiter bounds the rate of LLM calls, e.g. across all workers
+type RateLimiter interface {
+	// Acquire waits until a call to model estimated at tokens may be
+	// sent. release is called after the call with the tokens it used and
+	// its error.
+	Acquire(ctx context.Context, model string, tokens int) (release func(used int, err error), err error)
+}
+
+// WithRateLimiter makes every LLM call acquire capacity from l first
+func WithRateLimiter(l RateLimiter) Option {
+	return func(e *Executor) {
+		e.limiter = l
+	}
+}
+
+// NodeConfig represents the configuration for a node execution
+type NodeConfig = pkgexecutor.NodeConfig
+
 // ToolClient defines the interface for tool execution
 type ToolClient interface {
 	Execute(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error)
 	ListTools(ctx context.Context) ([]string, error)
 }
 
+// ToolDescriber is implemented by tool clients that describe their tools,
+// such as the tool catalog
+type ToolDescriber interface {
+	Definitions() []domain.Tool
+}
+
 // NewExecutor creates a new executor
-func NewExecutor(llmClient ports.LLMClient, toolClient ToolClient, logger *zap.Logger, maxIterations int) *Executor {
-	if maxIterations == 0 {
-		maxIterations = 10 // default
+func NewExecutor(llmClient ports.LLMClient, toolClient ToolClient, logger *zap.Logger, maxIterations int, opts ...Option) *Executor {
+	e := &Executor{
+		llmClient:  llmClient,
+		toolClient: toolClient,
+		logger:     logger,
 	}
+	e.UpdateSettings(Settings{MaxIterations: maxIterations})
+	e.registerBuiltinModes()
 
-	return &Executor{
-		llmClient:     llmClient,
-		toolClient:    toolClient,
-		logger:        logger,
-		maxIterations: maxIterations,
+	for _, opt := range opts {
+		opt(e)
 	}
+
+	return e
 }
 
 // Execute executes a node based on its configuration
 func (e *Executor) Execute(ctx context.Context, state *domain.GraphState, config *NodeConfig) (interface{}, error) {
-	mode := DetectMode(config)
+	mode, source, err := e.resolveMode(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot determine execution mode: %w", err)
+	}
 
 	e.logger.Info("executing node",
 		zap.String("node_id", config.NodeID),
 		zap.String("mode", string(mode)))
 
-	switch mode {
-	case ModeAgent:
-		return e.executeAgent(ctx, state, config)
-	case ModeLLM:
-		return e.executeLLM(ctx, state, config)
-	case ModeTool:
-		return e.executeTool(ctx, state, config)
-	default:
-		return nil, fmt.Errorf("unknown execution mode: %s", mode)
+	switch source {
+	case modeInferred:
+		e.logger.Warn("node mode inferred from config keys, which is deprecated; set \"mode\" explicitly",
+			zap.String("node_id", config.NodeID),
+			zap.String("mode", string(mode)))
+	case modeLegacy:
+		e.logger.Warn("node mode set with \"type\", which is deprecated; use \"mode\"",
+			zap.String("node_id", config.NodeID),
+			zap.String("mode", string(mode)))
+	}
+
+	call := toolchain.Call{NodeID: config.NodeID, Tenant: config.Tenant}
+	if state != nil {
+		call.GraphID = state.GraphID
+	}
+	ctx = toolchain.ContextWithCall(ctx, call)
+
+	nodePolicies, err := nodeToolPolicies(config)
+	if err != nil {
+		return nil, err
+	}
+	ctx = toolaccess.ContextWithNodePolicies(ctx, nodePolicies...)
+
+	ctx, span := tracing.Start(ctx, "executor.execute", trace.WithAttributes(
+		attribute.String("dago.node_id", config.NodeID),
+		attribute.String("dago.mode", string(mode)),
+	))
+
+	start := time.Now()
+
+	if err := e.preValidate(ctx, config); err != nil {
+		e.metrics.ObserveNode(string(mode), time.Since(start), err)
+		tracing.End(span, err)
+		return nil, err
+	}
+
+	handler, _ := e.modeHandler(mode)
+	result, err := handler.Execute(ctx, state, config)
+
+	e.metrics.ObserveNode(string(mode), time.Since(start), err)
+	tracing.End(span, err)
+
+	return result, err
+}
+
+// CallLLM sends a request to the LLM client and records its latency and usage
+func (e *Executor) CallLLM(ctx context.Context, req *domain.LLMRequest) (*domain.LLMResponse, error) {
+	ctx, span := tracing.Start(ctx, "llm.call", trace.WithAttributes(
+		attribute.String("gen_ai.request.model", req.Model),
+		attribute.Int("gen_ai.request.max_tokens", req.MaxTokens),
+		attribute.Float64("gen_ai.request.temperature", req.Temperature),
+	))
+
+	release, err := e.acquireLLM(ctx, req)
+	if err != nil {
+		notifyLLMCall(ctx, LLMCallInfo{Request: req, Err: err})
+		tracing.End(span, err)
+		return nil, err
+	}
+
+	start := time.Now()
+
+	respInterface, err := e.llmClient.GenerateCompletion(ctx, req)
+	if resp, ok := respInterface.(*domain.LLMResponse); ok && err == nil {
+		release(resp.Usage.InputTokens+resp.Usage.OutputTokens, nil)
+	} else {
+		release(0, err)
+	}
+	if err != nil {
+		e.metrics.ObserveLLMCall(req.Model, time.Since(start), 0, 0, err)
+		notifyLLMCall(ctx, LLMCallInfo{Request: req, Duration: time.Since(start), Err: err})
+		tracing.End(span, err)
+		return nil, err
+	}
+
+	resp, ok := respInterface.(*domain.LLMResponse)
+	if !ok {
+		err = fmt.Errorf("unexpected response type from LLM")
+		e.metrics.ObserveLLMCall(req.Model, time.Since(start), 0, 0, err)
+		notifyLLMCall(ctx, LLMCallInfo{Request: req, Duration: time.Since(start), Err: err})
+		tracing.End(span, err)
+		return nil, err
+	}
+
+	e.metrics.ObserveLLMCall(req.Model, time.Since(start), resp.Usage.InputTokens, resp.Usage.OutputTokens, nil)
+	notifyLLMCall(ctx, LLMCallInfo{Request: req, Response: resp, Duration: time.Since(start)})
+	e.metrics.ObserveLLMCost(req.Model, e.llmCost(req.Model, resp.Usage.InputTokens, resp.Usage.OutputTokens))
+
+	span.SetAttributes(
+		attribute.String("gen_ai.response.model", resp.Model),
+		attribute.Int("gen_ai.usage.input_tokens", resp.Usage.InputTokens),
+		attribute.Int("gen_ai.usage.output_tokens", resp.Usage.OutputTokens),
+		attribute.Int("gen_ai.response.tool_calls", len(resp.ToolCalls)),
+	)
+	tracing.End(span, nil)
+
+	return resp, nil
+}
+
+// acquireLLM acquires rate limit capacity for a request, returning a
+// release to call with its outcome
+func (e *Executor) acquireLLM(ctx context.Context, req *domain.LLMRequest) (func(used int, err error), error) {
+	if e.limiter == nil {
+		return func(int, error) {}, nil
+	}
+
+	ctx, span := tracing.Start(ctx, "llm.rate_limit", trace.WithAttributes(
+		attribute.String("gen_ai.request.model", req.Model),
+	))
+	release, err := e.limiter.Acquire(ctx, req.Model, estimateTokens(req))
+	tracing.End(span, err)
+	return release, err
+}
+
+// estimateTokens estimates the tokens a request may use before sending it:
+// about four characters per input token, plus the output budget
+func estimateTokens(req *domain.LLMRequest) int {
+	chars := len(req.System)
+	for _, message := range req.Messages {
+		chars += len(message.Content)
+	}
+	for _, tool := range req.Tools {
+		chars += len(tool.Name) + len(tool.Description)
+		if params, err := json.Marshal(tool.Parameters); err == nil {
+			chars += len(params)
+		}
+	}
+	return chars/4 + req.MaxTokens
+}
+
+// CallTool executes a tool through the tool client and records its latency
+func (e *Executor) CallTool(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error) {
+	ctx, span := tracing.Start(ctx, "tool.call", trace.WithAttributes(
+		attribute.String("dago.tool", toolName),
+	))
+
+	ctx, cancel := e.toolContext(ctx, toolName)
+	defer cancel()
+
+	start := time.Now()
+
+	result, err := e.toolClient.Execute(ctx, toolName, params)
+	e.metrics.ObserveToolCall(toolName, time.Since(start), err)
+	notifyToolCall(ctx, ToolCallInfo{Tool: toolName, Params: params, Result: result, Duration: time.Since(start), Err: err})
+	tracing.End(span, err)
+
+	return result, err
+}
+
+// toolDefinitions returns the tools offered to the model for a node's
+// tools list, with the definitions the tool client has for them. Globs
+// are expanded against the tool catalog; tools without a definition are
+// offered by name, taking any object.
+func (e *Executor) toolDefinitions(ctx context.Context, names []string) []domain.Tool {
+	described := make(map[string]domain.Tool)
+	if d, ok := e.toolClient.(ToolDescriber); ok {
+		for _, def := range d.Definitions() {
+			described[def.Name] = def
+		}
+	}
+
+	var available []string
+	for _, name := range names {
+		if toolaccess.IsPattern(name) && e.toolClient != nil {
+			var err error
+			if available, err = e.toolClient.ListTools(ctx); err != nil {
+				e.logger.Warn("failed to list tools for the model", zap.Error(err))
+			}
+			break
+		}
+	}
+
+	seen := make(map[string]bool)
+	var defs []domain.Tool
+	add := func(name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		def, ok := described[name]
+		if !ok {
+			def = domain.Tool{Name: name, Parameters: map[string]interface{}{"type": "object"}}
+		}
+		defs = append(defs, def)
+	}
+	for _, name := range names {
+		if !toolaccess.IsPattern(name) {
+			add(name)
+			continue
+		}
+		for _, tool := range available {
+			if toolaccess.Match(name, tool) {
+				add(tool)
+			}
+		}
+	}
+	return defs
+}
+
+// nodeToolPolicies returns the tool access policies of a node: its tools
+// list, which is the allowlist of an agent, and its tool_access policy
+func nodeToolPolicies(config *NodeConfig) ([]toolaccess.Policy, error) {
+	var policies []toolaccess.Policy
+
+	if tools := getSliceConfig(config.Config, "tools"); len(tools) > 0 {
+		allow := make([]string, 0, len(tools))
+		for i, tool := range tools {
+			name, ok := tool.(string)
+			if !ok || name == "" {
+				return nil, fmt.Errorf("node %s: tools[%d] must be a tool name", config.NodeID, i)
+			}
+			allow = append(allow, name)
+		}
+		policies = append(policies, toolaccess.Policy{Allow: allow})
+	}
+
+	policy, err := toolaccess.ParsePolicy(config.Config[toolAccessKey])
+	if err != nil {
+		return nil, fmt.Errorf("node %s: %w", config.NodeID, err)
+	}
+	if !policy.IsZero() {
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// ArtifactPatterns returns the globs of the workspace files a node exports
+// with its result, from its artifacts list
+func ArtifactPatterns(config *NodeConfig) ([]string, error) {
+	raw, ok := config.Config[artifactsKey]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a list of file patterns", artifactsKey)
+	}
+
+	patterns := make([]string, 0, len(list))
+	for i, item := range list {
+		pattern, ok := item.(string)
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("%s[%d] must be a file pattern", artifactsKey, i)
+		}
+		if err := function.ValidateGlob(pattern); err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", artifactsKey, i, err)
+		}
+		patterns = append(patterns, pattern)
 	}
+	return patterns, nil
 }
 
 // Helper functions for config extraction
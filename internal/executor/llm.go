@@ -9,12 +9,48 @@ import (
 	"go.uber.org/zap"
 )
 
+// llmMode is the built-in LLM mode handler
+type llmMode struct {
+	e *Executor
+}
+
+func (m *llmMode) Describe() ModeDescription {
+	return ModeDescription{
+		Mode:        ModeLLM,
+		Description: "Single LLM completion",
+		Keys:        []string{"llm_config"},
+	}
+}
+
+func (m *llmMode) Validate(ctx context.Context, config *NodeConfig, opts ValidateOptions) []ValidationIssue {
+	result := &ValidationResult{}
+
+	llmConfig := m.e.validateLLMConfig(result, config, ModeLLM)
+	if llmConfig == nil {
+		return result.Issues
+	}
+
+	prompt := getStringConfig(m.e.ResolveLLMConfig(llmConfig), "prompt", "")
+	if prompt == "" {
+		result.add(SeverityError, "llm_config.prompt", "required for llm mode")
+	} else if opts.State != nil {
+		checkTemplateVars(result, "llm_config.prompt", prompt, opts.State)
+	}
+
+	return result.Issues
+}
+
+func (m *llmMode) Execute(ctx context.Context, state *domain.GraphState, config *NodeConfig) (interface{}, error) {
+	return m.e.executeLLM(ctx, state, config)
+}
+
 // executeLLM executes a node in LLM mode (single completion)
 func (e *Executor) executeLLM(ctx context.Context, state *domain.GraphState, config *NodeConfig) (interface{}, error) {
 	llmConfig := getMapConfig(config.Config, "llm_config")
 	if llmConfig == nil {
 		return nil, fmt.Errorf("llm_config required for LLM mode")
 	}
+	llmConfig = e.ResolveLLMConfig(llmConfig)
 
 	// Get prompt template
 	promptTemplate := getStringConfig(llmConfig, "prompt", "")
@@ -23,7 +59,7 @@ func (e *Executor) executeLLM(ctx context.Context, state *domain.GraphState, con
 	}
 
 	// Render prompt with state variables
-	prompt := e.renderPrompt(promptTemplate, state, config)
+	prompt := e.RenderPrompt(promptTemplate, state, config)
 
 	e.logger.Debug("rendered prompt",
 		zap.String("node_id", config.NodeID),
@@ -31,8 +67,8 @@ func (e *Executor) executeLLM(ctx context.Context, state *domain.GraphState, con
 
 	// Build LLM request
 	req := &domain.LLMRequest{
-		Model:       getStringConfig(llmConfig, "model", "claude-sonnet-4-20250514"),
-		System:      getStringConfig(llmConfig, "system", ""),
+		Model:  getStringConfig(llmConfig, "model", ""),
+		System: getStringConfig(llmConfig, "system", ""),
 		Messages: []domain.Message{
 			{
 				Role:    "user",
@@ -44,17 +80,11 @@ func (e *Executor) executeLLM(ctx context.Context, state *domain.GraphState, con
 	}
 
 	// Call LLM
-	respInterface, err := e.llmClient.GenerateCompletion(ctx, req)
+	resp, err := e.CallLLM(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("LLM call failed: %w", err)
 	}
 
-	// Type assert response
-	resp, ok := respInterface.(*domain.LLMResponse)
-	if !ok {
-		return nil, fmt.Errorf("unexpected response type from LLM")
-	}
-
 	e.logger.Debug("LLM response received",
 		zap.String("node_id", config.NodeID),
 		zap.Int("input_tokens", resp.Usage.InputTokens),
@@ -66,8 +96,8 @@ func (e *Executor) executeLLM(ctx context.Context, state *domain.GraphState, con
 	}, nil
 }
 
-// renderPrompt renders a prompt template with state variables
-func (e *Executor) renderPrompt(template string, state *domain.GraphState, config *NodeConfig) string {
+// RenderPrompt renders a prompt template with state variables
+func (e *Executor) RenderPrompt(template string, state *domain.GraphState, config *NodeConfig) string {
 	// Simple template variable replacement
 	// Format: {{variable_name}}
 
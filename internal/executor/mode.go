@@ -1,7 +1,15 @@
 package executor
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	pkgexecutor "github.com/aescanero/dago-node-executor/pkg/executor"
+)
+
 // ExecutionMode represents the execution mode
-type ExecutionMode string
+type ExecutionMode = pkgexecutor.ExecutionMode
 
 const (
 	// ModeAgent: Reasoning-action loop with tools
@@ -14,26 +22,183 @@ const (
 	ModeTool ExecutionMode = "tool"
 )
 
-// DetectMode detects the execution mode based on configuration
+// modeKey is the config key naming a node's mode explicitly. Its values
+// match the node types used in work items.
+const modeKey = "mode"
+
+// legacyModeKey is the deprecated key older configs use for the mode
+const legacyModeKey = "type"
+
+// toolAccessKey is the config key of a node's tool access policy, which
+// restricts the tools any mode calls and their arguments
+const toolAccessKey = "tool_access"
+
+// artifactsKey is the config key listing the workspace files, as globs,
+// exported with a node's result
+const artifactsKey = "artifacts"
+
+// modeSource tells how a node's mode was determined
+type modeSource int
+
+const (
+	modeExplicit modeSource = iota
+	modeLegacy
+	modeInferred
+)
+
+// ModeDescription describes an execution mode
+type ModeDescription = pkgexecutor.ModeDescription
+
+// ModeHandler executes nodes in one execution mode. Handlers are registered
+// with Executor.RegisterMode, or for every executor with the RegisterMode
+// of pkg/executor, and selected by the node's "mode" field.
+type ModeHandler = pkgexecutor.ModeHandler
+
+// RegisterMode adds an execution mode. It fails if a mode with the same
+// name is already registered, including the built-in agent, llm and tool
+// modes. Modes should be registered at startup, before nodes execute.
+func (e *Executor) RegisterMode(handler ModeHandler) error {
+	mode := handler.Describe().Mode
+	if mode == "" {
+		return fmt.Errorf("mode handler has no mode name")
+	}
+
+	e.modesMu.Lock()
+	defer e.modesMu.Unlock()
+
+	if _, ok := e.modes[mode]; ok {
+		return fmt.Errorf("mode %q is already registered", mode)
+	}
+	e.modes[mode] = handler
+	return nil
+}
+
+// registerBuiltinModes registers the agent, llm and tool modes, then the
+// modes registered in pkg/executor
+func (e *Executor) registerBuiltinModes() {
+	e.modes = make(map[ExecutionMode]ModeHandler)
+	for _, handler := range []ModeHandler{&agentMode{e}, &llmMode{e}, &toolMode{e}} {
+		e.modes[handler.Describe().Mode] = handler
+	}
+	for _, mode := range pkgexecutor.Modes() {
+		factory, _ := pkgexecutor.Factory(mode)
+		e.modes[mode] = factory(e)
+	}
+}
+
+// modeHandler returns the handler registered for mode
+func (e *Executor) modeHandler(mode ExecutionMode) (ModeHandler, bool) {
+	e.modesMu.RLock()
+	defer e.modesMu.RUnlock()
+
+	handler, ok := e.modes[mode]
+	return handler, ok
+}
+
+// Modes describes the registered modes, sorted by name
+func (e *Executor) Modes() []ModeDescription {
+	e.modesMu.RLock()
+	defer e.modesMu.RUnlock()
+
+	modes := make([]ModeDescription, 0, len(e.modes))
+	for _, handler := range e.modes {
+		modes = append(modes, handler.Describe())
+	}
+	sort.Slice(modes, func(i, j int) bool { return modes[i].Mode < modes[j].Mode })
+	return modes
+}
+
+// ResolveMode returns the mode a node executes in. An explicit mode field
+// takes precedence and must name a registered mode. Otherwise the mode is
+// inferred from the config keys; with strict mode detection, configs whose
+// keys do not identify a single mode are rejected.
+func (e *Executor) ResolveMode(config *NodeConfig) (ExecutionMode, error) {
+	mode, _, err := e.resolveMode(config)
+	return mode, err
+}
+
+func (e *Executor) resolveMode(config *NodeConfig) (ExecutionMode, modeSource, error) {
+	if v, ok := config.Config[modeKey]; ok {
+		name, ok := v.(string)
+		if !ok || name == "" {
+			return "", modeExplicit, fmt.Errorf("mode must be a non-empty string")
+		}
+		mode := ExecutionMode(name)
+		if _, ok := e.modeHandler(mode); !ok {
+			return "", modeExplicit, fmt.Errorf("unknown mode %q (registered: %s)", name, e.modeList())
+		}
+		return mode, modeExplicit, nil
+	}
+
+	// Older configs name the mode in "type"; other node types are ignored
+	if name := getStringConfig(config.Config, legacyModeKey, ""); name != "" {
+		if _, ok := e.modeHandler(ExecutionMode(name)); ok {
+			return ExecutionMode(name), modeLegacy, nil
+		}
+	}
+
+	mode, ambiguity := inferMode(config)
+	if ambiguity != "" && e.currentSettings().StrictModeDetection {
+		return "", modeInferred, fmt.Errorf("ambiguous node config: %s; set mode explicitly", ambiguity)
+	}
+	return mode, modeInferred, nil
+}
+
+// modeList returns the registered modes as a comma-separated list
+func (e *Executor) modeList() string {
+	var names []string
+	for _, mode := range e.Modes() {
+		names = append(names, string(mode.Mode))
+	}
+	return strings.Join(names, ", ")
+}
+
+// DetectMode detects the execution mode based on configuration. An
+// explicit mode field is returned as is; otherwise the mode is inferred
+// from the config keys. Use Executor.ResolveMode to also check the mode
+// against the registered modes and the strict detection setting.
 func DetectMode(config *NodeConfig) ExecutionMode {
-	// Check for tool mode (has tool_name)
-	if toolName := getStringConfig(config.Config, "tool_name", ""); toolName != "" {
+	if name := getStringConfig(config.Config, modeKey, ""); name != "" {
+		return ExecutionMode(name)
+	}
+	switch ExecutionMode(getStringConfig(config.Config, legacyModeKey, "")) {
+	case ModeAgent:
+		return ModeAgent
+	case ModeLLM:
+		return ModeLLM
+	case ModeTool:
 		return ModeTool
 	}
 
-	// Check for llm_config
-	llmConfig := getMapConfig(config.Config, "llm_config")
-	if llmConfig == nil {
+	mode, _ := inferMode(config)
+	return mode
+}
+
+// inferMode infers the mode from the config keys. It also returns why the
+// config is ambiguous, or "" if the keys identify a single mode.
+func inferMode(config *NodeConfig) (ExecutionMode, string) {
+	hasToolName := getStringConfig(config.Config, "tool_name", "") != ""
+	hasLLMConfig := getMapConfig(config.Config, "llm_config") != nil
+
+	// Check for tool mode (has tool_name)
+	if hasToolName {
+		if hasLLMConfig {
+			return ModeTool, "both tool_name and llm_config are set"
+		}
+		return ModeTool, ""
+	}
+
+	if !hasLLMConfig {
 		// No LLM config, default to tool mode if no explicit mode
-		return ModeTool
+		return ModeTool, "neither tool_name nor llm_config is set"
 	}
 
 	// Check for tools (indicates agent mode)
 	tools := getSliceConfig(config.Config, "tools")
 	if len(tools) > 0 {
-		return ModeAgent
+		return ModeAgent, ""
 	}
 
 	// Has LLM config but no tools - LLM mode
-	return ModeLLM
+	return ModeLLM, ""
 }
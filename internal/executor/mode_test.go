@@ -0,0 +1,67 @@
+package executor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	pkgexecutor "github.com/aescanero/dago-node-executor/pkg/executor"
+	"go.uber.org/zap"
+)
+
+// echoMode returns its node's message
+type echoMode struct {
+	rt pkgexecutor.Runtime
+}
+
+func (m *echoMode) Describe() ModeDescription {
+	return ModeDescription{Mode: "echo", Description: "Returns message", Keys: []string{"message"}}
+}
+
+func (m *echoMode) Validate(ctx context.Context, config *NodeConfig, opts ValidateOptions) []ValidationIssue {
+	if _, ok := config.Config["message"].(string); !ok {
+		return []ValidationIssue{{Severity: SeverityError, Field: "message", Message: "must be a string"}}
+	}
+	return nil
+}
+
+func (m *echoMode) Execute(ctx context.Context, state *domain.GraphState, config *NodeConfig) (interface{}, error) {
+	return m.rt.RenderPrompt(config.Config["message"].(string), state, config), nil
+}
+
+func TestRegisteredModes(t *testing.T) {
+	pkgexecutor.RegisterMode("echo", func(rt pkgexecutor.Runtime) pkgexecutor.ModeHandler {
+		return &echoMode{rt: rt}
+	})
+	t.Cleanup(func() { pkgexecutor.UnregisterMode("echo") })
+
+	e := NewExecutor(nil, nil, zap.NewNop(), 10)
+	config := &NodeConfig{NodeID: "n1", Config: map[string]interface{}{"mode": "echo", "message": "hi {{name}}"}}
+
+	if mode, err := e.ResolveMode(config); err != nil || mode != "echo" {
+		t.Fatalf("ResolveMode() = %q, %v; want echo", mode, err)
+	}
+	if result := e.Validate(context.Background(), config, ValidateOptions{}); !result.Valid() {
+		t.Fatalf("Validate() = %v", result.Issues)
+	}
+
+	state := &domain.GraphState{GraphID: "g1", Inputs: map[string]interface{}{"name": "dago"}}
+	got, err := e.Execute(context.Background(), state, config)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got != "hi dago" {
+		t.Errorf("Execute() = %v, want the rendered message", got)
+	}
+
+	for _, mode := range []pkgexecutor.ExecutionMode{"agent", "echo"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterMode(%q) did not panic", mode)
+				}
+			}()
+			pkgexecutor.RegisterMode(mode, func(rt pkgexecutor.Runtime) pkgexecutor.ModeHandler { return &echoMode{rt: rt} })
+		}()
+	}
+}
@@ -0,0 +1,74 @@
+package executor
+
+import (
+	"context"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+)
+
+// LLMCallInfo describes a completed LLM call
+type LLMCallInfo struct {
+	Request  *domain.LLMRequest
+	Response *domain.LLMResponse
+	Duration time.Duration
+	Err      error
+}
+
+// ToolCallInfo describes a completed tool call
+type ToolCallInfo struct {
+	Tool     string
+	Params   map[string]interface{}
+	Result   interface{}
+	Duration time.Duration
+	Err      error
+}
+
+// Observer receives progress callbacks from executions. Nil fields are
+// skipped. Callbacks run synchronously on the executing goroutine.
+type Observer struct {
+	// OnIteration is called when an agent-mode iteration starts (from 1)
+	OnIteration func(iteration int)
+
+	// OnLLMCall is called after each LLM call
+	OnLLMCall func(call LLMCallInfo)
+
+	// OnToolCall is called after each tool call
+	OnToolCall func(call ToolCallInfo)
+}
+
+type observerKey struct{}
+
+// ContextWithObserver returns a context whose executions report progress to observer
+func ContextWithObserver(ctx context.Context, observer *Observer) context.Context {
+	return context.WithValue(ctx, observerKey{}, observer)
+}
+
+// observerFrom returns the observer in ctx, or an empty one
+func observerFrom(ctx context.Context) *Observer {
+	if observer, ok := ctx.Value(observerKey{}).(*Observer); ok && observer != nil {
+		return observer
+	}
+	return &Observer{}
+}
+
+// notifyIteration reports an iteration to the observer in ctx, if any
+func notifyIteration(ctx context.Context, iteration int) {
+	if fn := observerFrom(ctx).OnIteration; fn != nil {
+		fn(iteration)
+	}
+}
+
+// notifyLLMCall reports an LLM call to the observer in ctx, if any
+func notifyLLMCall(ctx context.Context, call LLMCallInfo) {
+	if fn := observerFrom(ctx).OnLLMCall; fn != nil {
+		fn(call)
+	}
+}
+
+// notifyToolCall reports a tool call to the observer in ctx, if any
+func notifyToolCall(ctx context.Context, call ToolCallInfo) {
+	if fn := observerFrom(ctx).OnToolCall; fn != nil {
+		fn(call)
+	}
+}
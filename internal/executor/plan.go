@@ -0,0 +1,83 @@
+package executor
+
+import (
+	"fmt"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+)
+
+// Plan describes what Execute would do for a node, without calling the LLM or any tool
+type Plan struct {
+	NodeID string        `json:"node_id"`
+	Mode   ExecutionMode `json:"mode"`
+
+	// LLM and agent modes
+	LLMConfig   map[string]interface{} `json:"llm_config,omitempty"`
+	Model       string                 `json:"model,omitempty"`
+	System      string                 `json:"system,omitempty"`
+	Prompt      string                 `json:"prompt,omitempty"`
+	Temperature float64                `json:"temperature,omitempty"`
+	MaxTokens   int                    `json:"max_tokens,omitempty"`
+
+	// Agent mode
+	Task          string        `json:"task,omitempty"`
+	Tools         []interface{} `json:"tools,omitempty"`
+	MaxIterations int           `json:"max_iterations,omitempty"`
+
+	// Tool mode
+	ToolName   string                 `json:"tool_name,omitempty"`
+	ToolParams map[string]interface{} `json:"tool_params,omitempty"`
+}
+
+// Plan resolves a node's configuration against state the same way Execute
+// does, returning the rendered prompt and resolved parameters
+func (e *Executor) Plan(state *domain.GraphState, config *NodeConfig) (*Plan, error) {
+	mode, err := e.ResolveMode(config)
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{NodeID: config.NodeID, Mode: mode}
+
+	switch mode {
+	case ModeAgent, ModeLLM:
+		llmConfig := getMapConfig(config.Config, "llm_config")
+		if llmConfig == nil {
+			return nil, fmt.Errorf("llm_config required for %s mode", mode)
+		}
+		llmConfig = e.ResolveLLMConfig(llmConfig)
+
+		plan.LLMConfig = llmConfig
+		plan.Model = getStringConfig(llmConfig, "model", "")
+		plan.Temperature = getFloatConfig(llmConfig, "temperature", 0.7)
+		plan.MaxTokens = getIntConfig(llmConfig, "max_tokens", 4096)
+
+		if mode == ModeLLM {
+			promptTemplate := getStringConfig(llmConfig, "prompt", "")
+			if promptTemplate == "" {
+				return nil, fmt.Errorf("prompt required in llm_config")
+			}
+			plan.System = getStringConfig(llmConfig, "system", "")
+			plan.Prompt = e.RenderPrompt(promptTemplate, state, config)
+			return plan, nil
+		}
+
+		plan.System = getStringConfig(llmConfig, "system", "You are a helpful AI assistant with access to tools.")
+		plan.Task = getStringConfig(config.Config, "task", "Complete the assigned task.")
+		plan.Tools = getSliceConfig(config.Config, "tools")
+		plan.MaxIterations = getIntConfig(config.Config, "max_iterations", e.currentSettings().MaxIterations)
+	case ModeTool:
+		plan.ToolName = getStringConfig(config.Config, "tool_name", "")
+		if plan.ToolName == "" {
+			return nil, fmt.Errorf("tool_name required for tool mode")
+		}
+		toolParams := getMapConfig(config.Config, "tool_params")
+		if toolParams == nil {
+			toolParams = make(map[string]interface{})
+		}
+		plan.ToolParams = e.resolveParams(toolParams, state)
+	default:
+		return nil, fmt.Errorf("dry run not supported for mode %s", mode)
+	}
+
+	return plan, nil
+}
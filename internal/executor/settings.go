@@ -0,0 +1,112 @@
+package executor
+
+import (
+	"context"
+	"time"
+)
+
+// defaultModel is used when neither the node nor the settings name a model
+const defaultModel = "claude-sonnet-4-20250514"
+
+// defaultProfile is applied to nodes whose llm_config names no profile
+const defaultProfile = "default"
+
+// ModelPrice is the price of a model in USD per million tokens
+type ModelPrice struct {
+	InputPerMillion  float64
+	OutputPerMillion float64
+}
+
+// Settings holds executor settings that may change at runtime (e.g. on
+// configuration reload). Maps must not be modified after being passed to
+// the executor.
+type Settings struct {
+	// MaxIterations is the default agent loop limit
+	MaxIterations int
+
+	// DefaultModel is used when a node's llm_config has no model
+	DefaultModel string
+
+	// ModelAliases maps alias names to model identifiers
+	ModelAliases map[string]string
+
+	// Profiles holds default llm_config values by profile name. A node
+	// selects one with llm_config.profile; "default" applies otherwise.
+	Profiles map[string]map[string]interface{}
+
+	// Prices is used to compute the cost of LLM calls
+	Prices map[string]ModelPrice
+
+	// ToolTimeouts bounds calls of individual tools
+	ToolTimeouts map[string]time.Duration
+
+	// StrictModeDetection rejects configs without an explicit mode whose
+	// keys do not identify a single mode
+	StrictModeDetection bool
+}
+
+// WithSettings sets the initial runtime settings
+func WithSettings(s Settings) Option {
+	return func(e *Executor) {
+		e.UpdateSettings(s)
+	}
+}
+
+// UpdateSettings replaces the runtime settings. Executions already running
+// keep the settings they started with for values read up front.
+func (e *Executor) UpdateSettings(s Settings) {
+	if s.MaxIterations <= 0 {
+		s.MaxIterations = 10
+	}
+	if s.DefaultModel == "" {
+		s.DefaultModel = defaultModel
+	}
+	e.settings.Store(&s)
+}
+
+// currentSettings returns the active settings
+func (e *Executor) currentSettings() *Settings {
+	return e.settings.Load()
+}
+
+// ResolveLLMConfig merges the node's llm_config over its profile defaults
+// and resolves the model alias
+func (e *Executor) ResolveLLMConfig(llmConfig map[string]interface{}) map[string]interface{} {
+	settings := e.currentSettings()
+
+	profileName := getStringConfig(llmConfig, "profile", defaultProfile)
+	profile := settings.Profiles[profileName]
+
+	resolved := make(map[string]interface{}, len(profile)+len(llmConfig))
+	for k, v := range profile {
+		resolved[k] = v
+	}
+	for k, v := range llmConfig {
+		resolved[k] = v
+	}
+
+	model := getStringConfig(resolved, "model", settings.DefaultModel)
+	if target, ok := settings.ModelAliases[model]; ok {
+		model = target
+	}
+	resolved["model"] = model
+
+	return resolved
+}
+
+// llmCost returns the cost in USD of a call, or 0 if the model has no price
+func (e *Executor) llmCost(model string, inputTokens, outputTokens int) float64 {
+	price, ok := e.currentSettings().Prices[model]
+	if !ok {
+		return 0
+	}
+	return (float64(inputTokens)*price.InputPerMillion + float64(outputTokens)*price.OutputPerMillion) / 1e6
+}
+
+// toolContext applies the tool's configured timeout, if any
+func (e *Executor) toolContext(ctx context.Context, toolName string) (context.Context, context.CancelFunc) {
+	if timeout := e.currentSettings().ToolTimeouts[toolName]; timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return ctx, func() {}
+}
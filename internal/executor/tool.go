@@ -8,6 +8,48 @@ import (
 	"go.uber.org/zap"
 )
 
+// toolMode is the built-in tool mode handler
+type toolMode struct {
+	e *Executor
+}
+
+func (m *toolMode) Describe() ModeDescription {
+	return ModeDescription{
+		Mode:        ModeTool,
+		Description: "Direct tool execution",
+		Keys:        []string{"tool_name", "tool_params"},
+	}
+}
+
+func (m *toolMode) Validate(ctx context.Context, config *NodeConfig, opts ValidateOptions) []ValidationIssue {
+	result := &ValidationResult{}
+
+	raw, ok := config.Config["tool_name"]
+	name, isString := raw.(string)
+	switch {
+	case !ok:
+		result.add(SeverityError, "tool_name", "required for tool mode")
+	case !isString || name == "":
+		result.add(SeverityError, "tool_name", "must be a non-empty string")
+	case !opts.SkipToolCheck:
+		m.e.checkToolsExist(ctx, result, "tool_name", []string{name})
+	}
+
+	if raw, ok := config.Config["tool_params"]; ok {
+		if params, ok := raw.(map[string]interface{}); ok {
+			checkParamTemplates(result, "tool_params", params, opts.State)
+		} else {
+			result.add(SeverityError, "tool_params", "must be an object")
+		}
+	}
+
+	return result.Issues
+}
+
+func (m *toolMode) Execute(ctx context.Context, state *domain.GraphState, config *NodeConfig) (interface{}, error) {
+	return m.e.executeTool(ctx, state, config)
+}
+
 // executeTool executes a node in tool mode (direct tool execution)
 func (e *Executor) executeTool(ctx context.Context, state *domain.GraphState, config *NodeConfig) (interface{}, error) {
 	toolName := getStringConfig(config.Config, "tool_name", "")
@@ -26,11 +68,10 @@ func (e *Executor) executeTool(ctx context.Context, state *domain.GraphState, co
 
 	e.logger.Debug("executing tool",
 		zap.String("node_id", config.NodeID),
-		zap.String("tool", toolName),
-		zap.Any("params", resolvedParams))
+		zap.String("tool", toolName))
 
 	// Execute tool
-	result, err := e.toolClient.Execute(ctx, toolName, resolvedParams)
+	result, err := e.CallTool(ctx, toolName, resolvedParams)
 	if err != nil {
 		return nil, fmt.Errorf("tool execution failed: %w", err)
 	}
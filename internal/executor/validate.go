@@ -0,0 +1,390 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-node-executor/internal/toolaccess"
+	pkgexecutor "github.com/aescanero/dago-node-executor/pkg/executor"
+	"go.uber.org/zap"
+)
+
+// Severity is the severity of a validation issue
+type Severity = pkgexecutor.Severity
+
+const (
+	// SeverityError marks a config that would fail or misbehave at execution time
+	SeverityError = pkgexecutor.SeverityError
+
+	// SeverityWarning marks a config that runs but is probably not what was meant
+	SeverityWarning = pkgexecutor.SeverityWarning
+)
+
+// ValidationIssue is a single problem found in a node config
+type ValidationIssue = pkgexecutor.ValidationIssue
+
+// ValidationResult is the outcome of validating a node config
+type ValidationResult struct {
+	NodeID string            `json:"node_id"`
+	Mode   ExecutionMode     `json:"mode"`
+	Issues []ValidationIssue `json:"issues"`
+}
+
+// Valid reports whether the result has no errors
+func (r *ValidationResult) Valid() bool {
+	return len(r.Errors()) == 0
+}
+
+// Errors returns the issues with error severity
+func (r *ValidationResult) Errors() []ValidationIssue {
+	var errs []ValidationIssue
+	for _, issue := range r.Issues {
+		if issue.Severity == SeverityError {
+			errs = append(errs, issue)
+		}
+	}
+	return errs
+}
+
+// Err returns the errors joined into one error, or nil if the config is valid
+func (r *ValidationResult) Err() error {
+	var errs []error
+	for _, issue := range r.Errors() {
+		errs = append(errs, errors.New(issue.String()))
+	}
+	return errors.Join(errs...)
+}
+
+func (r *ValidationResult) add(severity Severity, field, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, ValidationIssue{
+		Severity: severity,
+		Field:    field,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// ValidateOptions controls the optional checks of Validate
+type ValidateOptions = pkgexecutor.ValidateOptions
+
+// Known config keys
+var (
+	commonKeys    = []string{modeKey, legacyModeKey, toolAccessKey, artifactsKey}
+	llmConfigKeys = []string{"model", "prompt", "system", "temperature", "max_tokens", "profile"}
+)
+
+// templatePattern matches {{variable}} placeholders
+var templatePattern = regexp.MustCompile(`\{\{([^{}]+)\}\}`)
+
+// Validate checks a node config against the schema of its mode without
+// executing it. It flags missing and mistyped fields, unknown keys,
+// unresolvable template variables and tools the tool client does not know.
+func (e *Executor) Validate(ctx context.Context, config *NodeConfig, opts ValidateOptions) *ValidationResult {
+	result := &ValidationResult{NodeID: config.NodeID, Issues: []ValidationIssue{}}
+
+	mode, source, err := e.resolveMode(config)
+	if err != nil {
+		result.add(SeverityError, modeKey, "%v", err)
+		return result
+	}
+	result.Mode = mode
+
+	switch source {
+	case modeInferred:
+		_, ambiguity := inferMode(config)
+		if ambiguity != "" {
+			result.add(SeverityWarning, modeKey, "not set and the config is ambiguous (%s); inferred %q", ambiguity, mode)
+		} else {
+			result.add(SeverityWarning, modeKey, "not set; inferred %q from the config keys, which is deprecated", mode)
+		}
+	case modeLegacy:
+		result.add(SeverityWarning, legacyModeKey, "deprecated; use %q", modeKey)
+	}
+
+	handler, _ := e.modeHandler(mode)
+	known := append(append([]string{}, commonKeys...), handler.Describe().Keys...)
+	checkUnknownKeys(result, "", config.Config, known, e.otherModeKeys(mode))
+
+	if _, err := toolaccess.ParsePolicy(config.Config[toolAccessKey]); err != nil {
+		result.add(SeverityError, toolAccessKey, "%v", err)
+	}
+	if _, err := ArtifactPatterns(config); err != nil {
+		result.add(SeverityError, artifactsKey, "%v", err)
+	}
+
+	result.Issues = append(result.Issues, handler.Validate(ctx, config, opts)...)
+
+	return result
+}
+
+// otherModeKeys returns the keys read by registered modes other than mode
+func (e *Executor) otherModeKeys(mode ExecutionMode) map[string]bool {
+	keys := make(map[string]bool)
+	for _, desc := range e.Modes() {
+		if desc.Mode == mode {
+			continue
+		}
+		for _, key := range desc.Keys {
+			keys[key] = true
+		}
+	}
+	return keys
+}
+
+// validateLLMConfig checks the llm_config shared by the LLM and agent
+// modes. It returns the llm_config, or nil if it is missing or malformed.
+func (e *Executor) validateLLMConfig(result *ValidationResult, config *NodeConfig, mode ExecutionMode) map[string]interface{} {
+	raw, ok := config.Config["llm_config"]
+	if !ok {
+		result.add(SeverityError, "llm_config", "required for %s mode", mode)
+		return nil
+	}
+	llmConfig, ok := raw.(map[string]interface{})
+	if !ok {
+		result.add(SeverityError, "llm_config", "must be an object")
+		return nil
+	}
+
+	checkUnknownKeys(result, "llm_config.", llmConfig, llmConfigKeys, nil)
+
+	for _, key := range []string{"model", "prompt", "system", "profile"} {
+		if v, ok := llmConfig[key]; ok {
+			if _, ok := v.(string); !ok {
+				result.add(SeverityError, "llm_config."+key, "must be a string")
+			}
+		}
+	}
+	if v, ok := llmConfig["temperature"]; ok {
+		if f, ok := asNumber(v); !ok || f < 0 {
+			result.add(SeverityError, "llm_config.temperature", "must be a non-negative number")
+		}
+	}
+	if v, ok := llmConfig["max_tokens"]; ok {
+		if n, ok := asInteger(v); !ok || n <= 0 {
+			result.add(SeverityError, "llm_config.max_tokens", "must be a positive integer")
+		}
+	}
+
+	if profile, ok := llmConfig["profile"].(string); ok && profile != defaultProfile {
+		if _, ok := e.currentSettings().Profiles[profile]; !ok {
+			result.add(SeverityError, "llm_config.profile", "unknown profile %q", profile)
+		}
+	}
+
+	return llmConfig
+}
+
+// checkParamTemplates checks templates in tool params. Only whole-value
+// templates are substituted, so partial ones are flagged.
+func checkParamTemplates(result *ValidationResult, field string, params map[string]interface{}, state *domain.GraphState) {
+	for _, key := range sortedKeys(params) {
+		path := field + "." + key
+		switch v := params[key].(type) {
+		case string:
+			matches := templatePattern.FindAllStringSubmatch(v, -1)
+			if len(matches) == 0 {
+				continue
+			}
+			if len(matches) > 1 || matches[0][0] != v {
+				result.add(SeverityWarning, path, "templates are only substituted when they are the whole value")
+				continue
+			}
+			if state != nil {
+				checkTemplateVars(result, path, v, state)
+			}
+		case map[string]interface{}:
+			checkParamTemplates(result, path, v, state)
+		}
+	}
+}
+
+// checkTemplateVars checks that every {{variable}} in text resolves against state
+func checkTemplateVars(result *ValidationResult, field, text string, state *domain.GraphState) {
+	seen := make(map[string]bool)
+	for _, match := range templatePattern.FindAllStringSubmatch(text, -1) {
+		name := match[1]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		if _, ok := state.Inputs[name]; ok {
+			continue
+		}
+		if _, ok := state.NodeStates[name]; ok {
+			continue
+		}
+		result.add(SeverityError, field, "template variable %q is not an input or node of the sample state", name)
+	}
+}
+
+// checkToolsExist checks referenced tools against the tool client's catalog
+func (e *Executor) checkToolsExist(ctx context.Context, result *ValidationResult, field string, referenced []string) {
+	if e.toolClient == nil || len(referenced) == 0 {
+		return
+	}
+
+	available, err := e.toolClient.ListTools(ctx)
+	if err != nil {
+		result.add(SeverityWarning, "", "could not list tools: %v", err)
+		return
+	}
+	catalog := make(map[string]bool, len(available))
+	for _, name := range available {
+		catalog[name] = true
+	}
+
+	for _, name := range referenced {
+		// Globs in an agent's tools list may match no tool yet
+		if toolaccess.IsPattern(name) {
+			continue
+		}
+		if !catalog[name] {
+			result.add(SeverityWarning, field, "tool %q is not in the available tool catalog", name)
+		}
+	}
+}
+
+// checkUnknownKeys flags keys not in known, suggesting close matches. Keys
+// valid in another mode are reported as unused rather than unknown.
+func checkUnknownKeys(result *ValidationResult, prefix string, config map[string]interface{}, known []string, otherModes map[string]bool) {
+	knownSet := make(map[string]bool, len(known))
+	for _, key := range known {
+		knownSet[key] = true
+	}
+
+	for _, key := range sortedKeys(config) {
+		if knownSet[key] {
+			continue
+		}
+		if otherModes[key] {
+			result.add(SeverityWarning, prefix+key, "not used in this mode")
+			continue
+		}
+		if suggestion := closestKey(key, known, otherModes); suggestion != "" {
+			result.add(SeverityError, prefix+key, "unknown key (did you mean %q?)", suggestion)
+			continue
+		}
+		result.add(SeverityWarning, prefix+key, "unknown key")
+	}
+}
+
+// closestKey returns the known key within a small edit distance of key, if any
+func closestKey(key string, known []string, otherModes map[string]bool) string {
+	candidates := append([]string{}, known...)
+	for k := range otherModes {
+		candidates = append(candidates, k)
+	}
+	sort.Strings(candidates)
+
+	normalized := strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(key))
+	best, bestDistance := "", 3
+	for _, candidate := range candidates {
+		if strings.ReplaceAll(candidate, "_", "") == normalized {
+			return candidate
+		}
+		if d := editDistance(key, candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func asNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func asInteger(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case float64:
+		if n == float64(int(n)) {
+			return int(n), true
+		}
+	}
+	return 0, false
+}
+
+// ValidationPolicy controls how Execute validates node configs up front
+type ValidationPolicy string
+
+const (
+	// ValidationOff skips validation before execution
+	ValidationOff ValidationPolicy = "off"
+
+	// ValidationWarn logs validation issues and executes anyway
+	ValidationWarn ValidationPolicy = "warn"
+
+	// ValidationStrict rejects configs with validation errors
+	ValidationStrict ValidationPolicy = "strict"
+)
+
+// WithValidation sets the validation policy applied before each execution.
+// Referenced tools are not checked, to avoid listing tools per execution.
+func WithValidation(policy ValidationPolicy) Option {
+	return func(e *Executor) {
+		e.validation = policy
+	}
+}
+
+// preValidate applies the validation policy to a config about to be executed
+func (e *Executor) preValidate(ctx context.Context, config *NodeConfig) error {
+	if e.validation == "" || e.validation == ValidationOff {
+		return nil
+	}
+
+	result := e.Validate(ctx, config, ValidateOptions{SkipToolCheck: true})
+	for _, issue := range result.Issues {
+		e.logger.Warn("node config issue",
+			zap.String("node_id", config.NodeID),
+			zap.String("severity", string(issue.Severity)),
+			zap.String("field", issue.Field),
+			zap.String("message", issue.Message))
+	}
+
+	if e.validation == ValidationStrict && !result.Valid() {
+		return fmt.Errorf("invalid node config: %w", result.Err())
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package fakellm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// StatusError is an injected provider error with an HTTP status
+type StatusError struct {
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("fake LLM: %d %s (retry after %s)", e.StatusCode, e.Message, e.RetryAfter)
+	}
+	return fmt.Sprintf("fake LLM: %d %s", e.StatusCode, e.Message)
+}
+
+// Client is a scripted ports.LLMClient. It is safe for concurrent use;
+// concurrent calls take responses from the queue in arrival order.
+type Client struct {
+	mu        sync.Mutex
+	queue     []Response
+	fallback  *Response
+	requests  []interface{}
+	served    int
+	toolCalls int
+}
+
+// New creates a client serving the given responses in order
+func New(responses ...Response) *Client {
+	return NewFromScript(&Script{Responses: responses})
+}
+
+// NewFromScript creates a client serving a script
+func NewFromScript(script *Script) *Client {
+	return &Client{
+		queue:    script.expand(),
+		fallback: script.Default,
+	}
+}
+
+// NewFromFile creates a client serving the script file at path
+func NewFromFile(path string) (*Client, error) {
+	script, err := LoadScript(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromScript(script), nil
+}
+
+// Enqueue appends responses to the script
+func (c *Client) Enqueue(responses ...Response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queue = append(c.queue, (&Script{Responses: responses}).expand()...)
+}
+
+// Requests returns the requests received so far, in order. Entries are
+// *domain.LLMRequest for GenerateCompletion and ports.CompletionRequest
+// for the other methods.
+func (c *Client) Requests() []interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]interface{}(nil), c.requests...)
+}
+
+// Remaining returns the number of scripted responses not yet served
+func (c *Client) Remaining() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.queue)
+}
+
+// next records the request and takes the next scripted response
+func (c *Client) next(req interface{}) (Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.requests = append(c.requests, req)
+	c.served++
+
+	if len(c.queue) == 0 {
+		if c.fallback != nil {
+			return *c.fallback, nil
+		}
+		return Response{}, fmt.Errorf("fake LLM: script exhausted at call %d", c.served)
+	}
+
+	resp := c.queue[0]
+	c.queue = c.queue[1:]
+	return resp, nil
+}
+
+// nextToolCallID returns a generated tool call ID
+func (c *Client) nextToolCallID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.toolCalls++
+	return fmt.Sprintf("call_%d", c.toolCalls)
+}
+
+// serve waits for the response latency and applies injected failures.
+// It reports whether the response is to be returned malformed.
+func serve(ctx context.Context, resp Response) (bool, error) {
+	if resp.Latency > 0 {
+		timer := time.NewTimer(resp.Latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+
+	switch resp.Error {
+	case "":
+		return false, nil
+	case ErrorRateLimit:
+		return false, &StatusError{StatusCode: 429, Message: "rate limit exceeded", RetryAfter: resp.RetryAfter}
+	case ErrorServer:
+		return false, &StatusError{StatusCode: 500, Message: "internal server error"}
+	case ErrorTimeout:
+		<-ctx.Done()
+		return false, ctx.Err()
+	case ErrorMalformed:
+		return true, nil
+	default:
+		return false, fmt.Errorf("fake LLM: %s", resp.Error)
+	}
+}
+
+// GenerateCompletion serves the next response for a domain.LLMRequest.
+// A malformed response is returned as a string instead of *domain.LLMResponse.
+func (c *Client) GenerateCompletion(ctx context.Context, req interface{}) (interface{}, error) {
+	resp, err := c.next(req)
+	if err != nil {
+		return nil, err
+	}
+	malformed, err := serve(ctx, resp)
+	if err != nil {
+		return nil, err
+	}
+	if malformed {
+		return "{malformed", nil
+	}
+
+	var model string
+	var inputChars int
+	if r, ok := req.(*domain.LLMRequest); ok {
+		model = r.Model
+		inputChars = len(r.System)
+		for _, msg := range r.Messages {
+			inputChars += len(msg.Content)
+		}
+	}
+
+	out := &domain.LLMResponse{
+		Content: resp.Content,
+		Model:   model,
+		Usage:   domain.Usage(c.usage(resp, inputChars)),
+	}
+	for _, call := range resp.ToolCalls {
+		out.ToolCalls = append(out.ToolCalls, domain.ToolCall{
+			ID:    c.toolCallID(call),
+			Name:  call.Name,
+			Input: call.Input,
+		})
+	}
+	return out, nil
+}
+
+// Complete serves the next response for a text completion
+func (c *Client) Complete(ctx context.Context, req ports.CompletionRequest) (*ports.CompletionResponse, error) {
+	return c.complete(ctx, req)
+}
+
+// CompleteWithTools serves the next response for a completion with tools
+func (c *Client) CompleteWithTools(ctx context.Context, req ports.CompletionRequest, tools []ports.Tool) (*ports.CompletionResponse, error) {
+	return c.complete(ctx, req)
+}
+
+// CompleteStructured serves the next response's Data
+func (c *Client) CompleteStructured(ctx context.Context, req ports.CompletionRequest, schema ports.JSONSchema) (*ports.StructuredResponse, error) {
+	resp, err := c.next(req)
+	if err != nil {
+		return nil, err
+	}
+	malformed, err := serve(ctx, resp)
+	if err != nil {
+		return nil, err
+	}
+	if malformed {
+		return nil, fmt.Errorf("fake LLM: response does not match schema")
+	}
+
+	usage := c.usage(resp, completionChars(req))
+	return &ports.StructuredResponse{
+		Data:      resp.Data,
+		Usage:     portsUsage(usage),
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+func (c *Client) complete(ctx context.Context, req ports.CompletionRequest) (*ports.CompletionResponse, error) {
+	resp, err := c.next(req)
+	if err != nil {
+		return nil, err
+	}
+	malformed, err := serve(ctx, resp)
+	if err != nil {
+		return nil, err
+	}
+	if malformed {
+		return nil, fmt.Errorf("fake LLM: malformed response")
+	}
+
+	out := &ports.CompletionResponse{
+		ID:           fmt.Sprintf("fake-%d", len(c.Requests())),
+		Model:        req.Model,
+		Message:      ports.Message{Role: "assistant", Content: resp.Content},
+		FinishReason: "stop",
+		Usage:        portsUsage(c.usage(resp, completionChars(req))),
+		CreatedAt:    time.Now(),
+	}
+	for _, call := range resp.ToolCalls {
+		out.ToolCalls = append(out.ToolCalls, ports.ToolCall{
+			ID:        c.toolCallID(call),
+			Name:      call.Name,
+			Arguments: call.Input,
+		})
+	}
+	if len(out.ToolCalls) > 0 {
+		out.FinishReason = "tool_calls"
+	}
+	return out, nil
+}
+
+// usage returns the scripted usage, or an estimate of about four
+// characters per token if none is scripted
+func (c *Client) usage(resp Response, inputChars int) Usage {
+	if resp.Usage.InputTokens != 0 || resp.Usage.OutputTokens != 0 {
+		return resp.Usage
+	}
+	return Usage{
+		InputTokens:  (inputChars + 3) / 4,
+		OutputTokens: (len(resp.Content) + 3) / 4,
+	}
+}
+
+func (c *Client) toolCallID(call ToolCall) string {
+	if call.ID != "" {
+		return call.ID
+	}
+	return c.nextToolCallID()
+}
+
+func completionChars(req ports.CompletionRequest) int {
+	n := 0
+	for _, msg := range req.Messages {
+		n += len(msg.Content)
+	}
+	return n
+}
+
+func portsUsage(u Usage) ports.UsageInfo {
+	return ports.UsageInfo{
+		PromptTokens:     u.InputTokens,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      u.InputTokens + u.OutputTokens,
+	}
+}
@@ -0,0 +1,9 @@
+// Package fakellm provides a scriptable ports.LLMClient for tests.
+//
+// A Client serves a queue of scripted responses: text, tool calls
+// (several per response for parallel calls), usage numbers, artificial
+// latency and injected failures such as rate limits, server errors,
+// timeouts and malformed output. Scripts are built in Go or loaded from a
+// YAML or JSON file, which is how the worker uses it with
+// LLM_PROVIDER=fake.
+package fakellm
@@ -0,0 +1,110 @@
+package fakellm
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Injected failures. Any other non-empty Response.Error is returned as a
+// plain error with that message.
+const (
+	// ErrorRateLimit fails with a 429 StatusError
+	ErrorRateLimit = "rate_limit"
+
+	// ErrorServer fails with a 500 StatusError
+	ErrorServer = "server_error"
+
+	// ErrorTimeout blocks until the request context is done
+	ErrorTimeout = "timeout"
+
+	// ErrorMalformed returns output that is not a valid response
+	ErrorMalformed = "malformed"
+)
+
+// ToolCall is a scripted tool call. An empty ID is generated.
+type ToolCall struct {
+	ID    string                 `yaml:"id" json:"id"`
+	Name  string                 `yaml:"name" json:"name"`
+	Input map[string]interface{} `yaml:"input" json:"input"`
+}
+
+// Usage is scripted token usage. When both counts are zero, usage is
+// estimated from the request and response length.
+type Usage struct {
+	InputTokens  int `yaml:"input_tokens" json:"input_tokens"`
+	OutputTokens int `yaml:"output_tokens" json:"output_tokens"`
+}
+
+// Response is one scripted LLM response
+type Response struct {
+	// Content is the response text
+	Content string `yaml:"content" json:"content"`
+
+	// ToolCalls requests tool executions
+	ToolCalls []ToolCall `yaml:"tool_calls" json:"tool_calls"`
+
+	// Data is returned by CompleteStructured
+	Data map[string]interface{} `yaml:"data" json:"data"`
+
+	// Usage is the reported token usage
+	Usage Usage `yaml:"usage" json:"usage"`
+
+	// Latency delays the response
+	Latency time.Duration `yaml:"latency" json:"latency"`
+
+	// Error injects a failure instead of a response (see the Error* constants)
+	Error string `yaml:"error" json:"error"`
+
+	// RetryAfter is reported by rate limit errors
+	RetryAfter time.Duration `yaml:"retry_after" json:"retry_after"`
+
+	// Repeat serves the response this many times (default 1)
+	Repeat int `yaml:"repeat" json:"repeat"`
+}
+
+// Script is the sequence of responses a Client serves
+type Script struct {
+	// Responses are served in order
+	Responses []Response `yaml:"responses" json:"responses"`
+
+	// Default is served once Responses are exhausted. Without it, calls
+	// beyond the script fail.
+	Default *Response `yaml:"default" json:"default"`
+}
+
+// LoadScript reads a YAML or JSON script file
+func LoadScript(path string) (*Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fake LLM script: %w", err)
+	}
+
+	// JSON is valid YAML, so a single decoder handles both formats
+	var script Script
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&script); err != nil {
+		return nil, fmt.Errorf("failed to parse fake LLM script %s: %w", path, err)
+	}
+
+	return &script, nil
+}
+
+// expand returns the responses with repeats unrolled
+func (s *Script) expand() []Response {
+	var responses []Response
+	for _, resp := range s.Responses {
+		n := resp.Repeat
+		if n < 1 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			responses = append(responses, resp)
+		}
+	}
+	return responses
+}
@@ -0,0 +1,6 @@
+// Package metrics provides Prometheus instrumentation for the executor worker.
+//
+// Metrics cover node executions, LLM and tool calls, agent iterations,
+// stream consumption and in-flight work. They are exposed on the health
+// server under /metrics.
+package metrics
@@ -0,0 +1,345 @@
+package metrics
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "executor"
+
+// Outcome labels
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+)
+
+// UnknownTool is the tool label of names the tool filter doesn't know
+const UnknownTool = "unknown"
+
+// Metrics holds all Prometheus collectors for the worker.
+// A nil *Metrics is valid and records nothing.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	nodeExecutions *prometheus.CounterVec
+	nodeDuration   *prometheus.HistogramVec
+
+	llmRequests *prometheus.CounterVec
+	llmDuration *prometheus.HistogramVec
+	llmTokens   *prometheus.CounterVec
+	llmCost     *prometheus.CounterVec
+
+	llmRateLimitWait *prometheus.HistogramVec
+	llmRateLimited   *prometheus.CounterVec
+
+	toolCalls    *prometheus.CounterVec
+	toolDuration *prometheus.HistogramVec
+
+	toolRejected      *prometheus.CounterVec
+	toolBreakerOpened *prometheus.CounterVec
+
+	// knownTool bounds the tool label, see SetToolFilter
+	knownTool atomic.Pointer[func(name string) bool]
+
+	agentIterations prometheus.Histogram
+
+	streamLag     *prometheus.GaugeVec
+	streamPending *prometheus.GaugeVec
+	retries       *prometheus.CounterVec
+	deadLettered  *prometheus.CounterVec
+
+	inFlight prometheus.Gauge
+}
+
+// New creates and registers all collectors on a dedicated registry
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		nodeExecutions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "node_executions_total",
+			Help:      "Node executions by mode and outcome.",
+		}, []string{"mode", "outcome"}),
+		nodeDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "node_execution_duration_seconds",
+			Help:      "Node execution duration by mode.",
+			Buckets:   []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+		}, []string{"mode"}),
+
+		llmRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "llm_requests_total",
+			Help:      "LLM calls by model and outcome.",
+		}, []string{"model", "outcome"}),
+		llmDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "llm_request_duration_seconds",
+			Help:      "LLM call latency by model.",
+			Buckets:   []float64{0.25, 0.5, 1, 2, 4, 8, 15, 30, 60, 120},
+		}, []string{"model"}),
+		llmTokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "llm_tokens_total",
+			Help:      "LLM tokens by model and type (input, output).",
+		}, []string{"model", "type"}),
+		llmCost: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "llm_cost_usd_total",
+			Help:      "Estimated LLM cost in USD by model, from the configured price table.",
+		}, []string{"model"}),
+
+		llmRateLimitWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "llm_rate_limit_wait_seconds",
+			Help:      "Time LLM calls waited for the shared rate limit, by model.",
+			Buckets:   []float64{0.1, 0.25, 0.5, 1, 2, 5, 10, 20, 30, 60},
+		}, []string{"model"}),
+		llmRateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "llm_rate_limited_total",
+			Help:      "LLM calls rejected because the rate limit wait exceeded its bound, by model.",
+		}, []string{"model"}),
+
+		toolCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tool_calls_total",
+			Help:      "Tool calls by tool and outcome.",
+		}, []string{"tool", "outcome"}),
+		toolDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "tool_call_duration_seconds",
+			Help:      "Tool call latency by tool.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"tool"}),
+
+		toolRejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tool_calls_rejected_total",
+			Help:      "Tool calls rejected by the tool's policy, by tool and reason (circuit_open, rate_limited, busy).",
+		}, []string{"tool", "reason"}),
+		toolBreakerOpened: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tool_breaker_opened_total",
+			Help:      "Times this worker opened a tool's circuit breaker.",
+		}, []string{"tool"}),
+
+		agentIterations: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "agent_iterations",
+			Help:      "Iterations used per agent-mode execution.",
+			Buckets:   []float64{1, 2, 3, 5, 8, 10, 15, 20, 30, 50},
+		}),
+
+		streamLag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "stream_lag",
+			Help:      "Entries in the work stream not yet delivered to the consumer group.",
+		}, []string{"stream", "group"}),
+		streamPending: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "stream_pending_entries",
+			Help:      "Entries delivered to the consumer group but not yet acknowledged.",
+		}, []string{"stream", "group"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "work_retries_total",
+			Help:      "Work items scheduled for redelivery.",
+		}, []string{"stream"}),
+		deadLettered: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "work_dead_lettered_total",
+			Help:      "Work items moved to the dead letter queue.",
+		}, []string{"stream"}),
+
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "in_flight_nodes",
+			Help:      "Nodes currently being executed by this worker.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.nodeExecutions,
+		m.nodeDuration,
+		m.llmRequests,
+		m.llmDuration,
+		m.llmTokens,
+		m.llmCost,
+		m.llmRateLimitWait,
+		m.llmRateLimited,
+		m.toolCalls,
+		m.toolDuration,
+		m.toolRejected,
+		m.toolBreakerOpened,
+		m.agentIterations,
+		m.streamLag,
+		m.streamPending,
+		m.retries,
+		m.deadLettered,
+		m.inFlight,
+	)
+
+	return m
+}
+
+// Handler returns the HTTP handler serving the metrics
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveNode records a node execution
+func (m *Metrics) ObserveNode(mode string, duration time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	m.nodeExecutions.WithLabelValues(mode, outcome(err)).Inc()
+	m.nodeDuration.WithLabelValues(mode).Observe(duration.Seconds())
+}
+
+// ObserveLLMCall records an LLM call and its token usage
+func (m *Metrics) ObserveLLMCall(model string, duration time.Duration, inputTokens, outputTokens int, err error) {
+	if m == nil {
+		return
+	}
+	m.llmRequests.WithLabelValues(model, outcome(err)).Inc()
+	m.llmDuration.WithLabelValues(model).Observe(duration.Seconds())
+	if inputTokens > 0 {
+		m.llmTokens.WithLabelValues(model, "input").Add(float64(inputTokens))
+	}
+	if outputTokens > 0 {
+		m.llmTokens.WithLabelValues(model, "output").Add(float64(outputTokens))
+	}
+}
+
+// ObserveLLMCost records the estimated cost of an LLM call
+func (m *Metrics) ObserveLLMCost(model string, usd float64) {
+	if m == nil || usd <= 0 {
+		return
+	}
+	m.llmCost.WithLabelValues(model).Add(usd)
+}
+
+// ObserveLLMRateLimitWait records the time an LLM call waited for the rate limit
+func (m *Metrics) ObserveLLMRateLimitWait(model string, wait time.Duration) {
+	if m == nil {
+		return
+	}
+	m.llmRateLimitWait.WithLabelValues(model).Observe(wait.Seconds())
+}
+
+// IncLLMRateLimited records an LLM call rejected by the rate limit
+func (m *Metrics) IncLLMRateLimited(model string) {
+	if m == nil {
+		return
+	}
+	m.llmRateLimited.WithLabelValues(model).Inc()
+}
+
+// SetToolFilter bounds the tool label to the names known reports, such as
+// the tools of the catalog. Other names, e.g. made up by a model, are
+// labelled "unknown".
+func (m *Metrics) SetToolFilter(known func(name string) bool) {
+	if m == nil {
+		return
+	}
+	m.knownTool.Store(&known)
+}
+
+// toolLabel returns the tool label of a tool name
+func (m *Metrics) toolLabel(tool string) string {
+	if known := m.knownTool.Load(); known != nil && !(*known)(tool) {
+		return UnknownTool
+	}
+	return tool
+}
+
+// ObserveToolCall records a tool call
+func (m *Metrics) ObserveToolCall(tool string, duration time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	tool = m.toolLabel(tool)
+	m.toolCalls.WithLabelValues(tool, outcome(err)).Inc()
+	m.toolDuration.WithLabelValues(tool).Observe(duration.Seconds())
+}
+
+// IncToolRejected records a tool call rejected by its policy
+func (m *Metrics) IncToolRejected(tool, reason string) {
+	if m == nil {
+		return
+	}
+	m.toolRejected.WithLabelValues(m.toolLabel(tool), reason).Inc()
+}
+
+// IncToolBreakerOpened records a tool's circuit breaker opening
+func (m *Metrics) IncToolBreakerOpened(tool string) {
+	if m == nil {
+		return
+	}
+	m.toolBreakerOpened.WithLabelValues(m.toolLabel(tool)).Inc()
+}
+
+// ObserveAgentIterations records the iterations used by an agent execution
+func (m *Metrics) ObserveAgentIterations(iterations int) {
+	if m == nil {
+		return
+	}
+	m.agentIterations.Observe(float64(iterations))
+}
+
+// SetStreamStats records consumer group lag and pending entries for a stream
+func (m *Metrics) SetStreamStats(stream, group string, lag, pending int64) {
+	if m == nil {
+		return
+	}
+	m.streamLag.WithLabelValues(stream, group).Set(float64(lag))
+	m.streamPending.WithLabelValues(stream, group).Set(float64(pending))
+}
+
+// IncRetries records a work item scheduled for redelivery
+func (m *Metrics) IncRetries(stream string) {
+	if m == nil {
+		return
+	}
+	m.retries.WithLabelValues(stream).Inc()
+}
+
+// IncDeadLettered records a work item moved to the dead letter queue
+func (m *Metrics) IncDeadLettered(stream string) {
+	if m == nil {
+		return
+	}
+	m.deadLettered.WithLabelValues(stream).Inc()
+}
+
+// IncInFlight marks the start of a node execution
+func (m *Metrics) IncInFlight() {
+	if m == nil {
+		return
+	}
+	m.inFlight.Inc()
+}
+
+// DecInFlight marks the end of a node execution
+func (m *Metrics) DecInFlight() {
+	if m == nil {
+		return
+	}
+	m.inFlight.Dec()
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return OutcomeError
+	}
+	return OutcomeSuccess
+}
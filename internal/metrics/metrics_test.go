@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scrape returns the metrics as served to Prometheus
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	return rec.Body.String()
+}
+
+func TestToolLabel(t *testing.T) {
+	m := New()
+	m.ObserveToolCall("made_up", time.Millisecond, nil)
+	if out := scrape(t, m); !strings.Contains(out, `executor_tool_calls_total{outcome="success",tool="made_up"} 1`) {
+		t.Errorf("without a filter, made_up calls are not counted under their name:\n%s", out)
+	}
+
+	m = New()
+	m.SetToolFilter(func(name string) bool { return name == "search" })
+	m.ObserveToolCall("search", time.Millisecond, nil)
+	m.ObserveToolCall("made_up", time.Millisecond, errors.New("tool not found"))
+	m.ObserveToolCall("other", time.Millisecond, errors.New("tool not found"))
+	m.IncToolRejected("denied", "not_permitted")
+	out := scrape(t, m)
+
+	tests := []struct {
+		name   string
+		series string
+		want   bool
+	}{
+		{"known tool", `executor_tool_calls_total{outcome="success",tool="search"} 1`, true},
+		{"unknown tools", `executor_tool_calls_total{outcome="error",tool="unknown"} 2`, true},
+		{"unknown rejected", `executor_tool_calls_rejected_total{reason="not_permitted",tool="unknown"} 1`, true},
+		{"unknown name", `tool="made_up"`, false},
+		{"other unknown name", `tool="other"`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Contains(out, tt.series); got != tt.want {
+				t.Errorf("series %s present = %v, want %v", tt.series, got, tt.want)
+			}
+		})
+	}
+}
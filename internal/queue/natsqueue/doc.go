@@ -0,0 +1,15 @@
+// Package natsqueue implements the worker's work source and event sink on
+// NATS JetStream.
+//
+// Work is pulled from a durable consumer shared by all workers. A message
+// is acked once processed, or nakked with a delay when the worker asks for
+// redelivery. After MaxDeliver attempts JetStream stops redelivering and
+// emits a max deliveries advisory; one worker copies the message to the
+// DLQ subject and publishes node.failed for it.
+//
+// Events are published through JetStream on the node.completed and
+// node.failed subjects.
+//
+// Graph state is not stored on NATS; pair the backend with another
+// queue.StateStore.
+package natsqueue
@@ -0,0 +1,420 @@
+package natsqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aescanero/dago-node-executor/internal/metrics"
+	"github.com/aescanero/dago-node-executor/pkg/queue"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+// Defaults for Config fields left empty
+const (
+	DefaultStream       = "EXECUTOR_WORK"
+	DefaultSubject      = "executor.work"
+	DefaultDurable      = "executor-workers"
+	DefaultDLQSubject   = "executor.work.dlq"
+	DefaultEventsStream = "DAGO_EVENTS"
+	DefaultMaxDeliver   = 5
+	DefaultAckWait      = 15 * time.Minute
+	defaultFetchWait    = time.Second
+	advisoryTimeout     = 5 * time.Second
+	pingTimeout         = 2 * time.Second
+)
+
+// eventSubjects is captured by the events stream
+const eventSubjects = "node.>"
+
+// maxDeliveriesAdvisory is the subject prefix of JetStream's max deliveries
+// advisories, followed by <stream>.<consumer>
+const maxDeliveriesAdvisory = "$JS.EVENT.ADVISORY.CONSUMER.MAX_DELIVERIES"
+
+// Headers set on dead-lettered messages
+const (
+	headerStream     = "Dago-Stream"
+	headerStreamSeq  = "Dago-Stream-Seq"
+	headerSubject    = "Dago-Subject"
+	headerDeliveries = "Dago-Deliveries"
+)
+
+// Config holds NATS backend settings
+type Config struct {
+	// Stream holds the work subject and the DLQ subject (default EXECUTOR_WORK)
+	Stream string
+
+	// Subject is the work subject (default executor.work)
+	Subject string
+
+	// Durable is the pull consumer shared by all workers (default executor-workers)
+	Durable string
+
+	// DLQSubject receives messages that reached MaxDeliver (default executor.work.dlq)
+	DLQSubject string
+
+	// EventsStream captures node.> events (default DAGO_EVENTS), unless
+	// another stream already does
+	EventsStream string
+
+	// MaxDeliver is the delivery limit per message (default 5)
+	MaxDeliver int
+
+	// AckWait is how long a delivered message may go unacked before it is
+	// redelivered (default 15m). It should exceed the longest node run.
+	AckWait time.Duration
+
+	Logger  *zap.Logger
+	Metrics *metrics.Metrics
+}
+
+// Backend is a NATS JetStream work source and event sink
+type Backend struct {
+	nc  *nats.Conn
+	js  jetstream.JetStream
+	cfg Config
+
+	logger  *zap.Logger
+	metrics *metrics.Metrics
+
+	stream   jetstream.Stream
+	consumer jetstream.Consumer
+	advisory *nats.Subscription
+
+	mu      sync.Mutex
+	pending map[*queue.Message]jetstream.Msg
+}
+
+// New creates a NATS backend on an existing connection
+func New(nc *nats.Conn, cfg Config) (*Backend, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	if cfg.Stream == "" {
+		cfg.Stream = DefaultStream
+	}
+	if cfg.Subject == "" {
+		cfg.Subject = DefaultSubject
+	}
+	if cfg.Durable == "" {
+		cfg.Durable = DefaultDurable
+	}
+	if cfg.DLQSubject == "" {
+		cfg.DLQSubject = DefaultDLQSubject
+	}
+	if cfg.EventsStream == "" {
+		cfg.EventsStream = DefaultEventsStream
+	}
+	if cfg.MaxDeliver <= 0 {
+		cfg.MaxDeliver = DefaultMaxDeliver
+	}
+	if cfg.AckWait <= 0 {
+		cfg.AckWait = DefaultAckWait
+	}
+
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &Backend{
+		nc:      nc,
+		js:      js,
+		cfg:     cfg,
+		logger:  logger,
+		metrics: cfg.Metrics,
+		pending: make(map[*queue.Message]jetstream.Msg),
+	}, nil
+}
+
+// Name identifies the backend in health checks
+func (b *Backend) Name() string {
+	return "nats"
+}
+
+// Ping checks the NATS connection with a round trip to the server
+func (b *Backend) Ping(ctx context.Context) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, pingTimeout)
+		defer cancel()
+	}
+	if err := b.nc.FlushWithContext(ctx); err != nil {
+		return fmt.Errorf("nats ping failed: %w", err)
+	}
+	return nil
+}
+
+// Start creates the streams and the durable consumer if they do not exist
+// and subscribes to max deliveries advisories
+func (b *Backend) Start(ctx context.Context) error {
+	stream, err := b.ensureStream(ctx, jetstream.StreamConfig{
+		Name:      b.cfg.Stream,
+		Subjects:  []string{b.cfg.Subject, b.cfg.DLQSubject},
+		Retention: jetstream.WorkQueuePolicy,
+	})
+	if err != nil {
+		return err
+	}
+	b.stream = stream
+
+	if _, err := b.ensureStream(ctx, jetstream.StreamConfig{
+		Name:     b.cfg.EventsStream,
+		Subjects: []string{eventSubjects},
+	}); err != nil {
+		return err
+	}
+
+	b.consumer, err = stream.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
+		Durable:       b.cfg.Durable,
+		FilterSubject: b.cfg.Subject,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       b.cfg.AckWait,
+		MaxDeliver:    b.cfg.MaxDeliver,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create consumer %s: %w", b.cfg.Durable, err)
+	}
+
+	// A queue subscription, so that one worker handles each advisory
+	subject := fmt.Sprintf("%s.%s.%s", maxDeliveriesAdvisory, b.cfg.Stream, b.cfg.Durable)
+	b.advisory, err = b.nc.QueueSubscribe(subject, b.cfg.Durable, b.handleMaxDeliveries)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to max deliveries advisories: %w", err)
+	}
+
+	return nil
+}
+
+// errCodeSubjectOverlap is the JetStream error code for a stream whose
+// subjects overlap with those of an existing stream
+const errCodeSubjectOverlap jetstream.ErrorCode = 10065
+
+// ensureStream creates a stream. An existing stream with the same name, or
+// one already capturing the subjects, is used as configured.
+func (b *Backend) ensureStream(ctx context.Context, cfg jetstream.StreamConfig) (jetstream.Stream, error) {
+	stream, err := b.js.CreateStream(ctx, cfg)
+
+	var apiErr *jetstream.APIError
+	switch {
+	case errors.Is(err, jetstream.ErrStreamNameAlreadyInUse):
+		stream, err = b.js.Stream(ctx, cfg.Name)
+	case errors.As(err, &apiErr) && apiErr.ErrorCode == errCodeSubjectOverlap:
+		var name string
+		if name, err = b.js.StreamNameBySubject(ctx, cfg.Subjects[0]); err == nil {
+			stream, err = b.js.Stream(ctx, name)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stream %s: %w", cfg.Name, err)
+	}
+	return stream, nil
+}
+
+// Close unsubscribes from advisories. The connection is owned by the caller.
+func (b *Backend) Close() error {
+	if b.advisory == nil {
+		return nil
+	}
+	return b.advisory.Unsubscribe()
+}
+
+// Fetch pulls up to max messages from the durable consumer
+func (b *Backend) Fetch(ctx context.Context, max int) ([]*queue.Message, error) {
+	batch, err := b.consumer.Fetch(max, jetstream.FetchMaxWait(defaultFetchWait))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch from %s: %w", b.cfg.Durable, err)
+	}
+
+	var messages []*queue.Message
+	for msg := range batch.Messages() {
+		message := &queue.Message{
+			Source:  b.cfg.Stream,
+			Data:    msg.Data(),
+			Attempt: 1,
+		}
+		if meta, err := msg.Metadata(); err == nil {
+			message.ID = strconv.FormatUint(meta.Sequence.Stream, 10)
+			message.Attempt = int(meta.NumDelivered)
+		}
+
+		b.mu.Lock()
+		b.pending[message] = msg
+		b.mu.Unlock()
+
+		messages = append(messages, message)
+	}
+	if err := batch.Error(); err != nil && !errors.Is(err, nats.ErrTimeout) {
+		if len(messages) == 0 {
+			return nil, fmt.Errorf("failed to fetch from %s: %w", b.cfg.Durable, err)
+		}
+		// Deliver what arrived; the next fetch reports a persistent error
+		b.logger.Warn("fetch ended early", zap.Int("messages", len(messages)), zap.Error(err))
+	}
+
+	return messages, nil
+}
+
+// Ack acknowledges a message
+func (b *Backend) Ack(ctx context.Context, message *queue.Message) error {
+	msg, err := b.take(message)
+	if err != nil {
+		return err
+	}
+	return msg.Ack()
+}
+
+// Nak asks JetStream to redeliver a message after delay
+func (b *Backend) Nak(ctx context.Context, message *queue.Message, delay time.Duration) error {
+	msg, err := b.take(message)
+	if err != nil {
+		return err
+	}
+	if delay > 0 {
+		return msg.NakWithDelay(delay)
+	}
+	return msg.Nak()
+}
+
+// take removes a delivered message from the pending set
+func (b *Backend) take(message *queue.Message) (jetstream.Msg, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	msg, ok := b.pending[message]
+	if !ok {
+		return nil, fmt.Errorf("message %s was not delivered by this backend or was already settled", message.ID)
+	}
+	delete(b.pending, message)
+	return msg, nil
+}
+
+// Stats reports the consumer's backlog
+func (b *Backend) Stats(ctx context.Context) ([]queue.SourceStats, error) {
+	info, err := b.consumer.Info(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read consumer info: %w", err)
+	}
+
+	return []queue.SourceStats{{
+		Source:    b.cfg.Stream,
+		Group:     b.cfg.Durable,
+		Consumers: int64(info.NumWaiting),
+		Pending:   int64(info.NumAckPending),
+		Lag:       int64(info.NumPending),
+	}}, nil
+}
+
+// Publish publishes an event on its node.* subject
+func (b *Backend) Publish(ctx context.Context, event *queue.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	msg := nats.NewMsg(event.Type)
+	msg.Data = data
+	if event.TraceParent != "" {
+		msg.Header.Set("traceparent", event.TraceParent)
+	}
+
+	if _, err := b.js.PublishMsg(ctx, msg, jetstream.WithMsgID(event.ID)); err != nil {
+		return fmt.Errorf("failed to publish %s: %w", event.Type, err)
+	}
+	return nil
+}
+
+// deliveriesAdvisory is the payload of a max deliveries advisory
+type deliveriesAdvisory struct {
+	Stream     string `json:"stream"`
+	Consumer   string `json:"consumer"`
+	StreamSeq  uint64 `json:"stream_seq"`
+	Deliveries uint64 `json:"deliveries"`
+}
+
+// handleMaxDeliveries moves a message that reached MaxDeliver to the DLQ
+// subject and reports its node as failed
+func (b *Backend) handleMaxDeliveries(m *nats.Msg) {
+	var advisory deliveriesAdvisory
+	if err := json.Unmarshal(m.Data, &advisory); err != nil {
+		b.logger.Error("invalid max deliveries advisory", zap.Error(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), advisoryTimeout)
+	defer cancel()
+
+	raw, err := b.stream.GetMsg(ctx, advisory.StreamSeq)
+	if err != nil {
+		b.logger.Error("failed to read dead-lettered message",
+			zap.Uint64("stream_seq", advisory.StreamSeq),
+			zap.Error(err))
+		return
+	}
+
+	dlq := nats.NewMsg(b.cfg.DLQSubject)
+	dlq.Data = raw.Data
+	dlq.Header.Set(headerStream, b.cfg.Stream)
+	dlq.Header.Set(headerStreamSeq, strconv.FormatUint(advisory.StreamSeq, 10))
+	dlq.Header.Set(headerSubject, raw.Subject)
+	dlq.Header.Set(headerDeliveries, strconv.FormatUint(advisory.Deliveries, 10))
+	if _, err := b.js.PublishMsg(ctx, dlq); err != nil {
+		b.logger.Error("failed to dead-letter message",
+			zap.Uint64("stream_seq", advisory.StreamSeq),
+			zap.Error(err))
+		return
+	}
+
+	if err := b.stream.DeleteMsg(ctx, advisory.StreamSeq); err != nil {
+		b.logger.Warn("failed to delete dead-lettered message from the work subject",
+			zap.Uint64("stream_seq", advisory.StreamSeq),
+			zap.Error(err))
+	}
+
+	b.metrics.IncDeadLettered(b.cfg.Stream)
+	b.logger.Warn("work item dead-lettered",
+		zap.Uint64("stream_seq", advisory.StreamSeq),
+		zap.Uint64("deliveries", advisory.Deliveries),
+		zap.String("dlq_subject", b.cfg.DLQSubject))
+
+	b.publishDeadLettered(ctx, raw.Data, advisory.Deliveries)
+}
+
+// publishDeadLettered publishes node.failed for a dead-lettered work item,
+// so the graph does not wait for a node no worker will complete
+func (b *Backend) publishDeadLettered(ctx context.Context, data []byte, deliveries uint64) {
+	var work struct {
+		GraphID     string `json:"graph_id"`
+		NodeID      string `json:"node_id"`
+		TraceParent string `json:"traceparent"`
+	}
+	if err := json.Unmarshal(data, &work); err != nil || work.GraphID == "" {
+		return
+	}
+
+	event := &queue.Event{
+		ID:        uuid.New().String(),
+		Type:      queue.EventNodeFailed,
+		GraphID:   work.GraphID,
+		NodeID:    work.NodeID,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"graph_id": work.GraphID,
+			"node_id":  work.NodeID,
+			"error":    fmt.Sprintf("work item dead-lettered to %s after %d deliveries", b.cfg.DLQSubject, deliveries),
+		},
+		TraceParent: work.TraceParent,
+	}
+	if err := b.Publish(ctx, event); err != nil {
+		b.logger.Error("failed to publish dead-lettered node failure", zap.Error(err))
+	}
+}
@@ -0,0 +1,240 @@
+package natsqueue
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aescanero/dago-node-executor/internal/queue/natsqueue/natstest"
+	"github.com/aescanero/dago-node-executor/pkg/queue"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// newBackend starts an embedded server and a started backend on it
+func newBackend(t *testing.T, cfg Config) (*Backend, *nats.Conn, jetstream.JetStream) {
+	t.Helper()
+
+	srv, err := natstest.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Shutdown)
+
+	nc, err := srv.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(nc.Close)
+
+	b, err := New(nc, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := b.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { _ = b.Close() })
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b, nc, js
+}
+
+// submit publishes a work item on the work subject
+func submit(t *testing.T, js jetstream.JetStream, work map[string]interface{}) {
+	t.Helper()
+	data, _ := json.Marshal(work)
+	if _, err := js.Publish(context.Background(), DefaultSubject, data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fetchOne fetches until a message arrives or the test times out
+func fetchOne(t *testing.T, b *Backend) *queue.Message {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		messages, err := b.Fetch(context.Background(), 1)
+		if err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+		if len(messages) == 1 {
+			return messages[0]
+		}
+	}
+	t.Fatal("no message delivered")
+	return nil
+}
+
+func TestFetchAck(t *testing.T) {
+	b, _, js := newBackend(t, Config{})
+	ctx := context.Background()
+
+	submit(t, js, map[string]interface{}{"graph_id": "g1", "node_id": "n1"})
+	msg := fetchOne(t, b)
+	if msg.Attempt != 1 || msg.Source != DefaultStream || msg.ID != "1" {
+		t.Errorf("message = %+v, want the first delivery of sequence 1", msg)
+	}
+	var work map[string]interface{}
+	if err := json.Unmarshal(msg.Data, &work); err != nil || work["graph_id"] != "g1" {
+		t.Errorf("data = %s, want the submitted work item", msg.Data)
+	}
+
+	if err := b.Ack(ctx, msg); err != nil {
+		t.Fatalf("Ack() error = %v", err)
+	}
+	if err := b.Ack(ctx, msg); err == nil {
+		t.Error("second Ack() succeeded, want an error")
+	}
+
+	// The acked message is not redelivered
+	messages, err := b.Fetch(ctx, 1)
+	if err != nil || len(messages) != 0 {
+		t.Errorf("Fetch() after Ack = %v, %v; want nothing", messages, err)
+	}
+	stats, err := b.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats[0].Pending != 0 || stats[0].Lag != 0 {
+		t.Errorf("stats = %+v, want an empty backlog", stats[0])
+	}
+}
+
+func TestNakRedelivers(t *testing.T) {
+	b, _, js := newBackend(t, Config{})
+	ctx := context.Background()
+
+	submit(t, js, map[string]interface{}{"graph_id": "g1", "node_id": "n1"})
+	msg := fetchOne(t, b)
+
+	const delay = 500 * time.Millisecond
+	nakedAt := time.Now()
+	if err := b.Nak(ctx, msg, delay); err != nil {
+		t.Fatalf("Nak() error = %v", err)
+	}
+
+	again := fetchOne(t, b)
+	if elapsed := time.Since(nakedAt); elapsed < delay {
+		t.Errorf("redelivered after %s, want at least %s", elapsed, delay)
+	}
+	if again.ID != msg.ID || again.Attempt != 2 {
+		t.Errorf("redelivery = %+v, want attempt 2 of message %s", again, msg.ID)
+	}
+	if err := b.Ack(ctx, again); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMaxDeliverDeadLetters(t *testing.T) {
+	b, nc, js := newBackend(t, Config{MaxDeliver: 2})
+	ctx := context.Background()
+
+	failed := make(chan *nats.Msg, 1)
+	sub, err := nc.ChanSubscribe(queue.EventNodeFailed, failed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	submit(t, js, map[string]interface{}{"graph_id": "g1", "node_id": "n1", "traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"})
+	for attempt := 1; attempt <= 2; attempt++ {
+		msg := fetchOne(t, b)
+		if msg.Attempt != attempt {
+			t.Fatalf("attempt = %d, want %d", msg.Attempt, attempt)
+		}
+		if err := b.Nak(ctx, msg, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The next pull hits the limit, which raises the advisory
+	var event queue.Event
+	deadline := time.After(10 * time.Second)
+	for event.Type == "" {
+		if messages, err := b.Fetch(ctx, 1); err != nil || len(messages) != 0 {
+			t.Fatalf("Fetch() past MaxDeliver = %v, %v; want nothing", messages, err)
+		}
+		select {
+		case m := <-failed:
+			if err := json.Unmarshal(m.Data, &event); err != nil {
+				t.Fatal(err)
+			}
+			if got := m.Header.Get("traceparent"); got == "" {
+				t.Error("node.failed has no traceparent")
+			}
+		case <-deadline:
+			t.Fatal("no node.failed event")
+		default:
+		}
+	}
+	if event.GraphID != "g1" || event.NodeID != "n1" || event.Data["error"] == nil {
+		t.Errorf("event = %+v, want node.failed for g1/n1 with an error", event)
+	}
+
+	dlq, err := b.stream.GetLastMsgForSubject(ctx, DefaultDLQSubject)
+	if err != nil {
+		t.Fatalf("no message on the DLQ subject: %v", err)
+	}
+	if dlq.Header.Get(headerStreamSeq) != "1" || dlq.Header.Get(headerSubject) != DefaultSubject || dlq.Header.Get(headerDeliveries) != "2" {
+		t.Errorf("DLQ headers = %v", dlq.Header)
+	}
+	if _, err := b.stream.GetMsg(ctx, 1); err == nil {
+		t.Error("dead-lettered message is still on the work subject")
+	}
+}
+
+func TestPublish(t *testing.T) {
+	b, nc, js := newBackend(t, Config{})
+	ctx := context.Background()
+
+	received := make(chan *nats.Msg, 4)
+	sub, err := nc.ChanSubscribe(eventSubjects, received)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	for _, event := range []*queue.Event{
+		{ID: "e1", Type: queue.EventNodeCompleted, GraphID: "g1", NodeID: "n1", TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{ID: "e2", Type: queue.EventNodeFailed, GraphID: "g1", NodeID: "n2"},
+		{ID: "e2", Type: queue.EventNodeFailed, GraphID: "g1", NodeID: "n2"},
+	} {
+		if err := b.Publish(ctx, event); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+
+	for _, want := range []string{queue.EventNodeCompleted, queue.EventNodeFailed} {
+		select {
+		case m := <-received:
+			if m.Subject != want {
+				t.Errorf("subject = %s, want %s", m.Subject, want)
+			}
+			if want == queue.EventNodeCompleted && m.Header.Get("traceparent") == "" {
+				t.Error("event has no traceparent header")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no %s event", want)
+		}
+	}
+
+	// The events stream keeps them, and drops the duplicate by ID
+	stream, err := js.Stream(ctx, DefaultEventsStream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := stream.Info(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.State.Msgs != 2 {
+		t.Errorf("events stream has %d messages, want 2", info.State.Msgs)
+	}
+}
@@ -0,0 +1,69 @@
+// Package natstest runs an in-process NATS server with JetStream, to
+// exercise the NATS backend without external infrastructure.
+package natstest
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+// startTimeout bounds how long Start waits for the server to accept connections
+const startTimeout = 10 * time.Second
+
+// Server is an embedded NATS server with JetStream enabled
+type Server struct {
+	server   *server.Server
+	storeDir string
+}
+
+// Start starts a server on a random local port, storing JetStream data in
+// a temporary directory removed by Shutdown
+func Start() (*Server, error) {
+	storeDir, err := os.MkdirTemp("", "natstest-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JetStream store: %w", err)
+	}
+
+	srv, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  storeDir,
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		_ = os.RemoveAll(storeDir)
+		return nil, fmt.Errorf("failed to create NATS server: %w", err)
+	}
+
+	srv.Start()
+	if !srv.ReadyForConnections(startTimeout) {
+		srv.Shutdown()
+		_ = os.RemoveAll(storeDir)
+		return nil, fmt.Errorf("NATS server not ready after %s", startTimeout)
+	}
+
+	return &Server{server: srv, storeDir: storeDir}, nil
+}
+
+// URL returns the client URL of the server
+func (s *Server) URL() string {
+	return s.server.ClientURL()
+}
+
+// Connect opens a client connection to the server
+func (s *Server) Connect(opts ...nats.Option) (*nats.Conn, error) {
+	return nats.Connect(s.URL(), opts...)
+}
+
+// Shutdown stops the server and removes its JetStream data
+func (s *Server) Shutdown() {
+	s.server.Shutdown()
+	s.server.WaitForShutdown()
+	_ = os.RemoveAll(s.storeDir)
+}
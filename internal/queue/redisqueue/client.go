@@ -0,0 +1,161 @@
+package redisqueue
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis deployment modes
+const (
+	ModeStandalone = "standalone"
+	ModeSentinel   = "sentinel"
+	ModeCluster    = "cluster"
+)
+
+// ClientConfig describes how to connect to Redis
+type ClientConfig struct {
+	// Mode is standalone (default), sentinel or cluster
+	Mode string
+
+	// Addrs is the server address, or the cluster seed nodes
+	Addrs []string
+
+	// SentinelMaster and SentinelAddrs locate the master in sentinel mode
+	SentinelMaster   string
+	SentinelAddrs    []string
+	SentinelUsername string
+	SentinelPassword string
+
+	// Username and Password authenticate as an ACL user (or with
+	// requirepass when Username is empty)
+	Username string
+	Password string
+
+	// DB is ignored in cluster mode
+	DB int
+
+	ClientName string
+
+	TLS TLSConfig
+
+	// Pool tuning; zero values keep the go-redis defaults
+	PoolSize     int
+	MinIdleConns int
+	PoolTimeout  time.Duration
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	MaxRetries   int
+}
+
+// TLSConfig enables TLS to Redis, optionally with a private CA and a
+// client certificate
+type TLSConfig struct {
+	Enabled            bool
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+// NewClient creates a Redis client for the configured mode
+func NewClient(cfg ClientConfig) (redis.UniversalClient, error) {
+	opts := &redis.UniversalOptions{
+		Addrs:        cfg.Addrs,
+		ClientName:   cfg.ClientName,
+		DB:           cfg.DB,
+		Username:     cfg.Username,
+		Password:     cfg.Password,
+		MaxRetries:   cfg.MaxRetries,
+		DialTimeout:  cfg.DialTimeout,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		PoolSize:     cfg.PoolSize,
+		PoolTimeout:  cfg.PoolTimeout,
+		MinIdleConns: cfg.MinIdleConns,
+	}
+
+	if cfg.TLS.Enabled {
+		tlsConfig, err := cfg.TLS.load()
+		if err != nil {
+			return nil, err
+		}
+		opts.TLSConfig = tlsConfig
+	}
+
+	switch cfg.Mode {
+	case "", ModeStandalone:
+		if len(cfg.Addrs) != 1 {
+			return nil, fmt.Errorf("standalone mode needs exactly one address, got %d", len(cfg.Addrs))
+		}
+		return redis.NewClient(opts.Simple()), nil
+	case ModeSentinel:
+		if cfg.SentinelMaster == "" || len(cfg.SentinelAddrs) == 0 {
+			return nil, fmt.Errorf("sentinel mode needs a master name and sentinel addresses")
+		}
+		opts.Addrs = cfg.SentinelAddrs
+		opts.MasterName = cfg.SentinelMaster
+		opts.SentinelUsername = cfg.SentinelUsername
+		opts.SentinelPassword = cfg.SentinelPassword
+		return redis.NewFailoverClient(opts.Failover()), nil
+	case ModeCluster:
+		if len(cfg.Addrs) == 0 {
+			return nil, fmt.Errorf("cluster mode needs at least one seed address")
+		}
+		return redis.NewClusterClient(opts.Cluster()), nil
+	default:
+		return nil, fmt.Errorf("unsupported redis mode: %s", cfg.Mode)
+	}
+}
+
+// SplitAddrs splits a comma-separated address list, dropping empty entries
+func SplitAddrs(addrs string) []string {
+	var out []string
+	for _, addr := range strings.Split(addrs, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			out = append(out, addr)
+		}
+	}
+	return out
+}
+
+// load builds the tls.Config, reading the CA and client certificate files
+func (c TLSConfig) load() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read redis CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in redis CA file %s", c.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		if c.CertFile == "" || c.KeyFile == "" {
+			return nil, fmt.Errorf("redis client certificate needs both a cert and a key file")
+		}
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load redis client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
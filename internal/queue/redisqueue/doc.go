@@ -0,0 +1,11 @@
+// Package redisqueue implements the worker's work source, event sink and
+// state store on Redis.
+//
+// Work is consumed from one or more weighted streams through a consumer
+// group, events are added to dago:events:<type> streams and graph state is
+// stored as JSON under dago:state:<graph_id>, or dago:state:{<graph_id>}
+// with hash tags for Redis Cluster. Stream names, the group and the key
+// prefixes are configurable, and a namespace separates tenants sharing one
+// Redis. NewClient connects to a standalone, Sentinel-managed or clustered
+// Redis, optionally over TLS.
+package redisqueue
@@ -0,0 +1,403 @@
+package redisqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-node-executor/pkg/queue"
+	"github.com/redis/go-redis/v9"
+)
+
+// Defaults for Config fields left empty
+const (
+	DefaultStreamKey     = "executor.work"
+	DefaultConsumerGroup = "executor-workers"
+	DefaultStatePrefix   = "dago:state:"
+	DefaultEventsPrefix  = "dago:events:"
+	DefaultStateTTL      = 24 * time.Hour
+	defaultBlock         = time.Second
+)
+
+// Stream is a work stream and its share of fetches
+type Stream struct {
+	Key string
+
+	// Weight is the stream's relative share of fetches while several
+	// streams have work (default 1)
+	Weight int
+}
+
+// Config holds Redis backend settings
+type Config struct {
+	// Consumer is this worker's consumer name in the group
+	Consumer string
+
+	// Namespace, when set, prefixes every key as <namespace>:<key>, so that
+	// several environments or tenants can share one Redis
+	Namespace string
+
+	// Streams are the work streams (default executor.work)
+	Streams []Stream
+
+	// ConsumerGroup is shared by all workers (default executor-workers)
+	ConsumerGroup string
+
+	// StatePrefix and EventsPrefix prefix the state keys and the event
+	// streams (default dago:state: and dago:events:)
+	StatePrefix  string
+	EventsPrefix string
+
+	// StateTTL is the expiry of graph state, refreshed on every load and
+	// save (default 24h)
+	StateTTL time.Duration
+
+	// HashTags wraps the graph ID in state keys in a hash tag
+	// (dago:state:{<graph_id>}), so that a Redis Cluster keeps all keys of
+	// one graph in the same slot
+	HashTags bool
+}
+
+// Backend is a Redis work source, event sink and state store
+type Backend struct {
+	client        redis.UniversalClient
+	consumer      string
+	consumerGroup string
+	statePrefix   string
+	eventsPrefix  string
+	stateTTL      time.Duration
+	hashTags      bool
+	namespace     string
+
+	// streams and the smooth weighted round-robin state, one entry per
+	// stream, change with SetStreams
+	mu      sync.Mutex
+	streams []Stream
+	current []int
+}
+
+// New creates a Redis backend
+func New(client redis.UniversalClient, cfg Config) *Backend {
+	b := &Backend{
+		client:        client,
+		consumer:      cfg.Consumer,
+		consumerGroup: cfg.ConsumerGroup,
+		statePrefix:   cfg.StatePrefix,
+		eventsPrefix:  cfg.EventsPrefix,
+		stateTTL:      cfg.StateTTL,
+		hashTags:      cfg.HashTags,
+		namespace:     cfg.Namespace,
+	}
+	if b.consumerGroup == "" {
+		b.consumerGroup = DefaultConsumerGroup
+	}
+	if b.statePrefix == "" {
+		b.statePrefix = DefaultStatePrefix
+	}
+	if b.eventsPrefix == "" {
+		b.eventsPrefix = DefaultEventsPrefix
+	}
+	if b.stateTTL <= 0 {
+		b.stateTTL = DefaultStateTTL
+	}
+
+	b.streams = b.normalize(cfg.Streams)
+	b.current = make([]int, len(b.streams))
+
+	b.statePrefix = namespaced(cfg.Namespace, b.statePrefix)
+	b.eventsPrefix = namespaced(cfg.Namespace, b.eventsPrefix)
+
+	return b
+}
+
+// normalize applies the defaults and the namespace to streams and drops
+// duplicates
+func (b *Backend) normalize(streams []Stream) []Stream {
+	if len(streams) == 0 {
+		streams = []Stream{{Key: DefaultStreamKey}}
+	}
+
+	seen := make(map[string]bool, len(streams))
+	out := make([]Stream, 0, len(streams))
+	for _, stream := range streams {
+		if stream.Weight <= 0 {
+			stream.Weight = 1
+		}
+		stream.Key = namespaced(b.namespace, stream.Key)
+		if seen[stream.Key] {
+			continue
+		}
+		seen[stream.Key] = true
+		out = append(out, stream)
+	}
+	return out
+}
+
+// namespaced prefixes key with the namespace, if any
+func namespaced(namespace, key string) string {
+	if namespace == "" {
+		return key
+	}
+	return namespace + ":" + key
+}
+
+// Name identifies the backend in health checks
+func (b *Backend) Name() string {
+	return "redis"
+}
+
+// Ping checks the Redis connection
+func (b *Backend) Ping(ctx context.Context) error {
+	if err := b.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("redis ping failed: %w", err)
+	}
+	return nil
+}
+
+// Start creates the consumer group on every work stream if it doesn't exist
+func (b *Backend) Start(ctx context.Context) error {
+	for _, stream := range b.streamList() {
+		if err := b.createGroup(ctx, stream.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createGroup creates the consumer group on a stream if it doesn't exist
+func (b *Backend) createGroup(ctx context.Context, key string) error {
+	err := b.client.XGroupCreateMkStream(ctx, key, b.consumerGroup, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group on %s: %w", key, err)
+	}
+	return nil
+}
+
+// SetStreams replaces the work streams, creating the consumer group on the
+// new ones. Messages already fetched from a removed stream can still be
+// acknowledged.
+func (b *Backend) SetStreams(ctx context.Context, streams []Stream) error {
+	streams = b.normalize(streams)
+
+	current := make(map[string]bool)
+	for _, stream := range b.streamList() {
+		current[stream.Key] = true
+	}
+	for _, stream := range streams {
+		if !current[stream.Key] {
+			if err := b.createGroup(ctx, stream.Key); err != nil {
+				return err
+			}
+		}
+	}
+
+	b.mu.Lock()
+	b.streams = streams
+	b.current = make([]int, len(streams))
+	b.mu.Unlock()
+	return nil
+}
+
+// streamList returns a copy of the work streams
+func (b *Backend) streamList() []Stream {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Stream(nil), b.streams...)
+}
+
+// Fetch reads new messages for this consumer. With several streams, it
+// polls them in weighted order and returns the first that has work;
+// when all are empty it blocks on all of them for up to a second.
+func (b *Backend) Fetch(ctx context.Context, max int) ([]*queue.Message, error) {
+	order := b.pollOrder()
+	if len(order) > 1 {
+		for _, key := range order {
+			messages, err := b.read(ctx, []string{key}, max, -1)
+			if err != nil || len(messages) > 0 {
+				return messages, err
+			}
+		}
+	}
+
+	return b.read(ctx, order, max, defaultBlock)
+}
+
+// pollOrder picks the stream to poll first by smooth weighted round-robin
+// and returns the others after it by descending weight
+func (b *Backend) pollOrder() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	total, best := 0, 0
+	for i, stream := range b.streams {
+		b.current[i] += stream.Weight
+		total += stream.Weight
+		if b.current[i] > b.current[best] {
+			best = i
+		}
+	}
+	b.current[best] -= total
+
+	rest := make([]Stream, 0, len(b.streams)-1)
+	rest = append(rest, b.streams[:best]...)
+	rest = append(rest, b.streams[best+1:]...)
+	sort.SliceStable(rest, func(i, j int) bool {
+		return rest[i].Weight > rest[j].Weight
+	})
+
+	order := []string{b.streams[best].Key}
+	for _, stream := range rest {
+		order = append(order, stream.Key)
+	}
+	return order
+}
+
+// read reads new messages from keys; a negative block does not wait
+func (b *Backend) read(ctx context.Context, keys []string, max int, block time.Duration) ([]*queue.Message, error) {
+	streamArgs := make([]string, 0, 2*len(keys))
+	streamArgs = append(streamArgs, keys...)
+	for range keys {
+		streamArgs = append(streamArgs, ">")
+	}
+
+	streams, err := b.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    b.consumerGroup,
+		Consumer: b.consumer,
+		Streams:  streamArgs,
+		Count:    int64(max),
+		Block:    block,
+	}).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			// No messages
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read from stream: %w", err)
+	}
+
+	var messages []*queue.Message
+	for _, stream := range streams {
+		for _, message := range stream.Messages {
+			// Entries without a data field are delivered empty so the
+			// worker can reject and acknowledge them
+			data, _ := message.Values["data"].(string)
+			messages = append(messages, &queue.Message{
+				ID:      message.ID,
+				Source:  stream.Stream,
+				Data:    []byte(data),
+				Attempt: 1,
+			})
+		}
+	}
+	return messages, nil
+}
+
+// Ack acknowledges a message in the consumer group
+func (b *Backend) Ack(ctx context.Context, msg *queue.Message) error {
+	return b.client.XAck(ctx, msg.Source, b.consumerGroup, msg.ID).Err()
+}
+
+// Stats reports the consumer group's lag and pending entries per stream
+func (b *Backend) Stats(ctx context.Context) ([]queue.SourceStats, error) {
+	var stats []queue.SourceStats
+	for _, stream := range b.streamList() {
+		s, err := b.streamStats(ctx, stream.Key)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
+
+// streamStats reports the consumer group's backlog on one stream
+func (b *Backend) streamStats(ctx context.Context, key string) (queue.SourceStats, error) {
+	groups, err := b.client.XInfoGroups(ctx, key).Result()
+	if err != nil {
+		return queue.SourceStats{}, fmt.Errorf("failed to read consumer groups of %s: %w", key, err)
+	}
+
+	for _, group := range groups {
+		if group.Name == b.consumerGroup {
+			return queue.SourceStats{
+				Source:    key,
+				Group:     b.consumerGroup,
+				Consumers: group.Consumers,
+				Pending:   group.Pending,
+				Lag:       group.Lag,
+			}, nil
+		}
+	}
+
+	return queue.SourceStats{}, fmt.Errorf("consumer group %s not found on stream %s", b.consumerGroup, key)
+}
+
+// Publish adds an event to the <events prefix><type> stream
+func (b *Backend) Publish(ctx context.Context, event *queue.Event) error {
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	return b.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: b.eventsPrefix + event.Type,
+		Values: map[string]interface{}{
+			"data": string(eventJSON),
+		},
+	}).Err()
+}
+
+// Load loads graph state and refreshes its TTL
+func (b *Backend) Load(ctx context.Context, graphID string) (*domain.GraphState, error) {
+	data, err := b.client.GetEx(ctx, b.stateKey(graphID), b.stateTTL).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("%w: %s", queue.ErrStateNotFound, graphID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state: %w", err)
+	}
+
+	var state domain.GraphState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal state: %w", err)
+	}
+
+	return &state, nil
+}
+
+// Exists reports whether a graph's state exists, without refreshing its TTL
+func (b *Backend) Exists(ctx context.Context, graphID string) (bool, error) {
+	n, err := b.client.Exists(ctx, b.stateKey(graphID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check state: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Save stores graph state with the configured TTL
+func (b *Backend) Save(ctx context.Context, state *domain.GraphState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	if err := b.client.Set(ctx, b.stateKey(state.GraphID), data, b.stateTTL).Err(); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+
+	return nil
+}
+
+// stateKey returns the key holding a graph's state
+func (b *Backend) stateKey(graphID string) string {
+	if b.hashTags {
+		return b.statePrefix + "{" + graphID + "}"
+	}
+	return b.statePrefix + graphID
+}
@@ -0,0 +1,15 @@
+// Package ratelimit shares LLM provider rate limits between all workers.
+//
+// Limits are token buckets stored in Redis, one per provider, model and
+// API key, refilled continuously at the configured requests and tokens per
+// minute. A call acquires one request and its estimated tokens from every
+// bucket that applies, atomically and using the Redis clock, and waits up
+// to a bound when they are empty. After the call the estimate is replaced
+// by the tokens actually used. When a provider answers 429 with a
+// retry-after, the buckets are blocked for that long, pausing the whole
+// fleet rather than only the worker that was rejected.
+//
+// ToolLimiter applies per-tool policies the same way: a cap on concurrent
+// calls across all workers, a call rate, and a circuit breaker shared by
+// the fleet that rejects calls for a cooldown after consecutive failures.
+package ratelimit
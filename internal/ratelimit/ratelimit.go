@@ -0,0 +1,304 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aescanero/dago-node-executor/internal/metrics"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Defaults for Config fields left empty
+const (
+	DefaultKeyPrefix  = "dago:ratelimit:"
+	DefaultMaxWait    = time.Minute
+	DefaultRetryAfter = 10 * time.Second
+)
+
+const (
+	// bucketTTL expires idle buckets; a full bucket needs no state
+	bucketTTL = 2 * time.Minute
+
+	// maxPollInterval caps the sleep between attempts, since other
+	// workers may take the capacity first
+	maxPollInterval = 5 * time.Second
+
+	releaseTimeout = 2 * time.Second
+
+	// allModels is the model part of provider-wide bucket keys
+	allModels = "*"
+)
+
+// ErrRateLimited is returned when a call would have to wait longer than
+// the configured bound
+var ErrRateLimited = errors.New("llm rate limit exceeded")
+
+// Limit caps the calls to a provider's model, or to all its models when
+// Model is empty. Zero RPM or TPM means unlimited.
+type Limit struct {
+	Provider string
+	Model    string
+	RPM      int
+	TPM      int
+}
+
+// Config holds limiter settings
+type Config struct {
+	// Provider and APIKey identify the buckets; the key is hashed
+	Provider string
+	APIKey   string
+
+	Limits []Limit
+
+	// MaxWait bounds how long a call waits for capacity (default 1m)
+	MaxWait time.Duration
+
+	// DefaultRetryAfter blocks the buckets after a 429 without a
+	// retry-after header (default 10s)
+	DefaultRetryAfter time.Duration
+
+	// Namespace and KeyPrefix place the bucket keys, as
+	// <namespace>:<prefix>{<provider>:<key hash>}:<model>
+	Namespace string
+	KeyPrefix string
+
+	Logger  *zap.Logger
+	Metrics *metrics.Metrics
+}
+
+// Limiter acquires LLM call capacity from Redis token buckets
+type Limiter struct {
+	client            redis.UniversalClient
+	provider          string
+	keyBase           string
+	limits            []Limit
+	maxWait           time.Duration
+	defaultRetryAfter time.Duration
+	logger            *zap.Logger
+	metrics           *metrics.Metrics
+}
+
+// New creates a limiter
+func New(client redis.UniversalClient, cfg Config) *Limiter {
+	prefix := cfg.KeyPrefix
+	if prefix == "" {
+		prefix = DefaultKeyPrefix
+	}
+	if cfg.Namespace != "" {
+		prefix = cfg.Namespace + ":" + prefix
+	}
+
+	sum := sha256.Sum256([]byte(cfg.APIKey))
+	keyHash := hex.EncodeToString(sum[:6])
+
+	l := &Limiter{
+		client:            client,
+		provider:          cfg.Provider,
+		keyBase:           prefix + "{" + cfg.Provider + ":" + keyHash + "}:",
+		maxWait:           cfg.MaxWait,
+		defaultRetryAfter: cfg.DefaultRetryAfter,
+		logger:            cfg.Logger,
+		metrics:           cfg.Metrics,
+	}
+	if l.maxWait <= 0 {
+		l.maxWait = DefaultMaxWait
+	}
+	if l.defaultRetryAfter <= 0 {
+		l.defaultRetryAfter = DefaultRetryAfter
+	}
+	for _, limit := range cfg.Limits {
+		if limit.Provider == cfg.Provider && (limit.RPM > 0 || limit.TPM > 0) {
+			l.limits = append(l.limits, limit)
+		}
+	}
+	return l
+}
+
+// bucket is a token bucket key and its per-minute capacities
+type bucket struct {
+	key string
+	rpm int
+	tpm int
+}
+
+// buckets returns the buckets a call to model draws from: the model's own
+// bucket, which also carries retry-after blocks, and the provider-wide one
+// if configured
+func (l *Limiter) buckets(model string) []bucket {
+	own := bucket{key: l.keyBase + model}
+	buckets := []bucket{own}
+	for _, limit := range l.limits {
+		switch limit.Model {
+		case model:
+			buckets[0].rpm, buckets[0].tpm = limit.RPM, limit.TPM
+		case "":
+			buckets = append(buckets, bucket{key: l.keyBase + allModels, rpm: limit.RPM, tpm: limit.TPM})
+		}
+	}
+	return buckets
+}
+
+// acquireScript takes one request and ARGV[1] tokens from every bucket in
+// KEYS if all have capacity and none is blocked, and otherwise returns the
+// milliseconds to wait. ARGV[2i] and ARGV[2i+1] are the RPM and TPM of
+// KEYS[i]; zero is unlimited.
+var acquireScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local cost = tonumber(ARGV[1])
+local ttl = tonumber(ARGV[#ARGV])
+local wait = 0
+local state = {}
+for i, key in ipairs(KEYS) do
+  local rpm = tonumber(ARGV[2 * i])
+  local tpm = tonumber(ARGV[2 * i + 1])
+  local h = redis.call('HMGET', key, 'req', 'tok', 'ts', 'blocked')
+  local elapsed = math.max(0, now - (tonumber(h[3]) or now))
+  local req = math.min(rpm, (tonumber(h[1]) or rpm) + elapsed * rpm / 60000)
+  local tok = math.min(tpm, (tonumber(h[2]) or tpm) + elapsed * tpm / 60000)
+  local c = math.min(cost, tpm)
+  local blocked = tonumber(h[4]) or 0
+  if blocked > now then
+    wait = math.max(wait, blocked - now)
+  end
+  if rpm > 0 and req < 1 then
+    wait = math.max(wait, math.ceil((1 - req) * 60000 / rpm))
+  end
+  if tpm > 0 and tok < c then
+    wait = math.max(wait, math.ceil((c - tok) * 60000 / tpm))
+  end
+  state[i] = {req, tok, c, math.max(ttl, blocked - now)}
+end
+if wait > 0 then
+  return wait
+end
+for i, key in ipairs(KEYS) do
+  local s = state[i]
+  redis.call('HSET', key, 'req', s[1] - 1, 'tok', s[2] - s[3], 'ts', now)
+  redis.call('PEXPIRE', key, s[4])
+end
+return 0
+`)
+
+// adjustScript adds ARGV[1] tokens (negative to charge) to the buckets in
+// KEYS that exist
+var adjustScript = redis.NewScript(`
+for _, key in ipairs(KEYS) do
+  if redis.call('HEXISTS', key, 'tok') == 1 then
+    redis.call('HINCRBYFLOAT', key, 'tok', ARGV[1])
+  end
+end
+return 0
+`)
+
+// blockScript blocks the buckets in KEYS for ARGV[1] milliseconds, keeping
+// a longer existing block
+var blockScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local untilMs = now + tonumber(ARGV[1])
+for _, key in ipairs(KEYS) do
+  local blocked = tonumber(redis.call('HGET', key, 'blocked')) or 0
+  if untilMs > blocked then
+    redis.call('HSET', key, 'blocked', untilMs)
+    redis.call('PEXPIRE', key, math.max(tonumber(ARGV[2]), untilMs - now))
+  end
+end
+return 0
+`)
+
+// Acquire waits until a call to model estimated at tokens may be sent, for
+// at most the configured bound. The returned release must be called after
+// the call with the tokens it actually used and its error, which blocks
+// all workers when the provider rejected it with a retry-after.
+//
+// If Redis is unreachable the call is allowed, as the provider still
+// enforces its own limits.
+func (l *Limiter) Acquire(ctx context.Context, model string, tokens int) (func(used int, err error), error) {
+	buckets := l.buckets(model)
+	keys := make([]string, len(buckets))
+	args := make([]interface{}, 0, 2*len(buckets)+2)
+	args = append(args, tokens)
+	for i, b := range buckets {
+		keys[i] = b.key
+		args = append(args, b.rpm, b.tpm)
+	}
+	args = append(args, bucketTTL.Milliseconds())
+
+	start := time.Now()
+	deadline := start.Add(l.maxWait)
+	for {
+		wait, err := acquireScript.Run(ctx, l.client, keys, args...).Int64()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			l.logger.Warn("rate limiter unavailable, calling the provider without it",
+				zap.String("model", model),
+				zap.Error(err))
+			return func(int, error) {}, nil
+		}
+		if wait == 0 {
+			break
+		}
+
+		delay := time.Duration(wait) * time.Millisecond
+		if time.Now().Add(delay).After(deadline) {
+			l.metrics.IncLLMRateLimited(model)
+			return nil, fmt.Errorf("%w for %s %s: capacity in %s, more than the %s wait bound",
+				ErrRateLimited, l.provider, model, delay.Round(time.Millisecond), l.maxWait)
+		}
+
+		timer := time.NewTimer(min(delay, maxPollInterval))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if waited := time.Since(start); waited > 10*time.Millisecond {
+		l.metrics.ObserveLLMRateLimitWait(model, waited)
+		l.logger.Debug("waited for llm rate limit",
+			zap.String("model", model),
+			zap.Duration("waited", waited))
+	}
+
+	return func(used int, err error) {
+		l.release(model, keys, tokens, used, err)
+	}, nil
+}
+
+// release corrects the token estimate and applies a retry-after
+func (l *Limiter) release(model string, keys []string, reserved, used int, err error) {
+	// The call's context may be cancelled already
+	ctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+	defer cancel()
+
+	if err != nil {
+		if retryAfter, limited := RetryAfter(err); limited {
+			if retryAfter <= 0 {
+				retryAfter = l.defaultRetryAfter
+			}
+			l.logger.Warn("llm provider rate limited, pausing calls on all workers",
+				zap.String("provider", l.provider),
+				zap.String("model", model),
+				zap.Duration("retry_after", retryAfter))
+			if blockErr := blockScript.Run(ctx, l.client, keys, retryAfter.Milliseconds(), bucketTTL.Milliseconds()).Err(); blockErr != nil {
+				l.logger.Warn("failed to record retry-after", zap.Error(blockErr))
+			}
+		}
+	}
+
+	if delta := reserved - used; delta != 0 {
+		if adjustErr := adjustScript.Run(ctx, l.client, keys, delta).Err(); adjustErr != nil {
+			l.logger.Debug("failed to adjust llm token usage", zap.Error(adjustErr))
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// newLimiter returns a limiter on an in-memory Redis for provider "p"
+func newLimiter(t *testing.T, maxWait time.Duration, limits ...Limit) (*Limiter, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return New(client, Config{
+		Provider: "p",
+		APIKey:   "secret",
+		Limits:   limits,
+		MaxWait:  maxWait,
+		Logger:   zap.NewNop(),
+	}), server
+}
+
+// acquire acquires capacity for model, failing the test on errors other
+// than ErrRateLimited, and reports whether it was granted
+func acquire(t *testing.T, l *Limiter, model string, tokens int) (func(int, error), bool) {
+	t.Helper()
+	release, err := l.Acquire(context.Background(), model, tokens)
+	if errors.Is(err, ErrRateLimited) {
+		return nil, false
+	}
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	return release, true
+}
+
+func TestRequestBucket(t *testing.T) {
+	l, server := newLimiter(t, 10*time.Millisecond, Limit{Provider: "p", Model: "m", RPM: 2})
+	now := time.Now()
+	server.SetTime(now)
+
+	for i := 0; i < 2; i++ {
+		if _, ok := acquire(t, l, "m", 10); !ok {
+			t.Fatalf("request %d was limited, want it within the RPM", i+1)
+		}
+	}
+	if _, ok := acquire(t, l, "m", 10); ok {
+		t.Fatal("third request was granted, want it limited")
+	}
+
+	// Other models have their own bucket, unlimited here
+	if _, ok := acquire(t, l, "other", 10); !ok {
+		t.Error("request to another model was limited")
+	}
+
+	// One request refills every 30s at 2 RPM
+	server.SetTime(now.Add(29 * time.Second))
+	if _, ok := acquire(t, l, "m", 10); ok {
+		t.Error("request granted before the bucket refilled")
+	}
+	server.SetTime(now.Add(31 * time.Second))
+	if _, ok := acquire(t, l, "m", 10); !ok {
+		t.Error("request limited after the bucket refilled")
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	l, server := newLimiter(t, 10*time.Millisecond, Limit{Provider: "p", Model: "m", TPM: 100})
+	server.SetTime(time.Now())
+
+	release, ok := acquire(t, l, "m", 80)
+	if !ok {
+		t.Fatal("request within the TPM was limited")
+	}
+	if _, ok := acquire(t, l, "m", 30); ok {
+		t.Fatal("request over the TPM was granted")
+	}
+
+	// The call used less than estimated; the rest goes back
+	release(50, nil)
+	if _, ok := acquire(t, l, "m", 30); !ok {
+		t.Error("request limited after unused tokens were returned")
+	}
+
+	// A call larger than the TPM takes the whole bucket rather than wait forever
+	server.SetTime(time.Now().Add(time.Minute))
+	if _, ok := acquire(t, l, "m", 500); !ok {
+		t.Error("request larger than the TPM was limited with a full bucket")
+	}
+}
+
+func TestProviderBucket(t *testing.T) {
+	l, server := newLimiter(t, 10*time.Millisecond, Limit{Provider: "p", RPM: 1}, Limit{Provider: "other", RPM: 1})
+	server.SetTime(time.Now())
+
+	if _, ok := acquire(t, l, "a", 1); !ok {
+		t.Fatal("first request was limited")
+	}
+	if _, ok := acquire(t, l, "b", 1); ok {
+		t.Error("request to another model was granted, want the provider-wide limit")
+	}
+}
+
+func TestAcquireWaits(t *testing.T) {
+	// 6000 TPM refills 10 tokens every 100ms
+	l, _ := newLimiter(t, 5*time.Second, Limit{Provider: "p", Model: "m", TPM: 6000})
+
+	if _, ok := acquire(t, l, "m", 6000); !ok {
+		t.Fatal("request within the TPM was limited")
+	}
+	start := time.Now()
+	if _, ok := acquire(t, l, "m", 10); !ok {
+		t.Fatal("request was limited, want it to wait for capacity")
+	}
+	if waited := time.Since(start); waited < 50*time.Millisecond {
+		t.Errorf("waited %s, want about 100ms", waited)
+	}
+
+	// Cancellation ends a wait within the bound
+	if _, ok := acquire(t, l, "m", 6000); ok {
+		t.Fatal("request over the refilled capacity was granted")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx, "m", 100); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire() error = %v, want the context's", err)
+	}
+}
+
+func TestRetryAfterBlocks(t *testing.T) {
+	l, server := newLimiter(t, 10*time.Millisecond)
+	now := time.Now()
+	server.SetTime(now)
+
+	release, ok := acquire(t, l, "m", 10)
+	if !ok {
+		t.Fatal("unlimited request was limited")
+	}
+	release(0, &statusError{StatusCode: 429, RetryAfter: 20 * time.Second})
+
+	if _, ok := acquire(t, l, "m", 10); ok {
+		t.Error("request granted during the provider's retry-after")
+	}
+	server.SetTime(now.Add(21 * time.Second))
+	if _, ok := acquire(t, l, "m", 10); !ok {
+		t.Error("request limited after the retry-after")
+	}
+}
+
+func TestAcquireFailsOpen(t *testing.T) {
+	l, server := newLimiter(t, 10*time.Millisecond, Limit{Provider: "p", Model: "m", RPM: 1})
+	server.Close()
+
+	for i := 0; i < 3; i++ {
+		release, err := l.Acquire(context.Background(), "m", 10)
+		if err != nil {
+			t.Fatalf("Acquire() without Redis error = %v, want the call allowed", err)
+		}
+		release(10, nil)
+	}
+}
@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryAfterError is implemented by errors that know when to retry
+type RetryAfterError interface {
+	RetryAfter() time.Duration
+}
+
+// RetryAfter reports whether err is a provider rate limit rejection (HTTP
+// 429) and how long the provider asked to wait, or zero if it didn't say.
+//
+// Provider SDKs wrap responses in their own error types, so besides
+// RetryAfterError the error chain is searched for a Response *http.Response
+// field (Anthropic) or a StatusCode / HTTPStatusCode field (OpenAI,
+// Ollama), with an optional RetryAfter time.Duration field (fakellm).
+func RetryAfter(err error) (time.Duration, bool) {
+	var retryErr RetryAfterError
+	if errors.As(err, &retryErr) {
+		return retryErr.RetryAfter(), true
+	}
+
+	limited := false
+	var delay time.Duration
+	walk(err, func(e error) bool {
+		v := reflect.ValueOf(e)
+		for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return false
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return false
+		}
+
+		if f := v.FieldByName("Response"); f.IsValid() && f.CanInterface() {
+			if resp, ok := f.Interface().(*http.Response); ok && resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+				limited, delay = true, parseRetryAfter(resp.Header)
+				return true
+			}
+		}
+		for _, name := range []string{"StatusCode", "HTTPStatusCode"} {
+			if f := v.FieldByName(name); f.IsValid() && f.CanInt() && f.Int() == http.StatusTooManyRequests {
+				limited = true
+				if f := v.FieldByName("RetryAfter"); f.IsValid() && f.Type() == reflect.TypeOf(time.Duration(0)) {
+					delay = time.Duration(f.Int())
+				}
+				return true
+			}
+		}
+		return false
+	})
+	return delay, limited
+}
+
+// walk calls fn on err and the errors it wraps until fn returns true
+func walk(err error, fn func(error) bool) bool {
+	if err == nil {
+		return false
+	}
+	if fn(err) {
+		return true
+	}
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		return walk(e.Unwrap(), fn)
+	case interface{ Unwrap() []error }:
+		for _, inner := range e.Unwrap() {
+			if walk(inner, fn) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseRetryAfter reads retry-after-ms or retry-after (seconds or an HTTP
+// date) from response headers
+func parseRetryAfter(header http.Header) time.Duration {
+	if ms, err := strconv.ParseFloat(strings.TrimSpace(header.Get("Retry-After-Ms")), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
@@ -0,0 +1,117 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// statusError has the StatusCode and RetryAfter fields of the fake LLM
+// provider's errors
+type statusError struct {
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *statusError) Error() string { return fmt.Sprintf("status %d", e.StatusCode) }
+
+// responseError carries the response, like Anthropic SDK errors
+type responseError struct {
+	Response *http.Response
+}
+
+func (e *responseError) Error() string { return "response error" }
+
+// httpStatusError has the HTTPStatusCode field of some SDK errors
+type httpStatusError struct {
+	HTTPStatusCode int
+}
+
+func (e httpStatusError) Error() string { return "http status error" }
+
+// delayError reports its own delay
+type delayError struct{}
+
+func (delayError) Error() string             { return "slow down" }
+func (delayError) RetryAfter() time.Duration { return 3 * time.Second }
+
+// response returns an error carrying a response with status and headers
+func response(status int, header ...string) error {
+	resp := &http.Response{StatusCode: status, Header: http.Header{}}
+	for i := 0; i+1 < len(header); i += 2 {
+		resp.Header.Set(header[i], header[i+1])
+	}
+	return &responseError{Response: resp}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantDelay   time.Duration
+		wantLimited bool
+	}{
+		{"nil", nil, 0, false},
+		{"plain error", errors.New("boom"), 0, false},
+		{"RetryAfterError", delayError{}, 3 * time.Second, true},
+		{"status field", &statusError{StatusCode: 429, RetryAfter: time.Second}, time.Second, true},
+		{"status field without delay", &statusError{StatusCode: 429}, 0, true},
+		{"other status", &statusError{StatusCode: 500, RetryAfter: time.Second}, 0, false},
+		{"HTTPStatusCode field", httpStatusError{HTTPStatusCode: 429}, 0, true},
+		{"response retry-after seconds", response(429, "Retry-After", "2"), 2 * time.Second, true},
+		{"response retry-after-ms", response(429, "Retry-After-Ms", "1500", "Retry-After", "2"), 1500 * time.Millisecond, true},
+		{"response without header", response(429), 0, true},
+		{"response other status", response(503, "Retry-After", "2"), 0, false},
+		{"nil response", &responseError{}, 0, false},
+		{"wrapped", fmt.Errorf("call failed: %w", &statusError{StatusCode: 429, RetryAfter: time.Second}), time.Second, true},
+		{"joined", errors.Join(errors.New("first"), response(429, "Retry-After", "4")), 4 * time.Second, true},
+		{"nil pointer", (*statusError)(nil), 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, limited := RetryAfter(tt.err)
+			if delay != tt.wantDelay || limited != tt.wantLimited {
+				t.Errorf("RetryAfter() = %s, %v; want %s, %v", delay, limited, tt.wantDelay, tt.wantLimited)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+		// approx allows the want to be an upper bound, for HTTP dates
+		approx bool
+	}{
+		{"none", http.Header{}, 0, false},
+		{"seconds", http.Header{"Retry-After": {"30"}}, 30 * time.Second, false},
+		{"fractional seconds", http.Header{"Retry-After": {"0.5"}}, 500 * time.Millisecond, false},
+		{"milliseconds first", http.Header{"Retry-After-Ms": {"250"}, "Retry-After": {"30"}}, 250 * time.Millisecond, false},
+		{"invalid milliseconds", http.Header{"Retry-After-Ms": {"soon"}, "Retry-After": {"1"}}, time.Second, false},
+		{"zero", http.Header{"Retry-After": {"0"}}, 0, false},
+		{"negative", http.Header{"Retry-After": {"-5"}}, 0, false},
+		{"garbage", http.Header{"Retry-After": {"later"}}, 0, false},
+		{"past date", http.Header{"Retry-After": {"Mon, 02 Jan 2006 15:04:05 GMT"}}, 0, false},
+		{"future date", http.Header{"Retry-After": {time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)}}, time.Minute, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseRetryAfter(tt.header)
+			if tt.approx {
+				if got <= tt.want-2*time.Second || got > tt.want {
+					t.Errorf("parseRetryAfter() = %s, want about %s", got, tt.want)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("parseRetryAfter() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,377 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/aescanero/dago-node-executor/internal/metrics"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Defaults for ToolLimiterConfig fields left empty
+const (
+	DefaultToolKeyPrefix   = "dago:toollimit:"
+	DefaultToolMaxWait     = 30 * time.Second
+	DefaultToolLease       = 10 * time.Minute
+	DefaultBreakerCooldown = 30 * time.Second
+)
+
+const (
+	// toolStateTTL keeps rate and breaker state of idle tools
+	toolStateTTL = time.Hour
+
+	// busyPollInterval is how often a call waiting for a concurrency slot
+	// tries again
+	busyPollInterval = 100 * time.Millisecond
+)
+
+// Reasons a tool call is rejected
+const (
+	ReasonCircuitOpen = "circuit_open"
+	ReasonRateLimited = "rate_limited"
+	ReasonBusy        = "busy"
+)
+
+// ErrToolUnavailable is wrapped by ToolUnavailableError
+var ErrToolUnavailable = errors.New("tool temporarily unavailable")
+
+// ToolUnavailableError is returned when a tool call is rejected by its
+// policy. It is reported to the model as a structured tool result.
+type ToolUnavailableError struct {
+	Tool       string
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *ToolUnavailableError) Error() string {
+	switch e.Reason {
+	case ReasonCircuitOpen:
+		return fmt.Sprintf("tool %s temporarily unavailable: failing repeatedly, retry in %s", e.Tool, e.RetryAfter.Round(time.Second))
+	case ReasonRateLimited:
+		return fmt.Sprintf("tool %s temporarily unavailable: call rate limit reached", e.Tool)
+	default:
+		return fmt.Sprintf("tool %s temporarily unavailable: too many concurrent calls", e.Tool)
+	}
+}
+
+func (e *ToolUnavailableError) Unwrap() error { return ErrToolUnavailable }
+
+// ToolResult is the result reported to the model instead of the call
+func (e *ToolUnavailableError) ToolResult() map[string]interface{} {
+	return map[string]interface{}{
+		"error":               e.Error(),
+		"tool":                e.Tool,
+		"unavailable":         true,
+		"reason":              e.Reason,
+		"retry_after_seconds": math.Ceil(e.RetryAfter.Seconds()),
+	}
+}
+
+// ToolPolicy limits calls to one tool across all workers. Zero values
+// disable a limit.
+type ToolPolicy struct {
+	// MaxConcurrent caps the calls in progress
+	MaxConcurrent int
+
+	// RatePerSecond caps the call rate, with bursts of up to one second
+	// worth of calls
+	RatePerSecond float64
+
+	// BreakerThreshold consecutive failures open the circuit breaker for
+	// BreakerCooldown (default 30s). After the cooldown a single call
+	// probes the tool while others are still rejected: a failure opens the
+	// breaker again right away, a success closes it.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// ToolLimiterConfig holds tool limiter settings
+type ToolLimiterConfig struct {
+	Policies map[string]ToolPolicy
+
+	// MaxWait bounds how long a call waits for a rate or concurrency slot
+	// (default 30s)
+	MaxWait time.Duration
+
+	// Lease frees the concurrency slot of a worker that died mid-call
+	// (default 10m); keep it above the longest tool timeout
+	Lease time.Duration
+
+	// Namespace and KeyPrefix place the keys, as
+	// <namespace>:<prefix>{<tool>}:state and :calls
+	Namespace string
+	KeyPrefix string
+
+	// IsFailure reports whether a call error counts towards the breaker;
+	// errors it rejects neither count nor reset it. By default every error
+	// but cancellation counts.
+	IsFailure func(error) bool
+
+	Logger  *zap.Logger
+	Metrics *metrics.Metrics
+}
+
+// ToolLimiter applies tool policies through Redis
+type ToolLimiter struct {
+	client   redis.UniversalClient
+	prefix   string
+	policies atomic.Pointer[map[string]ToolPolicy]
+	maxWait  time.Duration
+	lease    time.Duration
+	failure  func(error) bool
+	logger   *zap.Logger
+	metrics  *metrics.Metrics
+}
+
+// NewToolLimiter creates a tool limiter
+func NewToolLimiter(client redis.UniversalClient, cfg ToolLimiterConfig) *ToolLimiter {
+	prefix := cfg.KeyPrefix
+	if prefix == "" {
+		prefix = DefaultToolKeyPrefix
+	}
+	if cfg.Namespace != "" {
+		prefix = cfg.Namespace + ":" + prefix
+	}
+
+	l := &ToolLimiter{
+		client:  client,
+		prefix:  prefix,
+		maxWait: cfg.MaxWait,
+		lease:   cfg.Lease,
+		failure: cfg.IsFailure,
+		logger:  cfg.Logger,
+		metrics: cfg.Metrics,
+	}
+	if l.maxWait <= 0 {
+		l.maxWait = DefaultToolMaxWait
+	}
+	if l.lease <= 0 {
+		l.lease = DefaultToolLease
+	}
+	if l.failure == nil {
+		l.failure = func(err error) bool { return !errors.Is(err, context.Canceled) }
+	}
+	l.SetPolicies(cfg.Policies)
+	return l
+}
+
+// SetPolicies replaces the tool policies, e.g. on configuration reload
+func (l *ToolLimiter) SetPolicies(policies map[string]ToolPolicy) {
+	l.policies.Store(&policies)
+}
+
+// policy returns the tool's policy and whether it limits anything
+func (l *ToolLimiter) policy(tool string) (ToolPolicy, bool) {
+	policy, ok := (*l.policies.Load())[tool]
+	if !ok || (policy.MaxConcurrent <= 0 && policy.RatePerSecond <= 0 && policy.BreakerThreshold <= 0) {
+		return ToolPolicy{}, false
+	}
+	if policy.BreakerCooldown <= 0 {
+		policy.BreakerCooldown = DefaultBreakerCooldown
+	}
+	return policy, true
+}
+
+// toolAcquireScript admits a call unless the breaker is open, the rate
+// bucket is empty or all concurrency slots are leased. After a cooldown
+// (half open) the admitted call becomes the probe, leased like a
+// concurrency slot, and others are rejected until it is released. It
+// returns {0, 0} when admitted, or {reason, milliseconds to wait} with
+// reason 1 (circuit open), 2 (rate limited) or 3 (busy).
+//
+// KEYS: state hash, calls sorted set
+// ARGV: rate per second, burst, max concurrent, call ID, lease ms, state TTL ms,
+// cooldown ms
+var toolAcquireScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local h = redis.call('HMGET', KEYS[1], 'tok', 'ts', 'open', 'half', 'probe_until')
+local open = tonumber(h[3]) or 0
+if open > now then
+  return {1, open - now}
+end
+local half = h[4] == '1'
+if half then
+  local probe = tonumber(h[5]) or 0
+  if probe > now then
+    return {1, math.min(probe - now, tonumber(ARGV[7]))}
+  end
+end
+local rps = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local tok = burst
+if rps > 0 then
+  local ts = tonumber(h[2]) or now
+  tok = math.min(burst, (tonumber(h[1]) or burst) + math.max(0, now - ts) * rps / 1000)
+  if tok < 1 then
+    return {2, math.ceil((1 - tok) * 1000 / rps)}
+  end
+end
+local max = tonumber(ARGV[3])
+if max > 0 then
+  redis.call('ZREMRANGEBYSCORE', KEYS[2], '-inf', now)
+  if redis.call('ZCARD', KEYS[2]) >= max then
+    return {3, 0}
+  end
+  redis.call('ZADD', KEYS[2], now + tonumber(ARGV[5]), ARGV[4])
+  redis.call('PEXPIRE', KEYS[2], ARGV[5])
+end
+if rps > 0 then
+  redis.call('HSET', KEYS[1], 'tok', tok - 1, 'ts', now)
+end
+if half then
+  redis.call('HSET', KEYS[1], 'probe', ARGV[4], 'probe_until', now + tonumber(ARGV[5]))
+end
+if rps > 0 or half then
+  redis.call('PEXPIRE', KEYS[1], math.max(tonumber(ARGV[6]), tonumber(ARGV[5])))
+end
+return {0, 0}
+`)
+
+// toolReleaseScript frees the call's concurrency slot and updates the
+// breaker. A failure counts towards the threshold, or reopens the breaker
+// right away while half open; a success closes it. An ignored outcome
+// only frees the probe for another call. It returns 1 if the breaker
+// opened.
+//
+// KEYS: state hash, calls sorted set
+// ARGV: call ID, outcome (0 success, 1 failure, 2 ignored), threshold,
+// cooldown ms, state TTL ms
+var toolReleaseScript = redis.NewScript(`
+redis.call('ZREM', KEYS[2], ARGV[1])
+local threshold = tonumber(ARGV[3])
+if threshold <= 0 then
+  return 0
+end
+if ARGV[2] == '0' then
+  redis.call('HDEL', KEYS[1], 'fails', 'half', 'probe', 'probe_until')
+  return 0
+end
+if ARGV[2] == '2' then
+  if redis.call('HGET', KEYS[1], 'probe') == ARGV[1] then
+    redis.call('HDEL', KEYS[1], 'probe', 'probe_until')
+  end
+  return 0
+end
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+if (tonumber(redis.call('HGET', KEYS[1], 'open')) or 0) > now then
+  -- Opened by another call meanwhile
+  return 0
+end
+local fails = redis.call('HINCRBY', KEYS[1], 'fails', 1)
+local half = redis.call('HGET', KEYS[1], 'half')
+local cooldown = tonumber(ARGV[4])
+if fails >= threshold or half == '1' then
+  redis.call('HSET', KEYS[1], 'open', now + cooldown, 'half', '1', 'fails', 0)
+  redis.call('HDEL', KEYS[1], 'probe', 'probe_until')
+  redis.call('PEXPIRE', KEYS[1], math.max(tonumber(ARGV[5]), cooldown))
+  return 1
+end
+redis.call('PEXPIRE', KEYS[1], ARGV[5])
+return 0
+`)
+
+// Acquire admits a call to tool under its policy, waiting up to the
+// configured bound for a rate or concurrency slot. It fails right away
+// with a ToolUnavailableError while the tool's breaker is open. The
+// returned release must be called with the call's error.
+//
+// If Redis is unreachable the call is allowed.
+func (l *ToolLimiter) Acquire(ctx context.Context, tool string) (func(err error), error) {
+	policy, ok := l.policy(tool)
+	if !ok {
+		return func(error) {}, nil
+	}
+
+	stateKey := l.prefix + "{" + tool + "}:state"
+	callsKey := l.prefix + "{" + tool + "}:calls"
+	keys := []string{stateKey, callsKey}
+	callID := uuid.New().String()
+	burst := math.Max(1, math.Ceil(policy.RatePerSecond))
+
+	deadline := time.Now().Add(l.maxWait)
+	for {
+		res, err := toolAcquireScript.Run(ctx, l.client, keys,
+			policy.RatePerSecond, burst, policy.MaxConcurrent, callID,
+			l.lease.Milliseconds(), toolStateTTL.Milliseconds(),
+			policy.BreakerCooldown.Milliseconds()).Int64Slice()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			l.logger.Warn("tool limiter unavailable, calling the tool without it",
+				zap.String("tool", tool),
+				zap.Error(err))
+			return func(error) {}, nil
+		}
+
+		reason, wait := res[0], time.Duration(res[1])*time.Millisecond
+		if reason == 0 {
+			break
+		}
+
+		rejection := &ToolUnavailableError{Tool: tool, RetryAfter: wait}
+		switch reason {
+		case 1:
+			rejection.Reason = ReasonCircuitOpen
+		case 2:
+			rejection.Reason = ReasonRateLimited
+		default:
+			rejection.Reason = ReasonBusy
+			wait = busyPollInterval
+		}
+		if reason == 1 || time.Now().Add(wait).After(deadline) {
+			l.metrics.IncToolRejected(tool, rejection.Reason)
+			return nil, rejection
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	return func(callErr error) {
+		l.release(tool, keys, callID, policy, callErr)
+	}, nil
+}
+
+// release frees the call's slot and records its outcome for the breaker
+func (l *ToolLimiter) release(tool string, keys []string, callID string, policy ToolPolicy, callErr error) {
+	ctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+	defer cancel()
+
+	outcome := "0"
+	if callErr != nil {
+		// Errors about the call itself say nothing about the tool
+		outcome = "2"
+		if l.failure(callErr) {
+			outcome = "1"
+		}
+	}
+
+	opened, err := toolReleaseScript.Run(ctx, l.client, keys, callID, outcome,
+		policy.BreakerThreshold, policy.BreakerCooldown.Milliseconds(), toolStateTTL.Milliseconds()).Int()
+	if err != nil {
+		l.logger.Warn("failed to release tool call", zap.String("tool", tool), zap.Error(err))
+		return
+	}
+	if opened == 1 {
+		l.metrics.IncToolBreakerOpened(tool)
+		l.logger.Warn("tool circuit breaker opened",
+			zap.String("tool", tool),
+			zap.Int("threshold", policy.BreakerThreshold),
+			zap.Duration("cooldown", policy.BreakerCooldown),
+			zap.Error(callErr))
+	}
+}
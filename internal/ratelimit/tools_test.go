@@ -0,0 +1,194 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// errInvalid is a call error the breaker ignores in these tests
+var errInvalid = errors.New("invalid params")
+
+// newToolLimiter returns a tool limiter on an in-memory Redis with a one
+// minute lease, with its clock frozen at the returned time
+func newToolLimiter(t *testing.T, policy ToolPolicy) (*ToolLimiter, *miniredis.Miniredis, time.Time) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	now := time.Now()
+	server.SetTime(now)
+
+	return NewToolLimiter(client, ToolLimiterConfig{
+		Policies:  map[string]ToolPolicy{"search": policy},
+		MaxWait:   10 * time.Millisecond,
+		Lease:     time.Minute,
+		IsFailure: func(err error) bool { return !errors.Is(err, errInvalid) },
+		Logger:    zap.NewNop(),
+	}), server, now
+}
+
+// acquireTool acquires a call to search, returning its release or the
+// reason it was rejected
+func acquireTool(t *testing.T, l *ToolLimiter) (func(error), string) {
+	t.Helper()
+	release, err := l.Acquire(context.Background(), "search")
+	var unavailable *ToolUnavailableError
+	if errors.As(err, &unavailable) {
+		return nil, unavailable.Reason
+	}
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	return release, ""
+}
+
+// mustAcquire acquires a call to search, failing the test if rejected
+func mustAcquire(t *testing.T, l *ToolLimiter, step string) func(error) {
+	t.Helper()
+	release, reason := acquireTool(t, l)
+	if reason != "" {
+		t.Fatalf("%s: call rejected as %s, want it admitted", step, reason)
+	}
+	return release
+}
+
+// mustReject checks that a call to search is rejected for reason
+func mustReject(t *testing.T, l *ToolLimiter, reason, step string) {
+	t.Helper()
+	if release, got := acquireTool(t, l); got != reason {
+		if release != nil {
+			release(nil)
+		}
+		t.Fatalf("%s: call rejected as %q, want %q", step, got, reason)
+	}
+}
+
+func TestToolConcurrencyLeases(t *testing.T) {
+	l, server, now := newToolLimiter(t, ToolPolicy{MaxConcurrent: 2})
+	const calls = "dago:toollimit:{search}:calls"
+
+	first := mustAcquire(t, l, "first call")
+	mustAcquire(t, l, "second call")
+	mustReject(t, l, ReasonBusy, "third call")
+
+	first(nil)
+	if members, _ := server.ZMembers(calls); len(members) != 1 {
+		t.Errorf("leases after release = %v, want one", members)
+	}
+	mustAcquire(t, l, "call after a release")
+	mustReject(t, l, ReasonBusy, "call with all slots leased")
+
+	// Leases of calls never released, e.g. by a dead worker, expire
+	server.SetTime(now.Add(2 * time.Minute))
+	mustAcquire(t, l, "call after the leases expired")
+	if members, _ := server.ZMembers(calls); len(members) != 1 {
+		t.Errorf("leases = %v, want only the new call's", members)
+	}
+}
+
+func TestToolRate(t *testing.T) {
+	l, server, now := newToolLimiter(t, ToolPolicy{RatePerSecond: 2})
+
+	mustAcquire(t, l, "first call")
+	mustAcquire(t, l, "second call")
+	_, err := l.Acquire(context.Background(), "search")
+	var unavailable *ToolUnavailableError
+	if !errors.As(err, &unavailable) || unavailable.Reason != ReasonRateLimited || unavailable.RetryAfter <= 0 {
+		t.Fatalf("Acquire() over the rate error = %v, want rate limited with a retry-after", err)
+	}
+
+	// One call refills every 500ms
+	server.SetTime(now.Add(600 * time.Millisecond))
+	mustAcquire(t, l, "call after the refill")
+	mustReject(t, l, ReasonRateLimited, "second call after the refill")
+
+	// Tools without a policy are not limited
+	for i := 0; i < 5; i++ {
+		if _, err := l.Acquire(context.Background(), "other"); err != nil {
+			t.Fatalf("Acquire() of an unlimited tool error = %v", err)
+		}
+	}
+}
+
+func TestToolBreaker(t *testing.T) {
+	l, server, now := newToolLimiter(t, ToolPolicy{BreakerThreshold: 2, BreakerCooldown: 30 * time.Second})
+	failure := errors.New("connection refused")
+
+	// Failures below the threshold are reset by a success
+	mustAcquire(t, l, "failing call")(failure)
+	mustAcquire(t, l, "succeeding call")(nil)
+	mustAcquire(t, l, "failing call")(failure)
+	mustAcquire(t, l, "call after one failure")
+
+	// Consecutive failures open the breaker
+	mustAcquire(t, l, "second consecutive failure")(failure)
+	_, err := l.Acquire(context.Background(), "search")
+	var unavailable *ToolUnavailableError
+	if !errors.As(err, &unavailable) || unavailable.Reason != ReasonCircuitOpen {
+		t.Fatalf("Acquire() with the breaker open error = %v, want circuit open", err)
+	}
+	if unavailable.RetryAfter <= 29*time.Second || unavailable.RetryAfter > 30*time.Second {
+		t.Errorf("retry after %s, want the 30s cooldown", unavailable.RetryAfter)
+	}
+
+	// After the cooldown a single call probes the tool
+	now = now.Add(31 * time.Second)
+	server.SetTime(now)
+	probe := mustAcquire(t, l, "probe")
+	mustReject(t, l, ReasonCircuitOpen, "call during the probe")
+
+	// A failed probe reopens the breaker right away
+	probe(failure)
+	mustReject(t, l, ReasonCircuitOpen, "call after the failed probe")
+
+	// A probe never released frees its slot after the lease
+	now = now.Add(31 * time.Second)
+	server.SetTime(now)
+	mustAcquire(t, l, "probe that is never released")
+	now = now.Add(2 * time.Minute)
+	server.SetTime(now)
+	probe = mustAcquire(t, l, "probe after the lease")
+
+	// A successful probe closes the breaker
+	probe(nil)
+	mustAcquire(t, l, "call after the successful probe")(failure)
+	mustAcquire(t, l, "call after one failure")
+}
+
+func TestToolBreakerIgnoresCallErrors(t *testing.T) {
+	l, server, now := newToolLimiter(t, ToolPolicy{BreakerThreshold: 1, BreakerCooldown: 30 * time.Second})
+
+	// Invalid params and cancelled calls say nothing about the tool
+	for i := 0; i < 3; i++ {
+		mustAcquire(t, l, "call with invalid params")(errInvalid)
+	}
+	mustAcquire(t, l, "call after invalid params")
+
+	// They don't close the breaker either, but free the probe
+	mustAcquire(t, l, "failing call")(errors.New("connection refused"))
+	server.SetTime(now.Add(31 * time.Second))
+	mustAcquire(t, l, "probe with invalid params")(errInvalid)
+	probe := mustAcquire(t, l, "next probe")
+	mustReject(t, l, ReasonCircuitOpen, "call during the next probe")
+	probe(errors.New("connection refused"))
+	mustReject(t, l, ReasonCircuitOpen, "call after the failed probe")
+}
+
+func TestToolLimiterFailsOpen(t *testing.T) {
+	l, server, _ := newToolLimiter(t, ToolPolicy{MaxConcurrent: 1, BreakerThreshold: 1})
+	server.Close()
+
+	for i := 0; i < 3; i++ {
+		release, err := l.Acquire(context.Background(), "search")
+		if err != nil {
+			t.Fatalf("Acquire() without Redis error = %v, want the call allowed", err)
+		}
+		release(errors.New("connection refused"))
+	}
+}
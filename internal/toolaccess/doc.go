@@ -0,0 +1,12 @@
+// Package toolaccess decides which tools a node may call, and with which
+// arguments.
+//
+// A Policy allows and denies tools by name or glob (github__*) and may
+// constrain the arguments of matching tools, e.g. to paths under a
+// directory. Policies apply at several scopes: the worker default, the
+// tenant and the graph from the configuration, and the node from its
+// config (its agent tools list and tool_access). A call must pass every
+// scope. The Enforcer checks calls before they run, as a toolchain hook,
+// and refuses the others with a DeniedError, which the agent receives as
+// a structured "tool not permitted" result.
+package toolaccess
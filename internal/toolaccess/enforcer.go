@@ -0,0 +1,149 @@
+package toolaccess
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/aescanero/dago-node-executor/internal/metrics"
+	"github.com/aescanero/dago-node-executor/internal/toolchain"
+	"go.uber.org/zap"
+)
+
+// Scopes a policy applies at
+const (
+	ScopeDefault = "default"
+	ScopeTenant  = "tenant"
+	ScopeGraph   = "graph"
+	ScopeNode    = "node"
+)
+
+// ErrNotPermitted is wrapped by DeniedError
+var ErrNotPermitted = errors.New("tool not permitted")
+
+// DeniedError is returned for a call refused by a policy. It is reported
+// to the model as a structured tool result.
+type DeniedError struct {
+	Tool   string
+	Scope  string
+	Reason string
+	Detail string
+}
+
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("tool %s not permitted by the %s policy: %s", e.Tool, e.Scope, e.Detail)
+}
+
+func (e *DeniedError) Unwrap() error { return ErrNotPermitted }
+
+// ToolResult is the result reported to the model instead of the call
+func (e *DeniedError) ToolResult() map[string]interface{} {
+	return map[string]interface{}{
+		"error":         e.Error(),
+		"tool":          e.Tool,
+		"not_permitted": true,
+		"scope":         e.Scope,
+		"reason":        e.Reason,
+	}
+}
+
+// Policies are the configured policies: Default applies to every call,
+// Tenants and Graphs to the calls of a tenant or graph ID
+type Policies struct {
+	Default Policy
+	Tenants map[string]Policy
+	Graphs  map[string]Policy
+}
+
+// Validate checks every policy
+func (p Policies) Validate() error {
+	if err := p.Default.Validate(); err != nil {
+		return fmt.Errorf("default tool access policy: %w", err)
+	}
+	for tenant, policy := range p.Tenants {
+		if err := policy.Validate(); err != nil {
+			return fmt.Errorf("tool access policy of tenant %s: %w", tenant, err)
+		}
+	}
+	for graph, policy := range p.Graphs {
+		if err := policy.Validate(); err != nil {
+			return fmt.Errorf("tool access policy of graph %s: %w", graph, err)
+		}
+	}
+	return nil
+}
+
+type nodePoliciesKey struct{}
+
+// ContextWithNodePolicies returns a context whose tool calls must pass the
+// node's policies
+func ContextWithNodePolicies(ctx context.Context, policies ...Policy) context.Context {
+	return context.WithValue(ctx, nodePoliciesKey{}, policies)
+}
+
+// Enforcer checks tool calls against the policies of their scopes
+type Enforcer struct {
+	policies atomic.Pointer[Policies]
+	logger   *zap.Logger
+	metrics  *metrics.Metrics
+}
+
+// NewEnforcer creates an enforcer
+func NewEnforcer(policies Policies, logger *zap.Logger, m *metrics.Metrics) *Enforcer {
+	e := &Enforcer{logger: logger, metrics: m}
+	e.SetPolicies(policies)
+	return e
+}
+
+// SetPolicies replaces the configured policies, e.g. on configuration reload
+func (e *Enforcer) SetPolicies(policies Policies) {
+	e.policies.Store(&policies)
+}
+
+// Check returns a *DeniedError if a policy in scope of ctx refuses the call
+func (e *Enforcer) Check(ctx context.Context, tool string, params map[string]interface{}) error {
+	call := toolchain.CallFrom(ctx)
+	configured := e.policies.Load()
+
+	type scoped struct {
+		scope  string
+		policy Policy
+	}
+	scopes := []scoped{{ScopeDefault, configured.Default}}
+	if policy, ok := configured.Tenants[call.Tenant]; ok && call.Tenant != "" {
+		scopes = append(scopes, scoped{ScopeTenant, policy})
+	}
+	if policy, ok := configured.Graphs[call.GraphID]; ok && call.GraphID != "" {
+		scopes = append(scopes, scoped{ScopeGraph, policy})
+	}
+	nodePolicies, _ := ctx.Value(nodePoliciesKey{}).([]Policy)
+	for _, policy := range nodePolicies {
+		scopes = append(scopes, scoped{ScopeNode, policy})
+	}
+
+	for _, s := range scopes {
+		reason, detail := s.policy.check(tool, params)
+		if reason == "" {
+			continue
+		}
+		e.metrics.IncToolRejected(tool, "not_permitted")
+		e.logger.Warn("tool call not permitted",
+			zap.String("graph_id", call.GraphID),
+			zap.String("node_id", call.NodeID),
+			zap.String("tenant", call.Tenant),
+			zap.String("tool", tool),
+			zap.String("scope", s.scope),
+			zap.String("reason", reason),
+			zap.String("detail", detail))
+		return &DeniedError{Tool: tool, Scope: s.scope, Reason: reason, Detail: detail}
+	}
+	return nil
+}
+
+// Hook returns a toolchain hook refusing calls that Check refuses
+func (e *Enforcer) Hook() toolchain.BeforeHook {
+	return func(ctx context.Context, toolName string, params map[string]interface{}) (map[string]interface{}, error) {
+		return nil, e.Check(ctx, toolName, params)
+	}
+}
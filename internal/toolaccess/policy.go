@@ -0,0 +1,239 @@
+package toolaccess
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aescanero/dago-node-executor/pkg/tools/function"
+)
+
+// Reasons a tool call is not permitted
+const (
+	ReasonDenied     = "denied"
+	ReasonNotAllowed = "not_allowed"
+	ReasonArgument   = "argument"
+)
+
+// Policy allows and denies tools by name or glob. A tool matching Deny is
+// refused; if Allow is not empty, a tool must match it. Args constrains,
+// per tool glob, the arguments of matching tools.
+type Policy struct {
+	Allow []string                            `json:"allow,omitempty"`
+	Deny  []string                            `json:"deny,omitempty"`
+	Args  map[string]map[string]ArgConstraint `json:"args,omitempty"`
+}
+
+// ArgConstraint restricts one argument. A missing argument passes unless
+// Required; a present one must satisfy every constraint set.
+type ArgConstraint struct {
+	Required bool `json:"required,omitempty"`
+
+	// Values lists the allowed values
+	Values []string `json:"values,omitempty"`
+
+	// Prefixes lists allowed string prefixes
+	Prefixes []string `json:"prefixes,omitempty"`
+
+	// Paths lists directories the argument, cleaned of . and .., must be
+	// in; relative paths never match, except for the fs_* tools whose
+	// paths are relative to the workspace root /
+	Paths []string `json:"paths,omitempty"`
+
+	// Pattern is a regular expression the whole value must match
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// IsZero reports whether the policy permits everything
+func (p Policy) IsZero() bool {
+	return len(p.Allow) == 0 && len(p.Deny) == 0 && len(p.Args) == 0
+}
+
+// ParsePolicy reads a policy from a node config value, as decoded from
+// JSON. A nil value is the empty policy.
+func ParsePolicy(value interface{}) (Policy, error) {
+	var policy Policy
+	if value == nil {
+		return policy, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return policy, fmt.Errorf("invalid tool access policy: %w", err)
+	}
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return policy, fmt.Errorf("invalid tool access policy: %w", err)
+	}
+	return policy, policy.Validate()
+}
+
+// Validate checks the globs and patterns of the policy
+func (p Policy) Validate() error {
+	for _, pattern := range append(append([]string(nil), p.Allow...), p.Deny...) {
+		if err := validateGlob(pattern); err != nil {
+			return err
+		}
+	}
+	for pattern, args := range p.Args {
+		if err := validateGlob(pattern); err != nil {
+			return err
+		}
+		for name, constraint := range args {
+			if constraint.Pattern == "" {
+				continue
+			}
+			if _, err := regexp.Compile(constraint.Pattern); err != nil {
+				return fmt.Errorf("invalid pattern for argument %s of %s: %w", name, pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateGlob checks a tool glob
+func validateGlob(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("empty tool pattern")
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid tool pattern %q: %w", pattern, err)
+	}
+	return nil
+}
+
+// IsPattern reports whether name is a glob rather than a tool name
+func IsPattern(name string) bool {
+	return strings.ContainsAny(name, "*?[\\")
+}
+
+// Match reports whether tool matches the name or glob pattern
+func Match(pattern, tool string) bool {
+	matched, err := path.Match(pattern, tool)
+	return err == nil && matched
+}
+
+// matchAny reports whether tool matches one of patterns
+func matchAny(patterns []string, tool string) bool {
+	for _, pattern := range patterns {
+		if Match(pattern, tool) {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns the reason and a description if the policy refuses the
+// call, or empty strings
+func (p Policy) check(tool string, params map[string]interface{}) (string, string) {
+	if matchAny(p.Deny, tool) {
+		return ReasonDenied, "tool is denied"
+	}
+	if len(p.Allow) > 0 && !matchAny(p.Allow, tool) {
+		return ReasonNotAllowed, "tool is not in the allowlist"
+	}
+
+	// Sorted for deterministic messages
+	patterns := make([]string, 0, len(p.Args))
+	for pattern := range p.Args {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		if !Match(pattern, tool) {
+			continue
+		}
+		args := p.Args[pattern]
+		names := make([]string, 0, len(args))
+		for name := range args {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if problem := args[name].check(params[name], function.IsFSTool(tool)); problem != "" {
+				return ReasonArgument, fmt.Sprintf("argument %s %s", name, problem)
+			}
+		}
+	}
+	return "", ""
+}
+
+// check returns what is wrong with value, or an empty string. Paths of
+// workspace tools are resolved from the workspace root as the tools do.
+func (c ArgConstraint) check(value interface{}, workspace bool) string {
+	if value == nil {
+		if c.Required {
+			return "is required"
+		}
+		return ""
+	}
+
+	s, isString := value.(string)
+	if !isString {
+		if len(c.Prefixes) > 0 || len(c.Paths) > 0 {
+			return "must be a string"
+		}
+		s = fmt.Sprint(value)
+	}
+
+	if len(c.Values) > 0 && !contains(c.Values, s) {
+		return fmt.Sprintf("must be one of %s", strings.Join(c.Values, ", "))
+	}
+	if len(c.Prefixes) > 0 && !hasAnyPrefix(s, c.Prefixes) {
+		return fmt.Sprintf("must start with %s", strings.Join(c.Prefixes, " or "))
+	}
+	if len(c.Paths) > 0 {
+		p := s
+		if workspace {
+			var err error
+			if p, err = function.WorkspacePath(s); err != nil {
+				return "must be a valid path"
+			}
+		}
+		if !inAnyDir(p, c.Paths) {
+			return fmt.Sprintf("must be a path under %s", strings.Join(c.Paths, " or "))
+		}
+	}
+	if c.Pattern != "" {
+		re, err := regexp.Compile("^(?:" + c.Pattern + ")$")
+		if err != nil || !re.MatchString(s) {
+			return fmt.Sprintf("must match %s", c.Pattern)
+		}
+	}
+	return ""
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// inAnyDir reports whether the cleaned absolute path p is one of dirs or
+// inside one
+func inAnyDir(p string, dirs []string) bool {
+	if !path.IsAbs(p) {
+		return false
+	}
+	p = path.Clean(p)
+	for _, dir := range dirs {
+		dir = path.Clean(dir)
+		if p == dir || strings.HasPrefix(p, strings.TrimSuffix(dir, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,235 @@
+package toolaccess
+
+import "testing"
+
+func TestPolicyCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		tool   string
+		params map[string]interface{}
+		reason string
+	}{
+		{
+			name:   "empty policy permits everything",
+			tool:   "github__create_issue",
+			reason: "",
+		},
+		{
+			name:   "namespace glob allows",
+			policy: Policy{Allow: []string{"github__*"}},
+			tool:   "github__create_issue",
+		},
+		{
+			name:   "dot glob does not match namespaced tools",
+			policy: Policy{Allow: []string{"github.*"}},
+			tool:   "github__create_issue",
+			reason: ReasonNotAllowed,
+		},
+		{
+			name:   "tool outside the allowlist",
+			policy: Policy{Allow: []string{"github__*"}},
+			tool:   "slack__post",
+			reason: ReasonNotAllowed,
+		},
+		{
+			name:   "deny wins over allow",
+			policy: Policy{Allow: []string{"github__*"}, Deny: []string{"github__delete_*"}},
+			tool:   "github__delete_repo",
+			reason: ReasonDenied,
+		},
+		{
+			name: "allowed value",
+			policy: Policy{Args: map[string]map[string]ArgConstraint{
+				"http_request": {"method": {Values: []string{"GET", "HEAD"}}},
+			}},
+			tool:   "http_request",
+			params: map[string]interface{}{"method": "GET"},
+		},
+		{
+			name: "value outside the list",
+			policy: Policy{Args: map[string]map[string]ArgConstraint{
+				"http_request": {"method": {Values: []string{"GET", "HEAD"}}},
+			}},
+			tool:   "http_request",
+			params: map[string]interface{}{"method": "DELETE"},
+			reason: ReasonArgument,
+		},
+		{
+			name: "missing optional argument passes",
+			policy: Policy{Args: map[string]map[string]ArgConstraint{
+				"http_request": {"method": {Values: []string{"GET"}}},
+			}},
+			tool: "http_request",
+		},
+		{
+			name: "missing required argument",
+			policy: Policy{Args: map[string]map[string]ArgConstraint{
+				"fs_*": {"path": {Required: true}},
+			}},
+			tool:   "fs_read",
+			params: map[string]interface{}{},
+			reason: ReasonArgument,
+		},
+		{
+			name: "constraints only apply to matching tools",
+			policy: Policy{Args: map[string]map[string]ArgConstraint{
+				"fs_*": {"path": {Required: true}},
+			}},
+			tool: "math_eval",
+		},
+		{
+			name: "prefix",
+			policy: Policy{Args: map[string]map[string]ArgConstraint{
+				"fetch": {"url": {Prefixes: []string{"https://api.example.com/"}}},
+			}},
+			tool:   "fetch",
+			params: map[string]interface{}{"url": "https://evil.example.com/"},
+			reason: ReasonArgument,
+		},
+		{
+			name: "path under an allowed directory",
+			policy: Policy{Args: map[string]map[string]ArgConstraint{
+				"fs_*": {"path": {Paths: []string{"/reports"}}},
+			}},
+			tool:   "fs_write",
+			params: map[string]interface{}{"path": "/reports/q1/summary.md"},
+		},
+		{
+			name: "path climbing out of the directory",
+			policy: Policy{Args: map[string]map[string]ArgConstraint{
+				"fs_*": {"path": {Paths: []string{"/reports"}}},
+			}},
+			tool:   "fs_write",
+			params: map[string]interface{}{"path": "/reports/../etc/passwd"},
+			reason: ReasonArgument,
+		},
+		{
+			name: "directory name prefix is not the directory",
+			policy: Policy{Args: map[string]map[string]ArgConstraint{
+				"fs_*": {"path": {Paths: []string{"/reports"}}},
+			}},
+			tool:   "fs_write",
+			params: map[string]interface{}{"path": "/reports-old/a.md"},
+			reason: ReasonArgument,
+		},
+		{
+			name: "relative path never matches",
+			policy: Policy{Args: map[string]map[string]ArgConstraint{
+				"filesystem__*": {"path": {Paths: []string{"/reports"}}},
+			}},
+			tool:   "filesystem__write",
+			params: map[string]interface{}{"path": "reports/a.md"},
+			reason: ReasonArgument,
+		},
+		{
+			name: "workspace path is relative to the workspace root",
+			policy: Policy{Args: map[string]map[string]ArgConstraint{
+				"fs_*": {"path": {Paths: []string{"/reports"}}},
+			}},
+			tool:   "fs_write",
+			params: map[string]interface{}{"path": "reports/a.md"},
+		},
+		{
+			name: "workspace path climbing above the root stops there",
+			policy: Policy{Args: map[string]map[string]ArgConstraint{
+				"fs_*": {"path": {Paths: []string{"/reports"}}},
+			}},
+			tool:   "fs_read",
+			params: map[string]interface{}{"path": "../../reports/a.md"},
+		},
+		{
+			name: "workspace path with backslashes",
+			policy: Policy{Args: map[string]map[string]ArgConstraint{
+				"fs_*": {"path": {Paths: []string{"/reports"}}},
+			}},
+			tool:   "fs_write",
+			params: map[string]interface{}{"path": `reports\..\secrets\key`},
+			reason: ReasonArgument,
+		},
+		{
+			name: "workspace path with a NUL byte",
+			policy: Policy{Args: map[string]map[string]ArgConstraint{
+				"fs_*": {"path": {Paths: []string{"/reports"}}},
+			}},
+			tool:   "fs_write",
+			params: map[string]interface{}{"path": "/reports/a.md\x00"},
+			reason: ReasonArgument,
+		},
+		{
+			name: "non-string path",
+			policy: Policy{Args: map[string]map[string]ArgConstraint{
+				"fs_*": {"path": {Paths: []string{"/reports"}}},
+			}},
+			tool:   "fs_write",
+			params: map[string]interface{}{"path": 42.0},
+			reason: ReasonArgument,
+		},
+		{
+			name: "pattern matches the whole value",
+			policy: Policy{Args: map[string]map[string]ArgConstraint{
+				"github__*": {"repo": {Pattern: `acme/[a-z-]+`}},
+			}},
+			tool:   "github__create_issue",
+			params: map[string]interface{}{"repo": "acme/web-app"},
+		},
+		{
+			name: "pattern is anchored",
+			policy: Policy{Args: map[string]map[string]ArgConstraint{
+				"github__*": {"repo": {Pattern: `acme/[a-z-]+`}},
+			}},
+			tool:   "github__create_issue",
+			params: map[string]interface{}{"repo": "acme/web-app/../../other"},
+			reason: ReasonArgument,
+		},
+		{
+			name: "numbers are compared as text",
+			policy: Policy{Args: map[string]map[string]ArgConstraint{
+				"search": {"limit": {Values: []string{"10", "20"}}},
+			}},
+			tool:   "search",
+			params: map[string]interface{}{"limit": 20.0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, description := tt.policy.check(tt.tool, tt.params)
+			if reason != tt.reason {
+				t.Fatalf("check(%s) reason = %q (%s), want %q", tt.tool, reason, description, tt.reason)
+			}
+			if reason != "" && description == "" {
+				t.Fatalf("check(%s) refused without a description", tt.tool)
+			}
+		})
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		wantErr bool
+	}{
+		{name: "empty", policy: Policy{}},
+		{name: "globs", policy: Policy{Allow: []string{"github__*", "math_eval"}, Deny: []string{"fs_?rite"}}},
+		{name: "empty glob", policy: Policy{Allow: []string{""}}, wantErr: true},
+		{name: "malformed glob", policy: Policy{Deny: []string{"fs_["}}, wantErr: true},
+		{
+			name: "invalid pattern",
+			policy: Policy{Args: map[string]map[string]ArgConstraint{
+				"fs_*": {"path": {Pattern: "("}},
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
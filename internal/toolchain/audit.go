@@ -0,0 +1,97 @@
+package toolchain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Audit outcomes
+const (
+	OutcomeSuccess  = "success"
+	OutcomeCached   = "cached"
+	OutcomeError    = "error"
+	OutcomeRejected = "rejected"
+)
+
+// AuditConfig holds audit log settings
+type AuditConfig struct {
+	Logger *zap.Logger
+
+	// LogParams adds the parameters, redacted by Redactor, to each entry;
+	// otherwise only their hash is logged
+	LogParams bool
+	Redactor  *Redactor
+}
+
+// auditRecord collects facts about a call from inner middlewares
+type auditRecord struct {
+	cached bool
+}
+
+type auditKey struct{}
+
+// markCached records that the call in ctx was served from cache
+func markCached(ctx context.Context) {
+	if record, ok := ctx.Value(auditKey{}).(*auditRecord); ok {
+		record.cached = true
+	}
+}
+
+// Audit logs one entry per tool call with its graph, node, tool,
+// parameters hash, duration and outcome: success, cached, error, or
+// rejected for calls refused with a structured result, such as by the
+// tool's limits
+func Audit(cfg AuditConfig) Middleware {
+	logger := cfg.Logger.Named("audit")
+	redactor := cfg.Redactor
+	if redactor == nil {
+		redactor = NewRedactor()
+	}
+
+	return WrapExecute(func(next ExecuteFunc) ExecuteFunc {
+		return func(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error) {
+			record := &auditRecord{}
+			start := time.Now()
+			result, err := next(context.WithValue(ctx, auditKey{}, record), toolName, params)
+			duration := time.Since(start)
+
+			call := CallFrom(ctx)
+			fields := []zap.Field{
+				zap.String("graph_id", call.GraphID),
+				zap.String("node_id", call.NodeID),
+				zap.String("tenant", call.Tenant),
+				zap.String("tool", toolName),
+				zap.String("params_hash", HashParams(params)),
+				zap.Duration("duration", duration),
+				zap.String("outcome", auditOutcome(record, err)),
+			}
+			if cfg.LogParams {
+				fields = append(fields, zap.Any("params", redactor.Redact(params)))
+			}
+			if err != nil {
+				fields = append(fields, zap.Error(err))
+			}
+			logger.Info("tool call", fields...)
+
+			return result, err
+		}
+	})
+}
+
+// auditOutcome classifies a call
+func auditOutcome(record *auditRecord, err error) string {
+	var described interface{ ToolResult() map[string]interface{} }
+	switch {
+	case err == nil && record.cached:
+		return OutcomeCached
+	case err == nil:
+		return OutcomeSuccess
+	case errors.As(err, &described):
+		return OutcomeRejected
+	default:
+		return OutcomeError
+	}
+}
@@ -0,0 +1,148 @@
+package toolchain
+
+import (
+	"container/list"
+	"context"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultCacheEntries bounds the cache when no size is given
+const DefaultCacheEntries = 1000
+
+// Cache keeps results of read-only tools for a per-tool TTL. Only tools
+// with a TTL are cached, so set one only for tools without side effects.
+type Cache struct {
+	ttls atomic.Pointer[map[string]time.Duration]
+
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List // least recently used at the back
+}
+
+// cacheEntry is a cached result
+type cacheEntry struct {
+	key     string
+	tool    string
+	result  interface{}
+	expires time.Time
+}
+
+// NewCache creates a cache of at most maxEntries results (default 1000)
+// with the given per-tool TTLs
+func NewCache(ttls map[string]time.Duration, maxEntries int) *Cache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultCacheEntries
+	}
+	c := &Cache{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+	c.SetTTLs(ttls)
+	return c
+}
+
+// SetTTLs replaces the per-tool TTLs, e.g. on configuration reload.
+// Results of tools no longer cached are dropped.
+func (c *Cache) SetTTLs(ttls map[string]time.Duration) {
+	c.ttls.Store(&ttls)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, elem := range c.entries {
+		if ttls[elem.Value.(*cacheEntry).tool] <= 0 {
+			c.remove(elem)
+		}
+	}
+}
+
+// ttl returns the tool's TTL, zero if it isn't cached
+func (c *Cache) ttl(tool string) time.Duration {
+	return (*c.ttls.Load())[tool]
+}
+
+// get returns the unexpired result stored under key
+func (c *Cache) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(elem)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.result, true
+}
+
+// put stores a result, evicting the least recently used one when full
+func (c *Cache) put(key, tool string, result interface{}, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &cacheEntry{key: key, tool: tool, result: result, expires: time.Now().Add(ttl)}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+	}
+}
+
+// remove drops an entry; c.mu must be held
+func (c *Cache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*cacheEntry).key)
+}
+
+// Len returns the number of cached results
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Cached serves repeated calls of tools with a TTL in cache from it.
+// Calls share a result only within the same graph, node and tenant, since
+// tools may act on them (e.g. workspace files or per-tenant credentials);
+// failed calls are not cached.
+func Cached(cache *Cache) Middleware {
+	return WrapExecute(func(next ExecuteFunc) ExecuteFunc {
+		return func(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error) {
+			ttl := cache.ttl(toolName)
+			if ttl <= 0 {
+				return next(ctx, toolName, params)
+			}
+
+			key := cacheKey(CallFrom(ctx), toolName, params)
+			if result, ok := cache.get(key); ok {
+				markCached(ctx)
+				return result, nil
+			}
+
+			result, err := next(ctx, toolName, params)
+			if err == nil {
+				cache.put(key, toolName, result, ttl)
+			}
+			return result, err
+		}
+	})
+}
+
+// cacheKey identifies a call of a tool with params from a node
+func cacheKey(call Call, toolName string, params map[string]interface{}) string {
+	sum := paramsSum(params)
+	return strings.Join([]string{call.Tenant, call.GraphID, call.NodeID, toolName, hex.EncodeToString(sum[:])}, "\x00")
+}
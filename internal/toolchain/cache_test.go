@@ -0,0 +1,123 @@
+package toolchain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// countingClient counts the calls it receives
+type countingClient struct {
+	calls int
+	err   error
+}
+
+func (c *countingClient) Execute(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.calls, nil
+}
+
+func (c *countingClient) ListTools(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
+
+func TestCached(t *testing.T) {
+	graphA := ContextWithCall(context.Background(), Call{GraphID: "g1", NodeID: "n1", Tenant: "acme"})
+	params := map[string]interface{}{"q": "go"}
+
+	tests := []struct {
+		name  string
+		first context.Context
+		tool  string
+		then  context.Context
+		other map[string]interface{}
+		calls int
+	}{
+		{name: "same call is cached", first: graphA, tool: "search", then: graphA, calls: 1},
+		{
+			name:  "other graph",
+			first: graphA, tool: "search",
+			then:  ContextWithCall(context.Background(), Call{GraphID: "g2", NodeID: "n1", Tenant: "acme"}),
+			calls: 2,
+		},
+		{
+			name:  "other node",
+			first: graphA, tool: "search",
+			then:  ContextWithCall(context.Background(), Call{GraphID: "g1", NodeID: "n2", Tenant: "acme"}),
+			calls: 2,
+		},
+		{
+			name:  "other tenant",
+			first: graphA, tool: "search",
+			then:  ContextWithCall(context.Background(), Call{GraphID: "g1", NodeID: "n1", Tenant: "globex"}),
+			calls: 2,
+		},
+		{name: "other params", first: graphA, tool: "search", then: graphA, other: map[string]interface{}{"q": "rust"}, calls: 2},
+		{name: "tool without a ttl", first: graphA, tool: "write", then: graphA, calls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := &countingClient{}
+			client := Chain(base, Cached(NewCache(map[string]time.Duration{"search": time.Minute}, 10)))
+
+			if _, err := client.Execute(tt.first, tt.tool, params); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			then := params
+			if tt.other != nil {
+				then = tt.other
+			}
+			if _, err := client.Execute(tt.then, tt.tool, then); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if base.calls != tt.calls {
+				t.Errorf("calls = %d, want %d", base.calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestCachedSkipsErrors(t *testing.T) {
+	base := &countingClient{err: errors.New("boom")}
+	cache := NewCache(map[string]time.Duration{"search": time.Minute}, 10)
+	client := Chain(base, Cached(cache))
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Execute(context.Background(), "search", nil); err == nil {
+			t.Fatal("Execute() error = nil, want the tool's error")
+		}
+	}
+	if base.calls != 2 || cache.Len() != 0 {
+		t.Errorf("calls = %d, cached = %d; want 2 calls and nothing cached", base.calls, cache.Len())
+	}
+}
+
+func TestCacheExpiryAndEviction(t *testing.T) {
+	cache := NewCache(map[string]time.Duration{"search": time.Minute}, 2)
+
+	cache.put("expired", "search", 1, -time.Second)
+	if _, ok := cache.get("expired"); ok {
+		t.Error("get() returned an expired result")
+	}
+
+	cache.put("a", "search", 1, time.Minute)
+	cache.put("b", "search", 2, time.Minute)
+	cache.get("a")
+	cache.put("c", "search", 3, time.Minute)
+	if _, ok := cache.get("b"); ok {
+		t.Error("least recently used result was not evicted")
+	}
+	if _, ok := cache.get("a"); !ok {
+		t.Error("recently used result was evicted")
+	}
+
+	cache.SetTTLs(map[string]time.Duration{})
+	if cache.Len() != 0 {
+		t.Errorf("Len() = %d after the tool's TTL was removed, want 0", cache.Len())
+	}
+}
@@ -0,0 +1,27 @@
+package toolchain
+
+import (
+	"context"
+)
+
+// Call identifies the node execution a tool call belongs to
+type Call struct {
+	GraphID string
+	NodeID  string
+
+	// Tenant is the tenant the graph runs for, if any
+	Tenant string
+}
+
+type callKey struct{}
+
+// ContextWithCall returns a context whose tool calls belong to call
+func ContextWithCall(ctx context.Context, call Call) context.Context {
+	return context.WithValue(ctx, callKey{}, call)
+}
+
+// CallFrom returns the call in ctx, or an empty one
+func CallFrom(ctx context.Context) Call {
+	call, _ := ctx.Value(callKey{}).(Call)
+	return call
+}
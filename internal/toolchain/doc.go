@@ -0,0 +1,12 @@
+// Package toolchain composes middleware around the worker's tool client.
+//
+// A Middleware wraps a ToolClient and returns another; Chain applies
+// several in order, the first being the outermost. The package provides
+// middlewares for an audit log of every call (Audit) with secret
+// parameters redacted (Redactor), policy hooks run before and after calls
+// (Before, After), a result cache for read-only tools (Cached) and the
+// per-tool limits of a ratelimit.ToolLimiter (Limit).
+//
+// The executor tags the context of each node execution with its graph and
+// node (ContextWithCall), which middlewares read with CallFrom.
+package toolchain
@@ -0,0 +1,48 @@
+package toolchain
+
+import (
+	"context"
+)
+
+// BeforeHook runs before a tool call. It may return replacement params, or
+// an error to refuse the call without running it; errors with a
+// ToolResult() map[string]interface{} method are reported to the agent as
+// that result.
+type BeforeHook func(ctx context.Context, toolName string, params map[string]interface{}) (map[string]interface{}, error)
+
+// AfterHook runs after a tool call, refused or not, and may replace its
+// result and error
+type AfterHook func(ctx context.Context, toolName string, params map[string]interface{}, result interface{}, err error) (interface{}, error)
+
+// Before runs hooks in order before each call. A nil params return keeps
+// the params unchanged.
+func Before(hooks ...BeforeHook) Middleware {
+	return WrapExecute(func(next ExecuteFunc) ExecuteFunc {
+		return func(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error) {
+			for _, hook := range hooks {
+				replaced, err := hook(ctx, toolName, params)
+				if err != nil {
+					return nil, err
+				}
+				if replaced != nil {
+					params = replaced
+				}
+			}
+			return next(ctx, toolName, params)
+		}
+	})
+}
+
+// After runs hooks in order after each call, each seeing the result and
+// error left by the previous one
+func After(hooks ...AfterHook) Middleware {
+	return WrapExecute(func(next ExecuteFunc) ExecuteFunc {
+		return func(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error) {
+			result, err := next(ctx, toolName, params)
+			for _, hook := range hooks {
+				result, err = hook(ctx, toolName, params, result, err)
+			}
+			return result, err
+		}
+	})
+}
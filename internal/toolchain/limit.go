@@ -0,0 +1,51 @@
+package toolchain
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aescanero/dago-node-executor/internal/ratelimit"
+	"github.com/aescanero/dago-node-executor/pkg/tools/api"
+	"github.com/aescanero/dago-node-executor/pkg/tools/catalog"
+	"github.com/aescanero/dago-node-executor/pkg/tools/function"
+	"github.com/aescanero/dago-node-executor/pkg/tools/mcp"
+)
+
+// Limit applies the limiter's per-tool policies to every call. Rejected
+// calls fail with a *ratelimit.ToolUnavailableError without reaching the
+// tool.
+func Limit(limiter *ratelimit.ToolLimiter) Middleware {
+	return WrapExecute(func(next ExecuteFunc) ExecuteFunc {
+		return func(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error) {
+			release, err := limiter.Acquire(ctx, toolName)
+			if err != nil {
+				return nil, err
+			}
+
+			result, err := next(ctx, toolName, params)
+			release(err)
+			return result, err
+		}
+	})
+}
+
+// BackendFailure reports whether a tool call failed in its transport or
+// backend. Errors about the call itself do not say the tool is failing:
+// invalid or disallowed params, unknown tools, failures an MCP tool
+// reports for the call, and cancellation.
+func BackendFailure(err error) bool {
+	var rpcErr *mcp.RPCError
+	var toolErr *mcp.ToolError
+	switch {
+	case err == nil,
+		errors.Is(err, context.Canceled),
+		errors.Is(err, function.ErrInvalidParams),
+		errors.Is(err, api.ErrNotAllowed),
+		errors.Is(err, catalog.ErrToolNotFound),
+		errors.As(err, &toolErr):
+		return false
+	case errors.As(err, &rpcErr):
+		return !rpcErr.InvalidCall()
+	}
+	return true
+}
@@ -0,0 +1,41 @@
+package toolchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aescanero/dago-node-executor/pkg/tools/api"
+	"github.com/aescanero/dago-node-executor/pkg/tools/catalog"
+	"github.com/aescanero/dago-node-executor/pkg/tools/function"
+	"github.com/aescanero/dago-node-executor/pkg/tools/mcp"
+)
+
+func TestBackendFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"success", nil, false},
+		{"transport error", errors.New("connection refused"), true},
+		{"deadline", context.DeadlineExceeded, true},
+		{"cancelled", fmt.Errorf("call: %w", context.Canceled), false},
+		{"invalid params", fmt.Errorf("%w for search: q is required", function.ErrInvalidParams), false},
+		{"request not allowed", fmt.Errorf("%w: unknown endpoint", api.ErrNotAllowed), false},
+		{"unknown tool", fmt.Errorf("%w: search", catalog.ErrToolNotFound), false},
+		{"tool reported error", &mcp.ToolError{Server: "s", Tool: "t", Message: "no such file"}, false},
+		{"MCP invalid params", &mcp.RPCError{Code: -32602, Message: "unknown tool"}, false},
+		{"MCP internal error", &mcp.RPCError{Code: -32603, Message: "internal error"}, true},
+		{"connection lost", fmt.Errorf("call: %w", mcp.ErrConnectionLost), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BackendFailure(tt.err); got != tt.want {
+				t.Errorf("BackendFailure(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package toolchain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Redacted replaces the values of secret parameters
+const Redacted = "[REDACTED]"
+
+// DefaultSecretKeys are parameter name fragments treated as secrets
+var DefaultSecretKeys = []string{
+	"password", "passwd", "secret", "token", "api_key", "apikey",
+	"authorization", "credential", "private_key", "cookie", "session",
+}
+
+// Redactor hides secret values in tool parameters before they are logged
+type Redactor struct {
+	keys []string
+}
+
+// NewRedactor creates a redactor hiding parameters whose name contains,
+// case-insensitively, one of DefaultSecretKeys or of extraKeys
+func NewRedactor(extraKeys ...string) *Redactor {
+	keys := make([]string, 0, len(DefaultSecretKeys)+len(extraKeys))
+	for _, key := range append(append([]string(nil), DefaultSecretKeys...), extraKeys...) {
+		if key = normalizeKey(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return &Redactor{keys: keys}
+}
+
+// Redact returns a copy of params with secret values replaced, in nested
+// objects and arrays too
+func (r *Redactor) Redact(params map[string]interface{}) map[string]interface{} {
+	if params == nil {
+		return nil
+	}
+	return r.redactValue(params).(map[string]interface{})
+}
+
+func (r *Redactor) redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, inner := range v {
+			if r.isSecret(key) {
+				redacted[key] = Redacted
+			} else {
+				redacted[key] = r.redactValue(inner)
+			}
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, inner := range v {
+			redacted[i] = r.redactValue(inner)
+		}
+		return redacted
+	default:
+		return value
+	}
+}
+
+// isSecret reports whether a parameter name looks like a secret
+func (r *Redactor) isSecret(key string) bool {
+	key = normalizeKey(key)
+	for _, secret := range r.keys {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeKey lower-cases a key and turns dashes into underscores, so
+// that apiKey, api-key and API_KEY look alike
+func normalizeKey(key string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(key)), "-", "_")
+}
+
+// HashParams returns a short stable hash of params, identifying calls with
+// the same arguments without revealing them
+func HashParams(params map[string]interface{}) string {
+	sum := paramsSum(params)
+	return hex.EncodeToString(sum[:8])
+}
+
+// paramsSum returns the SHA-256 of params in canonical form
+func paramsSum(params map[string]interface{}) [sha256.Size]byte {
+	// Maps are marshalled with sorted keys
+	data, err := json.Marshal(params)
+	if err != nil {
+		data = []byte(fmt.Sprintf("%v", params))
+	}
+	return sha256.Sum256(data)
+}
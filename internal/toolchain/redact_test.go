@@ -0,0 +1,80 @@
+package toolchain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name  string
+		extra []string
+		in    map[string]interface{}
+		want  map[string]interface{}
+	}{
+		{name: "nil", in: nil, want: nil},
+		{
+			name: "secret keys",
+			in:   map[string]interface{}{"query": "go", "api_key": "k", "Password": "p", "x-auth-token": "t"},
+			want: map[string]interface{}{"query": "go", "api_key": Redacted, "Password": Redacted, "x-auth-token": Redacted},
+		},
+		{
+			name: "spelling variants",
+			in:   map[string]interface{}{"apiKey": "k", "api-key": "k", "API_KEY": "k"},
+			want: map[string]interface{}{"apiKey": Redacted, "api-key": Redacted, "API_KEY": Redacted},
+		},
+		{
+			name: "nested objects and arrays",
+			in: map[string]interface{}{
+				"headers": map[string]interface{}{"Authorization": "Bearer x", "Accept": "json"},
+				"users":   []interface{}{map[string]interface{}{"name": "a", "secret": "s"}},
+			},
+			want: map[string]interface{}{
+				"headers": map[string]interface{}{"Authorization": Redacted, "Accept": "json"},
+				"users":   []interface{}{map[string]interface{}{"name": "a", "secret": Redacted}},
+			},
+		},
+		{
+			name: "whole secret objects",
+			in:   map[string]interface{}{"credentials": map[string]interface{}{"user": "u"}},
+			want: map[string]interface{}{"credentials": Redacted},
+		},
+		{
+			name:  "extra keys",
+			extra: []string{"SSN", " "},
+			in:    map[string]interface{}{"customer_ssn": "123", "name": "a"},
+			want:  map[string]interface{}{"customer_ssn": Redacted, "name": "a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewRedactor(tt.extra...).Redact(tt.in)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Redact() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactKeepsParams(t *testing.T) {
+	params := map[string]interface{}{"token": "t", "nested": map[string]interface{}{"password": "p"}}
+	NewRedactor().Redact(params)
+	if params["token"] != "t" || params["nested"].(map[string]interface{})["password"] != "p" {
+		t.Errorf("Redact() modified its input: %#v", params)
+	}
+}
+
+func TestHashParams(t *testing.T) {
+	a := HashParams(map[string]interface{}{"a": 1, "b": "x"})
+	b := HashParams(map[string]interface{}{"b": "x", "a": 1})
+	if a != b {
+		t.Errorf("HashParams() depends on key order: %s != %s", a, b)
+	}
+	if len(a) != 16 {
+		t.Errorf("HashParams() = %q, want 16 hex digits", a)
+	}
+	if a == HashParams(map[string]interface{}{"a": 2, "b": "x"}) {
+		t.Error("HashParams() is the same for different params")
+	}
+}
@@ -0,0 +1,65 @@
+package toolchain
+
+import (
+	"context"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+)
+
+// ToolClient executes tools. It has the method set of
+// executor.ToolClient.
+type ToolClient interface {
+	Execute(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error)
+	ListTools(ctx context.Context) ([]string, error)
+}
+
+// Describer is implemented by tool clients that describe their tools,
+// such as the tool catalog
+type Describer interface {
+	Definitions() []domain.Tool
+}
+
+// Middleware wraps a tool client
+type Middleware func(next ToolClient) ToolClient
+
+// Chain wraps client in middlewares, the first being the outermost
+func Chain(client ToolClient, middlewares ...Middleware) ToolClient {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		client = middlewares[i](client)
+	}
+	return client
+}
+
+// ExecuteFunc is the signature of ToolClient.Execute
+type ExecuteFunc func(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error)
+
+// executeOnly is a tool client that intercepts Execute and passes
+// ListTools through
+type executeOnly struct {
+	next    ToolClient
+	execute ExecuteFunc
+}
+
+func (c *executeOnly) Execute(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error) {
+	return c.execute(ctx, toolName, params)
+}
+
+func (c *executeOnly) ListTools(ctx context.Context) ([]string, error) {
+	return c.next.ListTools(ctx)
+}
+
+// Definitions passes the definitions of the wrapped client through
+func (c *executeOnly) Definitions() []domain.Tool {
+	if d, ok := c.next.(Describer); ok {
+		return d.Definitions()
+	}
+	return nil
+}
+
+// WrapExecute returns a middleware that replaces Execute with wrap(next)
+// and leaves ListTools and Definitions unchanged
+func WrapExecute(wrap func(next ExecuteFunc) ExecuteFunc) Middleware {
+	return func(next ToolClient) ToolClient {
+		return &executeOnly{next: next, execute: wrap(next.Execute)}
+	}
+}
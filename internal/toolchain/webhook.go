@@ -0,0 +1,255 @@
+package toolchain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultWebhookTimeout bounds a webhook request when no timeout is given
+const DefaultWebhookTimeout = 5 * time.Second
+
+// maxWebhookResponseBytes caps the webhook response read
+const maxWebhookResponseBytes = 1 << 20
+
+// ErrHookRefused is wrapped by HookError
+var ErrHookRefused = errors.New("tool call refused by hook")
+
+// HookError is returned for a call refused by a webhook, or whose webhook
+// failed without FailOpen. It is reported to the model as a structured
+// tool result.
+type HookError struct {
+	Hook   string
+	Tool   string
+	Reason string
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("tool %s refused by hook %s: %s", e.Tool, e.Hook, e.Reason)
+}
+
+func (e *HookError) Unwrap() error { return ErrHookRefused }
+
+// ToolResult is the result reported to the model instead of the call
+func (e *HookError) ToolResult() map[string]interface{} {
+	return map[string]interface{}{
+		"error":         e.Error(),
+		"tool":          e.Tool,
+		"not_permitted": true,
+		"hook":          e.Hook,
+		"reason":        e.Reason,
+	}
+}
+
+// WebhookConfig configures a policy service consulted about tool calls
+type WebhookConfig struct {
+	Name string
+	URL  string
+
+	// Tools are globs of the tools the hook applies to (default all)
+	Tools []string
+
+	// Timeout bounds a request (default 5s)
+	Timeout time.Duration
+
+	// FailOpen lets calls through when the service fails; otherwise they
+	// are refused
+	FailOpen bool
+
+	// Headers are added to every request, e.g. for credentials
+	Headers map[string]string
+
+	// Client sends the requests (default http.DefaultClient)
+	Client *http.Client
+
+	Logger *zap.Logger
+}
+
+// Webhook asks a policy service whether tool calls may run, before they
+// run, or may return their result, after.
+//
+// Each request is a JSON POST of a WebhookRequest. The service answers
+// with a WebhookResponse: allow false refuses the call with reason,
+// params replace the params of a call about to run, and result replaces
+// the result of a call that ran.
+type Webhook struct {
+	cfg WebhookConfig
+}
+
+// WebhookRequest is the body of a webhook request
+type WebhookRequest struct {
+	Phase   string                 `json:"phase"`
+	Tool    string                 `json:"tool"`
+	Params  map[string]interface{} `json:"params"`
+	GraphID string                 `json:"graph_id"`
+	NodeID  string                 `json:"node_id"`
+	Tenant  string                 `json:"tenant,omitempty"`
+
+	// Result and Error are set after the call
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// WebhookResponse is the body of a webhook response
+type WebhookResponse struct {
+	// Allow defaults to true when absent
+	Allow  *bool                  `json:"allow"`
+	Reason string                 `json:"reason"`
+	Params map[string]interface{} `json:"params"`
+	Result json.RawMessage        `json:"result"`
+}
+
+// Hook phases
+const (
+	PhaseBefore = "before"
+	PhaseAfter  = "after"
+)
+
+// NewWebhook creates a webhook
+func NewWebhook(cfg WebhookConfig) *Webhook {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultWebhookTimeout
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = zap.NewNop()
+	}
+	return &Webhook{cfg: cfg}
+}
+
+// Before returns a hook asking the service before each call
+func (w *Webhook) Before() BeforeHook {
+	return func(ctx context.Context, toolName string, params map[string]interface{}) (map[string]interface{}, error) {
+		if !w.applies(toolName) {
+			return nil, nil
+		}
+		resp, err := w.post(ctx, w.request(ctx, PhaseBefore, toolName, params))
+		if err != nil {
+			return nil, w.failed(ctx, toolName, err)
+		}
+		if resp.Allow != nil && !*resp.Allow {
+			return nil, &HookError{Hook: w.cfg.Name, Tool: toolName, Reason: resp.Reason}
+		}
+		return resp.Params, nil
+	}
+}
+
+// After returns a hook asking the service after each call, refused or
+// not
+func (w *Webhook) After() AfterHook {
+	return func(ctx context.Context, toolName string, params map[string]interface{}, result interface{}, callErr error) (interface{}, error) {
+		if !w.applies(toolName) {
+			return result, callErr
+		}
+		req := w.request(ctx, PhaseAfter, toolName, params)
+		req.Result = result
+		if callErr != nil {
+			req.Error = callErr.Error()
+		}
+		resp, err := w.post(ctx, req)
+		if err != nil {
+			if failErr := w.failed(ctx, toolName, err); failErr != nil {
+				return nil, failErr
+			}
+			return result, callErr
+		}
+		if resp.Allow != nil && !*resp.Allow {
+			return nil, &HookError{Hook: w.cfg.Name, Tool: toolName, Reason: resp.Reason}
+		}
+		if len(resp.Result) > 0 {
+			var replaced interface{}
+			if err := json.Unmarshal(resp.Result, &replaced); err != nil {
+				return nil, w.failed(ctx, toolName, fmt.Errorf("invalid result: %w", err))
+			}
+			return replaced, nil
+		}
+		return result, callErr
+	}
+}
+
+// applies reports whether the hook applies to a tool
+func (w *Webhook) applies(tool string) bool {
+	if len(w.cfg.Tools) == 0 {
+		return true
+	}
+	for _, glob := range w.cfg.Tools {
+		if matched, _ := path.Match(glob, tool); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// request describes a call to the service
+func (w *Webhook) request(ctx context.Context, phase, tool string, params map[string]interface{}) WebhookRequest {
+	call := CallFrom(ctx)
+	return WebhookRequest{
+		Phase:   phase,
+		Tool:    tool,
+		Params:  params,
+		GraphID: call.GraphID,
+		NodeID:  call.NodeID,
+		Tenant:  call.Tenant,
+	}
+}
+
+// post sends a request to the service
+func (w *Webhook) post(ctx context.Context, body WebhookRequest) (*WebhookResponse, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, w.cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.cfg.URL, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range w.cfg.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := w.cfg.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	var decoded WebhookResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxWebhookResponseBytes)).Decode(&decoded); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	return &decoded, nil
+}
+
+// failed handles a failed request: nil lets the call through when the hook
+// fails open, otherwise the call is refused
+func (w *Webhook) failed(ctx context.Context, tool string, err error) error {
+	call := CallFrom(ctx)
+	w.cfg.Logger.Warn("tool hook failed",
+		zap.String("hook", w.cfg.Name),
+		zap.String("graph_id", call.GraphID),
+		zap.String("node_id", call.NodeID),
+		zap.String("tool", tool),
+		zap.Bool("fail_open", w.cfg.FailOpen),
+		zap.Error(err))
+	if w.cfg.FailOpen {
+		return nil
+	}
+	return &HookError{Hook: w.cfg.Name, Tool: tool, Reason: "hook unavailable"}
+}
@@ -0,0 +1,126 @@
+package toolchain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// echoClient returns the params it receives
+type echoClient struct{}
+
+func (echoClient) Execute(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error) {
+	return params, nil
+}
+
+func (echoClient) ListTools(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
+
+func TestWebhook(t *testing.T) {
+	var got WebhookRequest
+	var gotAuth string
+	answer := ""
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = WebhookRequest{}
+		gotAuth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(answer))
+	}))
+	defer srv.Close()
+
+	ctx := ContextWithCall(context.Background(), Call{GraphID: "g1", NodeID: "n1", Tenant: "acme"})
+	params := map[string]interface{}{"repo": "acme/web"}
+
+	tests := []struct {
+		name     string
+		phase    string
+		tools    []string
+		failOpen bool
+		status   int
+		answer   string
+		refused  bool
+		want     interface{}
+		skipped  bool
+	}{
+		{name: "empty answer allows", phase: PhaseBefore, want: params},
+		{name: "allow", phase: PhaseBefore, answer: `{"allow": true}`, want: params},
+		{name: "refuse", phase: PhaseBefore, answer: `{"allow": false, "reason": "needs approval"}`, refused: true},
+		{
+			name:   "rewrite params",
+			phase:  PhaseBefore,
+			answer: `{"params": {"repo": "acme/sandbox"}}`,
+			want:   map[string]interface{}{"repo": "acme/sandbox"},
+		},
+		{name: "failure refuses", phase: PhaseBefore, status: http.StatusBadGateway, refused: true},
+		{name: "failure with fail open", phase: PhaseBefore, status: http.StatusBadGateway, failOpen: true, want: params},
+		{name: "invalid answer refuses", phase: PhaseBefore, answer: `{"allow": "yes"}`, refused: true},
+		{name: "other tools are skipped", phase: PhaseBefore, tools: []string{"slack__*"}, answer: `{"allow": false}`, want: params, skipped: true},
+		{name: "after keeps the result", phase: PhaseAfter, want: params},
+		{name: "after replaces the result", phase: PhaseAfter, answer: `{"result": {"redacted": true}}`, want: map[string]interface{}{"redacted": true}},
+		{name: "after refuses the result", phase: PhaseAfter, answer: `{"allow": false, "reason": "leaks"}`, refused: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answer = tt.answer
+			status = tt.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			got = WebhookRequest{Phase: "unset"}
+			hook := NewWebhook(WebhookConfig{
+				Name:     "policy",
+				URL:      srv.URL,
+				Tools:    tt.tools,
+				FailOpen: tt.failOpen,
+				Headers:  map[string]string{"Authorization": "Bearer hook"},
+			})
+			middleware := Before(hook.Before())
+			if tt.phase == PhaseAfter {
+				middleware = After(hook.After())
+			}
+
+			result, err := Chain(echoClient{}, middleware).Execute(ctx, "github__create_issue", params)
+			if tt.refused {
+				var hookErr *HookError
+				if !errors.As(err, &hookErr) || !errors.Is(err, ErrHookRefused) {
+					t.Fatalf("Execute() error = %v, want a HookError", err)
+				}
+				if hookErr.ToolResult()["not_permitted"] != true {
+					t.Errorf("ToolResult() = %v, want not_permitted", hookErr.ToolResult())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			resultJSON, _ := json.Marshal(result)
+			wantJSON, _ := json.Marshal(tt.want)
+			if string(resultJSON) != string(wantJSON) {
+				t.Errorf("result = %s, want %s", resultJSON, wantJSON)
+			}
+
+			if tt.skipped {
+				if got.Phase != "unset" {
+					t.Errorf("hook was called for a tool it doesn't apply to")
+				}
+				return
+			}
+			if got.Phase != tt.phase || got.Tool != "github__create_issue" || got.GraphID != "g1" || got.NodeID != "n1" || got.Tenant != "acme" {
+				t.Errorf("request = %+v, want the call and its graph", got)
+			}
+			if tt.phase == PhaseAfter && got.Result == nil {
+				t.Errorf("after request has no result")
+			}
+			if gotAuth != "Bearer hook" {
+				t.Errorf("Authorization = %q, want the configured header", gotAuth)
+			}
+		})
+	}
+}
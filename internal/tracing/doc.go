@@ -0,0 +1,6 @@
+// Package tracing provides OpenTelemetry tracing for the executor worker.
+//
+// Spans are exported via OTLP/HTTP. Trace context travels with work items
+// and published events in a W3C traceparent field, so a graph run appears
+// as a single distributed trace together with the orchestrator.
+package tracing
@@ -0,0 +1,146 @@
+package worker
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// AdminHandler serves the operator API for controlling a running worker.
+// All endpoints require "Authorization: Bearer <token>".
+//
+//	GET    /admin/status                             worker state summary
+//	POST   /admin/pause                              stop consuming new work
+//	POST   /admin/resume                             resume consuming work
+//	POST   /admin/drain                              finish in-flight work, then exit
+//	GET    /admin/inflight                           list in-flight nodes
+//	DELETE /admin/inflight/{graph_id}/{node_id}      cancel an in-flight node
+//	GET    /admin/loglevel                           current log level
+//	PUT    /admin/loglevel                           change log level ({"level":"debug"})
+type AdminHandler struct {
+	worker *Worker
+	token  string
+	level  zap.AtomicLevel
+	logger *zap.Logger
+	mux    *http.ServeMux
+}
+
+// NewAdminHandler creates the admin API handler. level is the logger's
+// atomic level, changed in place by the loglevel endpoint.
+func NewAdminHandler(worker *Worker, token string, level zap.AtomicLevel, logger *zap.Logger) *AdminHandler {
+	h := &AdminHandler{
+		worker: worker,
+		token:  token,
+		level:  level,
+		logger: logger,
+		mux:    http.NewServeMux(),
+	}
+
+	h.mux.HandleFunc("GET /admin/status", h.handleStatus)
+	h.mux.HandleFunc("POST /admin/pause", h.handlePause)
+	h.mux.HandleFunc("POST /admin/resume", h.handleResume)
+	h.mux.HandleFunc("POST /admin/drain", h.handleDrain)
+	h.mux.HandleFunc("GET /admin/inflight", h.handleInFlight)
+	h.mux.HandleFunc("DELETE /admin/inflight/{graph_id}/{node_id}", h.handleCancel)
+	h.mux.Handle("/admin/loglevel", level)
+
+	return h
+}
+
+// ServeHTTP authenticates the request and dispatches it
+func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{
+			"error": "unauthorized",
+		})
+		return
+	}
+
+	h.logger.Info("admin request",
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+		zap.String("remote_addr", r.RemoteAddr))
+
+	h.mux.ServeHTTP(w, r)
+}
+
+// authorized checks the bearer token in constant time
+func (h *AdminHandler) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || h.token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+func (h *AdminHandler) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.status())
+}
+
+func (h *AdminHandler) handlePause(w http.ResponseWriter, r *http.Request) {
+	h.worker.Pause()
+	writeJSON(w, http.StatusOK, h.status())
+}
+
+func (h *AdminHandler) handleResume(w http.ResponseWriter, r *http.Request) {
+	if h.worker.IsDraining() {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{
+			"error": "worker is draining",
+		})
+		return
+	}
+
+	h.worker.Resume()
+	writeJSON(w, http.StatusOK, h.status())
+}
+
+func (h *AdminHandler) handleDrain(w http.ResponseWriter, r *http.Request) {
+	h.worker.Drain()
+	writeJSON(w, http.StatusAccepted, h.status())
+}
+
+func (h *AdminHandler) handleInFlight(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"nodes": h.worker.InFlight(),
+	})
+}
+
+func (h *AdminHandler) handleCancel(w http.ResponseWriter, r *http.Request) {
+	graphID := r.PathValue("graph_id")
+	nodeID := r.PathValue("node_id")
+
+	if !h.worker.CancelNode(graphID, nodeID) {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{
+			"error": "node not in flight",
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"graph_id":  graphID,
+		"node_id":   nodeID,
+		"cancelled": true,
+	})
+}
+
+// status summarizes the worker state
+func (h *AdminHandler) status() map[string]interface{} {
+	return map[string]interface{}{
+		"worker_id":      h.worker.id,
+		"paused":         h.worker.IsPaused(),
+		"draining":       h.worker.IsDraining(),
+		"in_flight":      len(h.worker.InFlight()),
+		"last_processed": h.worker.GetLastProcessed(),
+		"log_level":      h.level.Level().String(),
+	}
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(body)
+}
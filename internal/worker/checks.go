@@ -0,0 +1,306 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// CheckStatus is the outcome of a health check
+type CheckStatus string
+
+const (
+	// StatusHealthy: the dependency works as expected
+	StatusHealthy CheckStatus = "healthy"
+
+	// StatusDegraded: the worker can still process some work
+	StatusDegraded CheckStatus = "degraded"
+
+	// StatusUnhealthy: the worker cannot process work
+	StatusUnhealthy CheckStatus = "unhealthy"
+)
+
+// severity orders statuses from best to worst
+func (s CheckStatus) severity() int {
+	switch s {
+	case StatusHealthy:
+		return 0
+	case StatusDegraded:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// CheckResult is the result of a single health check
+type CheckResult struct {
+	Status     CheckStatus            `json:"status"`
+	Message    string                 `json:"message,omitempty"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+	DurationMS int64                  `json:"duration_ms"`
+}
+
+// HealthCheck is a pluggable health probe
+type HealthCheck interface {
+	// Name identifies the check in reports
+	Name() string
+
+	// Check probes the dependency. Implementations must honor ctx.
+	Check(ctx context.Context) CheckResult
+}
+
+// HealthReport aggregates the results of all registered checks
+type HealthReport struct {
+	Status CheckStatus            `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// checkEntry is a registered check. A failing non-critical check only
+// degrades the overall status.
+type checkEntry struct {
+	check    HealthCheck
+	critical bool
+}
+
+// Checker runs registered health checks concurrently
+type Checker struct {
+	timeout time.Duration
+
+	mu     sync.RWMutex
+	checks []checkEntry
+}
+
+// NewChecker creates a checker applying timeout to each check
+func NewChecker(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return &Checker{timeout: timeout}
+}
+
+// Register adds a check. Unhealthy results of non-critical checks are
+// reported as degraded in the overall status.
+func (c *Checker) Register(check HealthCheck, critical bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, checkEntry{check: check, critical: critical})
+}
+
+// Run executes all checks and aggregates their results
+func (c *Checker) Run(ctx context.Context) HealthReport {
+	c.mu.RLock()
+	checks := make([]checkEntry, len(c.checks))
+	copy(checks, c.checks)
+	c.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+
+	var wg sync.WaitGroup
+	for i, entry := range checks {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			start := time.Now()
+			result := check.Check(checkCtx)
+			result.DurationMS = time.Since(start).Milliseconds()
+			results[i] = result
+		}(i, entry.check)
+	}
+	wg.Wait()
+
+	report := HealthReport{
+		Status: StatusHealthy,
+		Checks: make(map[string]CheckResult, len(checks)),
+	}
+	for i, entry := range checks {
+		result := results[i]
+		report.Checks[entry.check.Name()] = result
+
+		effective := result.Status
+		if !entry.critical && effective == StatusUnhealthy {
+			effective = StatusDegraded
+		}
+		if effective.severity() > report.Status.severity() {
+			report.Status = effective
+		}
+	}
+
+	return report
+}
+
+// CheckFunc adapts a function to the HealthCheck interface
+type CheckFunc struct {
+	name string
+	fn   func(ctx context.Context) CheckResult
+}
+
+// NewCheckFunc creates a named check from a function
+func NewCheckFunc(name string, fn func(ctx context.Context) CheckResult) *CheckFunc {
+	return &CheckFunc{name: name, fn: fn}
+}
+
+// Name returns the check name
+func (c *CheckFunc) Name() string {
+	return c.name
+}
+
+// Check runs the check function
+func (c *CheckFunc) Check(ctx context.Context) CheckResult {
+	return c.fn(ctx)
+}
+
+// healthy and unhealthy are shorthands for building results
+func healthy(details map[string]interface{}) CheckResult {
+	return CheckResult{Status: StatusHealthy, Details: details}
+}
+
+func unhealthy(err error) CheckResult {
+	return CheckResult{Status: StatusUnhealthy, Message: err.Error()}
+}
+
+// MCPServerProber is the subset of the MCP client used by the MCP check
+type MCPServerProber interface {
+	Servers() []string
+	Ping(ctx context.Context, server string) error
+	ListServerTools(ctx context.Context, server string) ([]string, error)
+}
+
+// NewMCPCheck checks that every configured MCP server is reachable and
+// reports its tool count. Servers are read on each run so configuration
+// reloads are picked up.
+func NewMCPCheck(client MCPServerProber) HealthCheck {
+	return NewCheckFunc("mcp", func(ctx context.Context) CheckResult {
+		servers := client.Servers()
+		details := make(map[string]interface{}, len(servers))
+		status := StatusHealthy
+
+		for _, server := range servers {
+			serverStatus := map[string]interface{}{"status": StatusHealthy}
+			details[server] = serverStatus
+
+			if err := client.Ping(ctx, server); err != nil {
+				serverStatus["status"] = StatusUnhealthy
+				serverStatus["error"] = fmt.Sprintf("unreachable: %v", err)
+				status = StatusUnhealthy
+				continue
+			}
+
+			tools, err := client.ListServerTools(ctx, server)
+			if err != nil {
+				serverStatus["status"] = StatusDegraded
+				serverStatus["error"] = fmt.Sprintf("failed to list tools: %v", err)
+				if status == StatusHealthy {
+					status = StatusDegraded
+				}
+				continue
+			}
+			serverStatus["tool_count"] = len(tools)
+		}
+
+		result := CheckResult{Status: status, Details: details}
+		if status != StatusHealthy {
+			result.Message = "one or more MCP servers are not healthy"
+		}
+		return result
+	})
+}
+
+// llmCheckTimeout bounds the provider call of the LLM check, which runs
+// off the probe path
+const llmCheckTimeout = 30 * time.Second
+
+// llmCheck validates LLM provider credentials with a minimal completion.
+// The provider is called in the background at most once per ttl, so probes
+// neither wait for it nor generate provider traffic.
+type llmCheck struct {
+	client  ports.LLMClient
+	model   string
+	ttl     time.Duration
+	timeout time.Duration
+
+	mu        sync.Mutex
+	last      CheckResult
+	checkedAt time.Time
+	running   bool
+}
+
+// NewLLMCheck creates a check validating provider credentials for model.
+// The provider is called at most once per ttl.
+func NewLLMCheck(client ports.LLMClient, model string, ttl time.Duration) HealthCheck {
+	return &llmCheck{
+		client:  client,
+		model:   model,
+		ttl:     ttl,
+		timeout: llmCheckTimeout,
+	}
+}
+
+// Name returns the check name
+func (c *llmCheck) Name() string {
+	return "llm"
+}
+
+// Check returns the last result, starting a provider call in the
+// background when it is older than ttl. Until the first call completes the
+// check reports healthy.
+func (c *llmCheck) Check(ctx context.Context) CheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.running && (c.checkedAt.IsZero() || time.Since(c.checkedAt) >= c.ttl) {
+		c.running = true
+		go c.refresh()
+	}
+	if c.checkedAt.IsZero() {
+		return healthy(map[string]interface{}{"model": c.model, "pending": true})
+	}
+	return c.last
+}
+
+// refresh calls the provider and caches the result, failed or not, for ttl
+func (c *llmCheck) refresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	var result CheckResult
+	_, err := c.client.GenerateCompletion(ctx, &domain.LLMRequest{
+		Model:     c.model,
+		Messages:  []domain.Message{{Role: "user", Content: "ping"}},
+		MaxTokens: 1,
+	})
+	switch {
+	case err != nil && ctx.Err() != nil:
+		result = unhealthy(fmt.Errorf("LLM provider check timed out after %s: %w", c.timeout, err))
+	case err != nil:
+		result = unhealthy(fmt.Errorf("LLM provider check failed: %w", err))
+	default:
+		result = healthy(map[string]interface{}{"model": c.model})
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checkedAt = time.Now()
+	c.last = result
+	c.last.Details = mergeDetails(c.last.Details, map[string]interface{}{
+		"checked_at": c.checkedAt,
+	})
+	c.running = false
+}
+
+func mergeDetails(a, b map[string]interface{}) map[string]interface{} {
+	if a == nil {
+		a = make(map[string]interface{}, len(b))
+	}
+	for k, v := range b {
+		a[k] = v
+	}
+	return a
+}
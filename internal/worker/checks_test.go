@@ -0,0 +1,89 @@
+package worker
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aescanero/dago-node-executor/internal/fakellm"
+)
+
+// checked waits for the LLM check's background call to complete
+func checked(t *testing.T, c *llmCheck) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		c.mu.Lock()
+		done := !c.running
+		c.mu.Unlock()
+		if done {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("LLM check still running")
+}
+
+func TestLLMCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		resp    fakellm.Response
+		status  CheckStatus
+		message string
+	}{
+		{"healthy", fakellm.Response{Content: "pong"}, StatusHealthy, ""},
+		{"failure", fakellm.Response{Error: fakellm.ErrorServer}, StatusUnhealthy, "check failed"},
+		{"timeout", fakellm.Response{Error: fakellm.ErrorTimeout}, StatusUnhealthy, "timed out"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm := fakellm.New(tt.resp)
+			c := NewLLMCheck(llm, "fake", time.Hour).(*llmCheck)
+			c.timeout = 50 * time.Millisecond
+
+			// The probe doesn't wait for the provider
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			if got := c.Check(ctx); got.Status != StatusHealthy || got.Details["pending"] != true {
+				t.Errorf("first Check() = %+v, want healthy and pending", got)
+			}
+			checked(t, c)
+
+			// The result, failed or not, is cached for the interval
+			for i := 0; i < 3; i++ {
+				got := c.Check(context.Background())
+				if got.Status != tt.status || !strings.Contains(got.Message, tt.message) {
+					t.Fatalf("Check() = %+v, want %s with %q", got, tt.status, tt.message)
+				}
+			}
+			if n := len(llm.Requests()); n != 1 {
+				t.Errorf("provider called %d times, want once", n)
+			}
+		})
+	}
+}
+
+func TestLLMCheckRefreshes(t *testing.T) {
+	llm := fakellm.New(fakellm.Response{Error: fakellm.ErrorServer}, fakellm.Response{Content: "pong"})
+	c := NewLLMCheck(llm, "fake", time.Hour).(*llmCheck)
+	c.Check(context.Background())
+	checked(t, c)
+
+	// Once the interval passed, the stale result is served while the
+	// provider is called again
+	c.mu.Lock()
+	c.checkedAt = c.checkedAt.Add(-time.Hour)
+	c.mu.Unlock()
+	if got := c.Check(context.Background()); got.Status != StatusUnhealthy {
+		t.Errorf("Check() = %+v, want the stale failure", got)
+	}
+	checked(t, c)
+	if got := c.Check(context.Background()); got.Status != StatusHealthy {
+		t.Errorf("Check() after the refresh = %+v, want healthy", got)
+	}
+	if n := len(llm.Requests()); n != 2 {
+		t.Errorf("provider called %d times, want twice", n)
+	}
+}
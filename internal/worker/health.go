@@ -2,7 +2,6 @@ package worker
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
@@ -12,18 +11,31 @@ import (
 
 // HealthServer provides health check endpoints
 type HealthServer struct {
-	worker *Worker
-	logger *zap.Logger
-	server *http.Server
+	worker  *Worker
+	checker *Checker
+	logger  *zap.Logger
+	mux     *http.ServeMux
+	server  *http.Server
 }
 
 // NewHealthServer creates a new health server
 func NewHealthServer(worker *Worker, port int, logger *zap.Logger) *HealthServer {
 	mux := http.NewServeMux()
 
+	checker := NewChecker(0)
+	for _, check := range worker.BackendChecks() {
+		checker.Register(check, true)
+	}
+	if check := worker.ConsumerGroupCheck(); check != nil {
+		checker.Register(check, true)
+	}
+	checker.Register(worker.LoopCheck(), true)
+
 	hs := &HealthServer{
-		worker: worker,
-		logger: logger,
+		worker:  worker,
+		checker: checker,
+		logger:  logger,
+		mux:     mux,
 		server: &http.Server{
 			Addr:    fmt.Sprintf(":%d", port),
 			Handler: mux,
@@ -36,6 +48,18 @@ func NewHealthServer(worker *Worker, port int, logger *zap.Logger) *HealthServer
 	return hs
 }
 
+// AddCheck registers an additional health check. Unhealthy results of
+// non-critical checks only degrade the reported status.
+func (hs *HealthServer) AddCheck(check HealthCheck, critical bool) {
+	hs.checker.Register(check, critical)
+}
+
+// Handle registers an additional handler (e.g. /metrics) on the health server.
+// It must be called before Start.
+func (hs *HealthServer) Handle(pattern string, handler http.Handler) {
+	hs.mux.Handle(pattern, handler)
+}
+
 // Start starts the health server
 func (hs *HealthServer) Start() error {
 	hs.logger.Info("starting health server", zap.String("addr", hs.server.Addr))
@@ -56,36 +80,48 @@ func (hs *HealthServer) Stop(ctx context.Context) error {
 
 // handleHealth handles health check requests
 func (hs *HealthServer) handleHealth(w http.ResponseWriter, r *http.Request) {
-	isHealthy := hs.worker.IsHealthy()
+	report := hs.checker.Run(r.Context())
 
-	status := "healthy"
 	statusCode := http.StatusOK
+	if report.Status == StatusUnhealthy {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	hs.writeReport(w, statusCode, report)
+}
+
+// handleReady handles readiness check requests
+func (hs *HealthServer) handleReady(w http.ResponseWriter, r *http.Request) {
+	// A draining worker must not receive traffic, regardless of dependencies
+	if hs.worker.IsDraining() {
+		hs.writeReport(w, http.StatusServiceUnavailable, HealthReport{
+			Status: StatusUnhealthy,
+			Checks: map[string]CheckResult{},
+		})
+		return
+	}
 
-	if !isHealthy {
-		status = "unhealthy"
+	report := hs.checker.Run(r.Context())
+
+	// Degraded workers still accept work
+	statusCode := http.StatusOK
+	if report.Status == StatusUnhealthy {
 		statusCode = http.StatusServiceUnavailable
 	}
 
+	hs.writeReport(w, statusCode, report)
+}
+
+// writeReport writes a health report as JSON
+func (hs *HealthServer) writeReport(w http.ResponseWriter, statusCode int, report HealthReport) {
 	response := map[string]interface{}{
-		"status":         status,
+		"status":         report.Status,
 		"worker_id":      hs.worker.id,
+		"draining":       hs.worker.IsDraining(),
 		"last_processed": hs.worker.GetLastProcessed(),
+		"checks":         report.Checks,
 		"timestamp":      time.Now(),
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	_ = json.NewEncoder(w).Encode(response)
-}
-
-// handleReady handles readiness check requests
-func (hs *HealthServer) handleReady(w http.ResponseWriter, r *http.Request) {
-	// Worker is ready if it's healthy
-	if hs.worker.IsHealthy() {
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("ready"))
-	} else {
-		w.WriteHeader(http.StatusServiceUnavailable)
-		_, _ = w.Write([]byte("not ready"))
-	}
+	writeJSON(w, statusCode, response)
 }
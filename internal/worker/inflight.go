@@ -0,0 +1,112 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// inFlightNode tracks a node currently being executed
+type inFlightNode struct {
+	graphID   string
+	nodeID    string
+	messageID string
+	startedAt time.Time
+	iteration atomic.Int64
+	cancel    context.CancelCauseFunc
+}
+
+// InFlightNode describes a node currently being executed
+type InFlightNode struct {
+	GraphID   string    `json:"graph_id"`
+	NodeID    string    `json:"node_id"`
+	MessageID string    `json:"message_id"`
+	StartedAt time.Time `json:"started_at"`
+	Elapsed   string    `json:"elapsed"`
+
+	// Iteration is the current agent iteration (0 for non-agent nodes)
+	Iteration int `json:"iteration"`
+}
+
+// ErrCancelledByOperator is the cause attached to nodes cancelled via CancelNode
+var ErrCancelledByOperator = errors.New("cancelled by operator")
+
+// inFlightTracker is a registry of nodes under execution
+type inFlightTracker struct {
+	mu    sync.RWMutex
+	nodes map[string]*inFlightNode
+}
+
+func newInFlightTracker() *inFlightTracker {
+	return &inFlightTracker{nodes: make(map[string]*inFlightNode)}
+}
+
+func inFlightKey(graphID, nodeID string) string {
+	return graphID + "/" + nodeID
+}
+
+// add registers a node; the returned function removes it
+func (t *inFlightTracker) add(node *inFlightNode) func() {
+	key := inFlightKey(node.graphID, node.nodeID)
+
+	t.mu.Lock()
+	t.nodes[key] = node
+	t.mu.Unlock()
+
+	return func() {
+		t.mu.Lock()
+		if t.nodes[key] == node {
+			delete(t.nodes, key)
+		}
+		t.mu.Unlock()
+	}
+}
+
+// list returns a snapshot of in-flight nodes, oldest first
+func (t *inFlightTracker) list() []InFlightNode {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	now := time.Now()
+	nodes := make([]InFlightNode, 0, len(t.nodes))
+	for _, n := range t.nodes {
+		nodes = append(nodes, InFlightNode{
+			GraphID:   n.graphID,
+			NodeID:    n.nodeID,
+			MessageID: n.messageID,
+			StartedAt: n.startedAt,
+			Elapsed:   now.Sub(n.startedAt).Round(time.Millisecond).String(),
+			Iteration: int(n.iteration.Load()),
+		})
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].StartedAt.Before(nodes[j].StartedAt)
+	})
+
+	return nodes
+}
+
+// cancel cancels an in-flight node
+func (t *inFlightTracker) cancel(graphID, nodeID string) bool {
+	t.mu.RLock()
+	node, ok := t.nodes[inFlightKey(graphID, nodeID)]
+	t.mu.RUnlock()
+
+	if !ok {
+		return false
+	}
+
+	node.cancel(ErrCancelledByOperator)
+	return true
+}
+
+// count returns the number of in-flight nodes
+func (t *inFlightTracker) count() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.nodes)
+}
@@ -3,25 +3,39 @@ package worker
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aescanero/dago-libs/pkg/domain"
 	"github.com/aescanero/dago-node-executor/internal/executor"
+	"github.com/aescanero/dago-node-executor/internal/metrics"
+	"github.com/aescanero/dago-node-executor/internal/tracing"
+	"github.com/aescanero/dago-node-executor/pkg/queue"
+	"github.com/aescanero/dago-node-executor/pkg/routing"
+	"github.com/aescanero/dago-node-executor/pkg/tools/function"
 	"github.com/google/uuid"
-	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
 // Worker represents an executor worker
 type Worker struct {
-	id           string
-	redisClient  *redis.Client
-	executor     *executor.Executor
-	logger       *zap.Logger
-	consumerGroup string
-	streamKey    string
+	id       string
+	source   queue.WorkSource
+	events   queue.EventSink
+	state    queue.StateStore
+	executor *executor.Executor
+	logger   *zap.Logger
+	metrics  *metrics.Metrics
+
+	capabilities     RequirementChecker
+	router           Router
+	artifacts        ArtifactExporter
+	loopStallTimeout time.Duration
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -29,29 +43,99 @@ type Worker struct {
 
 	lastProcessed time.Time
 	mu            sync.RWMutex
+
+	// lastTick is the unix nano time of the last processing loop iteration
+	lastTick atomic.Int64
+	draining atomic.Bool
+	paused   atomic.Bool
+
+	inFlight  *inFlightTracker
+	drained   chan struct{}
+	drainOnce sync.Once
 }
 
 // Config holds worker configuration
 type Config struct {
-	ID           string
-	RedisClient  *redis.Client
-	Executor     *executor.Executor
-	Logger       *zap.Logger
+	ID string
+
+	// Source delivers work, Events receives node events and State holds
+	// graph state. A single backend usually provides all three.
+	Source queue.WorkSource
+	Events queue.EventSink
+	State  queue.StateStore
+
+	Executor *executor.Executor
+	Logger   *zap.Logger
+	Metrics  *metrics.Metrics
+
+	// Capabilities, when set, rejects work whose requirements the worker
+	// doesn't satisfy
+	Capabilities RequirementChecker
+
+	// Router, when set, resubmits rejected work to the stream of the
+	// workers satisfying its requirements
+	Router Router
+
+	// Artifacts, when set, exports the workspace files listed in a node's
+	// artifacts config with its result
+	Artifacts ArtifactExporter
+
+	// LoopStallTimeout is how long the processing loop may go without
+	// ticking before the loop check reports it as stuck
+	LoopStallTimeout time.Duration
+}
+
+// RequirementChecker checks a work item's requirements against the
+// worker's capabilities
+type RequirementChecker interface {
+	Check(req routing.Requirements) error
+}
+
+// Router submits work to the stream of the workers able to execute it
+type Router interface {
+	Submit(ctx context.Context, data []byte, priority routing.Priority, req routing.Requirements) (string, string, error)
 }
 
+// ArtifactExporter exports the files of a graph's workspace matching the
+// given patterns
+type ArtifactExporter interface {
+	Export(ctx context.Context, graphID string, patterns []string) ([]function.Artifact, error)
+}
+
+// defaultLoopStallTimeout is used when Config.LoopStallTimeout is not set
+const defaultLoopStallTimeout = 15 * time.Minute
+
+// pausePollInterval is how often a paused worker checks whether it was resumed
+const pausePollInterval = 500 * time.Millisecond
+
+// streamStatsInterval is how often consumer group lag and pending counts are sampled
+const streamStatsInterval = 15 * time.Second
+
 // NewWorker creates a new worker
 func NewWorker(cfg *Config) *Worker {
 	ctx, cancel := context.WithCancel(context.Background())
 
+	loopStallTimeout := cfg.LoopStallTimeout
+	if loopStallTimeout <= 0 {
+		loopStallTimeout = defaultLoopStallTimeout
+	}
+
 	return &Worker{
-		id:            cfg.ID,
-		redisClient:   cfg.RedisClient,
-		executor:      cfg.Executor,
-		logger:        cfg.Logger,
-		consumerGroup: "executor-workers",
-		streamKey:     "executor.work",
-		ctx:           ctx,
-		cancel:        cancel,
+		id:               cfg.ID,
+		source:           cfg.Source,
+		events:           cfg.Events,
+		state:            cfg.State,
+		executor:         cfg.Executor,
+		logger:           cfg.Logger,
+		metrics:          cfg.Metrics,
+		capabilities:     cfg.Capabilities,
+		router:           cfg.Router,
+		artifacts:        cfg.Artifacts,
+		loopStallTimeout: loopStallTimeout,
+		ctx:              ctx,
+		cancel:           cancel,
+		inFlight:         newInFlightTracker(),
+		drained:          make(chan struct{}),
 	}
 }
 
@@ -59,15 +143,18 @@ func NewWorker(cfg *Config) *Worker {
 func (w *Worker) Start() error {
 	w.logger.Info("starting worker", zap.String("worker_id", w.id))
 
-	// Create consumer group if it doesn't exist
-	err := w.redisClient.XGroupCreateMkStream(w.ctx, w.streamKey, w.consumerGroup, "0").Err()
-	if err != nil && err.Error() != "BUSYGROUP Consumer Group name already exists" {
-		return fmt.Errorf("failed to create consumer group: %w", err)
+	if err := w.source.Start(w.ctx); err != nil {
+		return fmt.Errorf("failed to start work source: %w", err)
 	}
 
 	w.wg.Add(1)
 	go w.processLoop()
 
+	if _, ok := w.source.(queue.StatsReporter); ok && w.metrics != nil {
+		w.wg.Add(1)
+		go w.streamStatsLoop()
+	}
+
 	w.logger.Info("worker started", zap.String("worker_id", w.id))
 	return nil
 }
@@ -76,6 +163,7 @@ func (w *Worker) Start() error {
 func (w *Worker) Stop(ctx context.Context) error {
 	w.logger.Info("stopping worker", zap.String("worker_id", w.id))
 
+	w.draining.Store(true)
 	w.cancel()
 
 	// Wait for processing to finish with timeout
@@ -94,82 +182,227 @@ func (w *Worker) Stop(ctx context.Context) error {
 	}
 }
 
+// Pause stops consuming new work. In-flight nodes keep running.
+func (w *Worker) Pause() {
+	if !w.paused.Swap(true) {
+		w.logger.Info("worker paused", zap.String("worker_id", w.id))
+	}
+}
+
+// Resume resumes consuming work after Pause
+func (w *Worker) Resume() {
+	if w.paused.Swap(false) {
+		w.logger.Info("worker resumed", zap.String("worker_id", w.id))
+	}
+}
+
+// IsPaused returns whether consumption is paused
+func (w *Worker) IsPaused() bool {
+	return w.paused.Load()
+}
+
+// Drain stops consuming new work and lets in-flight nodes finish.
+// Drained is closed once the processing loop has exited.
+func (w *Worker) Drain() {
+	if !w.draining.Swap(true) {
+		w.logger.Info("worker draining",
+			zap.String("worker_id", w.id),
+			zap.Int("in_flight", w.inFlight.count()))
+	}
+}
+
+// Drained returns a channel closed when the worker has finished draining
+func (w *Worker) Drained() <-chan struct{} {
+	return w.drained
+}
+
+// InFlight returns the nodes currently being executed
+func (w *Worker) InFlight() []InFlightNode {
+	return w.inFlight.list()
+}
+
+// CancelNode cancels an in-flight node. The node is reported as failed.
+func (w *Worker) CancelNode(graphID, nodeID string) bool {
+	if !w.inFlight.cancel(graphID, nodeID) {
+		return false
+	}
+
+	w.logger.Warn("in-flight node cancelled by operator",
+		zap.String("graph_id", graphID),
+		zap.String("node_id", nodeID))
+	return true
+}
+
 // processLoop is the main worker loop
 func (w *Worker) processLoop() {
 	defer w.wg.Done()
+	defer w.drainOnce.Do(func() { close(w.drained) })
 
 	for {
+		w.lastTick.Store(time.Now().UnixNano())
+
 		select {
 		case <-w.ctx.Done():
 			return
 		default:
-			w.processWork()
 		}
+
+		if w.draining.Load() {
+			w.logger.Info("worker drained", zap.String("worker_id", w.id))
+			return
+		}
+
+		if w.paused.Load() {
+			select {
+			case <-w.ctx.Done():
+				return
+			case <-time.After(pausePollInterval):
+			}
+			continue
+		}
+
+		w.processWork()
 	}
 }
 
-// processWork reads and processes work from Redis Stream
-func (w *Worker) processWork() {
-	// Read from stream
-	streams, err := w.redisClient.XReadGroup(w.ctx, &redis.XReadGroupArgs{
-		Group:    w.consumerGroup,
-		Consumer: w.id,
-		Streams:  []string{w.streamKey, ">"},
-		Count:    1,
-		Block:    time.Second,
-	}).Result()
+// streamStatsLoop periodically samples the work source's lag and pending entries
+func (w *Worker) streamStatsLoop() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(streamStatsInterval)
+	defer ticker.Stop()
 
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			w.recordStreamStats()
+		}
+	}
+}
+
+// recordStreamStats samples the work source's backlog
+func (w *Worker) recordStreamStats() {
+	stats, err := w.source.(queue.StatsReporter).Stats(w.ctx)
 	if err != nil {
-		if err == redis.Nil {
-			// No messages, continue
+		w.logger.Debug("failed to read work source stats", zap.Error(err))
+		return
+	}
+
+	for _, s := range stats {
+		w.metrics.SetStreamStats(s.Source, s.Group, s.Lag, s.Pending)
+	}
+}
+
+// processWork fetches and processes work from the work source
+func (w *Worker) processWork() {
+	messages, err := w.source.Fetch(w.ctx, 1)
+	if err != nil {
+		if w.ctx.Err() != nil {
 			return
 		}
-		w.logger.Error("failed to read from stream", zap.Error(err))
+		w.logger.Error("failed to fetch work", zap.Error(err))
 		time.Sleep(time.Second)
 		return
 	}
 
-	// Process messages
-	for _, stream := range streams {
-		for _, message := range stream.Messages {
-			w.processMessage(message)
-		}
+	for _, message := range messages {
+		w.processMessage(message)
 	}
 }
 
 // processMessage processes a single work message
-func (w *Worker) processMessage(message redis.XMessage) {
+func (w *Worker) processMessage(message *queue.Message) {
 	w.logger.Info("processing work",
 		zap.String("worker_id", w.id),
 		zap.String("message_id", message.ID))
 
 	// Parse work data
-	workData, ok := message.Values["data"].(string)
-	if !ok {
+	if len(message.Data) == 0 {
 		w.logger.Error("invalid message format", zap.String("message_id", message.ID))
-		w.ackMessage(message.ID)
+		w.ackMessage(message)
 		return
 	}
 
 	var work WorkItem
-	if err := json.Unmarshal([]byte(workData), &work); err != nil {
+	if err := json.Unmarshal(message.Data, &work); err != nil {
 		w.logger.Error("failed to unmarshal work", zap.Error(err))
-		w.ackMessage(message.ID)
+		w.ackMessage(message)
 		return
 	}
 
+	// Continue the orchestrator's trace, if any
+	ctx := tracing.Extract(w.ctx, work.TraceParent)
+	ctx, span := tracing.Start(ctx, "work.process", trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("dago.graph_id", work.GraphID),
+			attribute.String("dago.node_id", work.NodeID),
+			attribute.String("messaging.message.id", message.ID),
+		))
+
+	// Misrouted work is left for a capable worker rather than run without
+	// what it needs
+	if err := w.checkRequirements(&work); err != nil {
+		w.logger.Warn("work requirements not met",
+			zap.String("graph_id", work.GraphID),
+			zap.String("node_id", work.NodeID),
+			zap.String("source", message.Source),
+			zap.Error(err))
+		if err = w.reroute(ctx, message, &work, err); err == nil {
+			tracing.End(span, nil)
+			return
+		}
+		w.publishResult(ctx, &work, nil, nil, err)
+		tracing.End(span, err)
+		w.ackMessage(message)
+		return
+	}
+
+	// Track the node so operators can inspect and cancel it
+	execCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	node := &inFlightNode{
+		graphID:   work.GraphID,
+		nodeID:    work.NodeID,
+		messageID: message.ID,
+		startedAt: time.Now(),
+		cancel:    cancel,
+	}
+	execCtx = executor.ContextWithObserver(execCtx, &executor.Observer{
+		OnIteration: func(iteration int) {
+			node.iteration.Store(int64(iteration))
+		},
+	})
+	removeInFlight := w.inFlight.add(node)
+
 	// Execute node
-	result, err := w.executeNode(&work)
+	w.metrics.IncInFlight()
+	result, artifacts, err := w.executeNode(execCtx, &work)
+	w.metrics.DecInFlight()
+	removeInFlight()
+
+	if err != nil && errors.Is(context.Cause(execCtx), ErrCancelledByOperator) {
+		err = fmt.Errorf("%w: %v", ErrCancelledByOperator, err)
+	}
+
+	// Hand the work back for redelivery instead of failing the node
+	if err != nil && w.retry(message, err) {
+		tracing.End(span, err)
+		return
+	}
 
 	// Publish result
 	if err != nil {
-		w.publishResult(&work, nil, err)
+		w.publishResult(ctx, &work, nil, nil, err)
 	} else {
-		w.publishResult(&work, result, nil)
+		w.publishResult(ctx, &work, result, artifacts, nil)
 	}
+	tracing.End(span, err)
 
 	// Acknowledge message
-	w.ackMessage(message.ID)
+	w.ackMessage(message)
 
 	// Update last processed time
 	w.mu.Lock()
@@ -177,25 +410,72 @@ func (w *Worker) processMessage(message redis.XMessage) {
 	w.mu.Unlock()
 }
 
+// checkRequirements checks the work item's requirements, if any, against
+// the worker's capabilities
+func (w *Worker) checkRequirements(work *WorkItem) error {
+	if work.Requirements == nil || w.capabilities == nil {
+		return nil
+	}
+	if err := w.capabilities.Check(*work.Requirements); err != nil {
+		return fmt.Errorf("worker %s cannot execute node: %w", w.id, err)
+	}
+	return nil
+}
+
+// reroute hands misrouted work over to a capable worker: it resubmits the
+// work to the route of its requirements and acknowledges it, or else
+// returns it to the source for redelivery. It returns the error to fail
+// the node with when neither is possible, or when no live worker
+// satisfies the requirements.
+func (w *Worker) reroute(ctx context.Context, message *queue.Message, work *WorkItem, err error) error {
+	if w.router != nil {
+		stream, _, submitErr := w.router.Submit(ctx, message.Data, work.Priority, *work.Requirements)
+		if submitErr == nil {
+			w.logger.Info("work rerouted",
+				zap.String("graph_id", work.GraphID),
+				zap.String("node_id", work.NodeID),
+				zap.String("source", message.Source),
+				zap.String("stream", stream))
+			w.ackMessage(message)
+			return nil
+		}
+		if errors.Is(submitErr, routing.ErrNoCapableWorker) {
+			return fmt.Errorf("%w: %w", submitErr, err)
+		}
+		err = fmt.Errorf("failed to reroute work: %w", submitErr)
+	}
+
+	if w.retry(message, &retryableError{err: err}) {
+		return nil
+	}
+	return err
+}
+
 // executeNode executes a node
-func (w *Worker) executeNode(work *WorkItem) (interface{}, error) {
-	// Load state from Redis
-	state, err := w.loadState(work.GraphID)
+func (w *Worker) executeNode(ctx context.Context, work *WorkItem) (interface{}, []function.Artifact, error) {
+	// Load state
+	state, err := w.loadState(ctx, work.GraphID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load state: %w", err)
+		err = fmt.Errorf("failed to load state: %w", err)
+		if !errors.Is(err, queue.ErrStateNotFound) {
+			err = &retryableError{err: err}
+		}
+		return nil, nil, err
 	}
 
 	// Create node config
 	nodeConfig := &executor.NodeConfig{
 		NodeID: work.NodeID,
 		Config: work.Config,
+		Tenant: work.Tenant,
 	}
 
 	// Execute
-	result, err := w.executor.Execute(w.ctx, state, nodeConfig)
+	result, err := w.executor.Execute(ctx, state, nodeConfig)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	artifacts := w.exportArtifacts(ctx, nodeConfig, work.GraphID)
 
 	// Update state
 	if state.NodeStates == nil {
@@ -216,102 +496,172 @@ func (w *Worker) executeNode(work *WorkItem) (interface{}, error) {
 	nodeState.Output = result
 	nodeState.Status = domain.ExecutionStatusCompleted
 	nodeState.CompletedAt = &now
+	if len(artifacts) > 0 {
+		if nodeState.Metadata == nil {
+			nodeState.Metadata = make(map[string]interface{})
+		}
+		nodeState.Metadata["artifacts"] = artifacts
+	}
 
 	// Save updated state
-	if err := w.saveState(state); err != nil {
+	if err := w.saveState(ctx, state); err != nil {
 		w.logger.Error("failed to save state", zap.Error(err))
 	}
 
-	return result, nil
+	return result, artifacts, nil
+}
+
+// exportArtifacts exports the workspace files listed in the node's
+// artifacts config. A failed export is logged and doesn't fail the node.
+func (w *Worker) exportArtifacts(ctx context.Context, nodeConfig *executor.NodeConfig, graphID string) []function.Artifact {
+	if w.artifacts == nil {
+		return nil
+	}
+	patterns, err := executor.ArtifactPatterns(nodeConfig)
+	if err != nil || len(patterns) == 0 {
+		return nil
+	}
+
+	artifacts, err := w.artifacts.Export(ctx, graphID, patterns)
+	if err != nil {
+		w.logger.Warn("failed to export node artifacts",
+			zap.String("graph_id", graphID),
+			zap.String("node_id", nodeConfig.NodeID),
+			zap.Error(err))
+		return nil
+	}
+	return artifacts
+}
+
+// retryableError marks a failure of the backend rather than of the node
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// Redelivery backoff after backend failures
+const (
+	retryBaseDelay = time.Second
+	retryMaxDelay  = time.Minute
+)
+
+// retryDelay returns the redelivery delay after a failed attempt
+func retryDelay(attempt int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempt && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, retryMaxDelay)
+}
+
+// retry returns a message to sources that support redelivery when it failed
+// for reasons unrelated to the node: a backend error, or the worker
+// stopping mid-execution. It reports whether the message was handed back.
+func (w *Worker) retry(message *queue.Message, err error) bool {
+	retrier, ok := w.source.(queue.Retrier)
+	if !ok {
+		return false
+	}
+
+	var delay time.Duration
+	var retryable *retryableError
+	switch {
+	case w.ctx.Err() != nil && !errors.Is(err, ErrCancelledByOperator):
+		// Interrupted by shutdown; another worker can pick it up right away
+	case errors.As(err, &retryable):
+		delay = retryDelay(message.Attempt)
+	default:
+		return false
+	}
+
+	// The worker context may already be cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if nakErr := retrier.Nak(ctx, message, delay); nakErr != nil {
+		w.logger.Error("failed to return message for redelivery",
+			zap.String("message_id", message.ID),
+			zap.Error(nakErr))
+		return false
+	}
+
+	w.metrics.IncRetries(message.Source)
+	w.logger.Warn("work returned for redelivery",
+		zap.String("message_id", message.ID),
+		zap.Int("attempt", message.Attempt),
+		zap.Duration("delay", delay),
+		zap.Error(err))
+	return true
 }
 
 // publishResult publishes execution result
-func (w *Worker) publishResult(work *WorkItem, result interface{}, err error) {
+func (w *Worker) publishResult(ctx context.Context, work *WorkItem, result interface{}, artifacts []function.Artifact, err error) {
 	var eventType string
 	var data map[string]interface{}
 
 	if err != nil {
-		eventType = "node.failed"
+		eventType = queue.EventNodeFailed
 		data = map[string]interface{}{
 			"graph_id": work.GraphID,
 			"node_id":  work.NodeID,
 			"error":    err.Error(),
 		}
 	} else {
-		eventType = "node.completed"
+		eventType = queue.EventNodeCompleted
 		data = map[string]interface{}{
 			"graph_id": work.GraphID,
 			"node_id":  work.NodeID,
 			"output":   result,
 		}
+		if len(artifacts) > 0 {
+			data["artifacts"] = artifacts
+		}
 	}
 
-	event := map[string]interface{}{
-		"id":        uuid.New().String(),
-		"type":      eventType,
-		"graph_id":  work.GraphID,
-		"node_id":   work.NodeID,
-		"timestamp": time.Now(),
-		"data":      data,
+	event := &queue.Event{
+		ID:          uuid.New().String(),
+		Type:        eventType,
+		GraphID:     work.GraphID,
+		NodeID:      work.NodeID,
+		Timestamp:   time.Now(),
+		Data:        data,
+		TraceParent: tracing.TraceParent(ctx),
 	}
 
-	eventJSON, _ := json.Marshal(event)
-
-	streamKey := fmt.Sprintf("dago:events:%s", eventType)
-	err = w.redisClient.XAdd(w.ctx, &redis.XAddArgs{
-		Stream: streamKey,
-		Values: map[string]interface{}{
-			"data": string(eventJSON),
-		},
-	}).Err()
-
-	if err != nil {
+	if err := w.events.Publish(ctx, event); err != nil {
 		w.logger.Error("failed to publish result", zap.Error(err))
 	}
 }
 
 // ackMessage acknowledges a message
-func (w *Worker) ackMessage(messageID string) {
-	err := w.redisClient.XAck(w.ctx, w.streamKey, w.consumerGroup, messageID).Err()
-	if err != nil {
+func (w *Worker) ackMessage(message *queue.Message) {
+	if err := w.source.Ack(w.ctx, message); err != nil {
 		w.logger.Error("failed to ack message",
-			zap.String("message_id", messageID),
+			zap.String("message_id", message.ID),
 			zap.Error(err))
 	}
 }
 
-// loadState loads graph state from Redis
-func (w *Worker) loadState(graphID string) (*domain.GraphState, error) {
-	key := fmt.Sprintf("dago:state:%s", graphID)
-
-	data, err := w.redisClient.Get(w.ctx, key).Bytes()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get state: %w", err)
-	}
-
-	var state domain.GraphState
-	if err := json.Unmarshal(data, &state); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal state: %w", err)
-	}
+// loadState loads graph state from the state store
+func (w *Worker) loadState(ctx context.Context, graphID string) (_ *domain.GraphState, err error) {
+	ctx, span := tracing.Start(ctx, "state.load", trace.WithAttributes(
+		attribute.String("dago.graph_id", graphID),
+	))
+	defer func() { tracing.End(span, err) }()
 
-	return &state, nil
+	return w.state.Load(ctx, graphID)
 }
 
-// saveState saves graph state to Redis
-func (w *Worker) saveState(state *domain.GraphState) error {
-	key := fmt.Sprintf("dago:state:%s", state.GraphID)
-
-	data, err := json.Marshal(state)
-	if err != nil {
-		return fmt.Errorf("failed to marshal state: %w", err)
-	}
+// saveState saves graph state to the state store
+func (w *Worker) saveState(ctx context.Context, state *domain.GraphState) (err error) {
+	ctx, span := tracing.Start(ctx, "state.save", trace.WithAttributes(
+		attribute.String("dago.graph_id", state.GraphID),
+	))
+	defer func() { tracing.End(span, err) }()
 
-	err = w.redisClient.Set(w.ctx, key, data, 24*time.Hour).Err()
-	if err != nil {
-		return fmt.Errorf("failed to save state: %w", err)
-	}
-
-	return nil
+	return w.state.Save(ctx, state)
 }
 
 // WorkItem represents a work item from the stream
@@ -321,6 +671,18 @@ type WorkItem struct {
 	NodeType     domain.NodeType        `json:"node_type"`
 	Config       map[string]interface{} `json:"config"`
 	Dependencies []string               `json:"dependencies"`
+
+	// Tenant the graph runs for, selecting its tool access policy
+	Tenant string `json:"tenant,omitempty"`
+
+	// TraceParent is the W3C trace context of the orchestrator span that
+	// scheduled this work item
+	TraceParent string `json:"traceparent,omitempty"`
+
+	// Priority and Requirements select the stream the item is routed to
+	// (see pkg/routing); Requirements are checked again before execution
+	Priority     routing.Priority      `json:"priority,omitempty"`
+	Requirements *routing.Requirements `json:"requirements,omitempty"`
 }
 
 // GetLastProcessed returns the last processed time
@@ -332,9 +694,103 @@ func (w *Worker) GetLastProcessed() time.Time {
 
 // IsHealthy returns whether the worker is healthy
 func (w *Worker) IsHealthy() bool {
-	// Check if Redis is connected
-	if err := w.redisClient.Ping(w.ctx).Err(); err != nil {
-		return false
+	// The worker context is cancelled during shutdown, so probe with a fresh one
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	for _, pinger := range w.pingers() {
+		if pinger.Ping(ctx) != nil {
+			return false
+		}
 	}
 	return true
 }
+
+// IsDraining returns whether the worker has stopped accepting new work
+func (w *Worker) IsDraining() bool {
+	return w.draining.Load()
+}
+
+// pingers returns the distinct backends behind the source, sink and store
+func (w *Worker) pingers() []queue.Pinger {
+	var pingers []queue.Pinger
+	seen := make(map[string]bool)
+	for _, component := range []interface{}{w.source, w.events, w.state} {
+		pinger, ok := component.(queue.Pinger)
+		if !ok || seen[pinger.Name()] {
+			continue
+		}
+		seen[pinger.Name()] = true
+		pingers = append(pingers, pinger)
+	}
+	return pingers
+}
+
+// BackendChecks returns a health check per backend, named after it (e.g. "redis")
+func (w *Worker) BackendChecks() []HealthCheck {
+	var checks []HealthCheck
+	for _, pinger := range w.pingers() {
+		checks = append(checks, NewCheckFunc(pinger.Name(), func(ctx context.Context) CheckResult {
+			if err := pinger.Ping(ctx); err != nil {
+				return unhealthy(err)
+			}
+			return healthy(nil)
+		}))
+	}
+	return checks
+}
+
+// ConsumerGroupCheck returns a health check verifying the work source's
+// consumer group exists, or nil if the source reports no stats
+func (w *Worker) ConsumerGroupCheck() HealthCheck {
+	reporter, ok := w.source.(queue.StatsReporter)
+	if !ok {
+		return nil
+	}
+
+	return NewCheckFunc("consumer_group", func(ctx context.Context) CheckResult {
+		stats, err := reporter.Stats(ctx)
+		if err != nil {
+			return unhealthy(err)
+		}
+
+		if len(stats) == 1 {
+			s := stats[0]
+			return healthy(map[string]interface{}{
+				"stream":    s.Source,
+				"group":     s.Group,
+				"consumers": s.Consumers,
+				"pending":   s.Pending,
+				"lag":       s.Lag,
+			})
+		}
+		return healthy(map[string]interface{}{"sources": stats})
+	})
+}
+
+// LoopCheck returns a health check detecting a stuck processing loop
+func (w *Worker) LoopCheck() HealthCheck {
+	return NewCheckFunc("processing_loop", func(ctx context.Context) CheckResult {
+		tick := w.lastTick.Load()
+		if tick == 0 {
+			return unhealthy(fmt.Errorf("processing loop not started"))
+		}
+
+		lastTick := time.Unix(0, tick)
+		since := time.Since(lastTick)
+		details := map[string]interface{}{
+			"last_tick":      lastTick,
+			"last_processed": w.GetLastProcessed(),
+		}
+
+		if since > w.loopStallTimeout {
+			return CheckResult{
+				Status:  StatusUnhealthy,
+				Message: fmt.Sprintf("processing loop stalled for %s", since.Round(time.Second)),
+				Details: details,
+			}
+		}
+
+		return healthy(details)
+	})
+}
@@ -0,0 +1,297 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-node-executor/internal/executor"
+	"github.com/aescanero/dago-node-executor/internal/fakellm"
+	"github.com/aescanero/dago-node-executor/pkg/queue"
+	"github.com/aescanero/dago-node-executor/pkg/queue/memqueue"
+	"github.com/aescanero/dago-node-executor/pkg/routing"
+	"go.uber.org/zap"
+)
+
+// fakeTools serves tools from functions and records the calls
+type fakeTools struct {
+	mu    sync.Mutex
+	tools map[string]func(params map[string]interface{}) (interface{}, error)
+	calls []string
+}
+
+func (f *fakeTools) Execute(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, toolName)
+	f.mu.Unlock()
+
+	fn, ok := f.tools[toolName]
+	if !ok {
+		return nil, fmt.Errorf("unknown tool: %s", toolName)
+	}
+	return fn(params)
+}
+
+func (f *fakeTools) ListTools(ctx context.Context) ([]string, error) {
+	names := make([]string, 0, len(f.tools))
+	for name := range f.tools {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// agentNode is the config of an agent node allowed to call search
+var agentNode = map[string]interface{}{
+	"mode":       "agent",
+	"llm_config": map[string]interface{}{"model": "fake"},
+	"tools":      []interface{}{"search"},
+	"task":       "Find the answer",
+}
+
+func TestProcessMessage(t *testing.T) {
+	tests := []struct {
+		name       string
+		work       interface{}
+		responses  []fakellm.Response
+		wantCalls  []string
+		wantEvent  string
+		wantOutput interface{}
+		wantState  interface{}
+	}{
+		{
+			name: "agent completes after a tool call",
+			work: WorkItem{GraphID: "g1", NodeID: "n1", Config: agentNode},
+			responses: []fakellm.Response{
+				{ToolCalls: []fakellm.ToolCall{{Name: "search", Input: map[string]interface{}{"q": "answer"}}}},
+				{Content: "42"},
+			},
+			wantCalls:  []string{"search"},
+			wantEvent:  queue.EventNodeCompleted,
+			wantOutput: map[string]interface{}{"result": "42", "iterations": 2},
+			wantState:  map[string]interface{}{"result": "42", "iterations": float64(2)},
+		},
+		{
+			name: "tool failures are reported to the agent",
+			work: WorkItem{GraphID: "g1", NodeID: "n1", Config: agentNode},
+			responses: []fakellm.Response{
+				{ToolCalls: []fakellm.ToolCall{{Name: "missing"}}},
+				{Content: "gave up"},
+			},
+			wantCalls:  []string{"missing"},
+			wantEvent:  queue.EventNodeCompleted,
+			wantOutput: map[string]interface{}{"result": "gave up", "iterations": 2},
+			wantState:  map[string]interface{}{"result": "gave up", "iterations": float64(2)},
+		},
+		{
+			name:      "LLM failure fails the node",
+			work:      WorkItem{GraphID: "g1", NodeID: "n1", Config: agentNode},
+			responses: []fakellm.Response{{Error: fakellm.ErrorServer}},
+			wantEvent: queue.EventNodeFailed,
+		},
+		{
+			name:      "unknown graph fails the node",
+			work:      WorkItem{GraphID: "unknown", NodeID: "n1", Config: agentNode},
+			wantEvent: queue.EventNodeFailed,
+		},
+		{
+			name: "malformed work is dropped",
+			work: "not a work item",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			backend := memqueue.New()
+			if err := backend.Save(ctx, &domain.GraphState{GraphID: "g1"}); err != nil {
+				t.Fatal(err)
+			}
+
+			llm := fakellm.New(tt.responses...)
+			tools := &fakeTools{tools: map[string]func(map[string]interface{}) (interface{}, error){
+				"search": func(params map[string]interface{}) (interface{}, error) {
+					return map[string]interface{}{"hits": []string{"42"}}, nil
+				},
+			}}
+			w := NewWorker(&Config{
+				ID:       "w1",
+				Source:   backend,
+				Events:   backend,
+				State:    backend,
+				Executor: executor.NewExecutor(llm, tools, zap.NewNop(), 5),
+				Logger:   zap.NewNop(),
+			})
+			defer w.cancel()
+
+			if _, err := backend.Submit(tt.work); err != nil {
+				t.Fatal(err)
+			}
+			messages, err := backend.Fetch(ctx, 1)
+			if err != nil || len(messages) != 1 {
+				t.Fatalf("Fetch() = %v, %v; want the submitted message", messages, err)
+			}
+			w.processMessage(messages[0])
+
+			if stats, _ := backend.Stats(ctx); stats[0].Pending != 0 {
+				t.Errorf("pending = %d, want the message acked", stats[0].Pending)
+			}
+			if llm.Remaining() != 0 {
+				t.Errorf("%d scripted responses left", llm.Remaining())
+			}
+			if !reflect.DeepEqual(tools.calls, tt.wantCalls) {
+				t.Errorf("tool calls = %v, want %v", tools.calls, tt.wantCalls)
+			}
+
+			events := backend.Events(0)
+			if tt.wantEvent == "" {
+				if len(events) != 0 {
+					t.Errorf("events = %v, want none", events)
+				}
+				return
+			}
+			if len(events) != 1 {
+				t.Fatalf("got %d events, want 1", len(events))
+			}
+			event := events[0]
+			work := tt.work.(WorkItem)
+			if event.Type != tt.wantEvent || event.GraphID != work.GraphID || event.NodeID != work.NodeID {
+				t.Errorf("event = %s %s/%s, want %s %s/%s", event.Type, event.GraphID, event.NodeID, tt.wantEvent, work.GraphID, work.NodeID)
+			}
+			if tt.wantEvent == queue.EventNodeFailed {
+				if msg, _ := event.Data["error"].(string); msg == "" {
+					t.Error("failure event has no error")
+				}
+				return
+			}
+			if !reflect.DeepEqual(event.Data["output"], tt.wantOutput) {
+				t.Errorf("output = %v, want %v", event.Data["output"], tt.wantOutput)
+			}
+
+			state, err := backend.Load(ctx, work.GraphID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			node := state.NodeStates[work.NodeID]
+			if node == nil || node.Status != domain.ExecutionStatusCompleted {
+				t.Fatalf("node state = %+v, want completed", node)
+			}
+			if !reflect.DeepEqual(node.Output, tt.wantState) {
+				t.Errorf("saved output = %v, want %v", node.Output, tt.wantState)
+			}
+		})
+	}
+}
+
+// missingTool rejects every requirement, like a worker without the tool
+type missingTool struct{}
+
+func (missingTool) Check(req routing.Requirements) error {
+	return fmt.Errorf("%w: missing tool browse", routing.ErrRequirementsNotMet)
+}
+
+// fakeRouter records the work it is asked to resubmit
+type fakeRouter struct {
+	err       error
+	submitted [][]byte
+}
+
+func (f *fakeRouter) Submit(ctx context.Context, data []byte, priority routing.Priority, req routing.Requirements) (string, string, error) {
+	if f.err != nil {
+		return "", "", f.err
+	}
+	f.submitted = append(f.submitted, data)
+	return routing.StreamFor("executor.work", priority, req), "1-0", nil
+}
+
+func TestProcessMisroutedMessage(t *testing.T) {
+	tests := []struct {
+		name          string
+		router        *fakeRouter
+		wantSubmitted bool
+		wantError     string
+	}{
+		{name: "rerouted to capable workers", router: &fakeRouter{}, wantSubmitted: true},
+		{
+			name:      "no capable worker fails the node",
+			router:    &fakeRouter{err: fmt.Errorf("%w: executor.work.high.req-1", routing.ErrNoCapableWorker)},
+			wantError: "no live worker satisfies the requirements",
+		},
+		{
+			name:      "reroute failure without redelivery fails the node",
+			router:    &fakeRouter{err: errors.New("redis unavailable")},
+			wantError: "failed to reroute work",
+		},
+		{name: "no router fails the node", wantError: "missing tool browse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			backend := memqueue.New()
+			llm := fakellm.New()
+			cfg := &Config{
+				ID:           "w1",
+				Source:       backend,
+				Events:       backend,
+				State:        backend,
+				Executor:     executor.NewExecutor(llm, &fakeTools{}, zap.NewNop(), 5),
+				Logger:       zap.NewNop(),
+				Capabilities: missingTool{},
+			}
+			if tt.router != nil {
+				cfg.Router = tt.router
+			}
+			w := NewWorker(cfg)
+			defer w.cancel()
+
+			work := WorkItem{
+				GraphID:      "g1",
+				NodeID:       "n1",
+				Priority:     routing.PriorityHigh,
+				Requirements: &routing.Requirements{Tools: []string{"browse"}},
+				Config:       agentNode,
+			}
+			if _, err := backend.Submit(work); err != nil {
+				t.Fatal(err)
+			}
+			messages, err := backend.Fetch(ctx, 1)
+			if err != nil || len(messages) != 1 {
+				t.Fatalf("Fetch() = %v, %v; want the submitted message", messages, err)
+			}
+			w.processMessage(messages[0])
+
+			if stats, _ := backend.Stats(ctx); stats[0].Pending != 0 {
+				t.Errorf("pending = %d, want the message acked", stats[0].Pending)
+			}
+			if n := len(llm.Requests()); n != 0 {
+				t.Errorf("LLM called %d times, want the node not run", n)
+			}
+
+			if tt.wantSubmitted {
+				if len(tt.router.submitted) != 1 || !bytes.Equal(tt.router.submitted[0], messages[0].Data) {
+					t.Errorf("submitted %q, want the work item", tt.router.submitted)
+				}
+			}
+
+			events := backend.Events(0)
+			if tt.wantError == "" {
+				if len(events) != 0 {
+					t.Errorf("events = %v, want none", events)
+				}
+				return
+			}
+			if len(events) != 1 || events[0].Type != queue.EventNodeFailed {
+				t.Fatalf("events = %v, want node.failed", events)
+			}
+			if msg, _ := events[0].Data["error"].(string); !strings.Contains(msg, tt.wantError) {
+				t.Errorf("error = %q, want %q", msg, tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+// Package executor is the public API for custom execution modes.
+//
+// A mode implements ModeHandler and is added with RegisterMode, usually
+// from an init function, by a package linked into the worker binary:
+//
+//	func init() {
+//		executor.RegisterMode("sql", func(rt executor.Runtime) executor.ModeHandler {
+//			return &sqlMode{rt: rt}
+//		})
+//	}
+//
+// Every executor created afterwards runs nodes whose "mode" field names
+// the mode with the handler built by the factory. Runtime gives handlers
+// the executor's LLM and tool calls, with their rate limits, tool policies
+// and metrics.
+package executor
@@ -0,0 +1,127 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+)
+
+// ExecutionMode names an execution mode
+type ExecutionMode string
+
+// ModeDescription describes an execution mode
+type ModeDescription struct {
+	// Mode is the value of the "mode" config field selecting this mode
+	Mode ExecutionMode `json:"mode"`
+
+	// Description is a one-line summary of what the mode does
+	Description string `json:"description"`
+
+	// Keys lists the top-level config keys the mode reads. Validate reports
+	// other keys as unknown.
+	Keys []string `json:"keys"`
+}
+
+// NodeConfig represents the configuration for a node execution
+type NodeConfig struct {
+	NodeID string
+	Config map[string]interface{}
+
+	// Tenant is the tenant the graph runs for, if any; tool access
+	// policies may depend on it
+	Tenant string
+}
+
+// ModeHandler executes nodes in one execution mode, selected by the node's
+// "mode" field
+type ModeHandler interface {
+	// Describe returns the mode's name and the config keys it reads
+	Describe() ModeDescription
+
+	// Validate checks a node config for this mode without executing it.
+	// Unknown keys are checked by the executor from Describe().Keys.
+	Validate(ctx context.Context, config *NodeConfig, opts ValidateOptions) []ValidationIssue
+
+	// Execute runs the node and returns its output
+	Execute(ctx context.Context, state *domain.GraphState, config *NodeConfig) (interface{}, error)
+}
+
+// Runtime is what the executor offers mode handlers
+type Runtime interface {
+	// CallLLM sends a request to the LLM, waiting for rate limits
+	CallLLM(ctx context.Context, req *domain.LLMRequest) (*domain.LLMResponse, error)
+
+	// CallTool calls a tool through the tool policies of the node in ctx
+	CallTool(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error)
+
+	// ResolveLLMConfig applies the llm_config profile, model aliases and
+	// defaults to a node's llm_config
+	ResolveLLMConfig(llmConfig map[string]interface{}) map[string]interface{}
+
+	// RenderPrompt replaces {{variables}} with state inputs and node results
+	RenderPrompt(template string, state *domain.GraphState, config *NodeConfig) string
+}
+
+// ModeFactory builds a mode's handler for an executor
+type ModeFactory func(rt Runtime) ModeHandler
+
+// builtinModes are the modes every executor has
+var builtinModes = map[ExecutionMode]bool{"agent": true, "llm": true, "tool": true}
+
+var (
+	modesMu sync.RWMutex
+	modes   = make(map[ExecutionMode]ModeFactory)
+)
+
+// RegisterMode adds an execution mode to the executors created afterwards.
+// It panics if the mode is a built-in one (agent, llm or tool) or is
+// already registered, so conflicts show at startup.
+func RegisterMode(mode ExecutionMode, factory ModeFactory) {
+	if mode == "" || factory == nil {
+		panic("executor: RegisterMode needs a mode and a factory")
+	}
+
+	modesMu.Lock()
+	defer modesMu.Unlock()
+
+	if builtinModes[mode] {
+		panic(fmt.Sprintf("executor: mode %q is built in", mode))
+	}
+	if _, ok := modes[mode]; ok {
+		panic(fmt.Sprintf("executor: mode %q is already registered", mode))
+	}
+	modes[mode] = factory
+}
+
+// UnregisterMode removes a registered mode, so tests can register their
+// own modes again. Executors already created keep it.
+func UnregisterMode(mode ExecutionMode) {
+	modesMu.Lock()
+	defer modesMu.Unlock()
+	delete(modes, mode)
+}
+
+// Modes returns the registered modes, sorted
+func Modes() []ExecutionMode {
+	modesMu.RLock()
+	defer modesMu.RUnlock()
+
+	names := make([]ExecutionMode, 0, len(modes))
+	for mode := range modes {
+		names = append(names, mode)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+// Factory returns the factory registered for mode
+func Factory(mode ExecutionMode) (ModeFactory, bool) {
+	modesMu.RLock()
+	defer modesMu.RUnlock()
+
+	factory, ok := modes[mode]
+	return factory, ok
+}
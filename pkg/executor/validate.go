@@ -0,0 +1,42 @@
+package executor
+
+import (
+	"fmt"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+)
+
+// Severity is the severity of a validation issue
+type Severity string
+
+const (
+	// SeverityError marks a config that would fail or misbehave at execution time
+	SeverityError Severity = "error"
+
+	// SeverityWarning marks a config that runs but is probably not what was meant
+	SeverityWarning Severity = "warning"
+)
+
+// ValidationIssue is a single problem found in a node config
+type ValidationIssue struct {
+	Severity Severity `json:"severity"`
+	Field    string   `json:"field,omitempty"`
+	Message  string   `json:"message"`
+}
+
+func (i ValidationIssue) String() string {
+	if i.Field == "" {
+		return fmt.Sprintf("%s: %s", i.Severity, i.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", i.Severity, i.Field, i.Message)
+}
+
+// ValidateOptions controls the optional checks of validation
+type ValidateOptions struct {
+	// State is a sample graph state. When set, template variables must
+	// resolve to one of its inputs or node states.
+	State *domain.GraphState
+
+	// SkipToolCheck disables checking referenced tools against the tool client
+	SkipToolCheck bool
+}
@@ -0,0 +1,8 @@
+// Package queue defines the transports the worker runs on.
+//
+// A WorkSource delivers work items, an EventSink publishes node events and
+// a StateStore loads and saves graph state. The worker's implementations
+// are internal/queue/redisqueue (Redis Streams and keys) and
+// internal/queue/natsqueue (NATS JetStream work and events); memqueue
+// (in process, for tests, embedding and local development) is public.
+package queue
@@ -0,0 +1,9 @@
+// Package memqueue implements the worker's work source, event sink and
+// state store in process.
+//
+// It lets the executor run without Redis: embedded in another binary, in
+// unit tests and on developer machines. Work is submitted with Submit,
+// published events are read with Events or Subscribe, and state lives in
+// memory. Only the latest events are kept (see WithMaxEvents), and
+// nothing survives a restart.
+package memqueue
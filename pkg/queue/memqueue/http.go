@@ -0,0 +1,98 @@
+package memqueue
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-node-executor/pkg/queue"
+)
+
+// Handler returns an HTTP API over the backend for local development:
+//
+//	POST /queue/work                submit a work item
+//	PUT  /queue/state/{graph_id}    set a graph's state
+//	GET  /queue/state/{graph_id}    read a graph's state
+//	GET  /queue/events?from=N       list published events
+//
+// Requests must carry "Authorization: Bearer <token>". An empty token
+// disables the check, which is only meant for tests.
+func Handler(b *Backend, token string) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /queue/work", func(w http.ResponseWriter, r *http.Request) {
+		var work map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&work); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid work item: "+err.Error())
+			return
+		}
+		id, err := b.Submit(work)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusAccepted, map[string]string{"message_id": id})
+	})
+
+	mux.HandleFunc("PUT /queue/state/{graph_id}", func(w http.ResponseWriter, r *http.Request) {
+		var state domain.GraphState
+		if err := json.NewDecoder(r.Body).Decode(&state); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid state: "+err.Error())
+			return
+		}
+		state.GraphID = r.PathValue("graph_id")
+		if err := b.Save(r.Context(), &state); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("GET /queue/state/{graph_id}", func(w http.ResponseWriter, r *http.Request) {
+		state, err := b.Load(r.Context(), r.PathValue("graph_id"))
+		if errors.Is(err, queue.ErrStateNotFound) {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, state)
+	})
+
+	mux.HandleFunc("GET /queue/events", func(w http.ResponseWriter, r *http.Request) {
+		from, _ := strconv.Atoi(r.URL.Query().Get("from"))
+		events := b.Events(from)
+		if events == nil {
+			events = []*queue.Event{}
+		}
+		writeJSON(w, http.StatusOK, events)
+	})
+
+	if token == "" {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	writeJSON(w, statusCode, map[string]string{"error": message})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(v)
+}
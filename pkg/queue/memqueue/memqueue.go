@@ -0,0 +1,262 @@
+package memqueue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-node-executor/pkg/queue"
+)
+
+// sourceName identifies the in-memory queue in messages and stats
+const sourceName = "memory"
+
+// fetchWait is how long Fetch waits for work before returning empty
+const fetchWait = time.Second
+
+// DefaultMaxEvents is the number of events kept when no limit is given
+const DefaultMaxEvents = 10000
+
+// Backend is an in-memory work source, event sink and state store. It is
+// safe for concurrent use.
+type Backend struct {
+	mu      sync.Mutex
+	nextID  int64
+	queue   []*queue.Message
+	pending map[string]*queue.Message
+	notify  chan struct{}
+
+	// events is a ring of the last maxEvents events; published counts
+	// all events, so event i is at events[i%maxEvents] while kept
+	events      []*queue.Event
+	maxEvents   int
+	published   int
+	subscribers []chan *queue.Event
+
+	// state holds JSON so callers never share structures with the worker
+	state map[string][]byte
+}
+
+// Option configures a backend
+type Option func(*Backend)
+
+// WithMaxEvents sets how many published events are kept for Events
+// (default 10000); older events are dropped
+func WithMaxEvents(n int) Option {
+	return func(b *Backend) {
+		if n > 0 {
+			b.maxEvents = n
+		}
+	}
+}
+
+// New creates an empty in-memory backend
+func New(opts ...Option) *Backend {
+	b := &Backend{
+		pending:   make(map[string]*queue.Message),
+		notify:    make(chan struct{}, 1),
+		state:     make(map[string][]byte),
+		maxEvents: DefaultMaxEvents,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Name identifies the backend in health checks
+func (b *Backend) Name() string {
+	return "memory"
+}
+
+// Ping always succeeds
+func (b *Backend) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Submit enqueues a work item, which must marshal to the worker's work
+// item JSON. It returns the message ID.
+func (b *Backend) Submit(work interface{}) (string, error) {
+	data, err := json.Marshal(work)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal work: %w", err)
+	}
+
+	b.mu.Lock()
+	b.nextID++
+	id := strconv.FormatInt(b.nextID, 10)
+	b.queue = append(b.queue, &queue.Message{
+		ID:      id,
+		Source:  sourceName,
+		Data:    data,
+		Attempt: 1,
+	})
+	b.mu.Unlock()
+
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+	return id, nil
+}
+
+// Start does nothing; the queue needs no setup
+func (b *Backend) Start(ctx context.Context) error {
+	return nil
+}
+
+// Fetch takes up to max queued messages, waiting up to a second for work
+func (b *Backend) Fetch(ctx context.Context, max int) ([]*queue.Message, error) {
+	timer := time.NewTimer(fetchWait)
+	defer timer.Stop()
+
+	for {
+		if messages := b.take(max); len(messages) > 0 {
+			return messages, nil
+		}
+
+		select {
+		case <-b.notify:
+		case <-timer.C:
+			return nil, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// take moves up to max messages from the queue to pending
+func (b *Backend) take(max int) []*queue.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := min(max, len(b.queue))
+	messages := b.queue[:n:n]
+	b.queue = b.queue[n:]
+	for _, msg := range messages {
+		b.pending[msg.ID] = msg
+	}
+
+	// Wake another fetcher if work remains
+	if len(b.queue) > 0 {
+		select {
+		case b.notify <- struct{}{}:
+		default:
+		}
+	}
+	return messages
+}
+
+// Ack removes a message from pending
+func (b *Backend) Ack(ctx context.Context, msg *queue.Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.pending, msg.ID)
+	return nil
+}
+
+// Stats reports queued and pending messages
+func (b *Backend) Stats(ctx context.Context) ([]queue.SourceStats, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return []queue.SourceStats{{
+		Source:  sourceName,
+		Group:   sourceName,
+		Pending: int64(len(b.pending)),
+		Lag:     int64(len(b.queue)),
+	}}, nil
+}
+
+// Publish records an event and delivers it to subscribers. Subscribers
+// that are not keeping up miss events rather than block the worker.
+func (b *Backend) Publish(ctx context.Context, event *queue.Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.events) < b.maxEvents {
+		b.events = append(b.events, event)
+	} else {
+		b.events[b.published%b.maxEvents] = event
+	}
+	b.published++
+	for _, ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+	return nil
+}
+
+// Events returns the events published so far, starting at index from,
+// where the first event published is 0. Events dropped to keep within the
+// limit are skipped.
+func (b *Backend) Events(from int) []*queue.Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if from < 0 || from >= b.published {
+		return nil
+	}
+	from = max(from, b.published-len(b.events))
+
+	events := make([]*queue.Event, 0, b.published-from)
+	for i := from; i < b.published; i++ {
+		events = append(events, b.events[i%b.maxEvents])
+	}
+	return events
+}
+
+// Subscribe returns a channel receiving events published from now on
+func (b *Backend) Subscribe(buffer int) <-chan *queue.Event {
+	ch := make(chan *queue.Event, buffer)
+
+	b.mu.Lock()
+	b.subscribers = append(b.subscribers, ch)
+	b.mu.Unlock()
+
+	return ch
+}
+
+// Load returns a copy of a graph's state
+func (b *Backend) Load(ctx context.Context, graphID string) (*domain.GraphState, error) {
+	b.mu.Lock()
+	data, ok := b.state[graphID]
+	b.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", queue.ErrStateNotFound, graphID)
+	}
+
+	var state domain.GraphState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal state: %w", err)
+	}
+	return &state, nil
+}
+
+// Exists reports whether a graph's state exists
+func (b *Backend) Exists(ctx context.Context, graphID string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, ok := b.state[graphID]
+	return ok, nil
+}
+
+// Save stores a copy of a graph's state
+func (b *Backend) Save(ctx context.Context, state *domain.GraphState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	b.mu.Lock()
+	b.state[state.GraphID] = data
+	b.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,94 @@
+package memqueue
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aescanero/dago-node-executor/pkg/queue"
+)
+
+func TestEventsAreCapped(t *testing.T) {
+	b := New(WithMaxEvents(3))
+	for i := 0; i < 5; i++ {
+		if err := b.Publish(context.Background(), &queue.Event{ID: strconv.Itoa(i)}); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		from int
+		want []string
+	}{
+		{from: 0, want: []string{"2", "3", "4"}},
+		{from: 3, want: []string{"3", "4"}},
+		{from: 4, want: []string{"4"}},
+		{from: 5, want: nil},
+		{from: -1, want: nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, event := range b.Events(tt.from) {
+			got = append(got, event.ID)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("Events(%d) = %v, want %v", tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestWorkRoundTrip(t *testing.T) {
+	b := New()
+	id, err := b.Submit(map[string]string{"graph_id": "g1"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	messages, err := b.Fetch(context.Background(), 10)
+	if err != nil || len(messages) != 1 || messages[0].ID != id {
+		t.Fatalf("Fetch() = %v, %v; want the submitted message", messages, err)
+	}
+	if stats, _ := b.Stats(context.Background()); stats[0].Pending != 1 || stats[0].Lag != 0 {
+		t.Errorf("Stats() = %+v, want one pending message", stats[0])
+	}
+	if err := b.Ack(context.Background(), messages[0]); err != nil {
+		t.Fatalf("Ack() error = %v", err)
+	}
+	if stats, _ := b.Stats(context.Background()); stats[0].Pending != 0 {
+		t.Errorf("Stats() = %+v, want nothing pending", stats[0])
+	}
+}
+
+func TestHandlerToken(t *testing.T) {
+	srv := httptest.NewServer(Handler(New(), "secret"))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		auth   string
+		status int
+	}{
+		{name: "no token", status: http.StatusUnauthorized},
+		{name: "wrong token", auth: "Bearer nope", status: http.StatusUnauthorized},
+		{name: "token", auth: "Bearer secret", status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+"/queue/events", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request error = %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+)
+
+// ErrStateNotFound is returned by StateStore.Load for unknown graphs
+var ErrStateNotFound = errors.New("graph state not found")
+
+// Message is a work item delivered by a WorkSource
+type Message struct {
+	// ID identifies the message within its source
+	ID string
+
+	// Source names the stream or subject the message came from
+	Source string
+
+	// Data is the JSON-encoded work item
+	Data []byte
+
+	// Attempt is the delivery attempt, starting at 1
+	Attempt int
+}
+
+// Event is a node lifecycle event published to an EventSink
+type Event struct {
+	ID          string                 `json:"id"`
+	Type        string                 `json:"type"`
+	GraphID     string                 `json:"graph_id"`
+	NodeID      string                 `json:"node_id"`
+	Timestamp   time.Time              `json:"timestamp"`
+	Data        map[string]interface{} `json:"data"`
+	TraceParent string                 `json:"traceparent,omitempty"`
+}
+
+// Event types
+const (
+	EventNodeCompleted = "node.completed"
+	EventNodeFailed    = "node.failed"
+)
+
+// WorkSource delivers work items to the worker
+type WorkSource interface {
+	// Start prepares the source, e.g. by creating consumer groups
+	Start(ctx context.Context) error
+
+	// Fetch waits briefly for up to max messages. It returns no messages
+	// and no error when none arrived in time.
+	Fetch(ctx context.Context, max int) ([]*Message, error)
+
+	// Ack marks a message as processed
+	Ack(ctx context.Context, msg *Message) error
+}
+
+// Retrier is implemented by work sources that can redeliver a message
+// instead of having it acknowledged
+type Retrier interface {
+	// Nak returns msg to the source for redelivery after delay. The
+	// source may give up after its own delivery limit.
+	Nak(ctx context.Context, msg *Message, delay time.Duration) error
+}
+
+// EventSink publishes node events
+type EventSink interface {
+	Publish(ctx context.Context, event *Event) error
+}
+
+// StateStore loads and saves graph state
+type StateStore interface {
+	// Load returns the state of a graph, or an error wrapping ErrStateNotFound
+	Load(ctx context.Context, graphID string) (*domain.GraphState, error)
+
+	// Save stores the state of a graph
+	Save(ctx context.Context, state *domain.GraphState) error
+}
+
+// StateChecker is implemented by state stores that can tell whether a
+// graph's state exists without loading it, which would refresh its TTL
+type StateChecker interface {
+	Exists(ctx context.Context, graphID string) (bool, error)
+}
+
+// Pinger is implemented by components backed by a remote service. The
+// worker reports one health check per distinct Name.
+type Pinger interface {
+	Name() string
+	Ping(ctx context.Context) error
+}
+
+// SourceStats describes the backlog of a work source
+type SourceStats struct {
+	Source    string `json:"stream"`
+	Group     string `json:"group"`
+	Consumers int64  `json:"consumers"`
+	Pending   int64  `json:"pending"`
+	Lag       int64  `json:"lag"`
+}
+
+// StatsReporter is implemented by work sources that can report their backlog
+type StatsReporter interface {
+	Stats(ctx context.Context) ([]SourceStats, error)
+}
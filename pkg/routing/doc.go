@@ -0,0 +1,14 @@
+// Package routing routes work items to the workers able to execute them.
+//
+// Workers advertise their capabilities (LLM providers, models, tools and
+// labels) in a Redis registry and keep them fresh with a heartbeat. A work
+// item carries a priority (high, normal or low) and optional requirements.
+// Items without requirements go to the priority stream <base>.<priority>;
+// items with requirements go to <base>.<priority>.<requirements key> and
+// the route is recorded in the registry. Every worker consumes the priority
+// streams plus the routes whose requirements it satisfies, so an item only
+// reaches a worker that can execute it.
+//
+// Dispatchers call Registry.Submit, or Registry.Route and add the item to
+// the returned stream themselves.
+package routing
@@ -0,0 +1,125 @@
+package routing
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Member keeps a worker registered and follows the routes it can serve
+type Member struct {
+	registry *Registry
+	collect  func(ctx context.Context) Capabilities
+	onRoutes func(routes []Route) error
+	logger   *zap.Logger
+
+	mu     sync.RWMutex
+	caps   Capabilities
+	served string
+}
+
+// Join creates a member of the registry. collect returns the worker's
+// current capabilities; onRoutes receives the routes the worker serves
+// whenever they change, starting with the first Refresh, and is called
+// again on the next Refresh if it fails.
+func (r *Registry) Join(collect func(ctx context.Context) Capabilities, onRoutes func(routes []Route) error, logger *zap.Logger) *Member {
+	return &Member{
+		registry: r,
+		collect:  collect,
+		onRoutes: onRoutes,
+		logger:   logger,
+	}
+}
+
+// Refresh registers the current capabilities and updates the served routes
+func (m *Member) Refresh(ctx context.Context) error {
+	caps := m.collect(ctx)
+	caps.Providers = sortedSet(caps.Providers)
+	caps.Models = sortedSet(caps.Models)
+	caps.Tools = sortedSet(caps.Tools)
+	caps.UpdatedAt = time.Now().UTC()
+	if err := m.registry.Register(ctx, caps); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.caps = caps
+	m.mu.Unlock()
+
+	routes, err := m.registry.Routes(ctx)
+	if err != nil {
+		return err
+	}
+
+	served := m.registry.PriorityRoutes()
+	for _, route := range routes {
+		if caps.Check(route.Requirements) == nil {
+			served = append(served, route)
+		}
+	}
+
+	streams := make([]string, len(served))
+	for i, route := range served {
+		streams[i] = route.Stream
+	}
+	fingerprint := strings.Join(streams, ",")
+
+	m.mu.RLock()
+	changed := fingerprint != m.served
+	m.mu.RUnlock()
+	if !changed {
+		return nil
+	}
+
+	if err := m.onRoutes(served); err != nil {
+		return err
+	}
+	m.logger.Info("serving routes", zap.Strings("streams", streams))
+
+	m.mu.Lock()
+	m.served = fingerprint
+	m.mu.Unlock()
+	return nil
+}
+
+// Run refreshes the registration every third of the TTL until ctx is done
+func (m *Member) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.registry.TTL() / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Refresh(ctx); err != nil && ctx.Err() == nil {
+				m.logger.Warn("failed to refresh routing registration", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Capabilities returns the capabilities last registered
+func (m *Member) Capabilities() Capabilities {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.caps
+}
+
+// Check checks requirements against the capabilities last registered
+func (m *Member) Check(req Requirements) error {
+	return m.Capabilities().Check(req)
+}
+
+// Registry returns the registry the member joined
+func (m *Member) Registry() *Registry {
+	return m.registry
+}
+
+// Leave removes the worker from the registry
+func (m *Member) Leave(ctx context.Context) error {
+	return m.registry.Deregister(ctx, m.Capabilities().WorkerID)
+}
@@ -0,0 +1,151 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// fakeWorker supplies a member's capabilities and records its routes
+type fakeWorker struct {
+	mu     sync.Mutex
+	caps   Capabilities
+	calls  [][]string
+	failed error
+}
+
+func (f *fakeWorker) collect(ctx context.Context) Capabilities {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.caps
+}
+
+func (f *fakeWorker) onRoutes(routes []Route) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failed != nil {
+		return f.failed
+	}
+	streams := make([]string, len(routes))
+	for i, route := range routes {
+		streams[i] = route.Stream
+	}
+	f.calls = append(f.calls, streams)
+	return nil
+}
+
+// set replaces the capabilities and the onRoutes error
+func (f *fakeWorker) set(caps Capabilities, failed error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.caps = caps
+	f.failed = failed
+}
+
+// routeCalls returns the streams of each onRoutes call so far
+func (f *fakeWorker) routeCalls() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]string(nil), f.calls...)
+}
+
+func TestMemberRefresh(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newRegistry(t, RegistryConfig{})
+	worker := &fakeWorker{caps: Capabilities{
+		WorkerID: "w1",
+		Models:   []string{"fast", "claude-sonnet", "fast", ""},
+		Tools:    []string{"search"},
+	}}
+	m := r.Join(worker.collect, worker.onRoutes, zap.NewNop())
+
+	refresh := func(wantErr bool) {
+		t.Helper()
+		if err := m.Refresh(ctx); (err != nil) != wantErr {
+			t.Fatalf("Refresh() error = %v, want error %v", err, wantErr)
+		}
+	}
+	registered := func() *Capabilities {
+		t.Helper()
+		workers, err := r.Workers(ctx)
+		if err != nil {
+			t.Fatalf("Workers() error = %v", err)
+		}
+		for _, caps := range workers {
+			if caps.WorkerID == "w1" {
+				return &caps
+			}
+		}
+		return nil
+	}
+	priority := []string{"executor.work.high", "executor.work.normal", "executor.work.low"}
+
+	// The first refresh registers the worker and serves the priority streams
+	refresh(false)
+	caps := registered()
+	if caps == nil || !reflect.DeepEqual(caps.Models, []string{"claude-sonnet", "fast"}) {
+		t.Fatalf("registered capabilities = %+v, want sorted distinct models", caps)
+	}
+	if calls := worker.routeCalls(); !reflect.DeepEqual(calls, [][]string{priority}) {
+		t.Fatalf("onRoutes calls = %v, want the priority streams", calls)
+	}
+
+	// A route the worker can't serve changes nothing
+	browse := Requirements{Tools: []string{"browse"}}
+	register(t, r, Capabilities{WorkerID: "w2", Tools: []string{"browse"}})
+	if _, err := r.Route(ctx, PriorityHigh, browse); err != nil {
+		t.Fatalf("Route() error = %v", err)
+	}
+	refresh(false)
+	if calls := worker.routeCalls(); len(calls) != 1 {
+		t.Errorf("onRoutes calls = %v, want no call for unchanged routes", calls)
+	}
+	if err := m.Check(browse); !errors.Is(err, ErrRequirementsNotMet) {
+		t.Errorf("Check() error = %v, want ErrRequirementsNotMet", err)
+	}
+
+	// Once it gains the capability, the next refresh registers it and
+	// serves the route
+	worker.set(Capabilities{WorkerID: "w1", Tools: []string{"search", "browse"}}, nil)
+	refresh(false)
+	if caps := registered(); caps == nil || !reflect.DeepEqual(caps.Tools, []string{"browse", "search"}) {
+		t.Errorf("registered capabilities = %+v, want the new tool", caps)
+	}
+	if err := m.Check(browse); err != nil {
+		t.Errorf("Check() error = %v, want nil", err)
+	}
+	withBrowse := append(append([]string(nil), priority...), "executor.work.high."+browse.Key())
+	if calls := worker.routeCalls(); len(calls) != 2 || !reflect.DeepEqual(calls[1], withBrowse) {
+		t.Errorf("onRoutes calls = %v, want %v last", calls, withBrowse)
+	}
+
+	// A worker dropped from the registry registers again
+	if err := r.Deregister(ctx, "w1"); err != nil {
+		t.Fatal(err)
+	}
+	refresh(false)
+	if registered() == nil {
+		t.Error("worker not registered again after a refresh")
+	}
+
+	// Losing the capability stops serving the route; a failed update is
+	// retried on the next refresh
+	worker.set(Capabilities{WorkerID: "w1", Tools: []string{"search"}}, errors.New("redis unavailable"))
+	refresh(true)
+	worker.set(Capabilities{WorkerID: "w1", Tools: []string{"search"}}, nil)
+	refresh(false)
+	if calls := worker.routeCalls(); len(calls) != 3 || !reflect.DeepEqual(calls[2], priority) {
+		t.Errorf("onRoutes calls = %v, want the priority streams last", calls)
+	}
+
+	if err := m.Leave(ctx); err != nil {
+		t.Fatalf("Leave() error = %v", err)
+	}
+	if registered() != nil {
+		t.Error("worker still registered after Leave()")
+	}
+}
@@ -0,0 +1,262 @@
+package routing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Defaults for RegistryConfig fields left empty
+const (
+	DefaultKeyPrefix  = "dago:routing:"
+	DefaultBaseStream = "executor.work"
+	DefaultTTL        = 30 * time.Second
+)
+
+// ErrNoCapableWorker is returned when routing work that no live worker can
+// execute
+var ErrNoCapableWorker = errors.New("no live worker satisfies the requirements")
+
+// RegistryConfig holds registry settings
+type RegistryConfig struct {
+	// Namespace, when set, prefixes every key as <namespace>:<key>, like
+	// the worker's REDIS_NAMESPACE
+	Namespace string
+
+	// KeyPrefix prefixes the registry keys (default dago:routing:)
+	KeyPrefix string
+
+	// BaseStream is the stream routed streams are named after (default
+	// executor.work)
+	BaseStream string
+
+	// TTL is how long a worker stays registered without a heartbeat
+	// (default 30s)
+	TTL time.Duration
+}
+
+// Registry stores worker capabilities and routes in Redis. Live workers
+// are kept in a sorted set scored by expiry, their capabilities in a hash,
+// and routes in a hash keyed by stream.
+type Registry struct {
+	client     redis.UniversalClient
+	namespace  string
+	workersKey string
+	capsKey    string
+	routesKey  string
+	base       string
+	ttl        time.Duration
+}
+
+// NewRegistry creates a registry
+func NewRegistry(client redis.UniversalClient, cfg RegistryConfig) *Registry {
+	prefix := cfg.KeyPrefix
+	if prefix == "" {
+		prefix = DefaultKeyPrefix
+	}
+	prefix = namespaced(cfg.Namespace, prefix)
+
+	r := &Registry{
+		client:     client,
+		namespace:  cfg.Namespace,
+		workersKey: prefix + "workers",
+		capsKey:    prefix + "capabilities",
+		routesKey:  prefix + "routes",
+		base:       cfg.BaseStream,
+		ttl:        cfg.TTL,
+	}
+	if r.base == "" {
+		r.base = DefaultBaseStream
+	}
+	if r.ttl <= 0 {
+		r.ttl = DefaultTTL
+	}
+	return r
+}
+
+// namespaced prefixes key with the namespace, if any
+func namespaced(namespace, key string) string {
+	if namespace == "" {
+		return key
+	}
+	return namespace + ":" + key
+}
+
+// TTL returns how long a registration lasts without a heartbeat
+func (r *Registry) TTL() time.Duration {
+	return r.ttl
+}
+
+// PriorityRoutes returns the routes of work without requirements, one per
+// priority
+func (r *Registry) PriorityRoutes() []Route {
+	routes := make([]Route, len(Priorities))
+	for i, priority := range Priorities {
+		routes[i] = Route{Stream: StreamFor(r.base, priority, Requirements{}), Priority: priority}
+	}
+	return routes
+}
+
+// Register records a worker's capabilities and extends its registration
+// by the TTL
+func (r *Registry) Register(ctx context.Context, caps Capabilities) error {
+	if caps.WorkerID == "" {
+		return fmt.Errorf("worker ID is required")
+	}
+	data, err := json.Marshal(caps)
+	if err != nil {
+		return fmt.Errorf("failed to marshal capabilities: %w", err)
+	}
+
+	expiry := time.Now().Add(r.ttl).UnixMilli()
+	pipe := r.client.Pipeline()
+	pipe.ZAdd(ctx, r.workersKey, redis.Z{Score: float64(expiry), Member: caps.WorkerID})
+	pipe.HSet(ctx, r.capsKey, caps.WorkerID, data)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to register worker %s: %w", caps.WorkerID, err)
+	}
+	return nil
+}
+
+// Deregister removes a worker from the registry
+func (r *Registry) Deregister(ctx context.Context, workerID string) error {
+	pipe := r.client.Pipeline()
+	pipe.ZRem(ctx, r.workersKey, workerID)
+	pipe.HDel(ctx, r.capsKey, workerID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to deregister worker %s: %w", workerID, err)
+	}
+	return nil
+}
+
+// Workers returns the capabilities of the live workers and drops the
+// workers whose registration expired
+func (r *Registry) Workers(ctx context.Context) ([]Capabilities, error) {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+
+	expired, err := r.client.ZRangeByScore(ctx, r.workersKey, &redis.ZRangeBy{Min: "-inf", Max: "(" + now}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workers: %w", err)
+	}
+	if len(expired) > 0 {
+		pipe := r.client.Pipeline()
+		pipe.ZRemRangeByScore(ctx, r.workersKey, "-inf", "("+now)
+		pipe.HDel(ctx, r.capsKey, expired...)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, fmt.Errorf("failed to drop expired workers: %w", err)
+		}
+	}
+
+	ids, err := r.client.ZRangeByScore(ctx, r.workersKey, &redis.ZRangeBy{Min: now, Max: "+inf"}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workers: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	values, err := r.client.HMGet(ctx, r.capsKey, ids...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read worker capabilities: %w", err)
+	}
+
+	workers := make([]Capabilities, 0, len(values))
+	for _, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var caps Capabilities
+		if err := json.Unmarshal([]byte(data), &caps); err != nil {
+			continue
+		}
+		workers = append(workers, caps)
+	}
+	return workers, nil
+}
+
+// Routes returns the recorded routes of work with requirements, ordered by
+// stream
+func (r *Registry) Routes(ctx context.Context) ([]Route, error) {
+	values, err := r.client.HGetAll(ctx, r.routesKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read routes: %w", err)
+	}
+
+	routes := make([]Route, 0, len(values))
+	for _, data := range values {
+		var route Route
+		if err := json.Unmarshal([]byte(data), &route); err != nil {
+			continue
+		}
+		routes = append(routes, route)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].Stream < routes[j].Stream
+	})
+	return routes, nil
+}
+
+// Route returns the stream for work with the given priority and
+// requirements. For work with requirements it records the route, so that
+// capable workers start consuming it, and returns ErrNoCapableWorker if no
+// live worker satisfies the requirements.
+func (r *Registry) Route(ctx context.Context, priority Priority, req Requirements) (string, error) {
+	if priority == "" {
+		priority = PriorityNormal
+	}
+	stream := StreamFor(r.base, priority, req)
+	if req.IsZero() {
+		return stream, nil
+	}
+
+	workers, err := r.Workers(ctx)
+	if err != nil {
+		return "", err
+	}
+	capable := false
+	for _, caps := range workers {
+		if caps.Check(req) == nil {
+			capable = true
+			break
+		}
+	}
+	if !capable {
+		return "", fmt.Errorf("%w: %s", ErrNoCapableWorker, stream)
+	}
+
+	data, err := json.Marshal(Route{Stream: stream, Priority: priority, Requirements: req.normalized()})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal route: %w", err)
+	}
+	if err := r.client.HSetNX(ctx, r.routesKey, stream, data).Err(); err != nil {
+		return "", fmt.Errorf("failed to record route %s: %w", stream, err)
+	}
+	return stream, nil
+}
+
+// Submit routes a work item and adds it to its stream. data is the JSON
+// work item. It returns the stream and the entry ID.
+func (r *Registry) Submit(ctx context.Context, data []byte, priority Priority, req Requirements) (string, string, error) {
+	stream, err := r.Route(ctx, priority, req)
+	if err != nil {
+		return "", "", err
+	}
+
+	id, err := r.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: namespaced(r.namespace, stream),
+		Values: map[string]interface{}{
+			"data": string(data),
+		},
+	}).Result()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to add work to %s: %w", stream, err)
+	}
+	return stream, id, nil
+}
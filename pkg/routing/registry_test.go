@@ -0,0 +1,115 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newRegistry returns a registry on an in-memory Redis
+func newRegistry(t *testing.T, cfg RegistryConfig) (*Registry, redis.UniversalClient) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return NewRegistry(client, cfg), client
+}
+
+// register registers a worker, failing the test on errors
+func register(t *testing.T, r *Registry, caps Capabilities) {
+	t.Helper()
+	if err := r.Register(context.Background(), caps); err != nil {
+		t.Fatalf("Register(%s) error = %v", caps.WorkerID, err)
+	}
+}
+
+func TestRoute(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newRegistry(t, RegistryConfig{})
+	gpu := Requirements{Models: []string{"llama3:70b"}, Labels: map[string]string{"gpu": "true"}}
+
+	// Work without requirements needs no live worker
+	if stream, err := r.Route(ctx, "", Requirements{}); err != nil || stream != "executor.work.normal" {
+		t.Errorf("Route() = %q, %v; want executor.work.normal", stream, err)
+	}
+
+	register(t, r, Capabilities{WorkerID: "cpu", Models: []string{"llama3:70b"}})
+	if _, err := r.Route(ctx, PriorityHigh, gpu); !errors.Is(err, ErrNoCapableWorker) {
+		t.Errorf("Route() without a capable worker error = %v, want ErrNoCapableWorker", err)
+	}
+	if routes, err := r.Routes(ctx); err != nil || len(routes) != 0 {
+		t.Errorf("Routes() = %v, %v; want none recorded", routes, err)
+	}
+
+	register(t, r, Capabilities{WorkerID: "gpu", Models: []string{"llama3:70b"}, Labels: map[string]string{"gpu": "true"}})
+	want := "executor.work.high." + gpu.Key()
+	stream, err := r.Route(ctx, PriorityHigh, gpu)
+	if err != nil || stream != want {
+		t.Fatalf("Route() = %q, %v; want %q", stream, err, want)
+	}
+	reordered := Requirements{Models: []string{"llama3:70b", "llama3:70b"}, Labels: map[string]string{"gpu": "true"}}
+	if stream, err := r.Route(ctx, PriorityHigh, reordered); err != nil || stream != want {
+		t.Errorf("Route() of the same requirements = %q, %v; want %q", stream, err, want)
+	}
+
+	routes, err := r.Routes(ctx)
+	if err != nil {
+		t.Fatalf("Routes() error = %v", err)
+	}
+	wantRoutes := []Route{{Stream: want, Priority: PriorityHigh, Requirements: gpu}}
+	if !reflect.DeepEqual(routes, wantRoutes) {
+		t.Errorf("Routes() = %+v, want %+v", routes, wantRoutes)
+	}
+}
+
+func TestWorkersExpire(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newRegistry(t, RegistryConfig{TTL: 50 * time.Millisecond})
+	req := Requirements{Tools: []string{"search"}}
+	register(t, r, Capabilities{WorkerID: "w1", Tools: []string{"search"}})
+
+	if _, err := r.Route(ctx, PriorityNormal, req); err != nil {
+		t.Fatalf("Route() error = %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if workers, err := r.Workers(ctx); err != nil || len(workers) != 0 {
+		t.Errorf("Workers() after the TTL = %+v, %v; want none", workers, err)
+	}
+	if _, err := r.Route(ctx, PriorityNormal, req); !errors.Is(err, ErrNoCapableWorker) {
+		t.Errorf("Route() after the TTL error = %v, want ErrNoCapableWorker", err)
+	}
+}
+
+func TestSubmit(t *testing.T) {
+	ctx := context.Background()
+	r, client := newRegistry(t, RegistryConfig{Namespace: "staging", BaseStream: "work"})
+	register(t, r, Capabilities{WorkerID: "w1", Tools: []string{"search"}})
+	req := Requirements{Tools: []string{"search"}}
+
+	stream, id, err := r.Submit(ctx, []byte(`{"graph_id":"g1"}`), PriorityLow, req)
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if want := "work.low." + req.Key(); stream != want {
+		t.Errorf("Submit() stream = %q, want %q", stream, want)
+	}
+
+	// The entry is added under the namespace, like the worker reads it
+	entries, err := client.XRange(ctx, "staging:"+stream, "-", "+").Result()
+	if err != nil || len(entries) != 1 || entries[0].ID != id {
+		t.Fatalf("XRange() = %v, %v; want entry %s", entries, err, id)
+	}
+	if data := entries[0].Values["data"]; data != `{"graph_id":"g1"}` {
+		t.Errorf("entry data = %v, want the work item", data)
+	}
+
+	if _, _, err := r.Submit(ctx, []byte(`{}`), PriorityLow, Requirements{Tools: []string{"browse"}}); !errors.Is(err, ErrNoCapableWorker) {
+		t.Errorf("Submit() without a capable worker error = %v, want ErrNoCapableWorker", err)
+	}
+}
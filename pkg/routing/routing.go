@@ -0,0 +1,156 @@
+package routing
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Priority orders work: workers favor higher priority streams
+type Priority string
+
+// Work priorities
+const (
+	PriorityHigh   Priority = "high"
+	PriorityNormal Priority = "normal"
+	PriorityLow    Priority = "low"
+)
+
+// Priorities lists the priorities from highest to lowest
+var Priorities = []Priority{PriorityHigh, PriorityNormal, PriorityLow}
+
+// ErrRequirementsNotMet is returned when a worker lacks a capability a
+// work item requires
+var ErrRequirementsNotMet = errors.New("requirements not met")
+
+// ParsePriority parses a priority; an empty string is normal
+func ParsePriority(s string) (Priority, error) {
+	switch p := Priority(strings.ToLower(strings.TrimSpace(s))); p {
+	case "":
+		return PriorityNormal, nil
+	case PriorityHigh, PriorityNormal, PriorityLow:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid priority %q: must be high, normal or low", s)
+	}
+}
+
+// Requirements are the capabilities a work item needs. Every listed
+// provider, model and tool must be available, and every label must match.
+type Requirements struct {
+	Providers []string          `json:"providers,omitempty"`
+	Models    []string          `json:"models,omitempty"`
+	Tools     []string          `json:"tools,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// IsZero reports whether there are no requirements
+func (r Requirements) IsZero() bool {
+	return len(r.Providers) == 0 && len(r.Models) == 0 && len(r.Tools) == 0 && len(r.Labels) == 0
+}
+
+// normalized returns the requirements with sorted, deduplicated lists
+func (r Requirements) normalized() Requirements {
+	return Requirements{
+		Providers: sortedSet(r.Providers),
+		Models:    sortedSet(r.Models),
+		Tools:     sortedSet(r.Tools),
+		Labels:    r.Labels,
+	}
+}
+
+// Key returns a short identifier of the requirements, equal for
+// requirements listing the same capabilities in any order
+func (r Requirements) Key() string {
+	// Map keys are marshalled in sorted order
+	data, _ := json.Marshal(r.normalized())
+	sum := sha256.Sum256(data)
+	return "req-" + hex.EncodeToString(sum[:6])
+}
+
+// Capabilities are what a worker can execute
+type Capabilities struct {
+	WorkerID  string            `json:"worker_id"`
+	Providers []string          `json:"providers,omitempty"`
+	Models    []string          `json:"models,omitempty"`
+	Tools     []string          `json:"tools,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// Check returns an error wrapping ErrRequirementsNotMet and naming the
+// missing capabilities, or nil if the requirements are satisfied
+func (c Capabilities) Check(r Requirements) error {
+	var missing []string
+	missing = appendMissing(missing, "provider", r.Providers, c.Providers)
+	missing = appendMissing(missing, "model", r.Models, c.Models)
+	missing = appendMissing(missing, "tool", r.Tools, c.Tools)
+
+	labels := make([]string, 0, len(r.Labels))
+	for label := range r.Labels {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		if value, ok := c.Labels[label]; !ok || value != r.Labels[label] {
+			missing = append(missing, fmt.Sprintf("label %s=%s", label, r.Labels[label]))
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: missing %s", ErrRequirementsNotMet, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// appendMissing appends the required names absent from available
+func appendMissing(missing []string, kind string, required, available []string) []string {
+	have := make(map[string]bool, len(available))
+	for _, name := range available {
+		have[name] = true
+	}
+	for _, name := range required {
+		if !have[name] {
+			missing = append(missing, kind+" "+name)
+		}
+	}
+	return missing
+}
+
+// Route is a work stream and the requirements of the items routed to it
+type Route struct {
+	Stream       string       `json:"stream"`
+	Priority     Priority     `json:"priority"`
+	Requirements Requirements `json:"requirements,omitempty"`
+}
+
+// StreamFor returns the stream of work with the given priority and
+// requirements, relative to the base stream
+func StreamFor(base string, priority Priority, r Requirements) string {
+	if priority == "" {
+		priority = PriorityNormal
+	}
+	if r.IsZero() {
+		return base + "." + string(priority)
+	}
+	return base + "." + string(priority) + "." + r.Key()
+}
+
+// sortedSet returns the distinct non-empty values in order
+func sortedSet(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var out []string
+	for _, value := range values {
+		if value != "" && !seen[value] {
+			seen[value] = true
+			out = append(out, value)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
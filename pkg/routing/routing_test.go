@@ -0,0 +1,126 @@
+package routing
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCapabilitiesCheck(t *testing.T) {
+	caps := Capabilities{
+		WorkerID:  "w1",
+		Providers: []string{"anthropic"},
+		Models:    []string{"claude-sonnet", "fast"},
+		Tools:     []string{"search", "filesystem__read"},
+		Labels:    map[string]string{"gpu": "true", "region": "eu"},
+	}
+
+	tests := []struct {
+		name    string
+		req     Requirements
+		missing string
+	}{
+		{name: "no requirements", req: Requirements{}},
+		{name: "all met", req: Requirements{
+			Providers: []string{"anthropic"},
+			Models:    []string{"fast", "claude-sonnet"},
+			Tools:     []string{"search"},
+			Labels:    map[string]string{"gpu": "true"},
+		}},
+		{name: "missing provider", req: Requirements{Providers: []string{"openai"}}, missing: "provider openai"},
+		{name: "missing model", req: Requirements{Models: []string{"fast", "llama3:70b"}}, missing: "model llama3:70b"},
+		{name: "missing tool", req: Requirements{Tools: []string{"filesystem__write"}}, missing: "tool filesystem__write"},
+		{name: "missing label", req: Requirements{Labels: map[string]string{"tier": "gold"}}, missing: "label tier=gold"},
+		{name: "label value differs", req: Requirements{Labels: map[string]string{"region": "us"}}, missing: "label region=us"},
+		{name: "every missing capability named", req: Requirements{
+			Models: []string{"gpt-4o"},
+			Tools:  []string{"search", "browse"},
+			Labels: map[string]string{"region": "us", "gpu": "false"},
+		}, missing: "missing model gpt-4o, tool browse, label gpu=false, label region=us"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := caps.Check(tt.req)
+			if tt.missing == "" {
+				if err != nil {
+					t.Errorf("Check() error = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrRequirementsNotMet) || !strings.Contains(err.Error(), tt.missing) {
+				t.Errorf("Check() error = %v, want ErrRequirementsNotMet naming %q", err, tt.missing)
+			}
+		})
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Priority
+		wantErr bool
+	}{
+		{in: "", want: PriorityNormal},
+		{in: "high", want: PriorityHigh},
+		{in: " Low ", want: PriorityLow},
+		{in: "NORMAL", want: PriorityNormal},
+		{in: "urgent", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParsePriority(tt.in)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParsePriority() = %q, %v; want %q, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStreamFor(t *testing.T) {
+	req := Requirements{
+		Models: []string{"llama3:70b"},
+		Tools:  []string{"search", "browse"},
+		Labels: map[string]string{"gpu": "true"},
+	}
+	key := req.Key()
+
+	tests := []struct {
+		name     string
+		priority Priority
+		req      Requirements
+		want     string
+	}{
+		{"default priority", "", Requirements{}, "executor.work.normal"},
+		{"high priority", PriorityHigh, Requirements{}, "executor.work.high"},
+		{"low priority", PriorityLow, Requirements{}, "executor.work.low"},
+		{"empty requirements", PriorityHigh, Requirements{Tools: []string{}, Labels: map[string]string{}}, "executor.work.high"},
+		{"requirements", PriorityHigh, req, "executor.work.high." + key},
+		{"requirements in another order", PriorityHigh, Requirements{
+			Models: []string{"llama3:70b", "llama3:70b"},
+			Tools:  []string{"browse", "search", ""},
+			Labels: map[string]string{"gpu": "true"},
+		}, "executor.work.high." + key},
+		{"requirements at default priority", "", req, "executor.work.normal." + key},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StreamFor("executor.work", tt.priority, tt.req); got != tt.want {
+				t.Errorf("StreamFor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Any other capability is another route
+	for _, other := range []Requirements{
+		{Models: []string{"llama3:70b"}, Tools: []string{"search", "browse"}},
+		{Models: []string{"llama3:70b"}, Tools: []string{"search", "browse"}, Labels: map[string]string{"gpu": "false"}},
+		{Providers: []string{"ollama"}, Models: []string{"llama3:70b"}, Tools: []string{"search", "browse"}, Labels: map[string]string{"gpu": "true"}},
+	} {
+		if other.Key() == key {
+			t.Errorf("requirements %+v share the key of %+v", other, req)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Auth profile types
+const (
+	AuthBearer = "bearer"
+	AuthBasic  = "basic"
+	AuthAPIKey = "api_key"
+)
+
+// defaultAPIKeyHeader carries API keys when a profile names neither a
+// header nor a query parameter
+const defaultAPIKeyHeader = "X-API-Key"
+
+// AuthProfile holds the credentials of a service
+type AuthProfile struct {
+	// Type is bearer, basic or api_key
+	Type string
+
+	// Token is the bearer token
+	Token string
+
+	// Username and Password are the basic auth credentials
+	Username string
+	Password string
+
+	// Key is the API key, sent in Header (default X-API-Key) or, when set,
+	// in the Query parameter
+	Key    string
+	Header string
+	Query  string
+}
+
+// Validate checks the profile has the fields of its type
+func (p AuthProfile) Validate() error {
+	switch p.Type {
+	case AuthBearer:
+		if p.Token == "" {
+			return fmt.Errorf("bearer profile needs a token")
+		}
+	case AuthBasic:
+		if p.Username == "" {
+			return fmt.Errorf("basic profile needs a username")
+		}
+	case AuthAPIKey:
+		if p.Key == "" {
+			return fmt.Errorf("api_key profile needs a key")
+		}
+		if p.Header != "" && p.Query != "" {
+			return fmt.Errorf("api_key profile takes a header or a query parameter, not both")
+		}
+	default:
+		return fmt.Errorf("unknown auth type %q", p.Type)
+	}
+	return nil
+}
+
+// apply adds the credentials to req
+func (p AuthProfile) apply(req *http.Request) {
+	switch p.Type {
+	case AuthBearer:
+		req.Header.Set("Authorization", "Bearer "+p.Token)
+	case AuthBasic:
+		req.SetBasicAuth(p.Username, p.Password)
+	case AuthAPIKey:
+		if p.Query != "" {
+			q := req.URL.Query()
+			q.Set(p.Query, p.Key)
+			req.URL.RawQuery = q.Encode()
+			return
+		}
+		header := p.Header
+		if header == "" {
+			header = defaultAPIKeyHeader
+		}
+		req.Header.Set(header, p.Key)
+	}
+}
@@ -0,0 +1,358 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-node-executor/pkg/tools/catalog"
+	"github.com/aescanero/dago-node-executor/pkg/tools/function"
+	"go.uber.org/zap"
+)
+
+// maxSpecBytes caps the size of an OpenAPI document
+const maxSpecBytes = 10 << 20
+
+// Spec is an OpenAPI 3 document whose operations become tools
+type Spec struct {
+	// Name is the namespace of the tools and the name of their endpoint
+	Name string
+
+	// Source is a file path or an http(s) URL
+	Source string
+
+	// BaseURL overrides the document's first server URL
+	BaseURL string
+
+	// Auth names the auth profile of the calls, also used to fetch a
+	// Source URL
+	Auth string
+
+	// Operations are globs of the tool names to expose (default all)
+	Operations []string
+
+	// Headers are added to every call; values may hold {{variable}}
+	// placeholders
+	Headers map[string]string
+}
+
+// Backend exposes the operations of OpenAPI documents as tools, one
+// namespace per document, and executes them with a Client. It implements
+// catalog.Backend.
+type Backend struct {
+	client *Client
+	specs  []Spec
+	logger *zap.Logger
+
+	// loaded holds the tools of each loaded document
+	mu     sync.RWMutex
+	loaded map[string]*function.Registry
+}
+
+// NewBackend creates a backend for specs. Documents are loaded by the
+// first Tools call; one that fails is retried on the next.
+func NewBackend(client *Client, specs []Spec, logger *zap.Logger) *Backend {
+	return &Backend{
+		client: client,
+		specs:  specs,
+		logger: logger,
+		loaded: make(map[string]*function.Registry),
+	}
+}
+
+// Tools lists the tools of each document, loading the ones not loaded yet
+func (b *Backend) Tools(ctx context.Context) (map[string][]string, error) {
+	var errs []error
+	for _, spec := range b.specs {
+		b.mu.RLock()
+		_, ok := b.loaded[spec.Name]
+		b.mu.RUnlock()
+		if ok {
+			continue
+		}
+		if err := b.load(ctx, spec); err != nil {
+			errs = append(errs, fmt.Errorf("openapi %s: %w", spec.Name, err))
+		}
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	tools := make(map[string][]string, len(b.loaded))
+	for name, registry := range b.loaded {
+		tools[name], _ = registry.ListTools(ctx)
+	}
+	return tools, errors.Join(errs...)
+}
+
+// Call executes an operation of a document
+func (b *Backend) Call(ctx context.Context, namespace, tool string, params map[string]interface{}) (interface{}, error) {
+	b.mu.RLock()
+	registry, ok := b.loaded[namespace]
+	b.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", catalog.ErrToolNotFound, catalog.Name(namespace, tool))
+	}
+	return registry.Execute(ctx, tool, params)
+}
+
+// Definitions returns the definitions of the loaded tools under their
+// catalog names, sorted by name
+func (b *Backend) Definitions() []domain.Tool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var defs []domain.Tool
+	for namespace, registry := range b.loaded {
+		for _, def := range registry.Definitions() {
+			def.Name = catalog.Name(namespace, def.Name)
+			defs = append(defs, def)
+		}
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs
+}
+
+// load reads and parses a document, adds its endpoint to the client and
+// registers its operations
+func (b *Backend) load(ctx context.Context, spec Spec) error {
+	data, err := b.read(ctx, spec)
+	if err != nil {
+		return err
+	}
+	doc, err := ParseOpenAPI(data)
+	if err != nil {
+		return err
+	}
+
+	baseURL, err := specBaseURL(spec, doc)
+	if err != nil {
+		return err
+	}
+
+	registry := function.NewRegistry(b.logger)
+	methods := make(map[string]bool)
+	count := 0
+	for _, op := range doc.Operations {
+		if !matchAny(spec.Operations, op.Tool) {
+			continue
+		}
+		count++
+		methods[op.Method] = true
+		registry.RegisterTool(op.Definition(op.Tool), func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+			return b.call(ctx, spec.Name, op, params)
+		})
+	}
+
+	endpoint := Endpoint{BaseURL: baseURL, Auth: spec.Auth, Headers: spec.Headers}
+	for method := range methods {
+		endpoint.Methods = append(endpoint.Methods, method)
+	}
+	if len(endpoint.Methods) == 0 {
+		endpoint.Methods = []string{http.MethodGet}
+	}
+	if err := b.client.AddEndpoint(spec.Name, endpoint); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.loaded[spec.Name] = registry
+	b.mu.Unlock()
+
+	b.logger.Info("OpenAPI tools loaded",
+		zap.String("spec", spec.Name),
+		zap.String("title", doc.Title),
+		zap.String("base_url", baseURL),
+		zap.Int("tools", count))
+	return nil
+}
+
+// read returns the document of a spec, from a file or a URL
+func (b *Backend) read(ctx context.Context, spec Spec) ([]byte, error) {
+	if !isURL(spec.Source) {
+		data, err := os.ReadFile(spec.Source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read document: %w", err)
+		}
+		return data, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, spec.Source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source: %w", err)
+	}
+	req.Header.Set("Accept", "application/json, application/yaml;q=0.9, */*;q=0.5")
+	if profile, ok := b.client.profiles[spec.Auth]; ok {
+		profile.apply(req)
+	}
+	client := &http.Client{Transport: b.client.transport, Timeout: b.client.timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch document: %w", redactURLError(err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch document: status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSpecBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch document: %w", err)
+	}
+	if len(data) > maxSpecBytes {
+		return nil, fmt.Errorf("document is larger than %d bytes", maxSpecBytes)
+	}
+	return data, nil
+}
+
+// call executes an operation with the params of a tool call
+func (b *Backend) call(ctx context.Context, endpoint string, op *Operation, params map[string]interface{}) (interface{}, error) {
+	req := Request{
+		Endpoint: endpoint,
+		Method:   op.Method,
+		Query:    make(map[string]string),
+		Headers:  make(map[string]string),
+	}
+
+	p := op.Path
+	for _, param := range op.params {
+		value, ok := params[param.property]
+		if !ok || value == nil {
+			continue
+		}
+		s := paramString(value)
+		switch param.in {
+		case "path":
+			p = strings.ReplaceAll(p, "{"+param.name+"}", url.PathEscape(s))
+		case "query":
+			req.Query[param.name] = s
+		case "header":
+			req.Headers[param.name] = s
+		}
+	}
+	if strings.ContainsAny(p, "{}") {
+		return nil, invalidRequest(fmt.Errorf("%s: missing path parameters in %s", op.Tool, p))
+	}
+	req.Path = p
+
+	if op.bodyParam != "" {
+		if body, ok := params[op.bodyParam]; ok {
+			encoded, err := encodeOperationBody(body, op.bodyType)
+			if err != nil {
+				return nil, invalidRequest(fmt.Errorf("%s: %w", op.Tool, err))
+			}
+			req.Body = encoded
+			req.ContentType = op.bodyType
+		}
+	}
+
+	return b.client.Do(ctx, req)
+}
+
+// encodeOperationBody encodes a body for its media type: JSON, form or,
+// for other types, strings as is
+func encodeOperationBody(body interface{}, mediaType string) ([]byte, error) {
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("body is not valid JSON: %w", err)
+		}
+		return data, nil
+	case mediaType == "application/x-www-form-urlencoded":
+		fields, ok := body.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("form body must be an object")
+		}
+		form := url.Values{}
+		for key, value := range fields {
+			form.Set(key, paramString(value))
+		}
+		return []byte(form.Encode()), nil
+	default:
+		if s, ok := body.(string); ok {
+			return []byte(s), nil
+		}
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("body is not valid JSON: %w", err)
+		}
+		return data, nil
+	}
+}
+
+// paramString formats a parameter value: scalars as text, arrays as
+// comma-separated values and objects as JSON
+func paramString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = paramString(item)
+		}
+		return strings.Join(parts, ",")
+	case map[string]interface{}:
+		data, _ := json.Marshal(v)
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// specBaseURL returns the base URL of a spec's calls: its BaseURL, or the
+// document's first server URL, resolved against a Source URL
+func specBaseURL(spec Spec, doc *OpenAPIDocument) (string, error) {
+	if spec.BaseURL != "" {
+		return spec.BaseURL, nil
+	}
+	if len(doc.Servers) == 0 {
+		return "", fmt.Errorf("document has no servers; set a base URL")
+	}
+	server, err := url.Parse(doc.Servers[0])
+	if err != nil {
+		return "", fmt.Errorf("invalid server URL %q: %w", doc.Servers[0], err)
+	}
+	if server.IsAbs() {
+		return server.String(), nil
+	}
+	if !isURL(spec.Source) {
+		return "", fmt.Errorf("server URL %q is relative; set a base URL", doc.Servers[0])
+	}
+	source, err := url.Parse(spec.Source)
+	if err != nil {
+		return "", fmt.Errorf("invalid source: %w", err)
+	}
+	return source.ResolveReference(server).String(), nil
+}
+
+// matchAny reports whether name matches one of the globs, or there are none
+func matchAny(globs []string, name string) bool {
+	if len(globs) == 0 {
+		return true
+	}
+	for _, glob := range globs {
+		if ok, _ := path.Match(glob, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isURL reports whether a spec source is an http(s) URL
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
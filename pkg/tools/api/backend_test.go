@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aescanero/dago-node-executor/pkg/tools/catalog"
+	"go.uber.org/zap"
+)
+
+const petsDocument = `
+openapi: 3.0.0
+info: {title: Pets}
+servers: [{url: "http://pets.example/v1"}]
+paths:
+  /pets/{id}:
+    get:
+      operationId: getPet
+      summary: Get a pet
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: integer}}
+  /pets:
+    post:
+      operationId: addPet
+      summary: Add a pet
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema: {$ref: "#/components/schemas/Pet"}
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string}
+`
+
+func TestBackendDefinitions(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "pets.yaml")
+	if err := os.WriteFile(file, []byte(petsDocument), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClient(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := NewBackend(client, []Spec{{Name: "pets", Source: file, Operations: []string{"get*"}}}, zap.NewNop())
+
+	tools := catalog.New(zap.NewNop())
+	tools.Add("openapi", backend)
+	if err := tools.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	// Only the selected operation is exposed and described
+	defs := tools.Definitions()
+	if len(defs) != 1 {
+		t.Fatalf("Definitions() = %+v, want getPet only", defs)
+	}
+	def := defs[0]
+	if def.Name != "pets__getPet" || def.Description != "Get a pet" {
+		t.Errorf("definition = %s %q, want pets__getPet %q", def.Name, def.Description, "Get a pet")
+	}
+	want := map[string]interface{}{"type": "integer"}
+	properties, _ := def.Parameters["properties"].(map[string]interface{})
+	if !reflect.DeepEqual(properties["id"], want) {
+		t.Errorf("id schema = %v, want %v", properties["id"], want)
+	}
+	if required, _ := def.Parameters["required"].([]string); !reflect.DeepEqual(required, []string{"id"}) {
+		t.Errorf("required = %v, want [id]", def.Parameters["required"])
+	}
+}
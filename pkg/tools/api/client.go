@@ -1,35 +1,530 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/aescanero/dago-node-executor/pkg/tools/function"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 	"go.uber.org/zap"
 )
 
-// Client implements API-based tool execution (deprecated in favor of MCP)
+// Defaults for Config fields left empty
+const (
+	DefaultTimeout          = 30 * time.Second
+	DefaultMaxResponseBytes = 1 << 20
+)
+
+// maxRedirects bounds the redirects followed by a request
+const maxRedirects = 5
+
+// ErrNotAllowed is returned for requests outside the configured endpoints
+var ErrNotAllowed = errors.New("request not allowed")
+
+// defaultMethods are the methods of endpoints that don't list theirs
+var defaultMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+}
+
+// reservedHeaders can't be set by requests; credentials come from auth
+// profiles only
+var reservedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Host":                true,
+	"Traceparent":         true,
+	"Tracestate":          true,
+}
+
+// templatePattern matches {{variable}} placeholders in paths and headers
+var templatePattern = regexp.MustCompile(`\{\{\s*([\w.-]+)\s*\}\}`)
+
+// Endpoint is a service the client may call
+type Endpoint struct {
+	// BaseURL is the URL requests must stay under
+	BaseURL string
+
+	// Auth names the auth profile applied to requests, if any
+	Auth string
+
+	// Methods are the allowed methods (default GET, HEAD, POST, PUT,
+	// PATCH and DELETE)
+	Methods []string
+
+	// Headers are added to every request; values may hold {{variable}}
+	// placeholders
+	Headers map[string]string
+}
+
+// Config holds client settings
+type Config struct {
+	Endpoints    map[string]Endpoint
+	AuthProfiles map[string]AuthProfile
+
+	// Timeout bounds a request (default 30s); requests may ask for less
+	Timeout time.Duration
+
+	// MaxResponseBytes caps the response body read (default 1 MiB);
+	// longer bodies are truncated
+	MaxResponseBytes int64
+
+	// Vars returns the template variables of a call, e.g. its graph ID.
+	// They take precedence over the variables of the request.
+	Vars func(ctx context.Context) map[string]string
+
+	// Transport sends the requests (default http.DefaultTransport)
+	Transport http.RoundTripper
+
+	Logger *zap.Logger
+}
+
+// endpoint is a resolved Endpoint
+type endpoint struct {
+	name    string
+	base    *url.URL
+	auth    *AuthProfile
+	methods map[string]bool
+	headers map[string]string
+}
+
+// Client sends HTTP requests to allowlisted endpoints
 type Client struct {
-	baseURL string
-	logger  *zap.Logger
+	profiles  map[string]AuthProfile
+	timeout   time.Duration
+	maxBytes  int64
+	vars      func(ctx context.Context) map[string]string
+	transport http.RoundTripper
+	logger    *zap.Logger
+
+	mu        sync.RWMutex
+	endpoints map[string]*endpoint
+}
+
+// Request is an HTTP request to an endpoint
+type Request struct {
+	// Endpoint names the endpoint; Path is appended to its base URL and
+	// may hold {{variable}} placeholders, filled in URL-escaped
+	Endpoint string
+	Path     string
+
+	// URL is a full URL under an endpoint's base URL, instead of
+	// Endpoint and Path
+	URL string
+
+	Method  string
+	Query   map[string]string
+	Headers map[string]string
+	Vars    map[string]string
+
+	// Body is sent as is if a string or []byte, and as JSON otherwise
+	Body interface{}
+
+	// ContentType overrides the content type derived from Body
+	ContentType string
+
+	// Extract selects how the response body is returned (default auto)
+	Extract string
+
+	// Timeout bounds this request below the client's timeout
+	Timeout time.Duration
+}
+
+// Response is the result of a request
+type Response struct {
+	Status      int               `json:"status"`
+	OK          bool              `json:"ok"`
+	Endpoint    string            `json:"endpoint"`
+	ContentType string            `json:"content_type,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        interface{}       `json:"body"`
+	Encoding    string            `json:"encoding,omitempty"`
+	Truncated   bool              `json:"truncated,omitempty"`
+}
+
+// responseHeaders are the response headers returned to callers
+var responseHeaders = []string{"Content-Type", "Location", "ETag", "Last-Modified", "Retry-After", "Link"}
+
+// NewClient creates a client for the configured endpoints
+func NewClient(cfg Config) (*Client, error) {
+	c := &Client{
+		profiles:  cfg.AuthProfiles,
+		timeout:   cfg.Timeout,
+		maxBytes:  cfg.MaxResponseBytes,
+		vars:      cfg.Vars,
+		transport: cfg.Transport,
+		logger:    cfg.Logger,
+		endpoints: make(map[string]*endpoint),
+	}
+	if c.timeout <= 0 {
+		c.timeout = DefaultTimeout
+	}
+	if c.maxBytes <= 0 {
+		c.maxBytes = DefaultMaxResponseBytes
+	}
+	if c.transport == nil {
+		c.transport = http.DefaultTransport
+	}
+	if c.logger == nil {
+		c.logger = zap.NewNop()
+	}
+
+	for name, profile := range c.profiles {
+		if err := profile.Validate(); err != nil {
+			return nil, fmt.Errorf("auth profile %s: %w", name, err)
+		}
+	}
+	for name, ep := range cfg.Endpoints {
+		if err := c.AddEndpoint(name, ep); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// AddEndpoint adds or replaces an endpoint
+func (c *Client) AddEndpoint(name string, ep Endpoint) error {
+	base, err := url.Parse(ep.BaseURL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return fmt.Errorf("endpoint %s: base_url must be an http or https URL", name)
+	}
+	base.Path = strings.TrimSuffix(base.Path, "/")
+	base.RawQuery, base.Fragment = "", ""
+
+	resolved := &endpoint{name: name, base: base, methods: make(map[string]bool), headers: ep.Headers}
+	if ep.Auth != "" {
+		profile, ok := c.profiles[ep.Auth]
+		if !ok {
+			return fmt.Errorf("endpoint %s: unknown auth profile %s", name, ep.Auth)
+		}
+		resolved.auth = &profile
+	}
+	methods := ep.Methods
+	if len(methods) == 0 {
+		methods = defaultMethods
+	}
+	for _, method := range methods {
+		resolved.methods[strings.ToUpper(method)] = true
+	}
+
+	c.mu.Lock()
+	c.endpoints[name] = resolved
+	c.mu.Unlock()
+	return nil
+}
+
+// Endpoints returns the endpoint names, sorted
+func (c *Client) Endpoints() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.endpoints))
+	for name := range c.endpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Do sends a request. Responses with any status are returned; errors are
+// for requests that are not allowed or could not be sent.
+func (c *Client) Do(ctx context.Context, r Request) (*Response, error) {
+	method := strings.ToUpper(r.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	vars := c.templateVars(ctx, r.Vars)
+	ep, target, err := c.resolve(r, vars)
+	if err != nil {
+		return nil, invalidRequest(err)
+	}
+	if !ep.methods[method] {
+		return nil, fmt.Errorf("%w: method %s on endpoint %s", ErrNotAllowed, method, ep.name)
+	}
+
+	if len(r.Query) > 0 {
+		q := target.Query()
+		for key, value := range r.Query {
+			q.Set(key, value)
+		}
+		target.RawQuery = q.Encode()
+	}
+
+	body, contentType, err := encodeBody(r.Body)
+	if err != nil {
+		return nil, invalidRequest(err)
+	}
+
+	timeout := c.timeout
+	if r.Timeout > 0 && r.Timeout < timeout {
+		timeout = r.Timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if r.ContentType != "" && body != nil {
+		contentType = r.ContentType
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for key, value := range ep.headers {
+		expanded, err := expandTemplate(value, vars, false)
+		if err != nil {
+			return nil, fmt.Errorf("endpoint %s header %s: %w", ep.name, key, err)
+		}
+		req.Header.Set(key, expanded)
+	}
+	for key, value := range r.Headers {
+		if reservedHeaders[http.CanonicalHeaderKey(key)] {
+			return nil, fmt.Errorf("%w: header %s is set by the endpoint's auth profile", ErrNotAllowed, key)
+		}
+		expanded, err := expandTemplate(value, vars, false)
+		if err != nil {
+			return nil, invalidRequest(fmt.Errorf("header %s: %w", key, err))
+		}
+		req.Header.Set(key, expanded)
+	}
+	if ep.auth != nil {
+		ep.auth.apply(req)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	client := &http.Client{
+		Transport: c.transport,
+		CheckRedirect: func(next *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			if !within(ep.base, next.URL) {
+				return fmt.Errorf("%w: redirect outside endpoint %s", ErrNotAllowed, ep.name)
+			}
+			return nil
+		},
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request to endpoint %s failed: %w", ep.name, redactURLError(err))
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, c.maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response from endpoint %s: %w", ep.name, err)
+	}
+
+	c.logger.Debug("http request",
+		zap.String("endpoint", ep.name),
+		zap.String("method", method),
+		zap.Int("status", resp.StatusCode),
+		zap.Duration("duration", time.Since(start)))
+
+	result := &Response{
+		Status:      resp.StatusCode,
+		OK:          resp.StatusCode >= 200 && resp.StatusCode < 300,
+		Endpoint:    ep.name,
+		ContentType: resp.Header.Get("Content-Type"),
+		Headers:     make(map[string]string),
+	}
+	for _, key := range responseHeaders {
+		if value := resp.Header.Get(key); value != "" && key != "Content-Type" {
+			result.Headers[key] = value
+		}
+	}
+	if int64(len(data)) > c.maxBytes {
+		data = data[:c.maxBytes]
+		result.Truncated = true
+	}
+	if err := extractBody(result, data, r.Extract); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// resolve returns the endpoint and URL of a request
+func (c *Client) resolve(r Request, vars map[string]string) (*endpoint, *url.URL, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if r.URL != "" {
+		target, err := url.Parse(r.URL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid url: %w", err)
+		}
+		target.Path = cleanPath(target.Path)
+		target.RawPath, target.Fragment = "", ""
+
+		// The longest matching base URL wins
+		var match *endpoint
+		for _, ep := range c.endpoints {
+			if r.Endpoint != "" && ep.name != r.Endpoint {
+				continue
+			}
+			if within(ep.base, target) && (match == nil || len(ep.base.Path) > len(match.base.Path)) {
+				match = ep
+			}
+		}
+		if match == nil {
+			return nil, nil, fmt.Errorf("%w: %s is not under a configured endpoint", ErrNotAllowed, target.Redacted())
+		}
+		return match, target, nil
+	}
+
+	ep, ok := c.endpoints[r.Endpoint]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: unknown endpoint %q", ErrNotAllowed, r.Endpoint)
+	}
+
+	p, rawQuery, _ := strings.Cut(r.Path, "?")
+	p, err := expandTemplate(p, vars, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("path: %w", err)
+	}
+	escaped := ep.base.EscapedPath() + cleanPath(p)
+	if escaped == "" {
+		escaped = "/"
+	}
+	target, err := url.Parse(ep.base.Scheme + "://" + ep.base.Host + escaped)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid path: %w", err)
+	}
+	// cleanPath saw the escaped path: dot segments escaped as %2e are only
+	// visible decoded, and servers that normalize them would climb
+	if hasDotSegment(target.Path) || !within(ep.base, target) {
+		return nil, nil, fmt.Errorf("%w: path %s leaves endpoint %s", ErrNotAllowed, r.Path, ep.name)
+	}
+	if _, err := url.ParseQuery(rawQuery); err != nil {
+		return nil, nil, fmt.Errorf("invalid query: %w", err)
+	}
+	target.RawQuery = rawQuery
+	return ep, target, nil
+}
+
+// templateVars merges the request's variables with the call's, which
+// take precedence
+func (c *Client) templateVars(ctx context.Context, requestVars map[string]string) map[string]string {
+	vars := make(map[string]string, len(requestVars))
+	for key, value := range requestVars {
+		vars[key] = value
+	}
+	if c.vars != nil {
+		for key, value := range c.vars(ctx) {
+			vars[key] = value
+		}
+	}
+	return vars
 }
 
-// NewClient creates a new API client
-func NewClient(baseURL string, logger *zap.Logger) *Client {
-	return &Client{
-		baseURL: baseURL,
-		logger:  logger,
+// expandTemplate fills {{variable}} placeholders, URL-escaping the values
+// in paths
+func expandTemplate(s string, vars map[string]string, escape bool) (string, error) {
+	var missing []string
+	expanded := templatePattern.ReplaceAllStringFunc(s, func(match string) string {
+		name := templatePattern.FindStringSubmatch(match)[1]
+		value, ok := vars[name]
+		if !ok {
+			missing = append(missing, name)
+			return match
+		}
+		if escape {
+			return url.PathEscape(value)
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("undefined variables: %s", strings.Join(missing, ", "))
 	}
+	return expanded, nil
 }
 
-// Execute executes a tool via REST API
-func (c *Client) Execute(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error) {
-	c.logger.Warn("API client is deprecated, use MCP instead",
-		zap.String("tool", toolName))
+// cleanPath resolves . and .. in a path so it can't climb above its base
+func cleanPath(p string) string {
+	if p == "" || p == "/" {
+		return ""
+	}
+	cleaned := path.Clean("/" + p)
+	if cleaned == "/" {
+		return ""
+	}
+	if strings.HasSuffix(p, "/") {
+		cleaned += "/"
+	}
+	return cleaned
+}
 
-	return nil, fmt.Errorf("API client deprecated, use MCP")
+// hasDotSegment reports whether a decoded path has . or .. segments
+func hasDotSegment(p string) bool {
+	for _, segment := range strings.Split(p, "/") {
+		if segment == "." || segment == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// within reports whether target is under base: same scheme and host, and
+// a path below the base path
+func within(base, target *url.URL) bool {
+	if !strings.EqualFold(base.Scheme, target.Scheme) || !strings.EqualFold(base.Host, target.Host) {
+		return false
+	}
+	p := path.Clean("/" + target.Path)
+	return base.Path == "" || p == base.Path || strings.HasPrefix(p, base.Path+"/")
 }
 
-// ListTools lists available tools
-func (c *Client) ListTools(ctx context.Context) ([]string, error) {
-	return []string{}, fmt.Errorf("API client deprecated, use MCP")
+// encodeBody returns the body of a request and its content type
+func encodeBody(body interface{}) (io.Reader, string, error) {
+	switch b := body.(type) {
+	case nil:
+		return nil, "", nil
+	case string:
+		return strings.NewReader(b), "text/plain; charset=utf-8", nil
+	case []byte:
+		return bytes.NewReader(b), "application/octet-stream", nil
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return nil, "", fmt.Errorf("body is not valid JSON: %w", err)
+		}
+		return bytes.NewReader(data), "application/json", nil
+	}
+}
+
+// invalidRequest marks an error about the request, as opposed to a
+// failure to send it
+func invalidRequest(err error) error {
+	if errors.Is(err, ErrNotAllowed) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", function.ErrInvalidParams, err)
+}
+
+// redactURLError drops the URL, which may carry an API key, from request
+// errors
+func redactURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
 }
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/aescanero/dago-node-executor/pkg/tools/function"
+)
+
+func newTestClient(t *testing.T, endpoints map[string]Endpoint) *Client {
+	t.Helper()
+	c, err := NewClient(Config{
+		Endpoints: endpoints,
+		AuthProfiles: map[string]AuthProfile{
+			"token": {Type: AuthBearer, Token: "secret"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return c
+}
+
+func TestClientResolve(t *testing.T) {
+	c := newTestClient(t, map[string]Endpoint{
+		"public": {BaseURL: "https://api.example.com/v1/public/"},
+		"admin":  {BaseURL: "https://api.example.com/v1/public/admin"},
+		"root":   {BaseURL: "https://other.example.com"},
+	})
+
+	tests := []struct {
+		name     string
+		req      Request
+		vars     map[string]string
+		endpoint string
+		url      string
+		denied   bool
+	}{
+		{
+			name:     "path under the base",
+			req:      Request{Endpoint: "public", Path: "/users/42"},
+			endpoint: "public",
+			url:      "https://api.example.com/v1/public/users/42",
+		},
+		{
+			name:     "query in the path",
+			req:      Request{Endpoint: "public", Path: "/search?q=go"},
+			endpoint: "public",
+			url:      "https://api.example.com/v1/public/search?q=go",
+		},
+		{
+			name:     "dot segments are resolved below the base",
+			req:      Request{Endpoint: "public", Path: "/../../admin"},
+			endpoint: "public",
+			url:      "https://api.example.com/v1/public/admin",
+		},
+		{
+			name:   "percent-encoded dot segments",
+			req:    Request{Endpoint: "public", Path: "/%2e%2e/%2e%2e/admin"},
+			denied: true,
+		},
+		{
+			name:   "uppercase percent-encoded dot segments",
+			req:    Request{Endpoint: "public", Path: "/users/%2E%2E/%2E%2E/%2E%2E/secret"},
+			denied: true,
+		},
+		{
+			name:   "percent-encoded single dot",
+			req:    Request{Endpoint: "public", Path: "/%2e/users"},
+			denied: true,
+		},
+		{
+			name:     "template values are escaped",
+			req:      Request{Endpoint: "public", Path: "/users/{{id}}"},
+			vars:     map[string]string{"id": "a/b c"},
+			endpoint: "public",
+			url:      "https://api.example.com/v1/public/users/a%2Fb%20c",
+		},
+		{
+			name:   "template values with dot segments",
+			req:    Request{Endpoint: "public", Path: "/users/{{id}}"},
+			vars:   map[string]string{"id": "../../admin"},
+			denied: true,
+		},
+		{
+			name:     "escaped slash stays in its segment",
+			req:      Request{Endpoint: "public", Path: "/files/a%2Fb"},
+			endpoint: "public",
+			url:      "https://api.example.com/v1/public/files/a%2Fb",
+		},
+		{
+			name:   "unknown endpoint",
+			req:    Request{Endpoint: "nope", Path: "/"},
+			denied: true,
+		},
+		{
+			name:     "url under an endpoint",
+			req:      Request{URL: "https://api.example.com/v1/public/users"},
+			endpoint: "public",
+			url:      "https://api.example.com/v1/public/users",
+		},
+		{
+			name:     "longest base url wins",
+			req:      Request{URL: "https://api.example.com/v1/public/admin/keys"},
+			endpoint: "admin",
+			url:      "https://api.example.com/v1/public/admin/keys",
+		},
+		{
+			name:   "url climbing out of the base",
+			req:    Request{URL: "https://api.example.com/v1/public/../private"},
+			denied: true,
+		},
+		{
+			name:   "url with encoded dot segments",
+			req:    Request{URL: "https://api.example.com/v1/public/%2e%2e/private"},
+			denied: true,
+		},
+		{
+			name:   "url with a base path prefix",
+			req:    Request{URL: "https://api.example.com/v1/publicity"},
+			denied: true,
+		},
+		{
+			name:   "url on another host",
+			req:    Request{URL: "https://evil.example.com/v1/public/users"},
+			denied: true,
+		},
+		{
+			name:   "url on another scheme",
+			req:    Request{URL: "http://api.example.com/v1/public/users"},
+			denied: true,
+		},
+		{
+			name:   "url of another endpoint",
+			req:    Request{Endpoint: "root", URL: "https://api.example.com/v1/public/users"},
+			denied: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ep, target, err := c.resolve(tt.req, tt.vars)
+			if tt.denied {
+				if !errors.Is(err, ErrNotAllowed) {
+					t.Fatalf("resolve() = %v, %v; want ErrNotAllowed", target, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolve() error = %v", err)
+			}
+			if ep.name != tt.endpoint {
+				t.Errorf("endpoint = %s, want %s", ep.name, tt.endpoint)
+			}
+			if got := target.String(); got != tt.url {
+				t.Errorf("url = %s, want %s", got, tt.url)
+			}
+		})
+	}
+}
+
+func TestWithin(t *testing.T) {
+	tests := []struct {
+		base   string
+		target string
+		want   bool
+	}{
+		{"https://a.example.com/v1", "https://a.example.com/v1", true},
+		{"https://a.example.com/v1", "https://a.example.com/v1/x", true},
+		{"https://a.example.com/v1", "https://A.EXAMPLE.COM/v1/x", true},
+		{"https://a.example.com", "https://a.example.com/anything", true},
+		{"https://a.example.com/v1", "https://a.example.com/v10", false},
+		{"https://a.example.com/v1", "https://a.example.com/v1/../v2", false},
+		{"https://a.example.com/v1", "https://a.example.com/", false},
+		{"https://a.example.com/v1", "http://a.example.com/v1/x", false},
+		{"https://a.example.com/v1", "https://a.example.com:8443/v1/x", false},
+		{"https://a.example.com/v1", "https://b.example.com/v1/x", false},
+	}
+
+	for _, tt := range tests {
+		base, _ := url.Parse(tt.base)
+		target, _ := url.Parse(tt.target)
+		if got := within(base, target); got != tt.want {
+			t.Errorf("within(%s, %s) = %v, want %v", tt.base, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestClientDo(t *testing.T) {
+	var got *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		switch r.URL.Path {
+		case "/api/redirect":
+			http.Redirect(w, r, "/elsewhere", http.StatusFound)
+		case "/api/page":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte("<html><head><title>t</title></head><body><h1>Hello</h1><script>x()</script></body></html>"))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"ok":true}`))
+		}
+	}))
+	defer srv.Close()
+
+	c := newTestClient(t, map[string]Endpoint{
+		"svc": {BaseURL: srv.URL + "/api", Auth: "token", Methods: []string{"GET"}},
+	})
+	ctx := context.Background()
+
+	resp, err := c.Do(ctx, Request{Endpoint: "svc", Path: "/items"})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if got.Header.Get("Authorization") != "Bearer secret" {
+		t.Errorf("Authorization = %q, want the profile's token", got.Header.Get("Authorization"))
+	}
+	if body, ok := resp.Body.(map[string]interface{}); !ok || body["ok"] != true {
+		t.Errorf("body = %#v, want parsed JSON", resp.Body)
+	}
+
+	resp, err = c.Do(ctx, Request{Endpoint: "svc", Path: "/page"})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if resp.Body != "Hello" {
+		t.Errorf("html body = %q, want extracted text", resp.Body)
+	}
+
+	tests := []struct {
+		name string
+		req  Request
+	}{
+		{"method not allowed", Request{Endpoint: "svc", Method: "DELETE", Path: "/items"}},
+		{"reserved header", Request{Endpoint: "svc", Path: "/items", Headers: map[string]string{"authorization": "Bearer other"}}},
+		{"redirect outside the endpoint", Request{Endpoint: "svc", Path: "/redirect"}},
+		{"encoded dot segments", Request{Endpoint: "svc", Path: "/%2e%2e/admin"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := c.Do(ctx, tt.req); !errors.Is(err, ErrNotAllowed) {
+				t.Fatalf("Do() error = %v, want ErrNotAllowed", err)
+			}
+		})
+	}
+
+	// Invalid requests are the caller's errors, never sent
+	invalid := []struct {
+		name string
+		req  Request
+	}{
+		{"undefined variable", Request{Endpoint: "svc", Path: "/items/{{missing}}"}},
+		{"invalid query", Request{Endpoint: "svc", Path: "/items?q=%zz"}},
+		{"body not JSON", Request{Endpoint: "svc", Path: "/items", Body: map[string]interface{}{"f": func() {}}}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			if _, err := c.Do(ctx, tt.req); !errors.Is(err, function.ErrInvalidParams) || errors.Is(err, ErrNotAllowed) {
+				t.Fatalf("Do() error = %v, want ErrInvalidParams", err)
+			}
+			if got != nil {
+				t.Error("invalid request was sent")
+			}
+		})
+	}
+}
@@ -1,5 +1,15 @@
-// Package api provides REST API-based tool execution (deprecated).
+// Package api calls HTTP services on behalf of agents.
 //
-// This package is deprecated in favor of MCP.
-// Use MCP client for tool execution.
+// A Client only reaches the endpoints it is configured with: each has a
+// base URL, the methods it accepts, headers and an auth profile. Profiles
+// hold the credentials (bearer tokens, basic auth, API keys) and are
+// referenced by name, so secrets never appear in node configs or tool
+// params. Responses are capped in size and returned as parsed JSON, text,
+// or text extracted from HTML.
+//
+// The http_request tool registered by RegisterHTTPTool exposes the client
+// to agents. A Backend generates tools from OpenAPI 3 documents instead:
+// each operation becomes a tool named after its operationId, with the
+// parameters and request body as its input schema, called through a
+// Client with the document's auth profile.
 package api
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Body extraction modes
+const (
+	ExtractAuto = "auto"
+	ExtractJSON = "json"
+	ExtractText = "text"
+	ExtractHTML = "html"
+	ExtractRaw  = "raw"
+)
+
+// ExtractModes lists the body extraction modes
+var ExtractModes = []string{ExtractAuto, ExtractJSON, ExtractText, ExtractHTML, ExtractRaw}
+
+// extractBody sets the response body from data: parsed JSON, text, text
+// extracted from HTML, or base64 for raw and binary bodies
+func extractBody(resp *Response, data []byte, mode string) error {
+	if mode == "" || mode == ExtractAuto {
+		mode = detectMode(resp.ContentType, data)
+	}
+
+	switch mode {
+	case ExtractJSON:
+		var value interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			if resp.Truncated || !utf8.Valid(data) {
+				// A truncated document can't be parsed; return what was read
+				resp.Body = strings.ToValidUTF8(string(data), "")
+				return nil
+			}
+			return fmt.Errorf("response is not valid JSON: %w", err)
+		}
+		resp.Body = value
+	case ExtractText:
+		resp.Body = strings.ToValidUTF8(string(data), "")
+	case ExtractHTML:
+		resp.Body = HTMLToText(strings.ToValidUTF8(string(data), ""))
+	case ExtractRaw:
+		resp.Body = base64.StdEncoding.EncodeToString(data)
+		resp.Encoding = "base64"
+	default:
+		return fmt.Errorf("unknown extract mode %q", mode)
+	}
+	return nil
+}
+
+// detectMode picks an extraction mode from the content type
+func detectMode(contentType string, data []byte) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return ExtractJSON
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		return ExtractHTML
+	case strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "xml") ||
+		mediaType == "application/x-www-form-urlencoded":
+		return ExtractText
+	case len(data) == 0 || utf8.Valid(data):
+		return ExtractText
+	default:
+		return ExtractRaw
+	}
+}
+
+var (
+	// htmlDropPattern matches elements whose content isn't text
+	htmlDropPattern = regexp.MustCompile(`(?is)<(script|style|noscript|template|svg|head)\b.*?</(script|style|noscript|template|svg|head)\s*>|<!--.*?-->`)
+
+	// htmlBlockPattern matches tags that break lines
+	htmlBlockPattern = regexp.MustCompile(`(?i)</?(p|div|br|hr|h[1-6]|ul|ol|table|tr|section|article|header|footer|nav|main|aside|blockquote|pre|form|dl|dt|dd|figure|figcaption)\b[^>]*>`)
+
+	// htmlItemPattern matches list items
+	htmlItemPattern = regexp.MustCompile(`(?i)<li\b[^>]*>`)
+
+	// htmlCellPattern matches table cells
+	htmlCellPattern = regexp.MustCompile(`(?i)</t[dh]\s*>`)
+
+	// htmlTagPattern matches any remaining tag
+	htmlTagPattern = regexp.MustCompile(`(?s)<[^>]*>`)
+
+	// spacePattern matches runs of horizontal whitespace
+	spacePattern = regexp.MustCompile(`[ \t\f\r\v\x{00a0}]+`)
+)
+
+// HTMLToText extracts the readable text of an HTML document: scripts,
+// styles and the head are dropped, block elements become line breaks,
+// list items are prefixed with "- " and entities are decoded
+func HTMLToText(doc string) string {
+	s := htmlDropPattern.ReplaceAllString(doc, " ")
+	s = htmlItemPattern.ReplaceAllString(s, "\n- ")
+	s = htmlBlockPattern.ReplaceAllString(s, "\n")
+	s = htmlCellPattern.ReplaceAllString(s, " ")
+	s = htmlTagPattern.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+
+	lines := strings.Split(s, "\n")
+	out := make([]string, 0, len(lines))
+	blank := true
+	for _, line := range lines {
+		line = strings.TrimSpace(spacePattern.ReplaceAllString(line, " "))
+		if line == "" {
+			if !blank {
+				out = append(out, "")
+			}
+			blank = true
+			continue
+		}
+		out = append(out, line)
+		blank = false
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
@@ -0,0 +1,456 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"gopkg.in/yaml.v3"
+)
+
+// maxSchemaDepth bounds the nesting of schemas inlined from $refs
+const maxSchemaDepth = 16
+
+// operationMethods are the HTTP methods of path items that become tools
+var operationMethods = []string{"get", "put", "post", "delete", "patch", "head"}
+
+// ignoredHeaderParams are header parameters set by the client, besides
+// the reserved headers
+var ignoredHeaderParams = map[string]bool{
+	"Accept":       true,
+	"Content-Type": true,
+}
+
+var (
+	// invalidNameChars matches characters not allowed in tool names
+	invalidNameChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+	// underscores matches runs of underscores, collapsed in tool names so
+	// they don't contain the catalog separator
+	underscores = regexp.MustCompile(`__+`)
+)
+
+// OpenAPIDocument is a parsed OpenAPI 3 document
+type OpenAPIDocument struct {
+	Title string
+
+	// Servers are the server URLs, with variables set to their defaults
+	Servers []string
+
+	Operations []*Operation
+}
+
+// Operation is an API operation exposed as a tool
+type Operation struct {
+	// Tool is the tool name, from the operationId or, without one, the
+	// method and path
+	Tool string
+
+	Method string
+	Path   string
+
+	// Summary describes the tool, falling back to the description
+	Summary string
+
+	// Parameters is the JSON Schema of the tool params: one property per
+	// parameter, and body for the request body
+	Parameters map[string]interface{}
+
+	params    []operationParam
+	bodyParam string
+	bodyType  string
+}
+
+// operationParam maps a tool param to an API parameter
+type operationParam struct {
+	property string
+	name     string
+	in       string
+}
+
+// Definition returns the tool definition of the operation under name
+func (o *Operation) Definition(name string) domain.Tool {
+	return domain.Tool{Name: name, Description: o.Summary, Parameters: o.Parameters}
+}
+
+// ParseOpenAPI parses an OpenAPI 3 document in JSON or YAML. Operations
+// are sorted by tool name; a name used twice is an error.
+func ParseOpenAPI(data []byte) (*OpenAPIDocument, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	root, ok := normalizeYAML(raw).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid OpenAPI document: not an object")
+	}
+	version, _ := root["openapi"].(string)
+	if !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q, want 3.x", version)
+	}
+
+	doc := &OpenAPIDocument{}
+	if info, ok := root["info"].(map[string]interface{}); ok {
+		doc.Title, _ = info["title"].(string)
+	}
+	for _, server := range objectList(root["servers"]) {
+		if u, ok := server["url"].(string); ok {
+			doc.Servers = append(doc.Servers, serverURL(u, server["variables"]))
+		}
+	}
+
+	r := &refResolver{root: root}
+	paths, _ := root["paths"].(map[string]interface{})
+	seen := make(map[string]string)
+	for _, p := range sortedKeys(paths) {
+		item, ok := r.resolve(paths[p]).(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, method := range operationMethods {
+			raw, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			op, err := r.operation(p, method, item, raw)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(method), p, err)
+			}
+			if previous, dup := seen[op.Tool]; dup {
+				return nil, fmt.Errorf("%s %s: tool name %s is also used by %s", op.Method, p, op.Tool, previous)
+			}
+			seen[op.Tool] = op.Method + " " + p
+			doc.Operations = append(doc.Operations, op)
+		}
+	}
+	sort.Slice(doc.Operations, func(i, j int) bool { return doc.Operations[i].Tool < doc.Operations[j].Tool })
+	return doc, nil
+}
+
+// operation converts an operation of a path item
+func (r *refResolver) operation(p, method string, item, raw map[string]interface{}) (*Operation, error) {
+	op := &Operation{Method: strings.ToUpper(method), Path: p}
+
+	id, _ := raw["operationId"].(string)
+	if id == "" {
+		id = method + "_" + p
+	}
+	op.Tool = strings.Trim(underscores.ReplaceAllString(invalidNameChars.ReplaceAllString(id, "_"), "_"), "_")
+	if op.Tool == "" {
+		return nil, fmt.Errorf("operation has no usable name")
+	}
+
+	op.Summary, _ = raw["summary"].(string)
+	if op.Summary == "" {
+		op.Summary, _ = raw["description"].(string)
+	}
+	if op.Summary == "" {
+		op.Summary = op.Method + " " + p
+	}
+	op.Summary = strings.TrimSpace(op.Summary)
+
+	properties := make(map[string]interface{})
+	var required []string
+
+	// Operation parameters override path item parameters of the same name
+	// and location
+	params := make(map[string]map[string]interface{})
+	var order []string
+	for _, list := range []interface{}{item["parameters"], raw["parameters"]} {
+		for _, entry := range asList(list) {
+			param, ok := r.resolve(entry).(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := param["name"].(string)
+			in, _ := param["in"].(string)
+			key := in + ":" + name
+			if _, ok := params[key]; !ok {
+				order = append(order, key)
+			}
+			params[key] = param
+		}
+	}
+
+	for _, key := range order {
+		param := params[key]
+		name, _ := param["name"].(string)
+		in, _ := param["in"].(string)
+		if name == "" || in == "cookie" || (in == "header" && ignoredHeader(name)) {
+			continue
+		}
+		if in != "path" && in != "query" && in != "header" {
+			continue
+		}
+
+		schema := r.paramSchema(param)
+		if description, ok := param["description"].(string); ok && description != "" {
+			schema["description"] = description
+		}
+
+		property := name
+		if _, taken := properties[property]; taken {
+			property = in + "_" + name
+		}
+		properties[property] = schema
+		if isRequired, _ := param["required"].(bool); isRequired || in == "path" {
+			required = append(required, property)
+		}
+		op.params = append(op.params, operationParam{property: property, name: name, in: in})
+	}
+
+	if body, ok := r.resolve(raw["requestBody"]).(map[string]interface{}); ok {
+		mediaType, schema := r.bodySchema(body)
+		if description, ok := body["description"].(string); ok && description != "" {
+			schema["description"] = description
+		}
+		op.bodyParam = "body"
+		if _, taken := properties[op.bodyParam]; taken {
+			op.bodyParam = "request_body"
+		}
+		op.bodyType = mediaType
+		properties[op.bodyParam] = schema
+		if isRequired, _ := body["required"].(bool); isRequired {
+			required = append(required, op.bodyParam)
+		}
+	}
+
+	op.Parameters = map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		op.Parameters["required"] = required
+	}
+	return op, nil
+}
+
+// paramSchema returns the JSON Schema of a parameter
+func (r *refResolver) paramSchema(param map[string]interface{}) map[string]interface{} {
+	if schema, ok := r.schema(param["schema"], 0).(map[string]interface{}); ok {
+		return schema
+	}
+	if content, ok := param["content"].(map[string]interface{}); ok {
+		for _, mediaType := range sortedKeys(content) {
+			if media, ok := content[mediaType].(map[string]interface{}); ok {
+				if schema, ok := r.schema(media["schema"], 0).(map[string]interface{}); ok {
+					return schema
+				}
+			}
+		}
+	}
+	return map[string]interface{}{"type": "string"}
+}
+
+// bodySchema returns the media type and JSON Schema of a request body,
+// preferring JSON, then form, then text content
+func (r *refResolver) bodySchema(body map[string]interface{}) (string, map[string]interface{}) {
+	content, _ := body["content"].(map[string]interface{})
+	types := sortedKeys(content)
+	rank := func(mediaType string) int {
+		switch {
+		case mediaType == "application/json":
+			return 0
+		case strings.HasSuffix(mediaType, "+json"):
+			return 1
+		case mediaType == "application/x-www-form-urlencoded":
+			return 2
+		case strings.HasPrefix(mediaType, "text/"):
+			return 3
+		default:
+			return 4
+		}
+	}
+	sort.SliceStable(types, func(i, j int) bool { return rank(types[i]) < rank(types[j]) })
+	if len(types) == 0 {
+		return "application/json", map[string]interface{}{}
+	}
+
+	mediaType := types[0]
+	if media, ok := content[mediaType].(map[string]interface{}); ok {
+		if schema, ok := r.schema(media["schema"], 0).(map[string]interface{}); ok {
+			return mediaType, schema
+		}
+	}
+	if rank(mediaType) <= 2 {
+		return mediaType, map[string]interface{}{}
+	}
+	return mediaType, map[string]interface{}{"type": "string"}
+}
+
+// refResolver inlines local $refs of a document
+type refResolver struct {
+	root map[string]interface{}
+
+	// inlining holds the $refs of the schemas being inlined
+	inlining map[string]bool
+}
+
+// resolve follows the $ref of an object, if it has one
+func (r *refResolver) resolve(v interface{}) interface{} {
+	for i := 0; i < maxSchemaDepth; i++ {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return v
+		}
+		ref, ok := obj["$ref"].(string)
+		if !ok {
+			return v
+		}
+		v = r.lookup(ref)
+	}
+	return nil
+}
+
+// lookup returns the value of a local reference such as
+// #/components/schemas/Pet
+func (r *refResolver) lookup(ref string) interface{} {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+	var v interface{} = r.root
+	for _, part := range strings.Split(ref[2:], "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = obj[part]
+	}
+	return v
+}
+
+// schema returns a copy of an OpenAPI schema as JSON Schema, with $refs
+// inlined, nullable turned into a null type and documentation-only
+// keywords dropped. A $ref to a schema being inlined, in recursive
+// schemas, and schemas nested deeper than maxSchemaDepth are left open.
+func (r *refResolver) schema(v interface{}, depth int) interface{} {
+	value, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	if depth > maxSchemaDepth {
+		return map[string]interface{}{}
+	}
+	if ref, ok := value["$ref"].(string); ok {
+		target := r.lookup(ref)
+		if target == nil || r.inlining[ref] {
+			return map[string]interface{}{}
+		}
+		if r.inlining == nil {
+			r.inlining = make(map[string]bool)
+		}
+		r.inlining[ref] = true
+		defer delete(r.inlining, ref)
+		return r.schema(target, depth+1)
+	}
+
+	out := make(map[string]interface{}, len(value))
+	for key, item := range value {
+		switch key {
+		case "nullable", "example", "examples", "xml", "externalDocs", "discriminator", "deprecated", "readOnly", "writeOnly":
+		case "properties", "patternProperties":
+			if props, ok := item.(map[string]interface{}); ok {
+				converted := make(map[string]interface{}, len(props))
+				for name, prop := range props {
+					converted[name] = r.schema(prop, depth+1)
+				}
+				out[key] = converted
+			}
+		case "allOf", "anyOf", "oneOf":
+			list := asList(item)
+			converted := make([]interface{}, len(list))
+			for i, sub := range list {
+				converted[i] = r.schema(sub, depth+1)
+			}
+			out[key] = converted
+		case "items", "additionalProperties", "not":
+			out[key] = r.schema(item, depth+1)
+		default:
+			out[key] = item
+		}
+	}
+	if nullable, _ := value["nullable"].(bool); nullable {
+		if t, ok := out["type"].(string); ok {
+			out["type"] = []interface{}{t, "null"}
+		}
+	}
+	return out
+}
+
+// serverURL fills the variables of a server URL with their defaults
+func serverURL(u string, variables interface{}) string {
+	vars, _ := variables.(map[string]interface{})
+	for name, v := range vars {
+		if variable, ok := v.(map[string]interface{}); ok {
+			u = strings.ReplaceAll(u, "{"+name+"}", fmt.Sprint(variable["default"]))
+		}
+	}
+	return u
+}
+
+// normalizeYAML converts the map[interface{}]interface{} values decoded
+// from YAML, e.g. for numeric keys, to map[string]interface{}
+func normalizeYAML(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			value[key] = normalizeYAML(item)
+		}
+		return value
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			out[fmt.Sprint(key)] = normalizeYAML(item)
+		}
+		return out
+	case []interface{}:
+		for i, item := range value {
+			value[i] = normalizeYAML(item)
+		}
+		return value
+	case int:
+		return float64(value)
+	default:
+		return v
+	}
+}
+
+// objectList returns the objects of a list
+func objectList(v interface{}) []map[string]interface{} {
+	var out []map[string]interface{}
+	for _, item := range asList(v) {
+		if obj, ok := item.(map[string]interface{}); ok {
+			out = append(out, obj)
+		}
+	}
+	return out
+}
+
+// asList returns v as a list, if it is one
+func asList(v interface{}) []interface{} {
+	list, _ := v.([]interface{})
+	return list
+}
+
+// sortedKeys returns the keys of an object, sorted
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ignoredHeader reports whether a header parameter is left out of tool
+// params: credentials come from auth profiles, and the client sets the
+// others
+func ignoredHeader(name string) bool {
+	name = http.CanonicalHeaderKey(name)
+	return reservedHeaders[name] || ignoredHeaderParams[name]
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-node-executor/pkg/tools/function"
+)
+
+// HTTPToolName is the name of the HTTP request tool
+const HTTPToolName = "http_request"
+
+// HTTPTool returns the definition of the HTTP request tool, listing the
+// client's endpoints
+func (c *Client) HTTPTool() domain.Tool {
+	stringMap := func(description string) map[string]interface{} {
+		return map[string]interface{}{
+			"type":                 "object",
+			"description":          description,
+			"additionalProperties": map[string]interface{}{"type": "string"},
+		}
+	}
+
+	return domain.Tool{
+		Name: HTTPToolName,
+		Description: "Send an HTTP request to a configured service endpoint. Credentials are added by the endpoint; " +
+			"{{name}} placeholders in path and headers are filled from vars. Returns the status and the body " +
+			"as parsed JSON, text, or text extracted from HTML.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"endpoint": map[string]interface{}{
+					"type":        "string",
+					"description": "Endpoint to call",
+					"enum":        c.Endpoints(),
+				},
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "Path under the endpoint's base URL, e.g. /customers/{{id}}",
+				},
+				"url": map[string]interface{}{
+					"type":        "string",
+					"description": "Full URL under an endpoint's base URL, instead of endpoint and path",
+				},
+				"method": map[string]interface{}{
+					"type":        "string",
+					"description": "HTTP method (default GET)",
+					"enum":        defaultMethods,
+				},
+				"query":   stringMap("Query parameters"),
+				"headers": stringMap("Extra request headers"),
+				"vars":    stringMap("Values of the {{name}} placeholders"),
+				"body":    map[string]interface{}{"description": "Request body: JSON for objects and arrays, text for strings"},
+				"extract": map[string]interface{}{
+					"type":        "string",
+					"description": "How to return the response body (default auto, by content type)",
+					"enum":        ExtractModes,
+				},
+				"timeout_seconds": map[string]interface{}{
+					"type":        "number",
+					"description": "Request timeout, up to the configured one",
+				},
+			},
+			"additionalProperties": false,
+		},
+	}
+}
+
+// RegisterHTTPTool registers the HTTP request tool of a client
+func RegisterHTTPTool(r *function.Registry, c *Client) {
+	r.RegisterTool(c.HTTPTool(), func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		req, err := requestFromParams(params)
+		if err != nil {
+			return nil, invalidRequest(err)
+		}
+		return c.Do(ctx, req)
+	})
+}
+
+// requestFromParams converts http_request params to a Request
+func requestFromParams(params map[string]interface{}) (Request, error) {
+	req := Request{Body: params["body"]}
+	req.Endpoint, _ = params["endpoint"].(string)
+	req.Path, _ = params["path"].(string)
+	req.URL, _ = params["url"].(string)
+	req.Method, _ = params["method"].(string)
+	req.Extract, _ = params["extract"].(string)
+	if req.Endpoint == "" && req.URL == "" {
+		return Request{}, fmt.Errorf("endpoint or url is required")
+	}
+	if seconds, ok := params["timeout_seconds"].(float64); ok && seconds > 0 {
+		req.Timeout = time.Duration(seconds * float64(time.Second))
+	}
+
+	var err error
+	if req.Query, err = stringMapParam(params, "query"); err != nil {
+		return Request{}, err
+	}
+	if req.Headers, err = stringMapParam(params, "headers"); err != nil {
+		return Request{}, err
+	}
+	if req.Vars, err = stringMapParam(params, "vars"); err != nil {
+		return Request{}, err
+	}
+	return req, nil
+}
+
+// stringMapParam reads an object param of scalar values as strings
+func stringMapParam(params map[string]interface{}, key string) (map[string]string, error) {
+	raw, ok := params[key].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	values := make(map[string]string, len(raw))
+	for name, value := range raw {
+		switch v := value.(type) {
+		case string:
+			values[name] = v
+		case float64, bool:
+			values[name] = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("%s.%s must be a string", key, name)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,132 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-node-executor/pkg/tools/function"
+)
+
+// MaxInputBytes bounds the JSON size of a call's params
+const MaxInputBytes = 1 << 20
+
+// Options configure the built-in tools
+type Options struct {
+	// Scratch stores the scratchpad; nil uses an in-memory store, which
+	// is not shared between workers
+	Scratch ScratchStore
+
+	// GraphID returns the graph a call belongs to, scoping the scratchpad.
+	// Without it, or when it returns "", scratch tools fail.
+	GraphID func(ctx context.Context) string
+
+	// Now returns the current time (default time.Now)
+	Now func() time.Time
+}
+
+// tool is a built-in tool definition and function
+type tool struct {
+	def domain.Tool
+	fn  function.ToolFunc
+}
+
+// Register registers the built-in tools in registry
+func Register(registry *function.Registry, opts Options) {
+	if opts.Scratch == nil {
+		opts.Scratch = NewMemoryScratch(DefaultScratchTTL)
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	var tools []tool
+	tools = append(tools, mathTools()...)
+	tools = append(tools, jsonTools()...)
+	tools = append(tools, regexTools()...)
+	tools = append(tools, textTools()...)
+	tools = append(tools, timeTools(opts.Now)...)
+	tools = append(tools, idTools()...)
+	tools = append(tools, csvTools()...)
+	tools = append(tools, scratchTools(opts.Scratch, opts.GraphID)...)
+
+	for _, t := range tools {
+		registry.RegisterTool(t.def, bounded(t.fn))
+	}
+}
+
+// bounded rejects params larger than MaxInputBytes
+func bounded(fn function.ToolFunc) function.ToolFunc {
+	return func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("params are not valid JSON: %w", err)
+		}
+		if len(data) > MaxInputBytes {
+			return nil, fmt.Errorf("params exceed %d bytes", MaxInputBytes)
+		}
+		return fn(ctx, params)
+	}
+}
+
+// Schema helpers
+
+// object is the schema of an object with the given properties
+func object(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// prop is the schema of a property
+func prop(typ, description string) map[string]interface{} {
+	return map[string]interface{}{"type": typ, "description": description}
+}
+
+// enumProp is the schema of a string property with fixed values
+func enumProp(description string, values ...string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "description": description, "enum": values}
+}
+
+// anyProp is the schema of a property of any JSON type
+func anyProp(description string) map[string]interface{} {
+	return map[string]interface{}{"description": description}
+}
+
+// Param helpers; types are checked by the registry against the schema
+
+func stringParam(params map[string]interface{}, key, def string) string {
+	if s, ok := params[key].(string); ok {
+		return s
+	}
+	return def
+}
+
+func numberParam(params map[string]interface{}, key string, def float64) float64 {
+	if f, ok := params[key].(float64); ok {
+		return f
+	}
+	if i, ok := params[key].(int); ok {
+		return float64(i)
+	}
+	return def
+}
+
+func intParam(params map[string]interface{}, key string, def int) int {
+	return int(numberParam(params, key, float64(def)))
+}
+
+func boolParam(params map[string]interface{}, key string, def bool) bool {
+	if b, ok := params[key].(bool); ok {
+		return b
+	}
+	return def
+}
@@ -0,0 +1,202 @@
+package builtin
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+)
+
+// maxCSVRows bounds csv.parse output
+const maxCSVRows = 10000
+
+func csvTools() []tool {
+	delimiter := prop("string", "Field delimiter (default ,)")
+
+	return []tool{
+		{
+			def: domain.Tool{
+				Name: "csv_parse",
+				Description: "Parse CSV text. With header (default true) rows are objects keyed by the first line, " +
+					"otherwise lists of fields.",
+				Parameters: object(map[string]interface{}{
+					"text":      prop("string", "CSV text"),
+					"delimiter": delimiter,
+					"header":    prop("boolean", "The first line holds column names (default true)"),
+				}, "text"),
+			},
+			fn: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				comma, err := csvDelimiter(params)
+				if err != nil {
+					return nil, err
+				}
+				r := csv.NewReader(strings.NewReader(stringParam(params, "text", "")))
+				r.Comma = comma
+				r.FieldsPerRecord = -1
+
+				header := boolParam(params, "header", true)
+				var columns []string
+				rows := []interface{}{}
+				for {
+					record, err := r.Read()
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						return nil, fmt.Errorf("invalid CSV: %w", err)
+					}
+					if header && columns == nil {
+						columns = record
+						continue
+					}
+					if len(rows) >= maxCSVRows {
+						return nil, fmt.Errorf("more than %d rows", maxCSVRows)
+					}
+					if !header {
+						fields := make([]interface{}, len(record))
+						for i, field := range record {
+							fields[i] = field
+						}
+						rows = append(rows, fields)
+						continue
+					}
+					row := make(map[string]interface{}, len(columns))
+					for i, column := range columns {
+						if i < len(record) {
+							row[column] = record[i]
+						} else {
+							row[column] = ""
+						}
+					}
+					rows = append(rows, row)
+				}
+
+				result := map[string]interface{}{"rows": rows, "count": len(rows)}
+				if header {
+					result["columns"] = stringsToInterfaces(columns)
+				}
+				return result, nil
+			},
+		},
+		{
+			def: domain.Tool{
+				Name: "csv_format",
+				Description: "Format rows as CSV. Rows are objects (columns default to their sorted keys) " +
+					"or lists of values.",
+				Parameters: object(map[string]interface{}{
+					"rows": map[string]interface{}{
+						"type":        "array",
+						"description": "Rows as objects or lists",
+					},
+					"columns": map[string]interface{}{
+						"type":        "array",
+						"description": "Column order and header; required to order object keys",
+						"items":       map[string]interface{}{"type": "string"},
+					},
+					"delimiter": delimiter,
+					"header":    prop("boolean", "Write a header line (default true when columns are known)"),
+				}, "rows"),
+			},
+			fn: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				comma, err := csvDelimiter(params)
+				if err != nil {
+					return nil, err
+				}
+				rows, _ := params["rows"].([]interface{})
+
+				var columns []string
+				if raw, ok := params["columns"].([]interface{}); ok {
+					for _, c := range raw {
+						s, _ := c.(string)
+						columns = append(columns, s)
+					}
+				} else if len(rows) > 0 {
+					if first, ok := rows[0].(map[string]interface{}); ok {
+						columns = sortedKeys(first)
+					}
+				}
+
+				var b strings.Builder
+				w := csv.NewWriter(&b)
+				w.Comma = comma
+				if len(columns) > 0 && boolParam(params, "header", true) {
+					_ = w.Write(columns)
+				}
+				for i, row := range rows {
+					var record []string
+					switch v := row.(type) {
+					case map[string]interface{}:
+						if len(columns) == 0 {
+							return nil, fmt.Errorf("row %d: object rows need columns", i)
+						}
+						for _, column := range columns {
+							record = append(record, csvField(v[column]))
+						}
+					case []interface{}:
+						for _, field := range v {
+							record = append(record, csvField(field))
+						}
+					default:
+						return nil, fmt.Errorf("row %d is neither an object nor a list", i)
+					}
+					if err := w.Write(record); err != nil {
+						return nil, fmt.Errorf("row %d: %w", i, err)
+					}
+				}
+				w.Flush()
+				if err := w.Error(); err != nil {
+					return nil, fmt.Errorf("failed to format CSV: %w", err)
+				}
+				return map[string]interface{}{"text": b.String()}, nil
+			},
+		},
+	}
+}
+
+// csvDelimiter returns the delimiter param as a rune
+func csvDelimiter(params map[string]interface{}) (rune, error) {
+	s := stringParam(params, "delimiter", ",")
+	if s == `\t` {
+		s = "\t"
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 || size != len(s) || r == '"' || r == '\n' || r == '\r' {
+		return 0, fmt.Errorf("invalid delimiter %q", s)
+	}
+	return r, nil
+}
+
+// csvField formats a JSON value as a CSV field
+func csvField(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func stringsToInterfaces(values []string) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
@@ -0,0 +1,12 @@
+// Package builtin is the standard library of tools registered in the
+// function registry.
+//
+// The tools cover what agents routinely need without an MCP server: math
+// expressions (math_eval), JSON query and patch (json_query, json_patch),
+// regular expressions (regex_extract), text utilities (text_*), dates and
+// times with time zones (time_*), UUIDs and hashes (uuid_generate,
+// hash_digest), CSV (csv_parse, csv_format) and a key-value scratchpad
+// scoped to the graph (scratch_*). Each is registered with a JSON Schema
+// of its parameters. They do no I/O besides the scratchpad store, and
+// inputs are bounded in size.
+package builtin
@@ -0,0 +1,106 @@
+package builtin
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/google/uuid"
+)
+
+// maxUUIDCount bounds uuid_generate counts
+const maxUUIDCount = 100
+
+// hashes are the algorithms of hash_digest
+var hashes = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+func idTools() []tool {
+	return []tool{
+		{
+			def: domain.Tool{
+				Name:        "uuid_generate",
+				Description: "Generate random (v4) or time-ordered (v7) UUIDs.",
+				Parameters: object(map[string]interface{}{
+					"version": enumProp("UUID version (default v4)", "v4", "v7"),
+					"count":   prop("integer", "Number of UUIDs (default 1, at most 100)"),
+				}),
+			},
+			fn: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				count := intParam(params, "count", 1)
+				if count < 1 || count > maxUUIDCount {
+					return nil, fmt.Errorf("count must be between 1 and %d", maxUUIDCount)
+				}
+				version := stringParam(params, "version", "v4")
+				ids := make([]interface{}, 0, count)
+				for i := 0; i < count; i++ {
+					var id uuid.UUID
+					var err error
+					switch version {
+					case "v4":
+						id, err = uuid.NewRandom()
+					case "v7":
+						id, err = uuid.NewV7()
+					default:
+						return nil, fmt.Errorf("unknown version %q", version)
+					}
+					if err != nil {
+						return nil, fmt.Errorf("failed to generate UUID: %w", err)
+					}
+					ids = append(ids, id.String())
+				}
+				return map[string]interface{}{"uuids": ids}, nil
+			},
+		},
+		{
+			def: domain.Tool{
+				Name:        "hash_digest",
+				Description: "Hash a text with md5, sha1, sha256 or sha512, optionally as an HMAC with a key.",
+				Parameters: object(map[string]interface{}{
+					"text":      prop("string", "Text to hash"),
+					"algorithm": enumProp("Hash algorithm (default sha256)", "md5", "sha1", "sha256", "sha512"),
+					"key":       prop("string", "HMAC key; omit for a plain hash"),
+					"encoding":  enumProp("Output encoding (default hex)", "hex", "base64"),
+				}, "text"),
+			},
+			fn: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				algorithm := stringParam(params, "algorithm", "sha256")
+				newHash, ok := hashes[algorithm]
+				if !ok {
+					return nil, fmt.Errorf("unknown algorithm %q", algorithm)
+				}
+				var h hash.Hash
+				if key, ok := params["key"].(string); ok {
+					h = hmac.New(newHash, []byte(key))
+				} else {
+					h = newHash()
+				}
+				h.Write([]byte(stringParam(params, "text", "")))
+				sum := h.Sum(nil)
+
+				var digest string
+				switch encoding := stringParam(params, "encoding", "hex"); encoding {
+				case "hex":
+					digest = hex.EncodeToString(sum)
+				case "base64":
+					digest = base64.StdEncoding.EncodeToString(sum)
+				default:
+					return nil, fmt.Errorf("unknown encoding %q", encoding)
+				}
+				return map[string]interface{}{"digest": digest, "algorithm": algorithm}, nil
+			},
+		},
+	}
+}
@@ -0,0 +1,442 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+)
+
+func jsonTools() []tool {
+	return []tool{
+		{
+			def: domain.Tool{
+				Name: "json_query",
+				Description: "Select values from a JSON document with a path such as $.items[0].name, " +
+					"items[*].price or $[\"key.with.dots\"]. [*] selects every element and makes the result a list.",
+				Parameters: object(map[string]interface{}{
+					"document": anyProp("JSON value, or a string containing JSON"),
+					"path":     prop("string", "Path to select; $ or empty selects the whole document"),
+				}, "document", "path"),
+			},
+			fn: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				doc := decodeDocument(params["document"])
+				result, found, err := QueryJSON(doc, stringParam(params, "path", ""))
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{"result": result, "found": found}, nil
+			},
+		},
+		{
+			def: domain.Tool{
+				Name: "json_patch",
+				Description: "Apply a JSON Patch (RFC 6902) to a JSON document: a list of operations " +
+					"add, remove, replace, move, copy and test with JSON Pointer paths such as /items/0/name.",
+				Parameters: object(map[string]interface{}{
+					"document": anyProp("JSON value, or a string containing JSON"),
+					"patch": map[string]interface{}{
+						"type":        "array",
+						"description": "Operations, e.g. [{\"op\": \"replace\", \"path\": \"/name\", \"value\": \"x\"}]",
+						"items":       prop("object", "Operation with op, path, and value or from"),
+					},
+				}, "document", "patch"),
+			},
+			fn: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				doc := decodeDocument(params["document"])
+				ops, _ := params["patch"].([]interface{})
+				result, err := PatchJSON(doc, ops)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{"result": result}, nil
+			},
+		},
+	}
+}
+
+// decodeDocument parses a string holding a JSON object or array, and
+// returns other values as they are
+func decodeDocument(value interface{}) interface{} {
+	s, ok := value.(string)
+	if !ok {
+		return value
+	}
+	trimmed := strings.TrimSpace(s)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return value
+	}
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(trimmed), &decoded); err != nil {
+		return value
+	}
+	return decoded
+}
+
+// pathSegment is a step of a query path: a key, an index or a wildcard
+type pathSegment struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parsePath splits a query path into segments
+func parsePath(path string) ([]pathSegment, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+
+	var segments []pathSegment
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '.':
+			i++
+			if i < len(path) && path[i] == '*' {
+				segments = append(segments, pathSegment{wildcard: true})
+				i++
+				continue
+			}
+			start := i
+			for i < len(path) && path[i] != '.' && path[i] != '[' {
+				i++
+			}
+			if start == i {
+				return nil, fmt.Errorf("empty key at position %d", start)
+			}
+			segments = append(segments, pathSegment{key: path[start:i]})
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [ at position %d", i)
+			}
+			inner := strings.TrimSpace(path[i+1 : i+end])
+			i += end + 1
+			switch {
+			case inner == "*":
+				segments = append(segments, pathSegment{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '"' || inner[0] == '\'') && inner[len(inner)-1] == inner[0]:
+				segments = append(segments, pathSegment{key: inner[1 : len(inner)-1]})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index %q", inner)
+				}
+				segments = append(segments, pathSegment{index: index, isIndex: true})
+			}
+		default:
+			// A leading bare key
+			start := i
+			for i < len(path) && path[i] != '.' && path[i] != '[' {
+				i++
+			}
+			segments = append(segments, pathSegment{key: path[start:i]})
+		}
+	}
+	return segments, nil
+}
+
+// QueryJSON selects the value at path in doc. With a wildcard in the path
+// the result is the list of matches.
+func QueryJSON(doc interface{}, path string) (interface{}, bool, error) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid path: %w", err)
+	}
+
+	current := []interface{}{doc}
+	multi := false
+	for _, seg := range segments {
+		var next []interface{}
+		for _, node := range current {
+			switch v := node.(type) {
+			case map[string]interface{}:
+				if seg.wildcard {
+					for _, key := range sortedKeys(v) {
+						next = append(next, v[key])
+					}
+				} else if !seg.isIndex {
+					if value, ok := v[seg.key]; ok {
+						next = append(next, value)
+					}
+				}
+			case []interface{}:
+				switch {
+				case seg.wildcard:
+					next = append(next, v...)
+				case seg.isIndex:
+					index := seg.index
+					if index < 0 {
+						index += len(v)
+					}
+					if index >= 0 && index < len(v) {
+						next = append(next, v[index])
+					}
+				}
+			}
+		}
+		if seg.wildcard {
+			multi = true
+		}
+		current = next
+	}
+
+	if multi {
+		if current == nil {
+			current = []interface{}{}
+		}
+		return current, len(current) > 0, nil
+	}
+	if len(current) == 0 {
+		return nil, false, nil
+	}
+	return current[0], true, nil
+}
+
+// PatchJSON applies RFC 6902 operations to a copy of doc
+func PatchJSON(doc interface{}, ops []interface{}) (interface{}, error) {
+	doc = deepCopy(doc)
+	for i, raw := range ops {
+		op, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("operation %d is not an object", i)
+		}
+		name, _ := op["op"].(string)
+		path, _ := op["path"].(string)
+		from, _ := op["from"].(string)
+		value, hasValue := op["value"]
+
+		var err error
+		switch name {
+		case "add":
+			if !hasValue {
+				return nil, fmt.Errorf("operation %d: add needs a value", i)
+			}
+			doc, err = pointerAdd(doc, path, deepCopy(value))
+		case "remove":
+			doc, _, err = pointerRemove(doc, path)
+		case "replace":
+			if !hasValue {
+				return nil, fmt.Errorf("operation %d: replace needs a value", i)
+			}
+			if doc, _, err = pointerRemove(doc, path); err == nil {
+				doc, err = pointerAdd(doc, path, deepCopy(value))
+			}
+		case "move":
+			var moved interface{}
+			if strings.HasPrefix(path, from+"/") {
+				err = fmt.Errorf("cannot move %s into itself", from)
+			} else if doc, moved, err = pointerRemove(doc, from); err == nil {
+				doc, err = pointerAdd(doc, path, moved)
+			}
+		case "copy":
+			var copied interface{}
+			if copied, err = pointerGet(doc, from); err == nil {
+				doc, err = pointerAdd(doc, path, deepCopy(copied))
+			}
+		case "test":
+			var actual interface{}
+			if actual, err = pointerGet(doc, path); err == nil && !jsonEqual(actual, value) {
+				err = fmt.Errorf("test failed: value at %s differs", path)
+			}
+		default:
+			err = fmt.Errorf("unknown op %q", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	return doc, nil
+}
+
+// splitPointer parses a JSON Pointer into unescaped tokens
+func splitPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("pointer %q must start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, tok := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// arrayIndex parses an array index token; "-" is the end for add
+func arrayIndex(tok string, length int, forAdd bool) (int, error) {
+	if forAdd && tok == "-" {
+		return length, nil
+	}
+	index, err := strconv.Atoi(tok)
+	if err != nil || index < 0 || (tok != "0" && strings.HasPrefix(tok, "0")) {
+		return 0, fmt.Errorf("invalid array index %q", tok)
+	}
+	limit := length
+	if forAdd {
+		limit++
+	}
+	if index >= limit {
+		return 0, fmt.Errorf("array index %d out of range", index)
+	}
+	return index, nil
+}
+
+// pointerGet returns the value at pointer
+func pointerGet(doc interface{}, pointer string) (interface{}, error) {
+	tokens, err := splitPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	current := doc
+	for _, tok := range tokens {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			value, ok := v[tok]
+			if !ok {
+				return nil, fmt.Errorf("path %s not found", pointer)
+			}
+			current = value
+		case []interface{}:
+			index, err := arrayIndex(tok, len(v), false)
+			if err != nil {
+				return nil, err
+			}
+			current = v[index]
+		default:
+			return nil, fmt.Errorf("path %s not found", pointer)
+		}
+	}
+	return current, nil
+}
+
+// pointerAdd adds value at pointer and returns the new document
+func pointerAdd(doc interface{}, pointer string, value interface{}) (interface{}, error) {
+	tokens, err := splitPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return setIn(doc, tokens, func(parent interface{}, last string) (interface{}, error) {
+		switch v := parent.(type) {
+		case map[string]interface{}:
+			v[last] = value
+			return v, nil
+		case []interface{}:
+			index, err := arrayIndex(last, len(v), true)
+			if err != nil {
+				return nil, err
+			}
+			v = append(v, nil)
+			copy(v[index+1:], v[index:])
+			v[index] = value
+			return v, nil
+		default:
+			return nil, fmt.Errorf("path %s not found", pointer)
+		}
+	}, pointer)
+}
+
+// pointerRemove removes the value at pointer and returns the new document
+// and the removed value
+func pointerRemove(doc interface{}, pointer string) (interface{}, interface{}, error) {
+	tokens, err := splitPointer(pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, doc, nil
+	}
+	var removed interface{}
+	doc, err = setIn(doc, tokens, func(parent interface{}, last string) (interface{}, error) {
+		switch v := parent.(type) {
+		case map[string]interface{}:
+			value, ok := v[last]
+			if !ok {
+				return nil, fmt.Errorf("path %s not found", pointer)
+			}
+			removed = value
+			delete(v, last)
+			return v, nil
+		case []interface{}:
+			index, err := arrayIndex(last, len(v), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = v[index]
+			return append(v[:index], v[index+1:]...), nil
+		default:
+			return nil, fmt.Errorf("path %s not found", pointer)
+		}
+	}, pointer)
+	return doc, removed, err
+}
+
+// setIn walks to the parent of the last token and replaces it with the
+// result of update, rebuilding the containers on the way since slices may
+// be reallocated
+func setIn(doc interface{}, tokens []string, update func(parent interface{}, last string) (interface{}, error), pointer string) (interface{}, error) {
+	if len(tokens) == 1 {
+		return update(doc, tokens[0])
+	}
+	tok := tokens[0]
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		child, ok := v[tok]
+		if !ok {
+			return nil, fmt.Errorf("path %s not found", pointer)
+		}
+		updated, err := setIn(child, tokens[1:], update, pointer)
+		if err != nil {
+			return nil, err
+		}
+		v[tok] = updated
+		return v, nil
+	case []interface{}:
+		index, err := arrayIndex(tok, len(v), false)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := setIn(v[index], tokens[1:], update, pointer)
+		if err != nil {
+			return nil, err
+		}
+		v[index] = updated
+		return v, nil
+	default:
+		return nil, fmt.Errorf("path %s not found", pointer)
+	}
+}
+
+// deepCopy copies JSON containers so patches don't modify the caller's
+// document
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, inner := range v {
+			out[key] = deepCopy(inner)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, inner := range v {
+			out[i] = deepCopy(inner)
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+// jsonEqual compares two JSON values
+func jsonEqual(a, b interface{}) bool {
+	da, errA := json.Marshal(a)
+	db, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(da) == string(db)
+}
@@ -0,0 +1,365 @@
+package builtin
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+)
+
+// maxExpressionLength bounds math_eval expressions
+const maxExpressionLength = 4096
+
+func mathTools() []tool {
+	return []tool{{
+		def: domain.Tool{
+			Name: "math_eval",
+			Description: "Evaluate an arithmetic expression. Supports + - * / % ^, parentheses, " +
+				"the constants pi and e, variables, and the functions abs, ceil, floor, round, sqrt, " +
+				"exp, ln, log (base 10), log2, sin, cos, tan, asin, acos, atan, min, max and pow.",
+			Parameters: object(map[string]interface{}{
+				"expression": prop("string", "Expression, e.g. (price * qty) * (1 + tax)"),
+				"variables":  prop("object", "Numeric values of the variables used in the expression"),
+			}, "expression"),
+		},
+		fn: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+			expression := stringParam(params, "expression", "")
+			vars := make(map[string]float64)
+			if raw, ok := params["variables"].(map[string]interface{}); ok {
+				for name, value := range raw {
+					f, ok := value.(float64)
+					if !ok {
+						return nil, fmt.Errorf("variable %s is not a number", name)
+					}
+					vars[name] = f
+				}
+			}
+
+			result, err := Eval(expression, vars)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"result": result}, nil
+		},
+	}}
+}
+
+// Eval evaluates an arithmetic expression with the given variables
+func Eval(expression string, vars map[string]float64) (float64, error) {
+	if len(expression) > maxExpressionLength {
+		return 0, fmt.Errorf("expression longer than %d characters", maxExpressionLength)
+	}
+	p := &exprParser{input: expression, vars: vars}
+	p.next()
+	value, err := p.parseExpr()
+	if err != nil {
+		return 0, err
+	}
+	if p.tok.kind != tokEOF {
+		return 0, fmt.Errorf("unexpected %q at position %d", p.tok.text, p.tok.pos)
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("result is not a finite number")
+	}
+	return value, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	num  float64
+	pos  int
+}
+
+// exprParser is a recursive descent parser evaluating as it parses:
+//
+//	expr   = term { ("+" | "-") term }
+//	term   = unary { ("*" | "/" | "%") unary }
+//	unary  = ("-" | "+") unary | power
+//	power  = atom [ "^" unary ]
+//	atom   = number | ident [ "(" args ")" ] | "(" expr ")"
+type exprParser struct {
+	input string
+	pos   int
+	tok   token
+	vars  map[string]float64
+	depth int
+	err   error
+}
+
+// maxExpressionDepth bounds nesting, and so recursion
+const maxExpressionDepth = 100
+
+func (p *exprParser) next() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.input) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return
+	}
+
+	c := p.input[p.pos]
+	switch {
+	case c >= '0' && c <= '9' || c == '.':
+		for p.pos < len(p.input) && (isDigit(p.input[p.pos]) || p.input[p.pos] == '.') {
+			p.pos++
+		}
+		// Exponent
+		if p.pos < len(p.input) && (p.input[p.pos] == 'e' || p.input[p.pos] == 'E') {
+			save := p.pos
+			p.pos++
+			if p.pos < len(p.input) && (p.input[p.pos] == '+' || p.input[p.pos] == '-') {
+				p.pos++
+			}
+			if p.pos < len(p.input) && isDigit(p.input[p.pos]) {
+				for p.pos < len(p.input) && isDigit(p.input[p.pos]) {
+					p.pos++
+				}
+			} else {
+				p.pos = save
+			}
+		}
+		text := p.input[start:p.pos]
+		num, err := strconv.ParseFloat(text, 64)
+		if err != nil && p.err == nil {
+			p.err = fmt.Errorf("invalid number %q at position %d", text, start)
+		}
+		p.tok = token{kind: tokNumber, text: text, num: num, pos: start}
+	case c == '_' || unicode.IsLetter(rune(c)):
+		for p.pos < len(p.input) && (p.input[p.pos] == '_' || isDigit(p.input[p.pos]) || unicode.IsLetter(rune(p.input[p.pos]))) {
+			p.pos++
+		}
+		p.tok = token{kind: tokIdent, text: p.input[start:p.pos], pos: start}
+	default:
+		p.pos++
+		p.tok = token{kind: tokOp, text: string(c), pos: start}
+	}
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func (p *exprParser) isOp(op string) bool {
+	return p.tok.kind == tokOp && p.tok.text == op
+}
+
+func (p *exprParser) parseExpr() (float64, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxExpressionDepth {
+		return 0, fmt.Errorf("expression nested too deeply")
+	}
+
+	left, err := p.parseTerm()
+	if err != nil {
+		return 0, err
+	}
+	for p.isOp("+") || p.isOp("-") {
+		op := p.tok.text
+		p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return 0, err
+		}
+		if op == "+" {
+			left += right
+		} else {
+			left -= right
+		}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseTerm() (float64, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+	for p.isOp("*") || p.isOp("/") || p.isOp("%") {
+		op := p.tok.text
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case "*":
+			left *= right
+		case "/":
+			if right == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			left /= right
+		case "%":
+			if right == 0 {
+				return 0, fmt.Errorf("modulo by zero")
+			}
+			left = math.Mod(left, right)
+		}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (float64, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxExpressionDepth {
+		return 0, fmt.Errorf("expression nested too deeply")
+	}
+
+	if p.isOp("-") || p.isOp("+") {
+		negate := p.tok.text == "-"
+		p.next()
+		value, err := p.parseUnary()
+		if negate {
+			value = -value
+		}
+		return value, err
+	}
+	return p.parsePower()
+}
+
+func (p *exprParser) parsePower() (float64, error) {
+	base, err := p.parseAtom()
+	if err != nil {
+		return 0, err
+	}
+	if p.isOp("^") {
+		p.next()
+		exponent, err := p.parseUnary()
+		if err != nil {
+			return 0, err
+		}
+		return math.Pow(base, exponent), nil
+	}
+	return base, nil
+}
+
+func (p *exprParser) parseAtom() (float64, error) {
+	if p.err != nil {
+		return 0, p.err
+	}
+
+	tok := p.tok
+	switch {
+	case tok.kind == tokNumber:
+		p.next()
+		return tok.num, p.err
+	case tok.kind == tokIdent:
+		p.next()
+		if p.isOp("(") {
+			p.next()
+			var args []float64
+			if !p.isOp(")") {
+				for {
+					arg, err := p.parseExpr()
+					if err != nil {
+						return 0, err
+					}
+					args = append(args, arg)
+					if !p.isOp(",") {
+						break
+					}
+					p.next()
+				}
+			}
+			if !p.isOp(")") {
+				return 0, fmt.Errorf("expected ) at position %d", p.tok.pos)
+			}
+			p.next()
+			return callFunction(tok.text, args)
+		}
+		if value, ok := p.vars[tok.text]; ok {
+			return value, nil
+		}
+		switch strings.ToLower(tok.text) {
+		case "pi":
+			return math.Pi, nil
+		case "e":
+			return math.E, nil
+		}
+		return 0, fmt.Errorf("unknown variable %q", tok.text)
+	case p.isOp("("):
+		p.next()
+		value, err := p.parseExpr()
+		if err != nil {
+			return 0, err
+		}
+		if !p.isOp(")") {
+			return 0, fmt.Errorf("expected ) at position %d", p.tok.pos)
+		}
+		p.next()
+		return value, nil
+	case tok.kind == tokEOF:
+		return 0, fmt.Errorf("unexpected end of expression")
+	default:
+		return 0, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+}
+
+// unaryFunctions are the functions of one argument
+var unaryFunctions = map[string]func(float64) float64{
+	"abs":   math.Abs,
+	"ceil":  math.Ceil,
+	"floor": math.Floor,
+	"round": math.Round,
+	"sqrt":  math.Sqrt,
+	"exp":   math.Exp,
+	"ln":    math.Log,
+	"log":   math.Log10,
+	"log2":  math.Log2,
+	"sin":   math.Sin,
+	"cos":   math.Cos,
+	"tan":   math.Tan,
+	"asin":  math.Asin,
+	"acos":  math.Acos,
+	"atan":  math.Atan,
+}
+
+// callFunction applies a named function
+func callFunction(name string, args []float64) (float64, error) {
+	name = strings.ToLower(name)
+	if fn, ok := unaryFunctions[name]; ok {
+		if len(args) != 1 {
+			return 0, fmt.Errorf("%s takes 1 argument, got %d", name, len(args))
+		}
+		return fn(args[0]), nil
+	}
+
+	switch name {
+	case "min", "max":
+		if len(args) == 0 {
+			return 0, fmt.Errorf("%s needs at least 1 argument", name)
+		}
+		result := args[0]
+		for _, arg := range args[1:] {
+			if name == "min" {
+				result = math.Min(result, arg)
+			} else {
+				result = math.Max(result, arg)
+			}
+		}
+		return result, nil
+	case "pow":
+		if len(args) != 2 {
+			return 0, fmt.Errorf("pow takes 2 arguments, got %d", len(args))
+		}
+		return math.Pow(args[0], args[1]), nil
+	default:
+		return 0, fmt.Errorf("unknown function %q", name)
+	}
+}
@@ -0,0 +1,71 @@
+package builtin
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+)
+
+// maxPatternLength bounds regex_extract patterns
+const maxPatternLength = 1024
+
+func regexTools() []tool {
+	return []tool{{
+		def: domain.Tool{
+			Name: "regex_extract",
+			Description: "Find matches of a regular expression (RE2 syntax) in a text. Returns each match " +
+				"with its numbered groups and named groups (?P<name>...).",
+			Parameters: object(map[string]interface{}{
+				"text":             prop("string", "Text to search"),
+				"pattern":          prop("string", "Regular expression"),
+				"limit":            prop("integer", "Maximum number of matches (default 100)"),
+				"case_insensitive": prop("boolean", "Match regardless of case"),
+			}, "text", "pattern"),
+		},
+		fn: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+			pattern := stringParam(params, "pattern", "")
+			if len(pattern) > maxPatternLength {
+				return nil, fmt.Errorf("pattern longer than %d characters", maxPatternLength)
+			}
+			if boolParam(params, "case_insensitive", false) {
+				pattern = "(?i)" + pattern
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern: %w", err)
+			}
+			limit := intParam(params, "limit", 100)
+			if limit <= 0 {
+				limit = 100
+			}
+
+			text := stringParam(params, "text", "")
+			names := re.SubexpNames()
+			matches := []interface{}{}
+			for _, loc := range re.FindAllStringSubmatchIndex(text, limit) {
+				groups := []interface{}{}
+				named := map[string]interface{}{}
+				for i := 1; i < len(names); i++ {
+					var value interface{}
+					if loc[2*i] >= 0 {
+						value = text[loc[2*i]:loc[2*i+1]]
+					}
+					groups = append(groups, value)
+					if names[i] != "" {
+						named[names[i]] = value
+					}
+				}
+				matches = append(matches, map[string]interface{}{
+					"match":  text[loc[0]:loc[1]],
+					"start":  loc[0],
+					"end":    loc[1],
+					"groups": groups,
+					"named":  named,
+				})
+			}
+			return map[string]interface{}{"matches": matches, "count": len(matches)}, nil
+		},
+	}}
+}
@@ -0,0 +1,338 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+// Scratchpad defaults and limits
+const (
+	DefaultScratchTTL       = 24 * time.Hour
+	DefaultScratchKeyPrefix = "dago:scratch:"
+	MaxScratchKeys          = 1000
+	maxScratchKeyLength     = 256
+)
+
+// ErrScratchFull is returned when setting a new key in a graph's
+// scratchpad holding MaxScratchKeys keys
+var ErrScratchFull = fmt.Errorf("scratchpad holds %d keys", MaxScratchKeys)
+
+// ScratchStore stores the scratchpad of each graph. Values are JSON. A
+// graph's scratchpad expires after its TTL without writes.
+type ScratchStore interface {
+	Set(ctx context.Context, graphID, key string, value []byte) error
+	Get(ctx context.Context, graphID, key string) ([]byte, bool, error)
+	Delete(ctx context.Context, graphID, key string) (bool, error)
+	Keys(ctx context.Context, graphID string) ([]string, error)
+}
+
+func scratchTools(store ScratchStore, graphID func(ctx context.Context) string) []tool {
+	key := prop("string", "Key in the scratchpad")
+
+	// scoped returns the graph of the call, failing outside of one
+	scoped := func(ctx context.Context) (string, error) {
+		if graphID == nil {
+			return "", errors.New("scratchpad is not available: no graph")
+		}
+		id := graphID(ctx)
+		if id == "" {
+			return "", errors.New("scratchpad is not available: no graph")
+		}
+		return id, nil
+	}
+
+	return []tool{
+		{
+			def: domain.Tool{
+				Name: "scratch_set",
+				Description: "Store a JSON value under a key in a scratchpad shared by the nodes of the current graph. " +
+					"Overwrites any previous value.",
+				Parameters: object(map[string]interface{}{
+					"key":   key,
+					"value": anyProp("Value to store"),
+				}, "key", "value"),
+			},
+			fn: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				graph, err := scoped(ctx)
+				if err != nil {
+					return nil, err
+				}
+				k := stringParam(params, "key", "")
+				if k == "" || len(k) > maxScratchKeyLength {
+					return nil, fmt.Errorf("key must be 1 to %d characters", maxScratchKeyLength)
+				}
+				data, err := json.Marshal(params["value"])
+				if err != nil {
+					return nil, fmt.Errorf("value is not valid JSON: %w", err)
+				}
+				if err := store.Set(ctx, graph, k, data); err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{"key": k, "stored": true}, nil
+			},
+		},
+		{
+			def: domain.Tool{
+				Name:        "scratch_get",
+				Description: "Read the value stored under a key in the current graph's scratchpad.",
+				Parameters: object(map[string]interface{}{
+					"key": key,
+				}, "key"),
+			},
+			fn: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				graph, err := scoped(ctx)
+				if err != nil {
+					return nil, err
+				}
+				k := stringParam(params, "key", "")
+				data, found, err := store.Get(ctx, graph, k)
+				if err != nil {
+					return nil, err
+				}
+				result := map[string]interface{}{"key": k, "found": found, "value": nil}
+				if found {
+					var value interface{}
+					if err := json.Unmarshal(data, &value); err != nil {
+						return nil, fmt.Errorf("stored value is not valid JSON: %w", err)
+					}
+					result["value"] = value
+				}
+				return result, nil
+			},
+		},
+		{
+			def: domain.Tool{
+				Name:        "scratch_delete",
+				Description: "Delete a key from the current graph's scratchpad.",
+				Parameters: object(map[string]interface{}{
+					"key": key,
+				}, "key"),
+			},
+			fn: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				graph, err := scoped(ctx)
+				if err != nil {
+					return nil, err
+				}
+				k := stringParam(params, "key", "")
+				deleted, err := store.Delete(ctx, graph, k)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{"key": k, "deleted": deleted}, nil
+			},
+		},
+		{
+			def: domain.Tool{
+				Name:        "scratch_list",
+				Description: "List the keys in the current graph's scratchpad.",
+				Parameters:  object(map[string]interface{}{}),
+			},
+			fn: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				graph, err := scoped(ctx)
+				if err != nil {
+					return nil, err
+				}
+				keys, err := store.Keys(ctx, graph)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{"keys": stringsToInterfaces(keys), "count": len(keys)}, nil
+			},
+		},
+	}
+}
+
+// memoryPad is the scratchpad of one graph in a MemoryScratch
+type memoryPad struct {
+	values  map[string][]byte
+	expires time.Time
+}
+
+// MemoryScratch is a ScratchStore in process memory, for single workers
+// and local runs
+type MemoryScratch struct {
+	ttl  time.Duration
+	mu   sync.Mutex
+	pads map[string]*memoryPad
+}
+
+// NewMemoryScratch creates an in-memory scratchpad store
+func NewMemoryScratch(ttl time.Duration) *MemoryScratch {
+	if ttl <= 0 {
+		ttl = DefaultScratchTTL
+	}
+	return &MemoryScratch{ttl: ttl, pads: make(map[string]*memoryPad)}
+}
+
+// pad returns the live scratchpad of a graph, dropping expired ones
+func (s *MemoryScratch) pad(graphID string, create bool) *memoryPad {
+	now := time.Now()
+	for id, p := range s.pads {
+		if now.After(p.expires) {
+			delete(s.pads, id)
+		}
+	}
+	p, ok := s.pads[graphID]
+	if !ok && create {
+		p = &memoryPad{values: make(map[string][]byte)}
+		s.pads[graphID] = p
+	}
+	return p
+}
+
+// Set stores a value
+func (s *MemoryScratch) Set(ctx context.Context, graphID, key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.pad(graphID, true)
+	if _, exists := p.values[key]; !exists && len(p.values) >= MaxScratchKeys {
+		return ErrScratchFull
+	}
+	p.values[key] = value
+	p.expires = time.Now().Add(s.ttl)
+	return nil
+}
+
+// Get reads a value
+func (s *MemoryScratch) Get(ctx context.Context, graphID, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.pad(graphID, false)
+	if p == nil {
+		return nil, false, nil
+	}
+	value, ok := p.values[key]
+	return value, ok, nil
+}
+
+// Delete removes a value
+func (s *MemoryScratch) Delete(ctx context.Context, graphID, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.pad(graphID, false)
+	if p == nil {
+		return false, nil
+	}
+	_, ok := p.values[key]
+	delete(p.values, key)
+	return ok, nil
+}
+
+// Keys lists the keys of a graph, sorted
+func (s *MemoryScratch) Keys(ctx context.Context, graphID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := []string{}
+	if p := s.pad(graphID, false); p != nil {
+		for key := range p.values {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// RedisScratchConfig holds Redis scratchpad settings
+type RedisScratchConfig struct {
+	// Namespace and KeyPrefix place each graph's scratchpad in a hash at
+	// <namespace>:<prefix>{<graph>}
+	Namespace string
+	KeyPrefix string
+
+	// TTL expires a scratchpad without writes (default 24h)
+	TTL time.Duration
+}
+
+// RedisScratch is a ScratchStore shared by all workers through Redis
+type RedisScratch struct {
+	client redis.UniversalClient
+	prefix string
+	ttl    time.Duration
+}
+
+// NewRedisScratch creates a Redis scratchpad store
+func NewRedisScratch(client redis.UniversalClient, cfg RedisScratchConfig) *RedisScratch {
+	prefix := cfg.KeyPrefix
+	if prefix == "" {
+		prefix = DefaultScratchKeyPrefix
+	}
+	if cfg.Namespace != "" {
+		prefix = cfg.Namespace + ":" + prefix
+	}
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = DefaultScratchTTL
+	}
+	return &RedisScratch{client: client, prefix: prefix, ttl: ttl}
+}
+
+func (s *RedisScratch) key(graphID string) string {
+	return s.prefix + "{" + graphID + "}"
+}
+
+// scratchSetScript sets a field unless that would exceed the key limit,
+// and refreshes the TTL
+var scratchSetScript = redis.NewScript(`
+if redis.call('HEXISTS', KEYS[1], ARGV[1]) == 0 and redis.call('HLEN', KEYS[1]) >= tonumber(ARGV[3]) then
+  return 0
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+return 1
+`)
+
+// Set stores a value
+func (s *RedisScratch) Set(ctx context.Context, graphID, key string, value []byte) error {
+	ok, err := scratchSetScript.Run(ctx, s.client, []string{s.key(graphID)},
+		key, value, MaxScratchKeys, s.ttl.Milliseconds()).Int()
+	if err != nil {
+		return fmt.Errorf("failed to store scratch value: %w", err)
+	}
+	if ok == 0 {
+		return ErrScratchFull
+	}
+	return nil
+}
+
+// Get reads a value
+func (s *RedisScratch) Get(ctx context.Context, graphID, key string) ([]byte, bool, error) {
+	value, err := s.client.HGet(ctx, s.key(graphID), key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read scratch value: %w", err)
+	}
+	return value, true, nil
+}
+
+// Delete removes a value
+func (s *RedisScratch) Delete(ctx context.Context, graphID, key string) (bool, error) {
+	n, err := s.client.HDel(ctx, s.key(graphID), key).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete scratch value: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Keys lists the keys of a graph, sorted
+func (s *RedisScratch) Keys(ctx context.Context, graphID string) ([]string, error) {
+	keys, err := s.client.HKeys(ctx, s.key(graphID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scratch keys: %w", err)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
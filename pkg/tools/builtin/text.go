@@ -0,0 +1,203 @@
+package builtin
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+)
+
+func textTools() []tool {
+	return []tool{
+		{
+			def: domain.Tool{
+				Name:        "text_transform",
+				Description: "Transform a text: upper, lower, title, trim, reverse or slug.",
+				Parameters: object(map[string]interface{}{
+					"text":      prop("string", "Text to transform"),
+					"operation": enumProp("Transformation", "upper", "lower", "title", "trim", "reverse", "slug"),
+				}, "text", "operation"),
+			},
+			fn: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				text := stringParam(params, "text", "")
+				var result string
+				switch op := stringParam(params, "operation", ""); op {
+				case "upper":
+					result = strings.ToUpper(text)
+				case "lower":
+					result = strings.ToLower(text)
+				case "title":
+					result = titleCase(text)
+				case "trim":
+					result = strings.TrimSpace(text)
+				case "reverse":
+					runes := []rune(text)
+					for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+						runes[i], runes[j] = runes[j], runes[i]
+					}
+					result = string(runes)
+				case "slug":
+					result = slugify(text)
+				default:
+					return nil, fmt.Errorf("unknown operation %q", op)
+				}
+				return map[string]interface{}{"result": result}, nil
+			},
+		},
+		{
+			def: domain.Tool{
+				Name:        "text_replace",
+				Description: "Replace occurrences of a literal string, or of a regular expression with $1-style group references.",
+				Parameters: object(map[string]interface{}{
+					"text":        prop("string", "Text to edit"),
+					"old":         prop("string", "String or pattern to replace"),
+					"new":         prop("string", "Replacement"),
+					"regex":       prop("boolean", "Treat old as a regular expression"),
+					"max_replace": prop("integer", "Maximum replacements for literal strings (default all)"),
+				}, "text", "old", "new"),
+			},
+			fn: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				text := stringParam(params, "text", "")
+				old := stringParam(params, "old", "")
+				replacement := stringParam(params, "new", "")
+				if boolParam(params, "regex", false) {
+					if len(old) > maxPatternLength {
+						return nil, fmt.Errorf("pattern longer than %d characters", maxPatternLength)
+					}
+					re, err := regexp.Compile(old)
+					if err != nil {
+						return nil, fmt.Errorf("invalid pattern: %w", err)
+					}
+					return map[string]interface{}{"result": re.ReplaceAllString(text, replacement)}, nil
+				}
+				if old == "" {
+					return nil, fmt.Errorf("old must not be empty")
+				}
+				return map[string]interface{}{
+					"result": strings.Replace(text, old, replacement, intParam(params, "max_replace", -1)),
+				}, nil
+			},
+		},
+		{
+			def: domain.Tool{
+				Name:        "text_split",
+				Description: "Split a text by a separator, or into lines or words.",
+				Parameters: object(map[string]interface{}{
+					"text":      prop("string", "Text to split"),
+					"separator": prop("string", "Separator; omit to split into words, use \\n for lines"),
+					"trim":      prop("boolean", "Trim spaces and drop empty parts"),
+				}, "text"),
+			},
+			fn: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				text := stringParam(params, "text", "")
+				var parts []string
+				if sep, ok := params["separator"].(string); ok && sep != "" {
+					parts = strings.Split(text, sep)
+				} else {
+					parts = strings.Fields(text)
+				}
+				items := []interface{}{}
+				trim := boolParam(params, "trim", false)
+				for _, part := range parts {
+					if trim {
+						part = strings.TrimSpace(part)
+						if part == "" {
+							continue
+						}
+					}
+					items = append(items, part)
+				}
+				return map[string]interface{}{"parts": items, "count": len(items)}, nil
+			},
+		},
+		{
+			def: domain.Tool{
+				Name:        "text_join",
+				Description: "Join a list of strings with a separator.",
+				Parameters: object(map[string]interface{}{
+					"parts": map[string]interface{}{
+						"type":        "array",
+						"description": "Strings to join",
+						"items":       map[string]interface{}{"type": "string"},
+					},
+					"separator": prop("string", "Separator (default empty)"),
+				}, "parts"),
+			},
+			fn: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				raw, _ := params["parts"].([]interface{})
+				parts := make([]string, 0, len(raw))
+				for _, part := range raw {
+					s, _ := part.(string)
+					parts = append(parts, s)
+				}
+				return map[string]interface{}{
+					"result": strings.Join(parts, stringParam(params, "separator", "")),
+				}, nil
+			},
+		},
+		{
+			def: domain.Tool{
+				Name:        "text_stats",
+				Description: "Count the characters, bytes, words and lines of a text.",
+				Parameters: object(map[string]interface{}{
+					"text": prop("string", "Text to measure"),
+				}, "text"),
+			},
+			fn: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				text := stringParam(params, "text", "")
+				lines := 0
+				if text != "" {
+					lines = strings.Count(text, "\n") + 1
+					if strings.HasSuffix(text, "\n") {
+						lines--
+					}
+				}
+				return map[string]interface{}{
+					"characters": utf8.RuneCountInString(text),
+					"bytes":      len(text),
+					"words":      len(strings.Fields(text)),
+					"lines":      lines,
+				}, nil
+			},
+		},
+	}
+}
+
+// titleCase upper-cases the first letter of each word
+func titleCase(s string) string {
+	runes := []rune(s)
+	start := true
+	for i, r := range runes {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start {
+				runes[i] = unicode.ToUpper(r)
+			}
+			start = false
+		} else {
+			start = true
+		}
+	}
+	return string(runes)
+}
+
+// slugify lower-cases s and joins its letters and digits with dashes
+func slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,223 @@
+package builtin
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+)
+
+// timeLayouts are the layouts accepted for times without an explicit format
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// namedLayouts are format names accepted besides Go layouts
+var namedLayouts = map[string]string{
+	"rfc3339":  time.RFC3339,
+	"rfc1123":  time.RFC1123,
+	"date":     "2006-01-02",
+	"datetime": "2006-01-02 15:04:05",
+	"time":     "15:04:05",
+	"kitchen":  time.Kitchen,
+}
+
+func timeTools(now func() time.Time) []tool {
+	timezone := prop("string", "IANA time zone, e.g. Europe/Madrid (default UTC)")
+	format := prop("string", "Output format: rfc3339 (default), rfc1123, date, datetime, time, kitchen, unix or a Go layout")
+	input := prop("string", "Time in RFC 3339 or YYYY-MM-DD[ HH:MM[:SS]]; times without an offset are in timezone")
+
+	return []tool{
+		{
+			def: domain.Tool{
+				Name:        "time_now",
+				Description: "Get the current date and time in a time zone.",
+				Parameters: object(map[string]interface{}{
+					"timezone": timezone,
+					"format":   format,
+				}),
+			},
+			fn: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				loc, err := loadLocation(stringParam(params, "timezone", ""))
+				if err != nil {
+					return nil, err
+				}
+				return timeResult(now().In(loc), stringParam(params, "format", ""))
+			},
+		},
+		{
+			def: domain.Tool{
+				Name: "time_add",
+				Description: "Add a duration and calendar units to a time. duration uses Go syntax " +
+					"such as 1h30m or -45s; years, months and days follow the calendar in the time zone.",
+				Parameters: object(map[string]interface{}{
+					"time":     input,
+					"timezone": timezone,
+					"duration": prop("string", "Duration such as 2h45m, may be negative"),
+					"years":    prop("integer", "Years to add"),
+					"months":   prop("integer", "Months to add"),
+					"days":     prop("integer", "Days to add"),
+					"format":   format,
+				}, "time"),
+			},
+			fn: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				loc, err := loadLocation(stringParam(params, "timezone", ""))
+				if err != nil {
+					return nil, err
+				}
+				t, err := parseTime(stringParam(params, "time", ""), loc)
+				if err != nil {
+					return nil, err
+				}
+				t = t.AddDate(intParam(params, "years", 0), intParam(params, "months", 0), intParam(params, "days", 0))
+				if s := stringParam(params, "duration", ""); s != "" {
+					d, err := time.ParseDuration(s)
+					if err != nil {
+						return nil, fmt.Errorf("invalid duration: %w", err)
+					}
+					t = t.Add(d)
+				}
+				return timeResult(t, stringParam(params, "format", ""))
+			},
+		},
+		{
+			def: domain.Tool{
+				Name:        "time_diff",
+				Description: "Compute end minus start, as a duration and in the given unit.",
+				Parameters: object(map[string]interface{}{
+					"start":    input,
+					"end":      input,
+					"timezone": timezone,
+					"unit":     enumProp("Unit of the value (default seconds)", "seconds", "minutes", "hours", "days", "weeks"),
+				}, "start", "end"),
+			},
+			fn: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				loc, err := loadLocation(stringParam(params, "timezone", ""))
+				if err != nil {
+					return nil, err
+				}
+				start, err := parseTime(stringParam(params, "start", ""), loc)
+				if err != nil {
+					return nil, fmt.Errorf("start: %w", err)
+				}
+				end, err := parseTime(stringParam(params, "end", ""), loc)
+				if err != nil {
+					return nil, fmt.Errorf("end: %w", err)
+				}
+
+				d := end.Sub(start)
+				unit := stringParam(params, "unit", "seconds")
+				var value float64
+				switch unit {
+				case "seconds":
+					value = d.Seconds()
+				case "minutes":
+					value = d.Minutes()
+				case "hours":
+					value = d.Hours()
+				case "days":
+					value = d.Hours() / 24
+				case "weeks":
+					value = d.Hours() / (24 * 7)
+				default:
+					return nil, fmt.Errorf("unknown unit %q", unit)
+				}
+				return map[string]interface{}{
+					"value":    math.Round(value*1e6) / 1e6,
+					"unit":     unit,
+					"duration": d.String(),
+				}, nil
+			},
+		},
+		{
+			def: domain.Tool{
+				Name:        "time_convert",
+				Description: "Convert a time to another time zone and format.",
+				Parameters: object(map[string]interface{}{
+					"time":   input,
+					"from":   prop("string", "Time zone of times without an offset (default UTC)"),
+					"to":     prop("string", "Target IANA time zone (default UTC)"),
+					"format": format,
+					"layout": prop("string", "Go layout to parse time with, if not a standard format"),
+				}, "time"),
+			},
+			fn: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				from, err := loadLocation(stringParam(params, "from", ""))
+				if err != nil {
+					return nil, err
+				}
+				to, err := loadLocation(stringParam(params, "to", ""))
+				if err != nil {
+					return nil, err
+				}
+				value := stringParam(params, "time", "")
+				var t time.Time
+				if layout := stringParam(params, "layout", ""); layout != "" {
+					t, err = time.ParseInLocation(layout, value, from)
+				} else {
+					t, err = parseTime(value, from)
+				}
+				if err != nil {
+					return nil, err
+				}
+				return timeResult(t.In(to), stringParam(params, "format", ""))
+			},
+		},
+	}
+}
+
+// loadLocation loads an IANA time zone; "" is UTC
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// parseTime parses a time in one of timeLayouts, in loc if it has no offset
+func parseTime(value string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", value)
+}
+
+// timeResult formats t with its zone and unix timestamp
+func timeResult(t time.Time, format string) (interface{}, error) {
+	var formatted interface{}
+	switch {
+	case format == "" || format == "rfc3339":
+		formatted = t.Format(time.RFC3339)
+	case format == "unix":
+		formatted = t.Unix()
+	case namedLayouts[format] != "":
+		formatted = t.Format(namedLayouts[format])
+	default:
+		formatted = t.Format(format)
+	}
+	name, offset := t.Zone()
+	return map[string]interface{}{
+		"time":           formatted,
+		"unix":           t.Unix(),
+		"timezone":       t.Location().String(),
+		"zone":           name,
+		"offset_seconds": offset,
+		"weekday":        t.Weekday().String(),
+	}, nil
+}
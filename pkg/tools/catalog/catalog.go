@@ -0,0 +1,336 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"go.uber.org/zap"
+)
+
+// Separator joins a namespace and a tool name
+const Separator = "__"
+
+// minMissRefreshInterval limits refreshes triggered by unknown tool names
+const minMissRefreshInterval = 5 * time.Second
+
+// ErrToolNotFound is returned for names no backend exposes
+var ErrToolNotFound = errors.New("tool not found")
+
+// Backend is a source of tools, grouped by namespace. Tools of the empty
+// namespace are exposed unprefixed.
+type Backend interface {
+	// Tools lists the tools of each namespace. On a partial failure it
+	// returns the namespaces it could list along with the error.
+	Tools(ctx context.Context) (map[string][]string, error)
+
+	// Call executes a tool of a namespace
+	Call(ctx context.Context, namespace, tool string, params map[string]interface{}) (interface{}, error)
+}
+
+// Describer is implemented by backends and clients that describe their
+// tools. Definitions are named as the catalog exposes them.
+type Describer interface {
+	Definitions() []domain.Tool
+}
+
+// FlatClient is a tool client without namespaces
+type FlatClient interface {
+	Execute(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error)
+	ListTools(ctx context.Context) ([]string, error)
+}
+
+// flatBackend exposes a flat client's tools unprefixed
+type flatBackend struct {
+	client FlatClient
+}
+
+// Flat adapts a tool client without namespaces, such as the function
+// registry, to a Backend
+func Flat(client FlatClient) Backend {
+	return flatBackend{client: client}
+}
+
+func (b flatBackend) Tools(ctx context.Context) (map[string][]string, error) {
+	tools, err := b.client.ListTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]string{"": tools}, nil
+}
+
+func (b flatBackend) Call(ctx context.Context, _, tool string, params map[string]interface{}) (interface{}, error) {
+	return b.client.Execute(ctx, tool, params)
+}
+
+// Definitions returns the definitions of the flat client, if it describes
+// its tools
+func (b flatBackend) Definitions() []domain.Tool {
+	if d, ok := b.client.(Describer); ok {
+		return d.Definitions()
+	}
+	return nil
+}
+
+// Name returns the exposed name of a namespace's tool
+func Name(namespace, tool string) string {
+	if namespace == "" {
+		return tool
+	}
+	return namespace + Separator + tool
+}
+
+// Entry is the owner of an exposed tool name
+type Entry struct {
+	Backend   string
+	Namespace string
+	Tool      string
+}
+
+// Conflict is a tool name exposed by several owners
+type Conflict struct {
+	Name   string
+	Owners []Entry
+}
+
+// ConflictError reports the tool names exposed twice
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	parts := make([]string, 0, len(e.Conflicts))
+	for _, conflict := range e.Conflicts {
+		owners := make([]string, 0, len(conflict.Owners))
+		for _, owner := range conflict.Owners {
+			owners = append(owners, owner.String())
+		}
+		parts = append(parts, fmt.Sprintf("%s (%s)", conflict.Name, strings.Join(owners, ", ")))
+	}
+	return "conflicting tool names: " + strings.Join(parts, "; ")
+}
+
+func (e Entry) String() string {
+	if e.Namespace == "" {
+		return e.Backend
+	}
+	return e.Backend + " " + e.Namespace
+}
+
+// namedBackend is a registered backend
+type namedBackend struct {
+	name    string
+	backend Backend
+}
+
+// Catalog maps tool names to their backends. It implements the executor's
+// tool client.
+type Catalog struct {
+	logger *zap.Logger
+
+	// refreshMu serializes refreshes
+	refreshMu   sync.Mutex
+	lastRefresh time.Time
+
+	mu       sync.RWMutex
+	backends []namedBackend
+	entries  map[string]Entry
+	listed   map[string]map[string][]string // backend -> namespace -> tools
+}
+
+// New creates an empty catalog
+func New(logger *zap.Logger) *Catalog {
+	return &Catalog{
+		logger:  logger,
+		entries: make(map[string]Entry),
+		listed:  make(map[string]map[string][]string),
+	}
+}
+
+// Add registers a backend. Earlier backends win conflicts at startup.
+func (c *Catalog) Add(name string, backend Backend) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.backends = append(c.backends, namedBackend{name: name, backend: backend})
+}
+
+// Refresh lists the tools of every backend and rebuilds the catalog. A
+// namespace that fails to list keeps its previous tools. Names exposed
+// twice are reported in a *ConflictError; the catalog is still rebuilt,
+// each such name kept by its current owner, or owned by the first backend
+// and namespace in order if it is new.
+func (c *Catalog) Refresh(ctx context.Context) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	c.lastRefresh = time.Now()
+
+	c.mu.RLock()
+	backends := c.backends
+	previous := c.listed
+	current := c.entries
+	c.mu.RUnlock()
+
+	listed := make(map[string]map[string][]string, len(backends))
+	var listErrs []error
+	for _, b := range backends {
+		tools, err := b.backend.Tools(ctx)
+		if err != nil {
+			listErrs = append(listErrs, fmt.Errorf("%s: %w", b.name, err))
+			c.logger.Warn("failed to list tools, keeping the previous list",
+				zap.String("backend", b.name),
+				zap.Error(err))
+			merged := make(map[string][]string, len(previous[b.name]))
+			for namespace, names := range previous[b.name] {
+				merged[namespace] = names
+			}
+			for namespace, names := range tools {
+				merged[namespace] = names
+			}
+			tools = merged
+		}
+		listed[b.name] = tools
+	}
+
+	owners := make(map[string][]Entry)
+	var order []string
+	for _, b := range backends {
+		namespaces := make([]string, 0, len(listed[b.name]))
+		for namespace := range listed[b.name] {
+			namespaces = append(namespaces, namespace)
+		}
+		sort.Strings(namespaces)
+		for _, namespace := range namespaces {
+			for _, tool := range listed[b.name][namespace] {
+				name := Name(namespace, tool)
+				if _, seen := owners[name]; !seen {
+					order = append(order, name)
+				}
+				owners[name] = append(owners[name], Entry{Backend: b.name, Namespace: namespace, Tool: tool})
+			}
+		}
+	}
+
+	entries := make(map[string]Entry, len(owners))
+	var conflicts []Conflict
+	for _, name := range order {
+		entries[name] = owners[name][0]
+		if len(owners[name]) > 1 {
+			conflicts = append(conflicts, Conflict{Name: name, Owners: owners[name]})
+			for _, owner := range owners[name] {
+				if owner == current[name] {
+					entries[name] = owner
+				}
+			}
+		}
+	}
+
+	c.mu.Lock()
+	c.entries = entries
+	c.listed = listed
+	c.mu.Unlock()
+
+	c.logger.Debug("tool catalog refreshed", zap.Int("tools", len(entries)))
+
+	if len(conflicts) > 0 {
+		return &ConflictError{Conflicts: conflicts}
+	}
+	return errors.Join(listErrs...)
+}
+
+// Lookup returns the owner of a tool name
+func (c *Catalog) Lookup(name string) (Entry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.entries[name]
+	return entry, ok
+}
+
+// Execute runs a tool with the backend that owns it. A name not in the
+// catalog triggers a refresh, at most every few seconds, in case the tool
+// appeared since.
+func (c *Catalog) Execute(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error) {
+	entry, ok := c.Lookup(toolName)
+	if !ok {
+		c.refreshOnMiss(ctx)
+		entry, ok = c.Lookup(toolName)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrToolNotFound, toolName)
+	}
+
+	backend := c.backend(entry.Backend)
+	if backend == nil {
+		return nil, fmt.Errorf("%w: %s", ErrToolNotFound, toolName)
+	}
+	return backend.Call(ctx, entry.Namespace, entry.Tool, params)
+}
+
+// refreshOnMiss refreshes the catalog unless it was refreshed recently.
+// A refresh in progress is waited for.
+func (c *Catalog) refreshOnMiss(ctx context.Context) {
+	c.refreshMu.Lock()
+	recent := time.Since(c.lastRefresh) < minMissRefreshInterval
+	c.refreshMu.Unlock()
+	if recent {
+		return
+	}
+
+	if err := c.Refresh(ctx); err != nil {
+		c.logger.Warn("tool catalog refresh incomplete", zap.Error(err))
+	}
+}
+
+// backend returns a registered backend by name
+func (c *Catalog) backend(name string) Backend {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, b := range c.backends {
+		if b.name == name {
+			return b.backend
+		}
+	}
+	return nil
+}
+
+// ListTools returns the exposed tool names, sorted
+func (c *Catalog) ListTools(ctx context.Context) ([]string, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	tools := make([]string, 0, len(c.entries))
+	for name := range c.entries {
+		tools = append(tools, name)
+	}
+	sort.Strings(tools)
+	return tools, nil
+}
+
+// Definitions returns the definitions of the exposed tools, each taken
+// from the backend owning its name, sorted by name. Tools whose backend
+// doesn't describe them are left out.
+func (c *Catalog) Definitions() []domain.Tool {
+	c.mu.RLock()
+	backends := c.backends
+	entries := c.entries
+	c.mu.RUnlock()
+
+	var defs []domain.Tool
+	for _, b := range backends {
+		d, ok := b.backend.(Describer)
+		if !ok {
+			continue
+		}
+		for _, def := range d.Definitions() {
+			if entry, ok := entries[def.Name]; ok && entry.Backend == b.name {
+				defs = append(defs, def)
+			}
+		}
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs
+}
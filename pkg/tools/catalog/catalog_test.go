@@ -0,0 +1,275 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"go.uber.org/zap"
+)
+
+// fakeBackend serves fixed namespaces and records its calls
+type fakeBackend struct {
+	mu      sync.Mutex
+	tools   map[string][]string
+	listErr error
+	callErr error
+	calls   []string
+}
+
+func (b *fakeBackend) Tools(ctx context.Context) (map[string][]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tools, b.listErr
+}
+
+func (b *fakeBackend) Call(ctx context.Context, namespace, tool string, params map[string]interface{}) (interface{}, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls = append(b.calls, Name(namespace, tool))
+	if b.callErr != nil {
+		return nil, b.callErr
+	}
+	return namespace + "/" + tool, nil
+}
+
+// set replaces the backend's listing
+func (b *fakeBackend) set(tools map[string][]string, listErr error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tools, b.listErr = tools, listErr
+}
+
+// describedBackend also describes its tools
+type describedBackend struct {
+	fakeBackend
+	defs []domain.Tool
+}
+
+func (b *describedBackend) Definitions() []domain.Tool { return b.defs }
+
+// fakeClient is a flat tool client
+type fakeClient struct {
+	tools []string
+}
+
+func (c *fakeClient) Execute(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error) {
+	return "flat/" + toolName, nil
+}
+
+func (c *fakeClient) ListTools(ctx context.Context) ([]string, error) {
+	return c.tools, nil
+}
+
+func TestCatalogNamespaces(t *testing.T) {
+	mcp := &fakeBackend{tools: map[string][]string{
+		"github": {"search", "create_issue"},
+		"files":  {"read"},
+	}}
+	c := New(zap.NewNop())
+	c.Add("mcp", mcp)
+	c.Add("registry", Flat(&fakeClient{tools: []string{"calculator", "search"}}))
+	if err := c.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	names, _ := c.ListTools(context.Background())
+	want := []string{"calculator", "files__read", "github__create_issue", "github__search", "search"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("ListTools() = %v, want %v", names, want)
+	}
+
+	// Each name has exactly one owner
+	tests := []struct {
+		name   string
+		want   interface{}
+		wantOK bool
+	}{
+		{"github__search", "github/search", true},
+		{"files__read", "files/read", true},
+		{"search", "flat/search", true},
+		{"calculator", "flat/calculator", true},
+		{"read", nil, false},
+		{"github__read", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.Execute(context.Background(), tt.name, nil)
+			if !tt.wantOK {
+				if !errors.Is(err, ErrToolNotFound) {
+					t.Fatalf("Execute() error = %v, want ErrToolNotFound", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("Execute() = %v, %v; want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCatalogConflicts(t *testing.T) {
+	first := &fakeBackend{tools: map[string][]string{"": {"search", "fetch"}}}
+	second := &fakeBackend{tools: map[string][]string{"": {"search"}, "web": {"fetch"}}}
+	c := New(zap.NewNop())
+	c.Add("first", first)
+	c.Add("second", second)
+
+	// Startup reports the conflicting name with its owners in order
+	err := c.Refresh(context.Background())
+	var conflicts *ConflictError
+	if !errors.As(err, &conflicts) {
+		t.Fatalf("Refresh() error = %v, want a ConflictError", err)
+	}
+	want := []Conflict{{Name: "search", Owners: []Entry{
+		{Backend: "first", Tool: "search"},
+		{Backend: "second", Tool: "search"},
+	}}}
+	if !reflect.DeepEqual(conflicts.Conflicts, want) {
+		t.Errorf("conflicts = %+v, want %+v", conflicts.Conflicts, want)
+	}
+
+	// The first owner keeps the name
+	if entry, _ := c.Lookup("search"); entry.Backend != "first" {
+		t.Errorf("search is owned by %s, want first", entry)
+	}
+	if entry, _ := c.Lookup("web__fetch"); entry.Backend != "second" {
+		t.Errorf("web__fetch is owned by %s, want second", entry)
+	}
+
+	// After startup a name stays with its current owner when another
+	// backend starts exposing it
+	second.set(map[string][]string{"": {"search", "translate"}}, nil)
+	if err := c.Refresh(context.Background()); !errors.As(err, &conflicts) {
+		t.Fatalf("Refresh() error = %v, want a ConflictError", err)
+	}
+	first.set(map[string][]string{"": {"search", "fetch", "translate"}}, nil)
+	if err := c.Refresh(context.Background()); !errors.As(err, &conflicts) {
+		t.Fatalf("Refresh() error = %v, want a ConflictError", err)
+	}
+	for _, name := range []string{"search", "translate"} {
+		if _, err := c.Execute(context.Background(), name, nil); err != nil {
+			t.Fatalf("Execute(%s) error = %v", name, err)
+		}
+	}
+	if !reflect.DeepEqual(first.calls, []string{"search"}) || !reflect.DeepEqual(second.calls, []string{"translate"}) {
+		t.Errorf("calls = %v on first, %v on second; want search on first, translate on second", first.calls, second.calls)
+	}
+
+	// A name whose owner dropped it goes to the next one
+	second.set(map[string][]string{"": {"search"}}, nil)
+	_ = c.Refresh(context.Background())
+	if entry, _ := c.Lookup("translate"); entry.Backend != "first" {
+		t.Errorf("translate is owned by %s, want first", entry)
+	}
+}
+
+func TestCatalogKeepsToolsOnListFailure(t *testing.T) {
+	mcp := &fakeBackend{tools: map[string][]string{"github": {"search"}, "files": {"read"}}}
+	c := New(zap.NewNop())
+	c.Add("mcp", mcp)
+	if err := c.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	// files fails to list: its tools stay, github's listing is updated
+	listErr := errors.New("files: connection refused")
+	mcp.set(map[string][]string{"github": {"search", "create_issue"}}, listErr)
+	if err := c.Refresh(context.Background()); !errors.Is(err, listErr) {
+		t.Fatalf("Refresh() error = %v, want the list error", err)
+	}
+	names, _ := c.ListTools(context.Background())
+	want := []string{"files__read", "github__create_issue", "github__search"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("ListTools() = %v, want %v", names, want)
+	}
+
+	// A failure with nothing listed keeps everything
+	mcp.set(nil, listErr)
+	_ = c.Refresh(context.Background())
+	if names, _ := c.ListTools(context.Background()); !reflect.DeepEqual(names, want) {
+		t.Errorf("ListTools() = %v, want %v", names, want)
+	}
+
+	// A successful listing drops tools that are gone
+	mcp.set(map[string][]string{"github": {"search"}}, nil)
+	if err := c.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if names, _ := c.ListTools(context.Background()); !reflect.DeepEqual(names, []string{"github__search"}) {
+		t.Errorf("ListTools() = %v, want [github__search]", names)
+	}
+}
+
+func TestCatalogErrorsFromOwner(t *testing.T) {
+	callErr := errors.New("bad params")
+	owner := &fakeBackend{tools: map[string][]string{"github": {"search"}}, callErr: callErr}
+	other := &fakeBackend{tools: map[string][]string{"": {"github__search", "search"}}}
+	c := New(zap.NewNop())
+	c.Add("mcp", owner)
+	c.Add("registry", other)
+	_ = c.Refresh(context.Background())
+
+	// The owner's error is returned without trying another backend
+	if _, err := c.Execute(context.Background(), "github__search", nil); !errors.Is(err, callErr) {
+		t.Fatalf("Execute() error = %v, want the owner's", err)
+	}
+	if len(other.calls) != 0 {
+		t.Errorf("other backend called %v, want no fallback", other.calls)
+	}
+	if len(owner.calls) != 1 {
+		t.Errorf("owner called %v, want once", owner.calls)
+	}
+}
+
+func TestCatalogRefreshesOnMiss(t *testing.T) {
+	mcp := &fakeBackend{tools: map[string][]string{"github": {"search"}}}
+	c := New(zap.NewNop())
+	c.Add("mcp", mcp)
+	_ = c.Refresh(context.Background())
+
+	// A tool added since the last refresh is found once the refresh
+	// interval has passed
+	mcp.set(map[string][]string{"github": {"search", "create_issue"}}, nil)
+	if _, err := c.Execute(context.Background(), "github__create_issue", nil); !errors.Is(err, ErrToolNotFound) {
+		t.Fatalf("Execute() right after a refresh error = %v, want ErrToolNotFound", err)
+	}
+	c.refreshMu.Lock()
+	c.lastRefresh = c.lastRefresh.Add(-minMissRefreshInterval)
+	c.refreshMu.Unlock()
+	if _, err := c.Execute(context.Background(), "github__create_issue", nil); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+}
+
+func TestCatalogDefinitions(t *testing.T) {
+	first := &describedBackend{
+		fakeBackend: fakeBackend{tools: map[string][]string{"": {"search"}}},
+		defs:        []domain.Tool{{Name: "search", Description: "first"}},
+	}
+	second := &describedBackend{
+		fakeBackend: fakeBackend{tools: map[string][]string{"": {"search"}, "web": {"fetch"}}},
+		defs: []domain.Tool{
+			{Name: "web__fetch", Description: "second"},
+			{Name: "search", Description: "second"},
+			{Name: "unlisted", Description: "second"},
+		},
+	}
+	c := New(zap.NewNop())
+	c.Add("first", first)
+	c.Add("second", second)
+	c.Add("plain", &fakeBackend{tools: map[string][]string{"": {"plain"}}})
+	_ = c.Refresh(context.Background())
+
+	// Definitions come from each name's owner only
+	want := []domain.Tool{
+		{Name: "search", Description: "first"},
+		{Name: "web__fetch", Description: "second"},
+	}
+	if got := c.Definitions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Definitions() = %+v, want %+v", got, want)
+	}
+}
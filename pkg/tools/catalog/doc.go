@@ -0,0 +1,10 @@
+// Package catalog routes each tool name to exactly one backend.
+//
+// Backends list their tools by namespace: an MCP client lists each server
+// as a namespace, and its tools are exposed as <server>__<tool>; flat
+// backends such as the function registry expose their tools as is. The
+// catalog maps every exposed name to its backend, namespace and tool,
+// detects names exposed twice, and executes a call with the owning
+// backend only, returning its error unchanged. It is rebuilt with Refresh,
+// e.g. when an MCP server reports that its tool list changed.
+package catalog
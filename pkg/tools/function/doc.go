@@ -1,5 +1,8 @@
 // Package function provides a simple function registry for built-in tools.
 //
-// This is a fallback mechanism when MCP is not available or for simple tools
-// that don't require external server integration.
+// It hosts simple tools that don't require external server integration.
+// Its tools are exposed unprefixed, alongside the namespaced MCP tools.
+//
+// The fs_* tools registered by RegisterFSTools work on files in a
+// Workspaces directory per graph, confined with os.Root.
 package function
@@ -0,0 +1,328 @@
+package function
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+)
+
+// Limits of the fs tools
+const (
+	defaultFSListEntries   = 1000
+	defaultFSSearchResults = 100
+	maxFSSearchLineLength  = 500
+)
+
+// IsFSTool reports whether name is one of the fs_* tools, whose path
+// arguments are relative to the workspace root
+func IsFSTool(name string) bool {
+	switch name {
+	case "fs_read", "fs_write", "fs_list", "fs_search", "fs_patch":
+		return true
+	}
+	return false
+}
+
+// WorkspacePath resolves a path argument of the fs tools to its absolute
+// workspace path, e.g. reports/../a.md to /a.md
+func WorkspacePath(p string) (string, error) {
+	name, err := CleanPath(p)
+	if err != nil {
+		return "", err
+	}
+	return displayPath(name), nil
+}
+
+// RegisterFSTools registers the fs_* tools, confined to the workspace of
+// the graph returned by graphID for each call
+func RegisterFSTools(r *Registry, workspaces *Workspaces, graphID func(ctx context.Context) string) {
+	scoped := func(ctx context.Context) (string, error) {
+		if graphID != nil {
+			if id := graphID(ctx); id != "" {
+				return id, nil
+			}
+		}
+		return "", errors.New("workspace is not available: no graph")
+	}
+	pathProp := func(description string) map[string]interface{} {
+		return map[string]interface{}{"type": "string", "description": description}
+	}
+
+	r.RegisterTool(domain.Tool{
+		Name: "fs_read",
+		Description: "Read a file from the graph's workspace. Paths are relative to the workspace root /. " +
+			"Binary files are returned base64 encoded.",
+		Parameters: fsSchema(map[string]interface{}{
+			"path":       pathProp("File path, e.g. /src/main.go"),
+			"start_line": map[string]interface{}{"type": "integer", "description": "First line to return, from 1"},
+			"end_line":   map[string]interface{}{"type": "integer", "description": "Last line to return"},
+		}, "path"),
+	}, func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		graph, err := scoped(ctx)
+		if err != nil {
+			return nil, err
+		}
+		p := workspacePath(params["path"])
+		data, err := workspaces.ReadFile(graph, p)
+		if err != nil {
+			return nil, err
+		}
+
+		result := map[string]interface{}{"path": p, "size": len(data)}
+		if !utf8.Valid(data) {
+			result["encoding"] = "base64"
+			result["content"] = base64.StdEncoding.EncodeToString(data)
+			return result, nil
+		}
+
+		content := string(data)
+		start, end := intValue(params["start_line"]), intValue(params["end_line"])
+		if start > 0 || end > 0 {
+			lines := strings.SplitAfter(content, "\n")
+			if start < 1 {
+				start = 1
+			}
+			if end <= 0 || end > len(lines) {
+				end = len(lines)
+			}
+			if start > end {
+				content = ""
+			} else {
+				content = strings.Join(lines[start-1:end], "")
+			}
+			result["start_line"] = start
+			result["end_line"] = end
+			result["total_lines"] = len(lines)
+		}
+		result["content"] = content
+		return result, nil
+	})
+
+	r.RegisterTool(domain.Tool{
+		Name:        "fs_write",
+		Description: "Write a file in the graph's workspace, creating parent directories. Replaces the file unless append is set.",
+		Parameters: fsSchema(map[string]interface{}{
+			"path":     pathProp("File path, e.g. /reports/summary.md"),
+			"content":  map[string]interface{}{"type": "string", "description": "File contents"},
+			"encoding": map[string]interface{}{"type": "string", "description": "Encoding of content (default text)", "enum": []string{"text", "base64"}},
+			"append":   map[string]interface{}{"type": "boolean", "description": "Append to the file instead of replacing it"},
+		}, "path", "content"),
+	}, func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		graph, err := scoped(ctx)
+		if err != nil {
+			return nil, err
+		}
+		p := workspacePath(params["path"])
+		content, _ := params["content"].(string)
+		data := []byte(content)
+		if encoding, _ := params["encoding"].(string); encoding == "base64" {
+			if data, err = base64.StdEncoding.DecodeString(content); err != nil {
+				return nil, fmt.Errorf("content is not valid base64: %w", err)
+			}
+		}
+		appendTo, _ := params["append"].(bool)
+
+		size, err := workspaces.WriteFile(graph, p, data, appendTo)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"path": p, "bytes_written": len(data), "size": size}, nil
+	})
+
+	r.RegisterTool(domain.Tool{
+		Name:        "fs_list",
+		Description: "List a directory of the graph's workspace.",
+		Parameters: fsSchema(map[string]interface{}{
+			"path":      pathProp("Directory path (default /)"),
+			"recursive": map[string]interface{}{"type": "boolean", "description": "Include subdirectories"},
+		}),
+	}, func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		graph, err := scoped(ctx)
+		if err != nil {
+			return nil, err
+		}
+		p, _ := params["path"].(string)
+		recursive, _ := params["recursive"].(bool)
+		entries, truncated, err := workspaces.List(graph, p, recursive, defaultFSListEntries)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"entries": entries, "truncated": truncated}, nil
+	})
+
+	r.RegisterTool(domain.Tool{
+		Name:        "fs_search",
+		Description: "Search the text files of the graph's workspace for lines containing a string or matching a regular expression.",
+		Parameters: fsSchema(map[string]interface{}{
+			"query":            map[string]interface{}{"type": "string", "description": "Text to find"},
+			"regex":            map[string]interface{}{"type": "boolean", "description": "Treat query as a regular expression"},
+			"case_insensitive": map[string]interface{}{"type": "boolean", "description": "Match regardless of case"},
+			"path":             pathProp("Directory to search (default /)"),
+			"glob":             map[string]interface{}{"type": "string", "description": "Only search files matching this glob, e.g. /src/**/*.go"},
+			"max_results":      map[string]interface{}{"type": "integer", "description": "Maximum matching lines (default 100)"},
+		}, "query"),
+	}, func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		graph, err := scoped(ctx)
+		if err != nil {
+			return nil, err
+		}
+		query, _ := params["query"].(string)
+		if query == "" {
+			return nil, errors.New("query must not be empty")
+		}
+		if isRegex, _ := params["regex"].(bool); !isRegex {
+			query = regexp.QuoteMeta(query)
+		}
+		if ci, _ := params["case_insensitive"].(bool); ci {
+			query = "(?i)" + query
+		}
+		re, err := regexp.Compile(query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		glob, _ := params["glob"].(string)
+		if glob != "" {
+			if err := ValidateGlob(glob); err != nil {
+				return nil, err
+			}
+		}
+		max := intValue(params["max_results"])
+		if max <= 0 {
+			max = defaultFSSearchResults
+		}
+
+		p, _ := params["path"].(string)
+		matches := []map[string]interface{}{}
+		truncated := false
+		err = workspaces.Walk(ctx, graph, p, func(file string, data []byte) bool {
+			if glob != "" && !matchGlob(glob, file) {
+				return true
+			}
+			if !utf8.Valid(data) {
+				return true
+			}
+			for i, line := range strings.Split(string(data), "\n") {
+				if !re.MatchString(line) {
+					continue
+				}
+				if len(matches) >= max {
+					truncated = true
+					return false
+				}
+				if len(line) > maxFSSearchLineLength {
+					line = line[:maxFSSearchLineLength]
+				}
+				matches = append(matches, map[string]interface{}{"path": file, "line": i + 1, "text": line})
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"matches": matches, "truncated": truncated}, nil
+	})
+
+	r.RegisterTool(domain.Tool{
+		Name: "fs_patch",
+		Description: "Edit a text file in the graph's workspace by replacing exact text. Each old_text must occur " +
+			"exactly once unless replace_all is set. Edits apply in order, and none apply if one fails.",
+		Parameters: fsSchema(map[string]interface{}{
+			"path": pathProp("File path"),
+			"edits": map[string]interface{}{
+				"type":        "array",
+				"description": "Replacements to apply",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"old_text":    map[string]interface{}{"type": "string", "description": "Text to replace"},
+						"new_text":    map[string]interface{}{"type": "string", "description": "Replacement"},
+						"replace_all": map[string]interface{}{"type": "boolean", "description": "Replace every occurrence"},
+					},
+					"required":             []string{"old_text", "new_text"},
+					"additionalProperties": false,
+				},
+			},
+		}, "path", "edits"),
+	}, func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		graph, err := scoped(ctx)
+		if err != nil {
+			return nil, err
+		}
+		p := workspacePath(params["path"])
+		data, err := workspaces.ReadFile(graph, p)
+		if err != nil {
+			return nil, err
+		}
+		if !utf8.Valid(data) {
+			return nil, fmt.Errorf("%s is not a text file", p)
+		}
+
+		content := string(data)
+		edits, _ := params["edits"].([]interface{})
+		replacements := 0
+		for i, raw := range edits {
+			edit, _ := raw.(map[string]interface{})
+			oldText, _ := edit["old_text"].(string)
+			newText, _ := edit["new_text"].(string)
+			all, _ := edit["replace_all"].(bool)
+			if oldText == "" {
+				return nil, fmt.Errorf("edit %d: old_text must not be empty", i)
+			}
+			count := strings.Count(content, oldText)
+			switch {
+			case count == 0:
+				return nil, fmt.Errorf("edit %d: old_text not found", i)
+			case count > 1 && !all:
+				return nil, fmt.Errorf("edit %d: old_text occurs %d times; add context or set replace_all", i, count)
+			}
+			content = strings.ReplaceAll(content, oldText, newText)
+			replacements += count
+		}
+
+		size, err := workspaces.WriteFile(graph, p, []byte(content), false)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"path": p, "replacements": replacements, "size": size}, nil
+	})
+}
+
+// fsSchema is the schema of an fs tool's params
+func fsSchema(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// workspacePath returns a path param as a clean workspace path, or the
+// value as is when it is invalid, for the workspace to report
+func workspacePath(value interface{}) string {
+	p, _ := value.(string)
+	if resolved, err := WorkspacePath(p); err == nil {
+		return resolved
+	}
+	return p
+}
+
+// intValue converts a JSON number to an int
+func intValue(value interface{}) int {
+	switch v := value.(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
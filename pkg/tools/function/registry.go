@@ -2,27 +2,36 @@ package function
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
 
+	"github.com/aescanero/dago-libs/pkg/domain"
 	"go.uber.org/zap"
 )
 
+// ErrInvalidParams is wrapped by errors about the params of a call, as
+// opposed to failures of the tool itself
+var ErrInvalidParams = errors.New("invalid params")
+
 // ToolFunc represents a tool function
 type ToolFunc func(ctx context.Context, params map[string]interface{}) (interface{}, error)
 
 // Registry is a registry for built-in tool functions
 type Registry struct {
-	tools  map[string]ToolFunc
-	mu     sync.RWMutex
-	logger *zap.Logger
+	tools       map[string]ToolFunc
+	definitions map[string]domain.Tool
+	mu          sync.RWMutex
+	logger      *zap.Logger
 }
 
 // NewRegistry creates a new tool registry
 func NewRegistry(logger *zap.Logger) *Registry {
 	return &Registry{
-		tools:  make(map[string]ToolFunc),
-		logger: logger,
+		tools:       make(map[string]ToolFunc),
+		definitions: make(map[string]domain.Tool),
+		logger:      logger,
 	}
 }
 
@@ -32,22 +41,40 @@ func (r *Registry) Register(name string, fn ToolFunc) {
 	defer r.mu.Unlock()
 
 	r.tools[name] = fn
+	delete(r.definitions, name)
 	r.logger.Info("tool registered", zap.String("tool", name))
 }
 
+// RegisterTool registers a tool function with its description and the
+// JSON Schema of its parameters. Calls are checked against the schema
+// before the function runs.
+func (r *Registry) RegisterTool(def domain.Tool, fn ToolFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tools[def.Name] = fn
+	r.definitions[def.Name] = def
+	r.logger.Debug("tool registered", zap.String("tool", def.Name))
+}
+
 // Execute executes a registered tool
 func (r *Registry) Execute(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error) {
 	r.mu.RLock()
 	fn, ok := r.tools[toolName]
+	def, hasDef := r.definitions[toolName]
 	r.mu.RUnlock()
 
 	if !ok {
 		return nil, fmt.Errorf("tool not found: %s", toolName)
 	}
 
-	r.logger.Debug("executing registered tool",
-		zap.String("tool", toolName),
-		zap.Any("params", params))
+	if hasDef && def.Parameters != nil {
+		if err := validateParams(def.Parameters, params); err != nil {
+			return nil, fmt.Errorf("%w for %s: %w", ErrInvalidParams, toolName, err)
+		}
+	}
+
+	r.logger.Debug("executing registered tool", zap.String("tool", toolName))
 
 	return fn(ctx, params)
 }
@@ -65,6 +92,29 @@ func (r *Registry) ListTools(ctx context.Context) ([]string, error) {
 	return tools, nil
 }
 
+// Definitions returns the definitions of the tools registered with one,
+// sorted by name
+func (r *Registry) Definitions() []domain.Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	defs := make([]domain.Tool, 0, len(r.definitions))
+	for _, def := range r.definitions {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs
+}
+
+// Definition returns the definition of a tool, if it was registered with one
+func (r *Registry) Definition(name string) (domain.Tool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	def, ok := r.definitions[name]
+	return def, ok
+}
+
 // Has checks if a tool is registered
 func (r *Registry) Has(name string) bool {
 	r.mu.RLock()
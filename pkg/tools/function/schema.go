@@ -0,0 +1,174 @@
+package function
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// validateParams checks params against the subset of JSON Schema used by
+// tool definitions: required properties, property types, enums and, with
+// additionalProperties false, unknown properties. Nested objects and
+// array items are checked the same way.
+func validateParams(schema map[string]interface{}, params map[string]interface{}) error {
+	return validateValue(schema, params, "")
+}
+
+func validateValue(schema map[string]interface{}, value interface{}, path string) error {
+	name := path
+	if name == "" {
+		name = "params"
+	}
+
+	if t, ok := schema["type"]; ok && !matchesType(t, value) {
+		return fmt.Errorf("%s must be of type %v", name, t)
+	}
+
+	if enum := enumValues(schema["enum"]); enum != nil {
+		found := false
+		for _, allowed := range enum {
+			if fmt.Sprint(allowed) == fmt.Sprint(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s must be one of %v", name, enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		for _, required := range stringList(schema["required"]) {
+			if _, ok := v[required]; !ok {
+				return fmt.Errorf("%s is required", join(path, required))
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			propSchema, ok := properties[key].(map[string]interface{})
+			if !ok {
+				if additional, set := schema["additionalProperties"].(bool); set && !additional {
+					return fmt.Errorf("unknown parameter %s", join(path, key))
+				}
+				continue
+			}
+			if err := validateValue(propSchema, v[key], join(path, key)); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				if err := validateValue(items, item, fmt.Sprintf("%s[%d]", name, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// matchesType reports whether value has the JSON Schema type t, a name or
+// a list of names
+func matchesType(t interface{}, value interface{}) bool {
+	for _, name := range stringList(t) {
+		if matchesTypeName(name, value) {
+			return true
+		}
+	}
+	if name, ok := t.(string); ok {
+		return matchesTypeName(name, value)
+	}
+	return false
+}
+
+func matchesTypeName(name string, value interface{}) bool {
+	switch name {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := toFloat(value)
+		return ok
+	case "integer":
+		f, ok := toFloat(value)
+		return ok && f == float64(int64(f))
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "null":
+		return value == nil
+	default:
+		return true
+	}
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// enumValues returns the values of an enum, as []interface{} or []string
+func enumValues(v interface{}) []interface{} {
+	switch list := v.(type) {
+	case []interface{}:
+		return list
+	case []string:
+		out := make([]interface{}, len(list))
+		for i, item := range list {
+			out[i] = item
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// stringList returns v as a list of strings, if it is one
+func stringList(v interface{}) []string {
+	switch list := v.(type) {
+	case []string:
+		return list
+	case []interface{}:
+		out := make([]string, 0, len(list))
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return strings.Join([]string{path, key}, ".")
+}
@@ -0,0 +1,608 @@
+package function
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Defaults for WorkspaceConfig fields left empty
+const (
+	DefaultWorkspaceQuotaBytes   = 100 << 20
+	DefaultWorkspaceMaxFileBytes = 10 << 20
+	DefaultWorkspaceMaxFiles     = 10000
+	DefaultArtifactInlineBytes   = 1 << 20
+)
+
+// ErrWorkspaceQuota is returned by writes that would exceed a workspace's
+// size or file quota
+var ErrWorkspaceQuota = errors.New("workspace quota exceeded")
+
+// WorkspaceConfig holds workspace settings
+type WorkspaceConfig struct {
+	// Dir holds one directory per graph
+	Dir string
+
+	// QuotaBytes and MaxFiles bound the contents of one workspace, and
+	// MaxFileBytes the size of one file
+	QuotaBytes   int64
+	MaxFileBytes int64
+	MaxFiles     int
+
+	// ArtifactInlineBytes bounds the file contents included in exported
+	// artifacts; larger files are exported without content
+	ArtifactInlineBytes int64
+}
+
+// Workspaces confines file access to one directory per graph. Paths are
+// resolved inside the graph's directory, with / as its root, and the
+// directory is opened as an os.Root so neither .. nor symlinks can reach
+// outside of it.
+type Workspaces struct {
+	cfg WorkspaceConfig
+
+	// mu serializes writes so quota checks see the current usage. It is
+	// per process: writers sharing Dir from other processes aren't
+	// serialized, and may together exceed the quota by a file each.
+	mu sync.Mutex
+}
+
+// Artifact is a workspace file exported with a node's result
+type Artifact struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+
+	// Content is the file as text, or base64 when Encoding is base64.
+	// It is omitted, and Omitted set, past ArtifactInlineBytes.
+	Content  string `json:"content,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Omitted  bool   `json:"omitted,omitempty"`
+}
+
+// WorkspaceEntry is a file or directory listed in a workspace
+type WorkspaceEntry struct {
+	Path  string `json:"path"`
+	IsDir bool   `json:"is_dir"`
+	Size  int64  `json:"size"`
+}
+
+// NewWorkspaces creates workspaces under cfg.Dir without touching the
+// disk; directories are created on first use
+func NewWorkspaces(cfg WorkspaceConfig) *Workspaces {
+	if cfg.Dir == "" {
+		cfg.Dir = filepath.Join(os.TempDir(), "dago-workspaces")
+	}
+	if cfg.QuotaBytes <= 0 {
+		cfg.QuotaBytes = DefaultWorkspaceQuotaBytes
+	}
+	if cfg.MaxFileBytes <= 0 {
+		cfg.MaxFileBytes = DefaultWorkspaceMaxFileBytes
+	}
+	if cfg.MaxFiles <= 0 {
+		cfg.MaxFiles = DefaultWorkspaceMaxFiles
+	}
+	if cfg.ArtifactInlineBytes <= 0 {
+		cfg.ArtifactInlineBytes = DefaultArtifactInlineBytes
+	}
+	return &Workspaces{cfg: cfg}
+}
+
+// Dir returns the directory holding the workspaces
+func (w *Workspaces) Dir() string {
+	return w.cfg.Dir
+}
+
+// workspaceName maps a graph ID to a directory name, reversibly and
+// without path separators or a leading dot
+func workspaceName(graphID string) string {
+	name := url.PathEscape(graphID)
+	if strings.HasPrefix(name, ".") {
+		name = "%2E" + name[1:]
+	}
+	return name
+}
+
+// open opens the workspace of a graph, creating it if asked
+func (w *Workspaces) open(graphID string, create bool) (*os.Root, error) {
+	if graphID == "" {
+		return nil, errors.New("workspace needs a graph")
+	}
+
+	if create {
+		if err := os.MkdirAll(w.cfg.Dir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create workspace directory: %w", err)
+		}
+	}
+	base, err := os.OpenRoot(w.cfg.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open workspace directory: %w", err)
+	}
+	defer base.Close()
+
+	name := workspaceName(graphID)
+	if create {
+		if err := base.Mkdir(name, 0o700); err != nil && !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("failed to create workspace: %w", err)
+		}
+	}
+	root, err := base.OpenRoot(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open workspace: %w", err)
+	}
+	return root, nil
+}
+
+// CleanPath resolves a workspace path to a name relative to the workspace
+// root. The path is taken as absolute from the workspace root, so .. stops
+// there.
+func CleanPath(p string) (string, error) {
+	if strings.ContainsRune(p, 0) {
+		return "", errors.New("path contains a NUL byte")
+	}
+	cleaned := strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(p, "\\", "/")), "/")
+	if cleaned == "" {
+		return ".", nil
+	}
+	return cleaned, nil
+}
+
+// displayPath is the path of a name as shown to agents
+func displayPath(name string) string {
+	if name == "." {
+		return "/"
+	}
+	return "/" + name
+}
+
+// ReadFile reads a file of a graph's workspace
+func (w *Workspaces) ReadFile(graphID, p string) ([]byte, error) {
+	name, err := CleanPath(p)
+	if err != nil {
+		return nil, err
+	}
+	root, err := w.open(graphID, false)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, workspaceError(p, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	info, err := root.Stat(name)
+	if err != nil {
+		return nil, workspaceError(p, err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", displayPath(name))
+	}
+	if info.Size() > w.cfg.MaxFileBytes {
+		return nil, fmt.Errorf("%s is larger than %d bytes", displayPath(name), w.cfg.MaxFileBytes)
+	}
+	data, err := root.ReadFile(name)
+	if err != nil {
+		return nil, workspaceError(p, err)
+	}
+	return data, nil
+}
+
+// WriteFile writes, or with appendTo appends to, a file of a graph's
+// workspace, creating parent directories. The write fails if the file or
+// the workspace would exceed its quota.
+func (w *Workspaces) WriteFile(graphID, p string, data []byte, appendTo bool) (int64, error) {
+	name, err := CleanPath(p)
+	if err != nil {
+		return 0, err
+	}
+	if name == "." {
+		return 0, errors.New("path must name a file")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	root, err := w.open(graphID, true)
+	if err != nil {
+		return 0, err
+	}
+	defer root.Close()
+
+	var existing int64
+	exists := false
+	if info, err := root.Stat(name); err == nil {
+		if info.IsDir() {
+			return 0, fmt.Errorf("%s is a directory", displayPath(name))
+		}
+		existing, exists = info.Size(), true
+	}
+
+	size := int64(len(data))
+	if appendTo {
+		size += existing
+	}
+	if size > w.cfg.MaxFileBytes {
+		return 0, fmt.Errorf("%w: %s would be larger than %d bytes", ErrWorkspaceQuota, displayPath(name), w.cfg.MaxFileBytes)
+	}
+
+	used, files, err := usage(root)
+	if err != nil {
+		return 0, err
+	}
+	if used-existing+size > w.cfg.QuotaBytes {
+		return 0, fmt.Errorf("%w: workspace would be larger than %d bytes", ErrWorkspaceQuota, w.cfg.QuotaBytes)
+	}
+	if !exists && files >= w.cfg.MaxFiles {
+		return 0, fmt.Errorf("%w: workspace holds %d files", ErrWorkspaceQuota, w.cfg.MaxFiles)
+	}
+
+	if dir := path.Dir(name); dir != "." {
+		if err := root.MkdirAll(dir, 0o700); err != nil {
+			return 0, workspaceError(p, err)
+		}
+	}
+
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appendTo {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	f, err := root.OpenFile(name, flag, 0o600)
+	if err != nil {
+		return 0, workspaceError(p, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return 0, workspaceError(p, err)
+	}
+	if err := f.Close(); err != nil {
+		return 0, workspaceError(p, err)
+	}
+	return size, nil
+}
+
+// List lists a directory of a graph's workspace, recursively if asked, up
+// to max entries. It reports whether entries were left out. The root of a
+// workspace not created yet is empty.
+func (w *Workspaces) List(graphID, p string, recursive bool, max int) ([]WorkspaceEntry, bool, error) {
+	name, err := CleanPath(p)
+	if err != nil {
+		return nil, false, err
+	}
+	root, err := w.open(graphID, false)
+	if errors.Is(err, fs.ErrNotExist) {
+		if name == "." {
+			return []WorkspaceEntry{}, false, nil
+		}
+		return nil, false, workspaceError(p, err)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer root.Close()
+
+	entries := []WorkspaceEntry{}
+	truncated := false
+	err = fs.WalkDir(root.FS(), name, func(current string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if current == name {
+			if !d.IsDir() {
+				return fmt.Errorf("%s is not a directory", displayPath(name))
+			}
+			return nil
+		}
+		if len(entries) >= max {
+			truncated = true
+			return fs.SkipAll
+		}
+		entry := WorkspaceEntry{Path: displayPath(current), IsDir: d.IsDir()}
+		if info, err := d.Info(); err == nil && !d.IsDir() {
+			entry.Size = info.Size()
+		}
+		entries = append(entries, entry)
+		if d.IsDir() && !recursive {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, workspaceError(p, err)
+	}
+	return entries, truncated, nil
+}
+
+// Walk calls fn for each regular file under a directory of a graph's
+// workspace, with its path as shown to agents and its contents. Files
+// larger than MaxFileBytes are skipped. fn returns false to stop. The root
+// of a workspace not created yet has no files.
+func (w *Workspaces) Walk(ctx context.Context, graphID, p string, fn func(path string, data []byte) bool) error {
+	name, err := CleanPath(p)
+	if err != nil {
+		return err
+	}
+	root, err := w.open(graphID, false)
+	if errors.Is(err, fs.ErrNotExist) {
+		if name == "." {
+			return nil
+		}
+		return workspaceError(p, err)
+	}
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	fsys := root.FS()
+	err = fs.WalkDir(fsys, name, func(current string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || info.Size() > w.cfg.MaxFileBytes {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, current)
+		if err != nil {
+			return err
+		}
+		if !fn(displayPath(current), data) {
+			return fs.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return workspaceError(p, err)
+	}
+	return nil
+}
+
+// Export returns the files of a graph's workspace matching any of the
+// patterns as artifacts. Patterns are globs on the workspace path, e.g.
+// /reports/*.md; ** matches any number of directories.
+func (w *Workspaces) Export(ctx context.Context, graphID string, patterns []string) ([]Artifact, error) {
+	root, err := w.open(graphID, false)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	var artifacts []Artifact
+	inline := w.cfg.ArtifactInlineBytes
+	fsys := root.FS()
+	err = fs.WalkDir(fsys, ".", func(current string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || !matchAny(patterns, displayPath(current)) {
+			return nil
+		}
+
+		f, err := fsys.Open(current)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		h := sha256.New()
+		var content strings.Builder
+		n, err := io.Copy(io.MultiWriter(h, limitWriter(&content, inline)), f)
+		if err != nil {
+			return err
+		}
+
+		artifact := Artifact{Path: displayPath(current), Size: n, SHA256: hex.EncodeToString(h.Sum(nil))}
+		if n <= inline {
+			inline -= n
+			if utf8.ValidString(content.String()) {
+				artifact.Content = content.String()
+			} else {
+				artifact.Content = base64.StdEncoding.EncodeToString([]byte(content.String()))
+				artifact.Encoding = "base64"
+			}
+		} else {
+			artifact.Omitted = true
+		}
+		artifacts = append(artifacts, artifact)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export workspace: %w", err)
+	}
+	return artifacts, nil
+}
+
+// Remove deletes the workspace of a graph
+func (w *Workspaces) Remove(graphID string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	base, err := os.OpenRoot(w.cfg.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to open workspace directory: %w", err)
+	}
+	defer base.Close()
+
+	if err := base.RemoveAll(workspaceName(graphID)); err != nil {
+		return fmt.Errorf("failed to remove workspace: %w", err)
+	}
+	return nil
+}
+
+// Sweep removes the workspaces of graphs for which expired returns true,
+// typically because their state is gone, and returns how many it removed
+func (w *Workspaces) Sweep(ctx context.Context, expired func(ctx context.Context, graphID string) (bool, error)) (int, error) {
+	entries, err := os.ReadDir(w.cfg.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to list workspaces: %w", err)
+	}
+
+	removed := 0
+	var errs []error
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		graphID, err := url.PathUnescape(entry.Name())
+		if err != nil {
+			continue
+		}
+		gone, err := expired(ctx, graphID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !gone {
+			continue
+		}
+		if err := w.Remove(graphID); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed++
+	}
+	return removed, errors.Join(errs...)
+}
+
+// usage returns the bytes and number of files in a workspace
+func usage(root *os.Root) (int64, int, error) {
+	var bytes int64
+	var files int
+	err := fs.WalkDir(root.FS(), ".", func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		files++
+		if info, err := d.Info(); err == nil {
+			bytes += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to measure workspace: %w", err)
+	}
+	return bytes, files, nil
+}
+
+// matchAny reports whether p matches one of the patterns
+func matchAny(patterns []string, p string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches a path against a glob where ** spans directories
+func matchGlob(pattern, p string) bool {
+	if !strings.HasPrefix(pattern, "/") {
+		pattern = "/" + pattern
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(p, "/"))
+}
+
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(pattern[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], parts[0]); err != nil || !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// ValidateGlob checks a workspace glob
+func ValidateGlob(pattern string) error {
+	for _, segment := range strings.Split(pattern, "/") {
+		if segment == "**" {
+			continue
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// workspaceError rewrites file errors so they show workspace paths
+// instead of host paths
+func workspaceError(p string, err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("%s: no such file or directory", p)
+	case errors.Is(err, fs.ErrPermission):
+		return fmt.Errorf("%s: permission denied", p)
+	case errors.Is(err, fs.ErrExist):
+		return fmt.Errorf("%s: already exists", p)
+	}
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return fmt.Errorf("%s: %w", p, pathErr.Err)
+	}
+	return err
+}
+
+// limitWriter writes up to n bytes to w and discards the rest
+func limitWriter(w io.Writer, n int64) io.Writer {
+	return &limitedWriter{w: w, n: n}
+}
+
+type limitedWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if l.n > 0 {
+		chunk := p
+		if int64(len(chunk)) > l.n {
+			chunk = chunk[:l.n]
+		}
+		if _, err := l.w.Write(chunk); err != nil {
+			return 0, err
+		}
+		l.n -= int64(len(chunk))
+	}
+	return len(p), nil
+}
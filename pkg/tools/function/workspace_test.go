@@ -0,0 +1,280 @@
+package function
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// newWorkspaces returns workspaces in a temporary directory
+func newWorkspaces(t *testing.T, cfg WorkspaceConfig) *Workspaces {
+	t.Helper()
+	cfg.Dir = filepath.Join(t.TempDir(), "workspaces")
+	return NewWorkspaces(cfg)
+}
+
+// write writes a workspace file, failing the test on errors
+func write(t *testing.T, w *Workspaces, graphID, p, data string) {
+	t.Helper()
+	if _, err := w.WriteFile(graphID, p, []byte(data), false); err != nil {
+		t.Fatalf("WriteFile(%s) error = %v", p, err)
+	}
+}
+
+func TestCleanPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{path: "", want: "."},
+		{path: "/", want: "."},
+		{path: "notes.md", want: "notes.md"},
+		{path: "/a/./b/", want: "a/b"},
+		{path: "../../etc/passwd", want: "etc/passwd"},
+		{path: "/a/../../b", want: "b"},
+		{path: `a\..\..\b`, want: "b"},
+		{path: `\reports\q1.md`, want: "reports/q1.md"},
+		{path: `..\..`, want: "."},
+		{path: "a\x00/b", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := CleanPath(tt.path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("CleanPath() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("CleanPath() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkspaceSymlinkEscape(t *testing.T) {
+	w := newWorkspaces(t, WorkspaceConfig{})
+	write(t, w, "g1", "/inside.txt", "ok")
+
+	outside := t.TempDir()
+	secret := filepath.Join(outside, "secret.txt")
+	if err := os.WriteFile(secret, []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(w.Dir(), workspaceName("g1"))
+	if err := os.Symlink(secret, filepath.Join(dir, "file-link")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "dir-link")); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := w.ReadFile("g1", "/file-link"); err == nil {
+		t.Errorf("ReadFile() through a symlink = %q, want an error", data)
+	}
+	if data, err := w.ReadFile("g1", "/dir-link/secret.txt"); err == nil {
+		t.Errorf("ReadFile() through a directory symlink = %q, want an error", data)
+	}
+	if _, err := w.WriteFile("g1", "/dir-link/new.txt", []byte("x"), false); err == nil {
+		t.Error("WriteFile() through a directory symlink succeeded, want an error")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "new.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("file created outside the workspace: %v", err)
+	}
+	var walked []string
+	if err := w.Walk(context.Background(), "g1", "/", func(p string, _ []byte) bool {
+		walked = append(walked, p)
+		return true
+	}); err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+	if !reflect.DeepEqual(walked, []string{"/inside.txt"}) {
+		t.Errorf("Walk() visited %v, want only /inside.txt", walked)
+	}
+}
+
+func TestWorkspaceQuotas(t *testing.T) {
+	w := newWorkspaces(t, WorkspaceConfig{QuotaBytes: 25, MaxFileBytes: 10, MaxFiles: 3})
+	appendFile := func(p, data string) error {
+		_, err := w.WriteFile("g1", p, []byte(data), true)
+		return err
+	}
+	writeFile := func(p, data string) error {
+		_, err := w.WriteFile("g1", p, []byte(data), false)
+		return err
+	}
+
+	steps := []struct {
+		name    string
+		do      func() error
+		wantErr bool
+	}{
+		{"file over MaxFileBytes", func() error { return writeFile("/a", "12345678901") }, true},
+		{"file within MaxFileBytes", func() error { return writeFile("/a", "12345678") }, false},
+		{"append past MaxFileBytes", func() error { return appendFile("/a", "123") }, true},
+		{"append up to MaxFileBytes", func() error { return appendFile("/a", "12") }, false},
+		{"second file", func() error { return writeFile("/b", "1234567890") }, false},
+		{"file past QuotaBytes", func() error { return writeFile("/c", "123456") }, true},
+		{"file up to QuotaBytes", func() error { return writeFile("/c", "12345") }, false},
+		{"append past QuotaBytes", func() error { return appendFile("/c", "1") }, true},
+		{"overwrite frees its old size", func() error { return writeFile("/a", "12345") }, false},
+		{"file past MaxFiles", func() error { return writeFile("/d", "1") }, true},
+		{"existing file at MaxFiles", func() error { return appendFile("/a", "1") }, false},
+	}
+	for _, step := range steps {
+		err := step.do()
+		if step.wantErr != (err != nil) {
+			t.Fatalf("%s: error = %v, want error %v", step.name, err, step.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrWorkspaceQuota) {
+			t.Fatalf("%s: error = %v, want ErrWorkspaceQuota", step.name, err)
+		}
+	}
+
+	if data, err := w.ReadFile("g1", "/a"); err != nil || string(data) != "123451" {
+		t.Errorf("ReadFile(/a) = %q, %v; want 123451", data, err)
+	}
+}
+
+func TestWorkspaceNotCreated(t *testing.T) {
+	w := newWorkspaces(t, WorkspaceConfig{})
+
+	if _, err := w.ReadFile("g1", "/notes.md"); err == nil || !strings.Contains(err.Error(), "no such file") {
+		t.Errorf("ReadFile() error = %v, want no such file", err)
+	}
+	entries, truncated, err := w.List("g1", "/", true, 10)
+	if err != nil || len(entries) != 0 || truncated {
+		t.Errorf("List(/) = %v, %v, %v; want empty", entries, truncated, err)
+	}
+	if _, _, err := w.List("g1", "/reports", false, 10); err == nil || !strings.Contains(err.Error(), "no such file") {
+		t.Errorf("List(/reports) error = %v, want no such file", err)
+	}
+	if err := w.Walk(context.Background(), "g1", "/", func(string, []byte) bool {
+		t.Error("Walk() visited a file")
+		return true
+	}); err != nil {
+		t.Errorf("Walk() error = %v", err)
+	}
+
+	// Reads don't create the workspace, nor the directory holding them
+	if _, err := os.Stat(w.Dir()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("workspace directory exists after reads: %v", err)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"/reports/*.md", "/reports/q1.md", true},
+		{"/reports/*.md", "/reports/2024/q1.md", false},
+		{"reports/*.md", "/reports/q1.md", true},
+		{"/reports/**/*.md", "/reports/q1.md", true},
+		{"/reports/**/*.md", "/reports/2024/q1/summary.md", true},
+		{"/reports/**/*.md", "/other/q1.md", false},
+		{"/**", "/a/b/c.txt", true},
+		{"**/*.csv", "/data.csv", true},
+		{"/*.txt", "/a/b.txt", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			if got := matchGlob(tt.pattern, tt.path); got != tt.want {
+				t.Errorf("matchGlob() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkspaceExport(t *testing.T) {
+	w := newWorkspaces(t, WorkspaceConfig{ArtifactInlineBytes: 10})
+	write(t, w, "g1", "/reports/a.md", "aaaaaa")
+	write(t, w, "g1", "/reports/sub/b.md", "bbbbbb")
+	write(t, w, "g1", "/reports/c.txt", "c")
+	write(t, w, "g1", "/bin.dat", "\xff\xfe")
+
+	artifacts, err := w.Export(context.Background(), "g1", []string{"/reports/**/*.md", "*.dat"})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	got := make(map[string]Artifact, len(artifacts))
+	for _, a := range artifacts {
+		got[a.Path] = a
+	}
+	var paths []string
+	for p := range got {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	if want := []string{"/bin.dat", "/reports/a.md", "/reports/sub/b.md"}; !reflect.DeepEqual(paths, want) {
+		t.Fatalf("exported %v, want %v", paths, want)
+	}
+
+	// The inline budget is shared: files past it are exported without content
+	if a := got["/bin.dat"]; a.Encoding != "base64" || a.Content != "//4=" {
+		t.Errorf("/bin.dat = %+v, want base64 content", a)
+	}
+	if a := got["/reports/a.md"]; a.Content != "aaaaaa" || a.Size != 6 || len(a.SHA256) != 64 {
+		t.Errorf("/reports/a.md = %+v, want inline content", a)
+	}
+	if a := got["/reports/sub/b.md"]; !a.Omitted || a.Content != "" || a.Size != 6 {
+		t.Errorf("/reports/sub/b.md = %+v, want content omitted past the budget", a)
+	}
+
+	// A graph without a workspace has no artifacts
+	if artifacts, err := w.Export(context.Background(), "g2", []string{"/**"}); err != nil || len(artifacts) != 0 {
+		t.Errorf("Export() without a workspace = %v, %v; want none", artifacts, err)
+	}
+}
+
+func TestWorkspaceSweep(t *testing.T) {
+	w := newWorkspaces(t, WorkspaceConfig{})
+	graphs := []string{".hidden", "tenant/graph", "plain", "..", "a%2Fb"}
+	for _, g := range graphs {
+		write(t, w, g, "/f.txt", g)
+	}
+
+	entries, err := os.ReadDir(w.Dir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") || strings.ContainsAny(entry.Name(), `/\`) {
+			t.Errorf("workspace directory %q starts with a dot or has a separator", entry.Name())
+		}
+	}
+
+	var seen []string
+	removed, err := w.Sweep(context.Background(), func(ctx context.Context, graphID string) (bool, error) {
+		seen = append(seen, graphID)
+		return graphID != "plain", nil
+	})
+	if err != nil {
+		t.Fatalf("Sweep() error = %v", err)
+	}
+	sort.Strings(seen)
+	want := append([]string(nil), graphs...)
+	sort.Strings(want)
+	if !reflect.DeepEqual(seen, want) {
+		t.Errorf("Sweep() saw graphs %v, want %v", seen, want)
+	}
+	if removed != len(graphs)-1 {
+		t.Errorf("Sweep() removed %d, want %d", removed, len(graphs)-1)
+	}
+	if data, err := w.ReadFile("plain", "/f.txt"); err != nil || string(data) != "plain" {
+		t.Errorf("ReadFile() of the kept graph = %q, %v", data, err)
+	}
+	if _, err := w.ReadFile(".hidden", "/f.txt"); err == nil {
+		t.Error("workspace of a swept graph still readable")
+	}
+}
@@ -2,44 +2,567 @@ package mcp
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 	"go.uber.org/zap"
 )
 
-// Client implements MCP (Model Context Protocol) client for tool execution
+// ServerConfig defines an MCP server reachable by URL or launched as a
+// command (stdio transport)
+type ServerConfig struct {
+	Name    string
+	URL     string
+	Command string
+	Args    []string
+	Env     map[string]string
+}
+
+// Client implements MCP (Model Context Protocol) client for tool execution.
+// It connects to each server on first use and keeps the session open:
+// command servers run as child processes until Close or until they are
+// reconfigured.
 type Client struct {
-	servers []string
-	logger  *zap.Logger
+	servers   map[string]ServerConfig
+	order     []string
+	listeners []func(server string)
+	mu        sync.RWMutex
+	logger    *zap.Logger
+
+	httpClient *http.Client
+
+	connsMu sync.Mutex
+	conns   map[string]*conn
+
+	defsMu      sync.RWMutex
+	definitions map[string][]domain.Tool
 }
 
+// conn holds the session of one server; mu serializes connecting
+type conn struct {
+	mu      sync.Mutex
+	session *session
+}
+
+// ToolsListChanged is the notification an MCP server sends when its tools
+// change
+const ToolsListChanged = "notifications/tools/list_changed"
+
+// namespaceSeparator joins a server name and a tool name
+const namespaceSeparator = "__"
+
+// maxToolPages bounds the pages of a tools/list
+const maxToolPages = 100
+
 // NewClient creates a new MCP client
-func NewClient(servers []string, logger *zap.Logger) *Client {
-	return &Client{
-		servers: servers,
-		logger:  logger,
+func NewClient(servers []ServerConfig, logger *zap.Logger) *Client {
+	c := &Client{
+		logger:      logger,
+		httpClient:  &http.Client{},
+		conns:       make(map[string]*conn),
+		definitions: make(map[string][]domain.Tool),
 	}
+	c.SetServers(servers)
+	return c
 }
 
-// Execute executes a tool via MCP
-func (c *Client) Execute(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error) {
+// SetServers replaces the configured MCP servers. Sessions of servers that
+// were removed or changed are closed.
+func (c *Client) SetServers(servers []ServerConfig) {
+	byName := make(map[string]ServerConfig, len(servers))
+	order := make([]string, 0, len(servers))
+	for _, server := range servers {
+		byName[server.Name] = server
+		order = append(order, server.Name)
+	}
+
+	c.mu.Lock()
+	var stale []string
+	for name, server := range c.servers {
+		if updated, ok := byName[name]; !ok || !reflect.DeepEqual(server, updated) {
+			stale = append(stale, name)
+		}
+	}
+	c.servers = byName
+	c.order = order
+	c.mu.Unlock()
+
+	for _, name := range stale {
+		c.disconnect(name)
+	}
+
+	c.logger.Info("MCP servers configured", zap.Strings("servers", order))
+	c.toolsChanged("")
+}
+
+// Close closes the sessions of all servers, stopping command servers
+func (c *Client) Close() error {
+	c.connsMu.Lock()
+	names := make([]string, 0, len(c.conns))
+	for name := range c.conns {
+		names = append(names, name)
+	}
+	c.connsMu.Unlock()
+
+	for _, name := range names {
+		c.disconnect(name)
+	}
+	return nil
+}
+
+// OnToolsChanged registers a function called when the tools of a server
+// change, or of all servers when server is empty
+func (c *Client) OnToolsChanged(fn func(server string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listeners = append(c.listeners, fn)
+}
+
+// HandleNotification processes a notification received from a server. The
+// transport dispatches server notifications here; tools/list_changed
+// notifies the OnToolsChanged listeners.
+func (c *Client) HandleNotification(server, method string) {
+	if method != ToolsListChanged {
+		return
+	}
+	c.logger.Info("MCP server tools changed", zap.String("server", server))
+	c.toolsChanged(server)
+}
+
+// toolsChanged notifies the listeners
+func (c *Client) toolsChanged(server string) {
+	c.mu.RLock()
+	listeners := append([]func(string){}, c.listeners...)
+	c.mu.RUnlock()
+
+	for _, fn := range listeners {
+		fn(server)
+	}
+}
+
+// Servers returns the names of the configured MCP servers
+func (c *Client) Servers() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, len(c.order))
+	copy(names, c.order)
+	return names
+}
+
+// server looks up a server by name
+func (c *Client) server(name string) (ServerConfig, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	server, ok := c.servers[name]
+	if !ok {
+		return ServerConfig{}, fmt.Errorf("unknown MCP server: %s", name)
+	}
+	return server, nil
+}
+
+// conn returns the connection slot of a server
+func (c *Client) conn(name string) *conn {
+	c.connsMu.Lock()
+	defer c.connsMu.Unlock()
+
+	cn, ok := c.conns[name]
+	if !ok {
+		cn = &conn{}
+		c.conns[name] = cn
+	}
+	return cn
+}
+
+// session returns the session of a server, connecting if needed
+func (c *Client) session(ctx context.Context, name string) (*session, error) {
+	server, err := c.server(name)
+	if err != nil {
+		return nil, err
+	}
+
+	cn := c.conn(name)
+	cn.mu.Lock()
+	defer cn.mu.Unlock()
+	if cn.session != nil {
+		return cn.session, nil
+	}
+
+	s, err := c.connect(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+	cn.session = s
+	return s, nil
+}
+
+// connect starts a session with a server
+func (c *Client) connect(ctx context.Context, server ServerConfig) (*session, error) {
+	logger := c.logger.With(zap.String("server", server.Name))
+	s := &session{server: server}
+	handler := func(msg *message) { c.handleMessage(s, msg) }
+
+	switch {
+	case server.Command != "":
+		t, err := startStdio(server, handler, logger)
+		if err != nil {
+			return nil, err
+		}
+		s.transport = t
+	case isHTTPURL(server.URL):
+		s.transport = newHTTPTransport(server.URL, c.httpClient, handler, logger)
+	default:
+		return nil, fmt.Errorf("MCP server URL must be http or https: %s", server.URL)
+	}
+
+	info, err := s.initialize(ctx)
+	if err != nil {
+		_ = s.transport.close()
+		return nil, err
+	}
+	if t, ok := s.transport.(*httpTransport); ok {
+		go t.listen()
+	}
+
+	logger.Info("connected to MCP server",
+		zap.String("server_name", info.ServerInfo.Name),
+		zap.String("server_version", info.ServerInfo.Version),
+		zap.String("protocol_version", info.ProtocolVersion))
+	return s, nil
+}
+
+// disconnect closes the session of a server, if any
+func (c *Client) disconnect(name string) {
+	c.drop(name, nil)
+}
+
+// drop closes the session of a server if it is s, or whatever it is when
+// s is nil
+func (c *Client) drop(name string, s *session) {
+	cn := c.conn(name)
+	cn.mu.Lock()
+	current := cn.session
+	if current == nil || (s != nil && current != s) {
+		cn.mu.Unlock()
+		return
+	}
+	cn.session = nil
+	cn.mu.Unlock()
+
+	if err := current.transport.close(); err != nil {
+		c.logger.Debug("failed to close MCP session", zap.String("server", name), zap.Error(err))
+	}
+}
+
+// do runs fn with the session of a server. A session the server expired
+// is replaced and fn retried, since the server did not process it; a lost
+// connection is dropped so the next call reconnects.
+func (c *Client) do(ctx context.Context, name string, fn func(s *session) error) error {
+	s, err := c.session(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	err = fn(s)
+	switch {
+	case errors.Is(err, errSessionExpired):
+		c.drop(name, s)
+		if s, err = c.session(ctx, name); err != nil {
+			return err
+		}
+		return fn(s)
+	case errors.Is(err, ErrConnectionLost):
+		c.drop(name, s)
+	}
+	return err
+}
+
+// handleMessage handles a notification or request a server sent
+func (c *Client) handleMessage(s *session, msg *message) {
+	if !msg.isRequest() {
+		c.HandleNotification(s.server.Name, msg.Method)
+		return
+	}
+
+	// The client declares no capabilities, so servers may only ping it
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), toolCallReplyTimeout)
+		defer cancel()
+
+		var err error
+		if msg.Method == "ping" {
+			err = s.reply(ctx, msg, struct{}{}, nil)
+		} else {
+			err = s.reply(ctx, msg, nil, &RPCError{Code: codeMethodNotFound, Message: "method not supported: " + msg.Method})
+		}
+		if err != nil {
+			c.logger.Debug("failed to answer MCP server request",
+				zap.String("server", s.server.Name),
+				zap.String("method", msg.Method),
+				zap.Error(err))
+		}
+	}()
+}
+
+// toolCallReplyTimeout bounds an answer to a server request
+const toolCallReplyTimeout = 10 * time.Second
+
+// ToolError is a failure reported by an MCP tool, as opposed to a failure
+// to reach its server
+type ToolError struct {
+	Server  string
+	Tool    string
+	Message string
+}
+
+func (e *ToolError) Error() string {
+	return fmt.Sprintf("MCP tool %s of %s failed: %s", e.Tool, e.Server, e.Message)
+}
+
+// callToolResult is the result of tools/call
+type callToolResult struct {
+	Content           []map[string]interface{} `json:"content"`
+	StructuredContent interface{}              `json:"structuredContent"`
+	IsError           bool                     `json:"isError"`
+}
+
+// Call executes a tool of one MCP server. The result is the tool's
+// structured content if it has some, its text if all its content is text,
+// and its content items otherwise.
+func (c *Client) Call(ctx context.Context, server, tool string, params map[string]interface{}) (interface{}, error) {
 	c.logger.Debug("executing tool via MCP",
-		zap.String("tool", toolName),
-		zap.Any("params", params))
+		zap.String("server", server),
+		zap.String("tool", tool))
+
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	var result callToolResult
+	err := c.do(ctx, server, func(s *session) error {
+		request := map[string]interface{}{"name": tool, "arguments": params}
+		if meta := traceMeta(ctx); meta != nil {
+			request["_meta"] = meta
+		}
+		return s.call(ctx, "tools/call", request, &result)
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	// MVP: Basic MCP implementation
-	// TODO: Implement full MCP protocol once SDK is available
+	text, allText := contentText(result.Content)
+	if result.IsError {
+		return nil, &ToolError{Server: server, Tool: tool, Message: text}
+	}
+	switch {
+	case result.StructuredContent != nil:
+		return result.StructuredContent, nil
+	case allText:
+		return text, nil
+	default:
+		return result.Content, nil
+	}
+}
 
-	return nil, fmt.Errorf("MCP execution not yet implemented (MVP)")
+// traceMeta returns the W3C trace context of the span in ctx, for the
+// _meta of a request, or nil if there is none. Command servers can't get
+// it from HTTP headers.
+func traceMeta(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
 }
 
-// ListTools lists available tools from MCP servers
-func (c *Client) ListTools(ctx context.Context) ([]string, error) {
+// contentText joins the text items of tool content, reporting whether
+// there were only text items
+func contentText(content []map[string]interface{}) (string, bool) {
+	var texts []string
+	allText := true
+	for _, item := range content {
+		text, ok := item["text"].(string)
+		if item["type"] != "text" || !ok {
+			allText = false
+			continue
+		}
+		texts = append(texts, text)
+	}
+	return strings.Join(texts, "\n"), allText
+}
+
+// Execute executes a tool named <server>__<tool> via MCP
+func (c *Client) Execute(ctx context.Context, toolName string, params map[string]interface{}) (interface{}, error) {
+	server, tool, ok := strings.Cut(toolName, namespaceSeparator)
+	if !ok {
+		return nil, fmt.Errorf("MCP tool name %q lacks a server prefix", toolName)
+	}
+	return c.Call(ctx, server, tool, params)
+}
+
+// Tools lists the tools of each MCP server. Servers that fail to answer
+// are left out and reported in the error.
+func (c *Client) Tools(ctx context.Context) (map[string][]string, error) {
+	servers := c.Servers()
+
 	c.logger.Debug("listing tools from MCP servers",
-		zap.Int("server_count", len(c.servers)))
+		zap.Int("server_count", len(servers)))
 
-	// MVP: Return empty list
-	// TODO: Implement tool discovery via MCP
+	tools := make(map[string][]string, len(servers))
+	var errs []error
+	for _, server := range servers {
+		serverTools, err := c.ListServerTools(ctx, server)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("MCP server %s: %w", server, err))
+			continue
+		}
+		tools[server] = serverTools
+	}
 
-	return []string{}, nil
+	return tools, errors.Join(errs...)
+}
+
+// ListTools lists the tools of all MCP servers as <server>__<tool>
+func (c *Client) ListTools(ctx context.Context) ([]string, error) {
+	byServer, err := c.Tools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var tools []string
+	for _, server := range c.Servers() {
+		for _, tool := range byServer[server] {
+			tools = append(tools, server+namespaceSeparator+tool)
+		}
+	}
+	return tools, nil
+}
+
+// listToolsResult is a page of tools/list
+type listToolsResult struct {
+	Tools []struct {
+		Name        string                 `json:"name"`
+		Description string                 `json:"description"`
+		InputSchema map[string]interface{} `json:"inputSchema"`
+	} `json:"tools"`
+	NextCursor string `json:"nextCursor"`
+}
+
+// ListServerTools lists the tools exposed by a single MCP server, following
+// the pages of tools/list, and keeps their definitions
+func (c *Client) ListServerTools(ctx context.Context, server string) ([]string, error) {
+	var names []string
+	var defs []domain.Tool
+	cursor := ""
+	for page := 0; ; page++ {
+		if page == maxToolPages {
+			return nil, fmt.Errorf("tools/list has more than %d pages", maxToolPages)
+		}
+
+		params := map[string]interface{}{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		var result listToolsResult
+		err := c.do(ctx, server, func(s *session) error {
+			return s.call(ctx, "tools/list", params, &result)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tool := range result.Tools {
+			names = append(names, tool.Name)
+			defs = append(defs, domain.Tool{Name: tool.Name, Description: tool.Description, Parameters: tool.InputSchema})
+		}
+		if result.NextCursor == "" {
+			break
+		}
+		cursor = result.NextCursor
+	}
+
+	c.defsMu.Lock()
+	c.definitions[server] = defs
+	c.defsMu.Unlock()
+	return names, nil
+}
+
+// Definitions returns the definitions of the tools last listed, named
+// <server>__<tool> and sorted by name
+func (c *Client) Definitions() []domain.Tool {
+	servers := make(map[string]bool)
+	for _, server := range c.Servers() {
+		servers[server] = true
+	}
+
+	c.defsMu.RLock()
+	defer c.defsMu.RUnlock()
+
+	var defs []domain.Tool
+	for server, serverDefs := range c.definitions {
+		if !servers[server] {
+			continue
+		}
+		for _, def := range serverDefs {
+			def.Name = server + namespaceSeparator + def.Name
+			defs = append(defs, def)
+		}
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs
+}
+
+// isHTTPURL reports whether a server URL uses HTTP(S)
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Ping checks that an MCP server is reachable. HTTP(S) servers are probed
+// with a GET request and command servers by resolving the command.
+func (c *Client) Ping(ctx context.Context, name string) error {
+	server, err := c.server(name)
+	if err != nil {
+		return err
+	}
+
+	if server.Command != "" {
+		if _, err := exec.LookPath(server.Command); err != nil {
+			return fmt.Errorf("MCP server command not found: %w", err)
+		}
+		return nil
+	}
+
+	if !isHTTPURL(server.URL) {
+		return fmt.Errorf("MCP server URL must be http or https: %s", server.URL)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		return fmt.Errorf("invalid MCP server URL: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+
+	// Any response means the server is up; MCP endpoints commonly reject
+	// plain GETs
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("MCP server returned status %d", resp.StatusCode)
+	}
+	return nil
 }
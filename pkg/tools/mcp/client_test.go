@@ -0,0 +1,347 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// fakeServer answers MCP requests the way a server with three tools
+// would: echo returns its arguments as text, fail reports a tool error and
+// touch changes the tool list, notifying the client before answering
+type fakeServer struct{}
+
+// handle returns the notifications to send, then the response, for msg
+func (fakeServer) handle(msg *message) ([]*message, *message) {
+	if !msg.isRequest() {
+		return nil, nil
+	}
+	result := func(v interface{}) *message {
+		data, _ := json.Marshal(v)
+		return &message{JSONRPC: "2.0", ID: msg.ID, Result: data}
+	}
+
+	var params struct {
+		Cursor    string                 `json:"cursor"`
+		Name      string                 `json:"name"`
+		Arguments map[string]interface{} `json:"arguments"`
+	}
+	_ = json.Unmarshal(msg.Params, &params)
+
+	switch msg.Method {
+	case "initialize":
+		return nil, result(map[string]interface{}{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{"listChanged": true}},
+			"serverInfo":      map[string]string{"name": "fake", "version": "1"},
+		})
+	case "tools/list":
+		// Two pages
+		if params.Cursor == "" {
+			return nil, result(map[string]interface{}{
+				"tools":      []map[string]interface{}{{"name": "echo", "description": "Echo", "inputSchema": map[string]interface{}{"type": "object"}}},
+				"nextCursor": "2",
+			})
+		}
+		return nil, result(map[string]interface{}{
+			"tools": []map[string]interface{}{{"name": "fail"}, {"name": "touch"}},
+		})
+	case "tools/call":
+		switch params.Name {
+		case "echo":
+			text, _ := json.Marshal(params.Arguments)
+			return nil, result(map[string]interface{}{"content": []map[string]interface{}{{"type": "text", "text": string(text)}}})
+		case "fail":
+			return nil, result(map[string]interface{}{"content": []map[string]interface{}{{"type": "text", "text": "boom"}}, "isError": true})
+		case "touch":
+			changed, _ := newMessage(nil, ToolsListChanged, nil)
+			return []*message{changed}, result(map[string]interface{}{"structuredContent": map[string]interface{}{"touched": true}})
+		}
+		return nil, &message{JSONRPC: "2.0", ID: msg.ID, Error: &RPCError{Code: -32602, Message: "unknown tool"}}
+	}
+	return nil, &message{JSONRPC: "2.0", ID: msg.ID, Error: &RPCError{Code: codeMethodNotFound, Message: "not found"}}
+}
+
+// newHTTPServer serves fakeServer over the streamable HTTP transport,
+// answering tools/call with event streams
+func newHTTPServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	var server fakeServer
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		case http.MethodDelete:
+			return
+		}
+
+		var msg message
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if msg.Method == "initialize" {
+			w.Header().Set(headerSessionID, "s1")
+		} else if r.Header.Get(headerSessionID) != "s1" || r.Header.Get(headerProtocolVersion) != ProtocolVersion {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		notifications, resp := server.handle(&msg)
+		if resp == nil {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		if msg.Method != "tools/call" {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range append(notifications, resp) {
+			data, _ := json.Marshal(event)
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestHelperProcess serves fakeServer over stdio when run as a command
+// server by the tests
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("MCP_HELPER_PROCESS") != "1" {
+		return
+	}
+	var server fakeServer
+	scanner := bufio.NewScanner(os.Stdin)
+	encoder := json.NewEncoder(os.Stdout)
+	for scanner.Scan() {
+		var msg message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			continue
+		}
+		notifications, resp := server.handle(&msg)
+		for _, notification := range notifications {
+			_ = encoder.Encode(notification)
+		}
+		if resp != nil {
+			_ = encoder.Encode(resp)
+		}
+	}
+	os.Exit(0)
+}
+
+func TestClient(t *testing.T) {
+	servers := map[string]ServerConfig{
+		"http": {Name: "fake", URL: newHTTPServer(t).URL},
+		"stdio": {
+			Name:    "fake",
+			Command: os.Args[0],
+			Args:    []string{"-test.run=^TestHelperProcess$"},
+			Env:     map[string]string{"MCP_HELPER_PROCESS": "1"},
+		},
+	}
+
+	for transport, server := range servers {
+		t.Run(transport, func(t *testing.T) {
+			c := NewClient([]ServerConfig{server}, zap.NewNop())
+			defer c.Close()
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			tools, err := c.ListTools(ctx)
+			if err != nil {
+				t.Fatalf("ListTools() error = %v", err)
+			}
+			if want := []string{"fake__echo", "fake__fail", "fake__touch"}; !reflect.DeepEqual(tools, want) {
+				t.Errorf("ListTools() = %v, want %v", tools, want)
+			}
+			if defs := c.Definitions(); len(defs) != 3 || defs[0].Name != "fake__echo" || defs[0].Parameters["type"] != "object" {
+				t.Errorf("Definitions() = %+v, want the listed tools with their schemas", defs)
+			}
+
+			got, err := c.Execute(ctx, "fake__echo", map[string]interface{}{"q": "go"})
+			if err != nil || got != `{"q":"go"}` {
+				t.Errorf("Execute(echo) = %v, %v; want the arguments as text", got, err)
+			}
+
+			var toolErr *ToolError
+			if _, err := c.Execute(ctx, "fake__fail", nil); !errors.As(err, &toolErr) || toolErr.Message != "boom" {
+				t.Errorf("Execute(fail) error = %v, want a ToolError", err)
+			}
+
+			var rpcErr *RPCError
+			if _, err := c.Execute(ctx, "fake__missing", nil); !errors.As(err, &rpcErr) {
+				t.Errorf("Execute(missing) error = %v, want an RPCError", err)
+			}
+
+			changed := make(chan string, 1)
+			c.OnToolsChanged(func(server string) {
+				select {
+				case changed <- server:
+				default:
+				}
+			})
+			got, err = c.Execute(ctx, "fake__touch", nil)
+			if err != nil || !reflect.DeepEqual(got, map[string]interface{}{"touched": true}) {
+				t.Errorf("Execute(touch) = %v, %v; want its structured content", got, err)
+			}
+			select {
+			case server := <-changed:
+				if server != "fake" {
+					t.Errorf("tools changed for %q, want fake", server)
+				}
+			case <-ctx.Done():
+				t.Fatal("tools/list_changed was not delivered")
+			}
+		})
+	}
+}
+
+func TestClientReconnects(t *testing.T) {
+	var mu sync.Mutex
+	sessions := 0
+	expire := false
+	var server fakeServer
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var msg message
+		_ = json.NewDecoder(r.Body).Decode(&msg)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if msg.Method == "initialize" {
+			sessions++
+			w.Header().Set(headerSessionID, fmt.Sprint(sessions))
+		} else if expire && r.Header.Get(headerSessionID) == "1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if _, resp := server.handle(&msg); resp != nil {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	c := NewClient([]ServerConfig{{Name: "fake", URL: srv.URL}}, zap.NewNop())
+	defer c.Close()
+	ctx := context.Background()
+
+	if _, err := c.Execute(ctx, "fake__echo", nil); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	mu.Lock()
+	expire = true
+	mu.Unlock()
+	if _, err := c.Execute(ctx, "fake__echo", nil); err != nil {
+		t.Fatalf("Execute() after the session expired error = %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if sessions != 2 {
+		t.Errorf("sessions = %d, want a new session after expiry", sessions)
+	}
+}
+
+func TestCallPropagatesTrace(t *testing.T) {
+	previous := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(previous)
+
+	var mu sync.Mutex
+	var header, meta string
+	var server fakeServer
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var msg message
+		_ = json.NewDecoder(r.Body).Decode(&msg)
+		if msg.Method == "tools/call" {
+			var params struct {
+				Meta map[string]string `json:"_meta"`
+			}
+			_ = json.Unmarshal(msg.Params, &params)
+			mu.Lock()
+			header, meta = r.Header.Get("traceparent"), params.Meta["traceparent"]
+			mu.Unlock()
+		}
+		if _, resp := server.handle(&msg); resp != nil {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	c := NewClient([]ServerConfig{{Name: "fake", URL: srv.URL}}, zap.NewNop())
+	defer c.Close()
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	if _, err := c.Execute(ctx, "fake__echo", nil); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	want := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	mu.Lock()
+	defer mu.Unlock()
+	if header != want {
+		t.Errorf("traceparent header = %q, want %q", header, want)
+	}
+	if meta != want {
+		t.Errorf("_meta traceparent = %q, want %q", meta, want)
+	}
+}
+
+func TestReadEvents(t *testing.T) {
+	stream := ": comment\n" +
+		"event: message\n" +
+		"data: {\"jsonrpc\":\"2.0\",\n" +
+		"data: \"method\":\"a\"}\n" +
+		"\n" +
+		"data: not json\n\n" +
+		"data: {\"jsonrpc\":\"2.0\",\"method\":\"b\"}\r\n\r\n"
+
+	var methods []string
+	err := readEvents(strings.NewReader(stream), func(msg *message) bool {
+		methods = append(methods, msg.Method)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("readEvents() error = %v", err)
+	}
+	if !reflect.DeepEqual(methods, []string{"a", "b"}) {
+		t.Errorf("methods = %v, want [a b]", methods)
+	}
+}
@@ -1,7 +1,14 @@
 // Package mcp provides MCP (Model Context Protocol) client implementation.
 //
 // MCP is the primary tool integration method for the executor.
-// Tools are discovered and executed via MCP servers.
+// Tools are discovered and executed via MCP servers. Each server is a
+// namespace: its tools are exposed to nodes as <server>__<tool>.
 //
-// MVP Note: Full MCP implementation pending SDK availability.
+// Servers configured with a command run as child processes speaking
+// JSON-RPC over stdio; servers configured with an http(s) URL use the
+// streamable HTTP transport. The client connects to a server on first use,
+// keeps its session open and reconnects when the session is lost. A
+// tools/list_changed notification from a server, received on the stdio
+// pipe, a response event stream or the HTTP notification stream, calls the
+// OnToolsChanged listeners.
 package mcp
@@ -0,0 +1,306 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.uber.org/zap"
+)
+
+// HTTP headers of the streamable HTTP transport
+const (
+	headerSessionID       = "Mcp-Session-Id"
+	headerProtocolVersion = "MCP-Protocol-Version"
+)
+
+// Reconnection delays of the notification stream
+const (
+	minListenBackoff = time.Second
+	maxListenBackoff = 30 * time.Second
+)
+
+// maxHTTPErrorBytes caps the response body quoted in errors
+const maxHTTPErrorBytes = 512
+
+// httpTransport speaks the MCP streamable HTTP transport: each message is
+// POSTed to the server URL, which answers with JSON or with an event
+// stream carrying the response, and a GET opens a stream of the server's
+// own notifications
+type httpTransport struct {
+	url     string
+	client  *http.Client
+	handler func(*message)
+	logger  *zap.Logger
+
+	mu              sync.Mutex
+	sessionID       string
+	protocolVersion string
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// newHTTPTransport creates a transport for a server URL
+func newHTTPTransport(url string, client *http.Client, handler func(*message), logger *zap.Logger) *httpTransport {
+	return &httpTransport{
+		url:     url,
+		client:  client,
+		handler: handler,
+		logger:  logger,
+		stop:    make(chan struct{}),
+	}
+}
+
+// setProtocolVersion sets the version sent with requests after
+// initialization
+func (t *httpTransport) setProtocolVersion(version string) {
+	t.mu.Lock()
+	t.protocolVersion = version
+	t.mu.Unlock()
+}
+
+// newRequest creates a request carrying the session headers
+func (t *httpTransport) newRequest(ctx context.Context, method string, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, t.url, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	if t.sessionID != "" {
+		req.Header.Set(headerSessionID, t.sessionID)
+	}
+	if t.protocolVersion != "" {
+		req.Header.Set(headerProtocolVersion, t.protocolVersion)
+	}
+	t.mu.Unlock()
+	return req, nil
+}
+
+// post sends a message, returning the response once its status is checked
+func (t *httpTransport) post(ctx context.Context, msg *message) (*http.Response, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+	req, err := t.newRequest(ctx, http.MethodPost, data)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if id := resp.Header.Get(headerSessionID); id != "" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+
+	if resp.StatusCode == http.StatusNotFound && req.Header.Get(headerSessionID) != "" {
+		_ = resp.Body.Close()
+		return nil, errSessionExpired
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxHTTPErrorBytes))
+		return nil, fmt.Errorf("MCP server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
+
+func (t *httpTransport) request(ctx context.Context, msg *message) (*message, error) {
+	resp, err := t.post(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if isEventStream(resp) {
+		var answer *message
+		err := readEvents(resp.Body, func(event *message) bool {
+			if event.isResponse() && string(event.ID) == string(msg.ID) {
+				answer = event
+				return false
+			}
+			t.dispatch(event)
+			return true
+		})
+		if answer != nil {
+			return answer, nil
+		}
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("%w: %v", ErrConnectionLost, err)
+	}
+
+	var answer message
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+		return nil, fmt.Errorf("invalid MCP response: %w", err)
+	}
+	return &answer, nil
+}
+
+func (t *httpTransport) send(ctx context.Context, msg *message) error {
+	resp, err := t.post(ctx, msg)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+// dispatch hands a message the server sent on its own to the handler
+func (t *httpTransport) dispatch(msg *message) {
+	if msg.Method != "" {
+		t.handler(msg)
+	}
+}
+
+// listen reads the server's notification stream until the transport
+// closes, reconnecting with backoff. Servers without one answer 405.
+func (t *httpTransport) listen() {
+	backoff := minListenBackoff
+	for {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			select {
+			case <-t.stop:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		supported, err := t.listenOnce(ctx)
+		cancel()
+		if !supported {
+			return
+		}
+		if err == nil {
+			backoff = minListenBackoff
+		} else {
+			t.logger.Debug("MCP notification stream interrupted", zap.Error(err))
+		}
+
+		select {
+		case <-t.stop:
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxListenBackoff)
+	}
+}
+
+// listenOnce reads the notification stream once. It reports false when
+// the server has no stream.
+func (t *httpTransport) listenOnce(ctx context.Context) (bool, error) {
+	req, err := t.newRequest(ctx, http.MethodGet, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusMethodNotAllowed:
+		return false, nil
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return true, fmt.Errorf("status %d", resp.StatusCode)
+	case !isEventStream(resp):
+		return false, nil
+	}
+
+	return true, readEvents(resp.Body, func(msg *message) bool {
+		t.dispatch(msg)
+		return true
+	})
+}
+
+func (t *httpTransport) close() error {
+	t.stopOnce.Do(func() { close(t.stop) })
+
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	if sessionID == "" {
+		return nil
+	}
+
+	// Let the server release the session
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := t.newRequest(ctx, http.MethodDelete, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// isEventStream reports whether a response is a server-sent event stream
+func isEventStream(resp *http.Response) bool {
+	return strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")
+}
+
+// readEvents decodes the JSON-RPC messages of a server-sent event stream
+// and passes them to fn until it returns false or the stream ends
+func readEvents(body io.Reader, fn func(*message) bool) error {
+	reader := bufio.NewReader(body)
+	var data strings.Builder
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line != "" {
+			if value, ok := strings.CutPrefix(line, "data:"); ok {
+				if data.Len() > 0 {
+					data.WriteByte('\n')
+				}
+				data.WriteString(strings.TrimPrefix(value, " "))
+			}
+			continue
+		}
+
+		// A blank line ends the event
+		if data.Len() == 0 {
+			continue
+		}
+		var msg message
+		if jsonErr := json.Unmarshal([]byte(data.String()), &msg); jsonErr == nil && !fn(&msg) {
+			return nil
+		}
+		data.Reset()
+	}
+}
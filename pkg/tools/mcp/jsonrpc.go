@@ -0,0 +1,87 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrConnectionLost is returned for requests whose server connection
+// closed before they were answered
+var ErrConnectionLost = errors.New("MCP server connection lost")
+
+// errSessionExpired is returned by transports whose server no longer
+// knows the session; the request was not processed
+var errSessionExpired = errors.New("MCP session expired")
+
+// message is a JSON-RPC 2.0 request, notification or response
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// isResponse reports whether the message answers a request
+func (m *message) isResponse() bool {
+	return m.Method == "" && len(m.ID) > 0
+}
+
+// isRequest reports whether the message is a request from the server,
+// which expects a response
+func (m *message) isRequest() bool {
+	return m.Method != "" && len(m.ID) > 0
+}
+
+// newMessage creates a request, or a notification when id is nil
+func newMessage(id json.RawMessage, method string, params interface{}) (*message, error) {
+	msg := &message{JSONRPC: "2.0", ID: id, Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s params: %w", method, err)
+		}
+		msg.Params = data
+	}
+	return msg, nil
+}
+
+// RPCError is an error returned by an MCP server
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("MCP error %d: %s", e.Code, e.Message)
+}
+
+// InvalidCall reports whether the server rejected the request itself, as
+// an unknown method or with invalid params
+func (e *RPCError) InvalidCall() bool {
+	return e.Code == codeMethodNotFound || e.Code == codeInvalidParams
+}
+
+// JSON-RPC error codes
+const (
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// transport carries messages to and from one MCP server. Messages the
+// server sends on its own, notifications and requests, go to the handler
+// given when the transport was created.
+type transport interface {
+	// request sends a request and waits for its response
+	request(ctx context.Context, msg *message) (*message, error)
+
+	// send sends a notification or a response
+	send(ctx context.Context, msg *message) error
+
+	// close releases the connection, stopping a command server
+	close() error
+}
@@ -0,0 +1,106 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+)
+
+// ProtocolVersion is the MCP revision the client asks for. Servers may
+// answer with an older one they support.
+const ProtocolVersion = "2025-06-18"
+
+// clientName identifies the client to servers
+const clientName = "dago-node-executor"
+
+// session is an initialized connection to one MCP server
+type session struct {
+	server    ServerConfig
+	transport transport
+	nextID    atomic.Int64
+
+	// protocolVersion is the revision agreed with the server
+	protocolVersion string
+}
+
+// initializeResult is the part of the initialize result the client uses
+type initializeResult struct {
+	ProtocolVersion string `json:"protocolVersion"`
+	ServerInfo      struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"serverInfo"`
+}
+
+// initialize performs the MCP handshake
+func (s *session) initialize(ctx context.Context) (*initializeResult, error) {
+	var result initializeResult
+	err := s.call(ctx, "initialize", map[string]interface{}{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      map[string]string{"name": clientName, "version": "1.0.0"},
+	}, &result)
+	if err != nil {
+		return nil, fmt.Errorf("initialize failed: %w", err)
+	}
+	if result.ProtocolVersion == "" {
+		return nil, fmt.Errorf("initialize failed: no protocol version in the answer")
+	}
+	s.protocolVersion = result.ProtocolVersion
+	if t, ok := s.transport.(*httpTransport); ok {
+		t.setProtocolVersion(result.ProtocolVersion)
+	}
+
+	if err := s.notify(ctx, "notifications/initialized", nil); err != nil {
+		return nil, fmt.Errorf("initialize failed: %w", err)
+	}
+	return &result, nil
+}
+
+// call sends a request and decodes its result into result
+func (s *session) call(ctx context.Context, method string, params, result interface{}) error {
+	id := json.RawMessage(strconv.FormatInt(s.nextID.Add(1), 10))
+	msg, err := newMessage(id, method, params)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.transport.request(ctx, msg)
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("invalid %s result: %w", method, err)
+	}
+	return nil
+}
+
+// notify sends a notification
+func (s *session) notify(ctx context.Context, method string, params interface{}) error {
+	msg, err := newMessage(nil, method, params)
+	if err != nil {
+		return err
+	}
+	return s.transport.send(ctx, msg)
+}
+
+// reply answers a request of the server
+func (s *session) reply(ctx context.Context, req *message, result interface{}, rpcErr *RPCError) error {
+	msg := &message{JSONRPC: "2.0", ID: req.ID, Error: rpcErr}
+	if rpcErr == nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		msg.Result = data
+	}
+	return s.transport.send(ctx, msg)
+}
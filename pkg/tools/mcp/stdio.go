@@ -0,0 +1,217 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// stdioStopTimeout is how long a command server has to exit once its
+// stdin is closed before it is killed
+const stdioStopTimeout = 2 * time.Second
+
+// maxStdioMessageBytes caps a message read from a command server
+const maxStdioMessageBytes = 16 << 20
+
+// stdioTransport runs an MCP server as a child process and exchanges
+// newline-delimited JSON-RPC messages over its stdin and stdout
+type stdioTransport struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	handler func(*message)
+	logger  *zap.Logger
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[string]chan *message
+	err     error
+
+	// done is closed when stdout closes, exited when the process exits
+	done   chan struct{}
+	exited chan struct{}
+}
+
+// startStdio starts a command server
+func startStdio(server ServerConfig, handler func(*message), logger *zap.Logger) (*stdioTransport, error) {
+	cmd := exec.Command(server.Command, server.Args...)
+	cmd.Env = os.Environ()
+	for key, value := range server.Env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start MCP server: %w", err)
+	}
+
+	t := &stdioTransport{
+		cmd:     cmd,
+		stdin:   stdin,
+		handler: handler,
+		logger:  logger,
+		pending: make(map[string]chan *message),
+		done:    make(chan struct{}),
+		exited:  make(chan struct{}),
+	}
+	go t.logStderr(stderr)
+	go t.read(stdout)
+	return t, nil
+}
+
+// read dispatches the messages of the server until its stdout closes
+func (t *stdioTransport) read(stdout io.Reader) {
+	reader := bufio.NewReaderSize(stdout, 64<<10)
+	var err error
+	for {
+		var line []byte
+		line, err = readLine(reader)
+		if err != nil {
+			break
+		}
+		if len(line) == 0 {
+			continue
+		}
+
+		var msg message
+		if jsonErr := json.Unmarshal(line, &msg); jsonErr != nil {
+			t.logger.Warn("invalid message from MCP server", zap.Error(jsonErr))
+			continue
+		}
+		if msg.isResponse() {
+			t.deliver(&msg)
+		} else if msg.Method != "" {
+			t.handler(&msg)
+		}
+	}
+
+	t.mu.Lock()
+	t.err = fmt.Errorf("%w: %v", ErrConnectionLost, err)
+	t.pending = nil
+	t.mu.Unlock()
+	close(t.done)
+
+	_ = t.cmd.Wait()
+	close(t.exited)
+}
+
+// readLine reads one line, failing on lines over maxStdioMessageBytes
+func readLine(reader *bufio.Reader) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, chunk...)
+		if len(line) > maxStdioMessageBytes {
+			return nil, fmt.Errorf("message larger than %d bytes", maxStdioMessageBytes)
+		}
+		if !isPrefix {
+			return line, nil
+		}
+	}
+}
+
+// deliver hands a response to the request waiting for it
+func (t *stdioTransport) deliver(msg *message) {
+	t.mu.Lock()
+	ch, ok := t.pending[string(msg.ID)]
+	delete(t.pending, string(msg.ID))
+	t.mu.Unlock()
+
+	if ok {
+		ch <- msg
+	}
+}
+
+// logStderr logs what the server writes to stderr
+func (t *stdioTransport) logStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		t.logger.Debug("MCP server stderr", zap.String("line", scanner.Text()))
+	}
+}
+
+func (t *stdioTransport) request(ctx context.Context, msg *message) (*message, error) {
+	ch := make(chan *message, 1)
+	id := string(msg.ID)
+
+	t.mu.Lock()
+	if t.pending == nil {
+		err := t.err
+		t.mu.Unlock()
+		return nil, err
+	}
+	t.pending[id] = ch
+	t.mu.Unlock()
+
+	if err := t.send(ctx, msg); err != nil {
+		t.forget(id)
+		return nil, err
+	}
+
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-t.done:
+		t.mu.Lock()
+		err := t.err
+		t.mu.Unlock()
+		return nil, err
+	case <-ctx.Done():
+		t.forget(id)
+		return nil, ctx.Err()
+	}
+}
+
+// forget drops a request that will not wait for its response
+func (t *stdioTransport) forget(id string) {
+	t.mu.Lock()
+	delete(t.pending, id)
+	t.mu.Unlock()
+}
+
+func (t *stdioTransport) send(ctx context.Context, msg *message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if _, err := t.stdin.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("%w: %v", ErrConnectionLost, err)
+	}
+	return nil
+}
+
+func (t *stdioTransport) close() error {
+	_ = t.stdin.Close()
+	select {
+	case <-t.exited:
+	case <-time.After(stdioStopTimeout):
+		_ = t.cmd.Process.Kill()
+		<-t.exited
+	}
+	return nil
+}